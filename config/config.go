@@ -1,13 +1,20 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/hkdf"
 )
 
 type DbConfig struct {
@@ -22,6 +29,8 @@ type ApiConfig struct {
 	ApiHost     string
 	ApiPort     string
 	FrontEndApi string
+	// TrustedProxies may set X-Forwarded-For, empty trusts none and the client ip is the remote address
+	TrustedProxies []string
 }
 
 type SMTPConfig struct {
@@ -33,10 +42,11 @@ type SMTPConfig struct {
 }
 
 type TokenConfig struct {
-	ApplicationName     string
-	JwtSignatureKey     string
-	JwtSigningMethod    *jwt.SigningMethodHMAC
-	AccessTokenLifeTime time.Duration
+	ApplicationName            string
+	JwtSignatureKey            string
+	JwtSigningMethod           *jwt.SigningMethodHMAC
+	AccessTokenLifeTime        time.Duration
+	ImpersonationTokenLifeTime time.Duration
 }
 
 type RedisConfig struct {
@@ -61,6 +71,44 @@ type WhatsAppConfig struct {
 
 type EncryptionConfig struct {
 	SecretKeyEncryption string
+	// FieldKeys maps a key version to its secret, only FieldKeyVersion is used to encrypt
+	FieldKeys       map[string]string
+	FieldKeyVersion string
+	BlindIndexKey   string
+}
+
+type TwoFactorConfig struct {
+	Issuer            string
+	RequiredRoles     []string
+	ChallengeLifeTime time.Duration
+	StepUpLifeTime    time.Duration
+	// EnrollmentLifeTime bounds the token a user who must enroll gets at login, it only reaches the enrollment routes
+	EnrollmentLifeTime time.Duration
+	// ChallengeAttempts is how many codes a login challenge takes before it is dropped
+	ChallengeAttempts int64
+}
+
+type RateLimitRule struct {
+	Capacity int
+	Period   time.Duration
+}
+
+type RateLimitConfig struct {
+	RateLimitEnabled bool
+	RateLimitRules   map[string]RateLimitRule
+}
+
+type JobConfig struct {
+	JobWorkers      int
+	JobPollInterval time.Duration
+	// running jobs without a heartbeat for this long are assumed to belong to a dead worker and are queued again
+	JobStaleAfter time.Duration
+	JobRetention  time.Duration
+}
+
+type PhaseLifecycleConfig struct {
+	// PhaseLifecycleInterval is how often phases are opened and closed by date, zero turns the ticker off
+	PhaseLifecycleInterval time.Duration
 }
 
 type Config struct {
@@ -72,6 +120,10 @@ type Config struct {
 	GoogleOAuthConfig
 	WhatsAppConfig
 	EncryptionConfig
+	TwoFactorConfig
+	RateLimitConfig
+	JobConfig
+	PhaseLifecycleConfig
 }
 
 func (c *Config) ReadConfigFile() error {
@@ -104,6 +156,12 @@ func (c *Config) ReadConfigFile() error {
 		c.ApiConfig.ApiPort = os.Getenv("PORT")
 	}
 
+	if os.Getenv("TRUSTED_PROXIES") != "" {
+		for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+			c.ApiConfig.TrustedProxies = append(c.ApiConfig.TrustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+
 	c.SMTPConfig = SMTPConfig{
 		SMTPHost:       os.Getenv("SMTP_HOST"),
 		SMTPPort:       os.Getenv("SMTP_PORT"),
@@ -113,10 +171,11 @@ func (c *Config) ReadConfigFile() error {
 	}
 
 	c.TokenConfig = TokenConfig{
-		ApplicationName:     "CalibrationSystem",
-		JwtSignatureKey:     "x/A?D(G+KaPdSgVkYp3s6v9y$B&E)H@M",
-		JwtSigningMethod:    jwt.SigningMethodHS256,
-		AccessTokenLifeTime: time.Hour * 2,
+		ApplicationName:            "CalibrationSystem",
+		JwtSignatureKey:            "x/A?D(G+KaPdSgVkYp3s6v9y$B&E)H@M",
+		JwtSigningMethod:           jwt.SigningMethodHS256,
+		AccessTokenLifeTime:        time.Hour * 2,
+		ImpersonationTokenLifeTime: time.Minute * 30,
 	}
 
 	c.RedisConfig = RedisConfig{
@@ -139,6 +198,118 @@ func (c *Config) ReadConfigFile() error {
 
 	c.EncryptionConfig = EncryptionConfig{
 		SecretKeyEncryption: os.Getenv("SECRET_KEY_ENCRYPTION"),
+		FieldKeys:           map[string]string{},
+		FieldKeyVersion:     "v1",
+	}
+
+	// without dedicated keys the field key and the blind index key are derived apart from the master secret, the
+	// AES-GCM key is never the HMAC key
+	if c.EncryptionConfig.SecretKeyEncryption != "" {
+		fieldKey, err := deriveKey(c.EncryptionConfig.SecretKeyEncryption, "field-encryption:v1")
+		if err != nil {
+			return err
+		}
+		blindIndexKey, err := deriveKey(c.EncryptionConfig.SecretKeyEncryption, "blind-index")
+		if err != nil {
+			return err
+		}
+		c.EncryptionConfig.FieldKeys["v1"] = fieldKey
+		c.EncryptionConfig.BlindIndexKey = blindIndexKey
+	}
+
+	if os.Getenv("FIELD_ENCRYPTION_KEYS") != "" {
+		fieldKeys, err := parseFieldKeys(os.Getenv("FIELD_ENCRYPTION_KEYS"))
+		if err != nil {
+			return err
+		}
+		c.EncryptionConfig.FieldKeys = fieldKeys
+	}
+
+	if os.Getenv("FIELD_ENCRYPTION_KEY_VERSION") != "" {
+		c.EncryptionConfig.FieldKeyVersion = os.Getenv("FIELD_ENCRYPTION_KEY_VERSION")
+	}
+
+	if os.Getenv("BLIND_INDEX_KEY") != "" {
+		c.EncryptionConfig.BlindIndexKey = os.Getenv("BLIND_INDEX_KEY")
+	}
+
+	if c.EncryptionConfig.FieldKeys[c.EncryptionConfig.FieldKeyVersion] == "" || c.EncryptionConfig.BlindIndexKey == "" {
+		return errors.New("Missing field encryption key")
+	}
+
+	c.TwoFactorConfig = TwoFactorConfig{
+		Issuer:             c.TokenConfig.ApplicationName,
+		RequiredRoles:      []string{"admin", "spmo"},
+		ChallengeLifeTime:  time.Minute * 5,
+		StepUpLifeTime:     time.Minute * 15,
+		EnrollmentLifeTime: time.Minute * 15,
+		ChallengeAttempts:  5,
+	}
+
+	if os.Getenv("TWO_FACTOR_ISSUER") != "" {
+		c.TwoFactorConfig.Issuer = os.Getenv("TWO_FACTOR_ISSUER")
+	}
+
+	if os.Getenv("TWO_FACTOR_REQUIRED_ROLES") != "" {
+		c.TwoFactorConfig.RequiredRoles = strings.Split(os.Getenv("TWO_FACTOR_REQUIRED_ROLES"), ",")
+	}
+
+	c.RateLimitConfig = RateLimitConfig{
+		RateLimitEnabled: os.Getenv("RATE_LIMIT_ENABLED") != "false",
+		RateLimitRules: map[string]RateLimitRule{
+			"login":     {Capacity: 10, Period: time.Minute},
+			"password":  {Capacity: 5, Period: time.Minute * 15},
+			"autologin": {Capacity: 5, Period: time.Minute * 15},
+			"public":    {Capacity: 20, Period: time.Minute},
+			"auth":      {Capacity: 300, Period: time.Minute},
+		},
+	}
+
+	for group := range c.RateLimitConfig.RateLimitRules {
+		value := os.Getenv(fmt.Sprintf("RATE_LIMIT_%s", strings.ToUpper(group)))
+		if value == "" {
+			continue
+		}
+		rule, err := parseRateLimitRule(value)
+		if err != nil {
+			return err
+		}
+		c.RateLimitConfig.RateLimitRules[group] = rule
+	}
+
+	c.JobConfig = JobConfig{
+		JobWorkers:      2,
+		JobPollInterval: time.Second * 2,
+		JobStaleAfter:   time.Minute * 10,
+		JobRetention:    time.Hour * 24,
+	}
+
+	if os.Getenv("JOB_WORKERS") != "" {
+		workers, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+		if err != nil || workers < 0 {
+			return fmt.Errorf("Invalid JOB_WORKERS %s", os.Getenv("JOB_WORKERS"))
+		}
+		c.JobConfig.JobWorkers = workers
+	}
+
+	if os.Getenv("JOB_RETENTION") != "" {
+		retention, err := time.ParseDuration(os.Getenv("JOB_RETENTION"))
+		if err != nil || retention <= 0 {
+			return fmt.Errorf("Invalid JOB_RETENTION %s", os.Getenv("JOB_RETENTION"))
+		}
+		c.JobConfig.JobRetention = retention
+	}
+
+	c.PhaseLifecycleConfig = PhaseLifecycleConfig{
+		PhaseLifecycleInterval: time.Minute * 5,
+	}
+
+	if os.Getenv("PHASE_LIFECYCLE_INTERVAL") != "" {
+		interval, err := time.ParseDuration(os.Getenv("PHASE_LIFECYCLE_INTERVAL"))
+		if err != nil || interval < 0 {
+			return fmt.Errorf("Invalid PHASE_LIFECYCLE_INTERVAL %s", os.Getenv("PHASE_LIFECYCLE_INTERVAL"))
+		}
+		c.PhaseLifecycleConfig.PhaseLifecycleInterval = interval
 	}
 
 	if c.SMTPEmail == "" || c.SMTPHost == "" || c.SMTPPassword == "" || c.SMTPPort == "" || c.SMTPSenderName == "" ||
@@ -148,6 +319,51 @@ func (c *Config) ReadConfigFile() error {
 	return nil
 }
 
+// parseRateLimitRule reads "<capacity>/<period>", e.g. "10/1m" allows a burst of 10 refilled over one minute
+func parseRateLimitRule(value string) (RateLimitRule, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return RateLimitRule{}, fmt.Errorf("Invalid rate limit %s", value)
+	}
+
+	capacity, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || capacity <= 0 {
+		return RateLimitRule{}, fmt.Errorf("Invalid rate limit capacity %s", value)
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return RateLimitRule{}, fmt.Errorf("Invalid rate limit period %s", value)
+	}
+
+	return RateLimitRule{
+		Capacity: capacity,
+		Period:   period,
+	}, nil
+}
+
+// deriveKey expands a master secret into the subkey of one purpose with HKDF-SHA256
+func deriveKey(secret, purpose string) (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte(purpose)), key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// parseFieldKeys reads "<version>:<secret>" pairs separated by comma, e.g. "v1:old-secret,v2:new-secret"
+func parseFieldKeys(value string) (map[string]string, error) {
+	fieldKeys := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid field encryption key %s", pair)
+		}
+		fieldKeys[parts[0]] = parts[1]
+	}
+	return fieldKeys, nil
+}
+
 func NewConfig() (*Config, error) {
 	cfg := &Config{}
 	err := cfg.ReadConfigFile()
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestDeriveKey(t *testing.T) {
+	fieldKey, err := deriveKey("master-secret", "field-encryption:v1")
+	if err != nil {
+		t.Fatalf("deriveKey error: %v", err)
+	}
+	blindIndexKey, err := deriveKey("master-secret", "blind-index")
+	if err != nil {
+		t.Fatalf("deriveKey error: %v", err)
+	}
+	again, err := deriveKey("master-secret", "field-encryption:v1")
+	if err != nil {
+		t.Fatalf("deriveKey error: %v", err)
+	}
+
+	if fieldKey == blindIndexKey {
+		t.Error("field and blind index keys are the same")
+	}
+	if fieldKey == "master-secret" || blindIndexKey == "master-secret" {
+		t.Error("a derived key is the master secret")
+	}
+	if fieldKey != again {
+		t.Error("deriveKey is not deterministic")
+	}
+	if len(fieldKey) != 64 {
+		t.Errorf("deriveKey length = %d, want 64 hex characters", len(fieldKey))
+	}
+}
@@ -1,7 +1,14 @@
 package api
 
 import (
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
 	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/utils/exporter"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,6 +21,57 @@ func (b *BaseApi) ParseRequestBody(c *gin.Context, payload interface{}) error {
 	return nil
 }
 
+// ParseUploadFile returns the excelFile form field, csv, json and zip archives can also be posted as the raw request body
+func (b *BaseApi) ParseUploadFile(c *gin.Context) (io.ReadCloser, error) {
+	switch c.ContentType() {
+	case "text/csv", "application/json", "application/x-ndjson", "application/ndjson", "application/zip":
+		return c.Request.Body, nil
+	}
+
+	file, _, err := c.Request.FormFile("excelFile")
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// NewDownloadResponse streams a report as an attachment. Errors before the first byte get the usual json error,
+// after that the headers are already sent and the error can only be logged
+func (b *BaseApi) NewDownloadResponse(c *gin.Context, name string, write func(w io.Writer) error) {
+	c.Header("Content-Type", exporter.ContentType(strings.TrimPrefix(path.Ext(name), ".")))
+	c.Header("Content-Disposition", "attachment; filename="+name)
+
+	err := write(c.Writer)
+	if err == nil {
+		return
+	}
+	if !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		b.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf("Failed to write %s: %v", name, err)
+}
+
+// NewExportResponse streams a report table as csv or json
+func (b *BaseApi) NewExportResponse(c *gin.Context, format string, table *exporter.Table) {
+	b.NewDownloadResponse(c, "report."+format, func(w io.Writer) error {
+		return exporter.Write(w, format, table)
+	})
+}
+
+// ParseProjectID reads the projectID query parameter, answering 400 when it is missing since no query falls back to
+// the active project anymore
+func (b *BaseApi) ParseProjectID(c *gin.Context) (string, bool) {
+	projectID := c.Query("projectID")
+	if projectID == "" {
+		b.NewFailedResponse(c, http.StatusBadRequest, "projectID is required")
+		return "", false
+	}
+	return projectID, true
+}
+
 func (b *BaseApi) NewSuccessSingleResponse(c *gin.Context, data interface{}, desc string) {
 	response.SendSingleResponse(c, data, desc)
 }
@@ -22,6 +80,10 @@ func (b *BaseApi) NewFailedResponse(c *gin.Context, code int, desc string) {
 	response.SendErrorResponse(c, code, desc)
 }
 
+func (b *BaseApi) NewFailedDataResponse(c *gin.Context, code int, data interface{}, desc string) {
+	response.SendFailedDataResponse(c, code, data, desc)
+}
+
 func (b *BaseApi) NewSuccesPagedResponse(c *gin.Context, data []interface{}, description string, paging response.Paging) {
 	response.SendPagedResponse(c, data, description, paging)
 }
@@ -0,0 +1,16 @@
+package request
+
+type CreateServiceAccount struct {
+	Name        string
+	Description string
+}
+
+type CreateApiKey struct {
+	Name          string
+	Scopes        []string
+	ExpiresInDays int
+}
+
+type RotateApiKey struct {
+	GracePeriodHours int
+}
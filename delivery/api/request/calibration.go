@@ -26,3 +26,15 @@ type CalibrationDataForm struct {
 	Spmo2ID        string
 	Spmo3ID        string
 }
+
+// ReassignCalibrations moves EmployeeIDs, or every employee of FromID when it is empty, to ToID in the given phases.
+// Role is calibrator, spmo, spmo2 or spmo3.
+type ReassignCalibrations struct {
+	ProjectID       string
+	ProjectPhaseIDs []string
+	Role            string
+	FromID          string
+	ToID            string
+	EmployeeIDs     []string
+	Reason          string
+}
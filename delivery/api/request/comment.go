@@ -0,0 +1,8 @@
+package request
+
+type CommentRequest struct {
+	ProjectPhaseID string
+	ParentID       *string
+	Body           string
+	Visibility     string
+}
@@ -0,0 +1,10 @@
+package request
+
+type TwoFactorCode struct {
+	Code string
+}
+
+type TwoFactorLogin struct {
+	ChallengeToken string
+	Code           string
+}
@@ -0,0 +1,8 @@
+package response
+
+import "calibration-system.com/model"
+
+type ApiKeyCreated struct {
+	ApiKey model.ApiKey
+	Key    string
+}
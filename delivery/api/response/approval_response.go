@@ -0,0 +1,14 @@
+package response
+
+// ApprovalResult is the state of an SPMO approval chain after one of its levels decided. NextSpmoID is the SPMO a
+// sequential chain moved on to.
+type ApprovalResult struct {
+	ProjectID      string
+	ProjectPhaseID string
+	PhaseOrder     int
+	EmployeeID     string
+	CalibratorID   string
+	Level          int
+	Status         string
+	NextSpmoID     string
+}
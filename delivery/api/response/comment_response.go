@@ -0,0 +1,28 @@
+package response
+
+import (
+	"time"
+
+	"calibration-system.com/model"
+)
+
+type CommentEntry struct {
+	model.CalibrationComment
+	AuthorName string
+	PhaseOrder int `json:",omitempty"`
+	Unread     bool
+}
+
+// CommentThread is the discussion on the calibration of an employee as the caller may see it
+type CommentThread struct {
+	ProjectID  string
+	EmployeeID string
+	Comments   []CommentEntry
+	Unread     int
+	LastReadAt *time.Time
+}
+
+type UnreadComments struct {
+	EmployeeID string
+	Unread     int
+}
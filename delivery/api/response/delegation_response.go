@@ -0,0 +1,23 @@
+package response
+
+import "time"
+
+// DelegationSummary shows who is standing in for a calibrator
+type DelegationSummary struct {
+	DelegationID   string
+	CalibratorID   string
+	CalibratorName string
+	DelegateID     string
+	DelegateName   string
+	ProjectID      *string
+	StartDate      time.Time
+	EndDate        time.Time
+	Reason         string
+	RevokedAt      *time.Time `json:",omitempty"`
+}
+
+// UserDelegations are the delegations a user gave as calibrator and received as delegate
+type UserDelegations struct {
+	Given    []DelegationSummary
+	Received []DelegationSummary
+}
@@ -0,0 +1,18 @@
+package response
+
+import "time"
+
+// EmployeeResult is a published result as the employee sees it, parts hidden by the project are left out
+type EmployeeResult struct {
+	ProjectID   string
+	ProjectName string
+	Year        int
+	PublishedAt time.Time
+	FinalRating string
+	FinalScore  *float64 `json:",omitempty"`
+	PTTScore    *float64 `json:",omitempty"`
+	PATScore    *float64 `json:",omitempty"`
+	Score360    *float64 `json:",omitempty"`
+	Y1Rating    string   `json:",omitempty"`
+	Y2Rating    string   `json:",omitempty"`
+}
@@ -0,0 +1,16 @@
+package response
+
+import (
+	"time"
+
+	"calibration-system.com/model"
+)
+
+type ImpersonationResponse struct {
+	AccessToken   string
+	TokenModel    model.ModifiedTokenModel
+	Actor         model.TokenModel
+	ExpiresAt     time.Time
+	Impersonating bool
+	Banner        string
+}
@@ -25,6 +25,16 @@ func SendErrorResponse(c *gin.Context, code int, desc string) {
 	})
 }
 
+func SendFailedDataResponse(c *gin.Context, code int, data interface{}, desc string) {
+	c.AbortWithStatusJSON(code, &SingleResponse{
+		Status: Status{
+			Code:        code,
+			Description: desc,
+		},
+		Data: data,
+	})
+}
+
 func SendPagedResponse(c *gin.Context, data []interface{}, description string, paging Paging) {
 	c.JSON(http.StatusOK, &PagedResponse{
 		Status: Status{
@@ -3,6 +3,9 @@ package response
 import "calibration-system.com/model"
 
 type LoginResponse struct {
-	AccessToken string
-	TokenModel  model.TokenModel
+	AccessToken                 string
+	TokenModel                  model.TokenModel
+	TwoFactorRequired           bool
+	TwoFactorEnrollmentRequired bool
+	ChallengeToken              string
 }
@@ -76,6 +76,7 @@ type SPMOSummaryResult struct {
 	CalibratorID     string
 	ProjectPhaseID   string
 	Order            int
+	SpmoLevel        int
 	LastLogin        time.Time
 }
 
@@ -91,9 +92,11 @@ type BUPerformanceSummarySPMO struct {
 	ProjectPhaseSummary []*ProjectPhaseSummarySPMO
 }
 
+// ProjectPhaseSummarySPMO is one phase of the worksheets the SPMO reviews at one level of the approval chain
 type ProjectPhaseSummarySPMO struct {
 	ProjectPhaseID     string
 	Order              int
+	SpmoLevel          int
 	DataCount          int
 	CalibratorSummarys []*CalibratorSummary
 }
@@ -104,6 +107,7 @@ type CalibratorSummary struct {
 	Count          int
 	Status         string
 	LastLogin      time.Time
+	Delegation     *DelegationSummary `json:",omitempty"`
 }
 
 // type DepartmentCountSummarySPMO struct {
@@ -0,0 +1,19 @@
+package response
+
+import "time"
+
+type TwoFactorStatus struct {
+	Enabled                bool
+	Required               bool
+	EnabledAt              time.Time
+	RemainingRecoveryCodes int
+}
+
+type TwoFactorEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string
+}
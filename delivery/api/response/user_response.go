@@ -10,7 +10,7 @@ type UserResponse struct {
 	model.BaseModel
 	CreatedBy         string `gorm:"default:admin" json:"-"`
 	UpdatedBy         string `gorm:"default:admin" json:"-"`
-	Email             string `gorm:"unique" `
+	Email             string `gorm:"unique;serializer:encrypted" `
 	Name              string
 	Nik               string
 	SupervisorNames   string
@@ -89,9 +89,9 @@ type BottomRemarkResponse struct {
 	EmployeeID     string `gorm:"primaryKey"`
 	ProjectPhaseID string `gorm:"primaryKey"`
 	LowPerformance string
-	Indisipliner   string
-	Attitude       string
-	WarningLetter  string
+	Indisipliner   string `gorm:"serializer:encrypted"`
+	Attitude       string `gorm:"serializer:encrypted"`
+	WarningLetter  string `gorm:"serializer:encrypted"`
 }
 
 type UserCalibration struct {
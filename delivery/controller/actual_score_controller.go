@@ -2,7 +2,6 @@ package controller
 
 import (
 	"net/http"
-	"strings"
 
 	"calibration-system.com/delivery/api"
 	"calibration-system.com/delivery/middleware"
@@ -83,23 +82,25 @@ func (r *ActualScoreController) deleteHandler(c *gin.Context) {
 func (r *ActualScoreController) uploadHandler(c *gin.Context) {
 	// Menerima file Excel dari permintaan HTTP POST
 	projectID := c.Request.FormValue("projectID")
-	file, err := c.FormFile("excelFile")
+	file, err := r.ParseUploadFile(c)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	defer file.Close()
 
-	logs, err := r.uc.BulkInsert(file, projectID)
+	dryRun := c.Query("dryRun") == "true"
+	report, err := r.uc.BulkInsert(c.Request.Context(), file, projectID, dryRun)
 	if err != nil {
-		if len(logs) > 0 {
-			r.NewFailedResponse(c, http.StatusInternalServerError, strings.Join(logs, ","))
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
 		} else {
 			r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	r.NewSuccessSingleResponse(c, "", "OK")
+	r.NewSuccessSingleResponse(c, report, "OK")
 }
 
 func NewActualScoreController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.ActualScoreUsecase) *ActualScoreController {
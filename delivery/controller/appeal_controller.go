@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+// an appeal carries a handful of supporting documents, not a case file
+const maxAppealAttachments = 5
+
+type AppealController struct {
+	router *gin.Engine
+	uc     usecase.AppealUsecase
+	api.BaseApi
+}
+
+// fileHandler takes a multipart form with ProjectID, Reason and the files under "attachments"
+func (r *AppealController) fileHandler(c *gin.Context) {
+	payload := model.Appeal{
+		ProjectID: c.PostForm("ProjectID"),
+		Reason:    c.PostForm("Reason"),
+	}
+
+	if form, err := c.MultipartForm(); err == nil {
+		files := form.File["attachments"]
+		if len(files) > maxAppealAttachments {
+			r.NewFailedResponse(c, http.StatusBadRequest, fmt.Sprintf("An appeal takes at most %d attachments", maxAppealAttachments))
+			return
+		}
+		for _, header := range files {
+			file, err := header.Open()
+			if err != nil {
+				r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			content, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			payload.Attachments = append(payload.Attachments, model.AppealAttachment{Name: filepath.Base(header.Filename), Content: content})
+		}
+	}
+
+	if err := r.uc.File(c.GetString("ID"), &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, payload, "OK")
+}
+
+func (r *AppealController) listMineHandler(c *gin.Context) {
+	appeals, err := r.uc.FindByEmployee(c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, appeals, "OK")
+}
+
+// listHandler is the review queue, appeals routed to the caller or every appeal for an admin
+func (r *AppealController) listHandler(c *gin.Context) {
+	reviewerID := c.GetString("ID")
+	if isAdmin(c) {
+		reviewerID = ""
+	}
+	appeals, err := r.uc.FindByReviewer(reviewerID, c.Query("projectID"), c.Query("status"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, appeals, "OK")
+}
+
+func (r *AppealController) getByIdHandler(c *gin.Context) {
+	appeal, ok := r.access(c, false)
+	if !ok {
+		return
+	}
+	r.NewSuccessSingleResponse(c, appeal, "OK")
+}
+
+func (r *AppealController) attachmentHandler(c *gin.Context) {
+	appeal, ok := r.access(c, false)
+	if !ok {
+		return
+	}
+
+	attachment, err := r.uc.FindAttachment(appeal.ID, c.Param("attachmentID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, "Attachment not found")
+		return
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(attachment.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.Name))
+	c.Data(http.StatusOK, contentType, attachment.Content)
+}
+
+func (r *AppealController) reviewHandler(c *gin.Context) {
+	if _, ok := r.access(c, true); !ok {
+		return
+	}
+	appeal, err := r.uc.StartReview(c.Param("id"), c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, appeal, "OK")
+}
+
+func (r *AppealController) upholdHandler(c *gin.Context) {
+	r.decide(c, r.uc.Uphold)
+}
+
+func (r *AppealController) amendHandler(c *gin.Context) {
+	r.decide(c, r.uc.Amend)
+}
+
+func (r *AppealController) decide(c *gin.Context, decide func(id string, decision model.AppealDecision, reviewerID string) (*model.Appeal, error)) {
+	if _, ok := r.access(c, true); !ok {
+		return
+	}
+
+	var payload model.AppealDecision
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	appeal, err := decide(c.Param("id"), payload, c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, appeal, "OK")
+}
+
+// access lets the HRBP and calibrator an appeal is routed to and admins in, the employee who filed it can only read
+func (r *AppealController) access(c *gin.Context, review bool) (*model.Appeal, bool) {
+	appeal, err := r.uc.FindById(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, "Appeal not found")
+		return nil, false
+	}
+
+	userID := c.GetString("ID")
+	if userID == appeal.HrbpID || userID == appeal.CalibratorID || isAdmin(c) {
+		return appeal, true
+	}
+	if !review && userID == appeal.EmployeeID {
+		return appeal, true
+	}
+
+	r.NewFailedResponse(c, http.StatusNotFound, "Appeal not found")
+	return nil, false
+}
+
+func isAdmin(c *gin.Context) bool {
+	for _, role := range c.GetStringSlice("Roles") {
+		if strings.EqualFold(role, model.RoleAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+func NewAppealController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.AppealUsecase) *AppealController {
+	controller := AppealController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	auth.POST("/me/appeals", controller.fileHandler)
+	auth.GET("/me/appeals", controller.listMineHandler)
+	auth.GET("/appeals", controller.listHandler)
+	auth.GET("/appeals/:id", controller.getByIdHandler)
+	auth.GET("/appeals/:id/attachments/:attachmentID", controller.attachmentHandler)
+	auth.POST("/appeals/:id/review", controller.reviewHandler)
+	auth.POST("/appeals/:id/uphold", controller.upholdHandler)
+	auth.POST("/appeals/:id/amend", controller.amendHandler)
+	return &controller
+}
@@ -20,6 +20,7 @@ import (
 type AuthController struct {
 	router       *gin.Engine
 	uc           usecase.AuthUsecase
+	twoFactor    usecase.TwoFactorUsecase
 	tokenService authenticator.AccessToken
 	cfg          config.Config
 	api.BaseApi
@@ -37,34 +38,133 @@ func (a *AuthController) login(c *gin.Context) {
 		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	a.startSession(c, user)
+}
 
-	var roles []string
-	for _, v := range user.Roles {
-		roles = append(roles, v.Name)
+// startSession answers every first factor, password, google or autologin link. Every user who enabled two factor
+// gets a challenge for the code, roles that require it get a token that only reaches the enrollment routes while
+// they haven't enrolled.
+func (a *AuthController) startSession(c *gin.Context, user *model.User) {
+	if a.twoFactor.IsEnabled(user.ID) {
+		challenge, err := a.tokenService.StoreTwoFactorChallenge(user.ID)
+		if err != nil {
+			a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		a.NewSuccessSingleResponse(c, response.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, "OK")
+		return
 	}
 
-	cred := model.TokenModel{
-		Username: "",
-		Email:    user.Email,
-		Role:     roles,
-		ID:       user.ID,
-		Name:     user.Name,
+	if a.twoFactor.IsRequired(user) {
+		cred := tokenModel(user)
+		tokenDetail, err := a.tokenService.CreateEnrollmentToken(&cred)
+		if err != nil {
+			a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := a.tokenService.StoreAccessToken(user.Email, tokenDetail); err != nil {
+			a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		a.NewSuccessSingleResponse(c, response.LoginResponse{
+			AccessToken:                 tokenDetail.AccessToken,
+			TokenModel:                  cred,
+			TwoFactorEnrollmentRequired: true,
+		}, "OK")
+		return
 	}
-	tokenDetail, err := a.tokenService.CreateAccessToken(&cred)
+
+	response, _, err := a.createLoginResponse(user)
 	if err != nil {
 		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	if err := a.tokenService.StoreAccessToken(user.Email, tokenDetail); err != nil {
+	a.NewSuccessSingleResponse(c, response, "OK")
+}
+
+func (a *AuthController) loginTwoFactor(c *gin.Context) {
+	var payload request.TwoFactorLogin
+
+	if err := a.ParseRequestBody(c, &payload); err != nil {
+		a.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := a.tokenService.FetchTwoFactorChallenge(payload.ChallengeToken)
+	if err != nil {
+		a.NewFailedResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// a challenge takes a few codes, then the password has to be given again
+	attempts, err := a.tokenService.CountTwoFactorAttempt(payload.ChallengeToken)
+	if err != nil || attempts > a.cfg.ChallengeAttempts {
+		a.tokenService.DeleteTwoFactorChallenge(payload.ChallengeToken)
+		a.NewFailedResponse(c, http.StatusUnauthorized, "Too many invalid codes, log in again")
+		return
+	}
+
+	if err := a.twoFactor.Verify(userID, payload.Code); err != nil {
+		a.NewFailedResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := a.tokenService.DeleteTwoFactorChallenge(payload.ChallengeToken); err != nil {
+		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := a.uc.GetUserByID(userID)
+	if err != nil {
+		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response, tokenDetail, err := a.createLoginResponse(user)
+	if err != nil {
+		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := a.tokenService.StoreTwoFactorVerified(tokenDetail.AccessUUID); err != nil {
 		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	a.NewSuccessSingleResponse(c, response, "OK")
+}
+
+func (a *AuthController) createLoginResponse(user *model.User) (*response.LoginResponse, *authenticator.TokenDetail, error) {
+	cred := tokenModel(user)
+	tokenDetail, err := a.tokenService.CreateAccessToken(&cred)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := a.tokenService.StoreAccessToken(user.Email, tokenDetail); err != nil {
+		return nil, nil, err
+	}
 	// redis add token
-	response := response.LoginResponse{
+	return &response.LoginResponse{
 		AccessToken: tokenDetail.AccessToken,
 		TokenModel:  cred,
+	}, &tokenDetail, nil
+}
+
+func tokenModel(user *model.User) model.TokenModel {
+	var roles []string
+	for _, v := range user.Roles {
+		roles = append(roles, v.Name)
+	}
+
+	return model.TokenModel{
+		Username: "",
+		Email:    user.Email,
+		Role:     roles,
+		ID:       user.ID,
+		Name:     user.Name,
 	}
-	a.NewSuccessSingleResponse(c, response, "OK")
 }
 
 func (a *AuthController) redirectGoogle(c *gin.Context) {
@@ -98,35 +198,7 @@ func (a *AuthController) loginGoogle(ctx *gin.Context) {
 		a.NewFailedResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	var roles []string
-	for _, v := range user.Roles {
-		roles = append(roles, v.Name)
-	}
-
-	cred := model.TokenModel{
-		Username: "",
-		Email:    user.Email,
-		Role:     roles,
-		ID:       user.ID,
-	}
-
-	tokenDetail, err := a.tokenService.CreateAccessToken(&cred)
-	if err != nil {
-		a.NewFailedResponse(ctx, http.StatusInternalServerError, err.Error())
-		return
-	}
-	if err := a.tokenService.StoreAccessToken(user.Email, tokenDetail); err != nil {
-		a.NewFailedResponse(ctx, http.StatusInternalServerError, err.Error())
-		return
-	}
-	// redis add token
-	response := response.LoginResponse{
-		AccessToken: tokenDetail.AccessToken,
-		TokenModel:  cred,
-	}
-	// set cookie and redirect to oauth url
-	a.NewSuccessSingleResponse(ctx, response, "OK")
+	a.startSession(ctx, user)
 }
 
 func (a *AuthController) logout(c *gin.Context) {
@@ -238,53 +310,26 @@ func (a *AuthController) autoLogin(c *gin.Context) {
 		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	var roles []string
-	for _, v := range user.Roles {
-		roles = append(roles, v.Name)
-	}
-
-	cred := model.TokenModel{
-		Username: "",
-		Email:    user.Email,
-		Role:     roles,
-		ID:       user.ID,
-		Name:     user.Name,
-	}
-
-	tokenDetail, err := a.tokenService.CreateAccessToken(&cred)
-	if err != nil {
-		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	if err := a.tokenService.StoreAccessToken(user.Email, tokenDetail); err != nil {
-		a.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
-		return
-	}
-	// redis add token
-	response := response.LoginResponse{
-		AccessToken: tokenDetail.AccessToken,
-		TokenModel:  cred,
-	}
-	a.NewSuccessSingleResponse(c, response, "OK")
+	a.startSession(c, user)
 }
 
-func NewAuthController(r *gin.Engine, uc usecase.AuthUsecase, tokenService authenticator.AccessToken, cfg config.Config) *AuthController {
+func NewAuthController(r *gin.Engine, uc usecase.AuthUsecase, twoFactor usecase.TwoFactorUsecase, tokenService authenticator.AccessToken, limiter middleware.RateLimiter, cfg config.Config) *AuthController {
 	controller := AuthController{
 		router:       r,
 		uc:           uc,
+		twoFactor:    twoFactor,
 		tokenService: tokenService,
 		cfg:          cfg,
 	}
 
 	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
-	r.POST("/login", controller.login)
-	r.POST("/forget-password", controller.forgetPassword)
-	r.GET("/sessions/oauth/google", controller.redirectGoogle)
-	r.GET("/sessions/oauth", controller.loginGoogle)
-	r.PATCH("/reset-password/:email/:resetToken", controller.resetPassword)
-	r.POST("/autologin/:token", controller.autoLogin)
+	r.POST("/login", limiter.Limit("login"), controller.login)
+	r.POST("/login/2fa", limiter.Limit("login"), controller.loginTwoFactor)
+	r.POST("/forget-password", limiter.Limit("password"), controller.forgetPassword)
+	r.GET("/sessions/oauth/google", limiter.Limit("public"), controller.redirectGoogle)
+	r.GET("/sessions/oauth", limiter.Limit("login"), controller.loginGoogle)
+	r.PATCH("/reset-password/:email/:resetToken", limiter.Limit("password"), controller.resetPassword)
+	r.POST("/autologin/:token", limiter.Limit("autologin"), controller.autoLogin)
 	auth.POST("/change-password", controller.changePassword)
 	auth.POST("/logout", controller.logout)
 	return &controller
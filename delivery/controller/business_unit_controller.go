@@ -3,7 +3,6 @@ package controller
 import (
 	"net/http"
 	"strconv"
-	"strings"
 
 	"calibration-system.com/delivery/api"
 	"calibration-system.com/delivery/api/request"
@@ -115,23 +114,25 @@ func (r *BusinessUnitController) deleteHandler(c *gin.Context) {
 
 func (r *BusinessUnitController) uploadHandler(c *gin.Context) {
 	// Menerima file Excel dari permintaan HTTP POST
-	file, err := c.FormFile("excelFile")
+	file, err := r.ParseUploadFile(c)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	defer file.Close()
 
-	logs, err := r.uc.BulkInsert(file)
+	dryRun := c.Query("dryRun") == "true"
+	report, err := r.uc.BulkInsert(c.Request.Context(), file, dryRun)
 	if err != nil {
-		if len(logs) > 0 {
-			r.NewFailedResponse(c, http.StatusInternalServerError, strings.Join(logs, "."))
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
 		} else {
 			r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	r.NewSuccessSingleResponse(c, "", "OK")
+	r.NewSuccessSingleResponse(c, report, "OK")
 }
 
 func NewBusinessUnitController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.BusinessUnitUsecase) *BusinessUnitController {
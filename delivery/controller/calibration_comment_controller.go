@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/api/request"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type CalibrationCommentController struct {
+	router *gin.Engine
+	uc     usecase.CalibrationCommentUsecase
+	api.BaseApi
+}
+
+func (r *CalibrationCommentController) threadHandler(c *gin.Context) {
+	thread, err := r.uc.FindThread(c.Param("id"), c.Param("employeeID"), c.GetString("ID"), isAdmin(c))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, thread, "OK")
+}
+
+func (r *CalibrationCommentController) postHandler(c *gin.Context) {
+	var payload request.CommentRequest
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comment, err := r.uc.Post(c.Param("id"), c.Param("employeeID"), &payload, c.GetString("ID"), isAdmin(c))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, comment, "OK")
+}
+
+func (r *CalibrationCommentController) editHandler(c *gin.Context) {
+	var payload request.CommentRequest
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comment, err := r.uc.Edit(c.Param("id"), c.Param("employeeID"), c.Param("commentID"), payload.Body, c.GetString("ID"), isAdmin(c))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, comment, "OK")
+}
+
+func (r *CalibrationCommentController) revisionsHandler(c *gin.Context) {
+	revisions, err := r.uc.FindRevisions(c.Param("id"), c.Param("employeeID"), c.Param("commentID"), c.GetString("ID"), isAdmin(c))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, revisions, "OK")
+}
+
+func (r *CalibrationCommentController) readHandler(c *gin.Context) {
+	if err := r.uc.MarkRead(c.Param("id"), c.Param("employeeID"), c.GetString("ID"), isAdmin(c)); err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+// unreadHandler lists the employees of a project with comments the caller hasn't read yet
+func (r *CalibrationCommentController) unreadHandler(c *gin.Context) {
+	unread, err := r.uc.FindUnread(c.Param("id"), c.GetString("ID"), isAdmin(c))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, unread, "OK")
+}
+
+func NewCalibrationCommentController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.CalibrationCommentUsecase) *CalibrationCommentController {
+	controller := CalibrationCommentController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	auth.GET("/projects/:id/employees/:employeeID/comments", controller.threadHandler)
+	auth.POST("/projects/:id/employees/:employeeID/comments", controller.postHandler)
+	auth.POST("/projects/:id/employees/:employeeID/comments/read", controller.readHandler)
+	auth.PUT("/projects/:id/employees/:employeeID/comments/:commentID", controller.editHandler)
+	auth.GET("/projects/:id/employees/:employeeID/comments/:commentID/revisions", controller.revisionsHandler)
+	auth.GET("/projects/:id/comments/unread", controller.unreadHandler)
+	return &controller
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -18,6 +19,7 @@ import (
 type CalibrationController struct {
 	router       *gin.Engine
 	uc           usecase.CalibrationUsecase
+	delegation   usecase.DelegationUsecase
 	tokenService authenticator.AccessToken
 	api.BaseApi
 }
@@ -62,7 +64,7 @@ func (r *CalibrationController) createHandler(c *gin.Context) {
 	}
 
 	if err := r.uc.SaveData(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -78,7 +80,7 @@ func (r *CalibrationController) updateHandler(c *gin.Context) {
 	}
 
 	if err := r.uc.SaveData(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -89,7 +91,7 @@ func (r *CalibrationController) deleteHandler(c *gin.Context) {
 	projectID := c.Param("projectID")
 	employeeID := c.Param("employeeID")
 	if err := r.uc.DeleteData(projectID, employeeID); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 	c.String(http.StatusNoContent, "")
@@ -97,28 +99,35 @@ func (r *CalibrationController) deleteHandler(c *gin.Context) {
 
 func (r *CalibrationController) uploadHandler(c *gin.Context) {
 	projectID := c.Request.FormValue("projectID")
-	file, err := c.FormFile("excelFile")
+	file, err := r.ParseUploadFile(c)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	defer file.Close()
 
-	err = r.uc.BulkInsert(file, projectID)
+	dryRun := c.Query("dryRun") == "true"
+	report, err := r.uc.BulkInsert(c.Request.Context(), file, projectID, dryRun)
 	if err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
+		} else {
+			r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
+		}
 		return
 	}
 
-	r.NewSuccessSingleResponse(c, "", "OK")
+	r.NewSuccessSingleResponse(c, report, "OK")
 }
 
 func (r *CalibrationController) uploadNikHandler(c *gin.Context) {
 	projectID := c.Request.FormValue("projectID")
-	file, err := c.FormFile("excelFile")
+	file, err := r.ParseUploadFile(c)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	defer file.Close()
 
 	logs, err := r.uc.CheckEmployee(file, projectID)
 	if err != nil {
@@ -135,11 +144,12 @@ func (r *CalibrationController) uploadNikHandler(c *gin.Context) {
 
 func (r *CalibrationController) uploadCalibratorHandler(c *gin.Context) {
 	projectID := c.Request.FormValue("projectID")
-	file, err := c.FormFile("excelFile")
+	file, err := r.ParseUploadFile(c)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	defer file.Close()
 
 	logs, err := r.uc.CheckCalibrator(file, projectID)
 	if err != nil {
@@ -161,8 +171,21 @@ func (r *CalibrationController) saveCalibrationsHandler(c *gin.Context) {
 		return
 	}
 
-	if err := r.uc.SaveCalibrations(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+	var principalID string
+	for _, calibration := range payload.RequestData {
+		writer, ok := r.authorizeCalibration(c, calibration)
+		if !ok {
+			return
+		}
+		if principalID != "" && writer != principalID {
+			r.NewFailedResponse(c, http.StatusBadRequest, "Save the worksheet of one calibrator at a time")
+			return
+		}
+		principalID = writer
+	}
+
+	if err := r.uc.SaveCalibrations(&payload, principalID); err != nil {
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -176,8 +199,13 @@ func (r *CalibrationController) changeScoreRatingHandler(c *gin.Context) {
 		return
 	}
 
-	if err := r.uc.SaveScoreAndRating(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+	principalID, ok := r.authorizeCalibration(c, &payload)
+	if !ok {
+		return
+	}
+
+	if err := r.uc.SaveScoreAndRating(&payload, principalID); err != nil {
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -191,8 +219,13 @@ func (r *CalibrationController) saveCommentCalibrationHandler(c *gin.Context) {
 		return
 	}
 
-	if err := r.uc.SaveCommentCalibration(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+	principalID, ok := r.authorizeCalibration(c, &payload)
+	if !ok {
+		return
+	}
+
+	if err := r.uc.SaveCommentCalibration(&payload, principalID); err != nil {
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -203,9 +236,12 @@ func (r *CalibrationController) submitCalibrationsHandler(c *gin.Context) {
 	calibratorID := c.Param("calibratorID")
 	projectID := c.Param("projectID")
 	businessUnit := c.Param("businessUnit")
+	if !r.authorizeCalibrator(c, calibratorID, projectID) {
+		return
+	}
 
 	if err := r.uc.SubmitCalibrations(calibratorID, projectID, businessUnit); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -214,12 +250,18 @@ func (r *CalibrationController) submitCalibrationsHandler(c *gin.Context) {
 
 func (r *CalibrationController) sendCalibrationToManagerHandler(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
+	if !r.authorizeCalibrator(c, calibratorID, projectID) {
+		return
+	}
 
 	if err := r.uc.SendCalibrationsToManager(calibratorID, projectID, prevCalibrator, businessUnit); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -228,21 +270,51 @@ func (r *CalibrationController) sendCalibrationToManagerHandler(c *gin.Context)
 
 func (r *CalibrationController) sendBackCalibrationsToOnePhaseBeforeHandler(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
+	if !r.authorizeCalibrator(c, calibratorID, projectID) {
+		return
+	}
 
 	if err := r.uc.SendBackCalibrationsToOnePhaseBefore(calibratorID, projectID, prevCalibrator, businessUnit); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
 	r.NewSuccessSingleResponse(c, "", "OK")
 }
 
+// reassignHandler moves employees to another calibrator or SPMO in the middle of a project
+func (r *CalibrationController) reassignHandler(c *gin.Context) {
+	var payload request.ReassignCalibrations
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reassignments, err := r.uc.Reassign(&payload, c.GetString("ID"))
+	if err != nil {
+		status := phaseWriteStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		r.NewFailedResponse(c, status, err.Error())
+		return
+	}
+
+	r.NewSuccessSingleResponse(c, reassignments, "OK")
+}
+
 func (r *CalibrationController) getSummaryCalibrationsBySPMOIDHandler(c *gin.Context) {
 	spmoID := c.Query("spmoID")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	payload, err := r.uc.FindSummaryCalibrationBySPMOID(spmoID, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -254,8 +326,12 @@ func (r *CalibrationController) getSummaryCalibrationsBySPMOIDHandler(c *gin.Con
 
 func (r *CalibrationController) getAllActiveCalibrationsBySPMOIDHandler(c *gin.Context) {
 	spmoID := c.Param("spmoID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 
-	payload, err := r.uc.FindActiveUserBySPMOID(spmoID)
+	payload, err := r.uc.FindActiveUserBySPMOID(spmoID, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -272,8 +348,8 @@ func (r *CalibrationController) spmoAcceptApprovalHandler(c *gin.Context) {
 		return
 	}
 
-	if err := r.uc.SpmoAcceptApproval(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+	if err := r.uc.SpmoAcceptApproval(&payload, c.GetString("ID"), isAdmin(c)); err != nil {
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 	r.NewSuccessSingleResponse(c, "", "OK")
@@ -286,8 +362,8 @@ func (r *CalibrationController) spmoAcceptMultipleApprovalHandler(c *gin.Context
 		return
 	}
 
-	if err := r.uc.SpmoAcceptMultipleApproval(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+	if err := r.uc.SpmoAcceptMultipleApproval(&payload, c.GetString("ID"), isAdmin(c)); err != nil {
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 	r.NewSuccessSingleResponse(c, "", "OK")
@@ -300,8 +376,8 @@ func (r *CalibrationController) spmoRejectApprovalHandler(c *gin.Context) {
 		return
 	}
 
-	if err := r.uc.SpmoRejectApproval(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+	if err := r.uc.SpmoRejectApproval(&payload, c.GetString("ID"), isAdmin(c)); err != nil {
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 	r.NewSuccessSingleResponse(c, "", "OK")
@@ -315,7 +391,7 @@ func (r *CalibrationController) spmoSubmitHandler(c *gin.Context) {
 	}
 
 	if err := r.uc.SpmoSubmit(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 	r.NewSuccessSingleResponse(c, "", "OK")
@@ -358,7 +434,7 @@ func (r *CalibrationController) createByUserHandler(c *gin.Context) {
 	}
 
 	if err := r.uc.SaveDataByUser(&payload); err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, phaseWriteStatus(err), err.Error())
 		return
 	}
 
@@ -396,7 +472,10 @@ func (r *CalibrationController) getRatingQuotaSPMOHandlerByID(c *gin.Context) {
 }
 
 func (r *CalibrationController) getLatestJustificationHandler(c *gin.Context) {
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	calibratorID := c.Query("calibratorID")
 	employeeID := c.Query("employeeID")
 
@@ -408,13 +487,68 @@ func (r *CalibrationController) getLatestJustificationHandler(c *gin.Context) {
 	r.NewSuccessSingleResponse(c, calibrations, "OK")
 }
 
-func NewCalibrationController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.CalibrationUsecase) *CalibrationController {
+// authorizeCalibrator lets the calibrator, admins and an active delegate of the calibrator act on the worksheet. A
+// delegate's request is tagged with the delegation so it is recorded as made on behalf of the calibrator.
+func (r *CalibrationController) authorizeCalibrator(c *gin.Context, calibratorID, projectID string) bool {
+	userID := c.GetString("ID")
+	if calibratorID == userID || isAdmin(c) {
+		return true
+	}
+
+	delegation, err := r.delegation.FindActive(calibratorID, userID, projectID)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusForbidden, "You are not the calibrator of this worksheet")
+		return false
+	}
+	c.Set("DelegationID", delegation.ID)
+	c.Set("OnBehalfOf", calibratorID)
+	return true
+}
+
+// authorizeCalibration checks the caller against the calibrator stored on a calibration, never the one in the payload.
+// It returns who the phase window is checked for: the calibrator a delegate stands in for, else the caller.
+func (r *CalibrationController) authorizeCalibration(c *gin.Context, calibration *model.Calibration) (string, bool) {
+	existing, err := r.uc.FindById(calibration.ProjectID, calibration.ProjectPhaseID, calibration.EmployeeID)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, "Calibrations not found!")
+		return "", false
+	}
+	if calibration.CalibratorID != "" && calibration.CalibratorID != existing.CalibratorID {
+		r.NewFailedResponse(c, http.StatusBadRequest, "CalibratorID doesn't match the calibration")
+		return "", false
+	}
+	if !r.authorizeCalibrator(c, existing.CalibratorID, calibration.ProjectID) {
+		return "", false
+	}
+	calibration.CalibratorID = existing.CalibratorID
+
+	userID := c.GetString("ID")
+	if userID == existing.CalibratorID || isAdmin(c) {
+		return userID, true
+	}
+	return existing.CalibratorID, true
+}
+
+// phaseWriteStatus answers 403 for writes outside the phase window and 500 for anything else
+func phaseWriteStatus(err error) int {
+	var windowErr *usecase.PhaseWindowError
+	var frozenErr *usecase.ProjectFrozenError
+	if errors.As(err, &windowErr) || errors.As(err, &frozenErr) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+func NewCalibrationController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.CalibrationUsecase, delegation usecase.DelegationUsecase) *CalibrationController {
 	controller := CalibrationController{
 		router:       r,
 		tokenService: tokenService,
 		uc:           uc,
+		delegation:   delegation,
 	}
 	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
 	auth.GET("/calibrations", controller.listHandler)
 	auth.GET("/calibrations/:projectID/:projectPhaseID/:employeeID", controller.getByIdHandler)
 	auth.GET("/calibrations-project-employee/:projectID/:employeeID", controller.getByProjectEmployeeIdHandler)
@@ -435,10 +569,11 @@ func NewCalibrationController(r *gin.Engine, tokenService authenticator.AccessTo
 	auth.POST("/calibrations/submit-calibrations/:calibratorID/:projectID/:businessUnit", controller.submitCalibrationsHandler)
 	auth.POST("/calibrations/send-calibration-to-manager", controller.sendCalibrationToManagerHandler)
 	auth.POST("/calibrations/send-calibrations-back", controller.sendBackCalibrationsToOnePhaseBeforeHandler)
+	auth.POST("/calibrations/reassign", admin, controller.reassignHandler)
 	auth.POST("/calibrations/accept-approval", controller.spmoAcceptApprovalHandler)
 	auth.POST("/calibrations/accept-multiple-approval", controller.spmoAcceptMultipleApprovalHandler)
 	auth.POST("/calibrations/reject-approval", controller.spmoRejectApprovalHandler)
-	auth.POST("/calibrations/spmo/submit", controller.spmoSubmitHandler)
+	auth.POST("/calibrations/spmo/submit", twoFactor, controller.spmoSubmitHandler)
 	auth.DELETE("/calibrations/:projectID/:employeeID", controller.deleteHandler)
 	auth.POST("/projects/send-notificaition-first-calibrator/:projectID", controller.sendNotificationFirstCalibratorHandler)
 	return &controller
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"calibration-system.com/utils/exporter"
+	"github.com/gin-gonic/gin"
+)
+
+type CalibrationDraftController struct {
+	router *gin.Engine
+	uc     usecase.CalibrationDraftUsecase
+	api.BaseApi
+}
+
+func (r *CalibrationDraftController) generateHandler(c *gin.Context) {
+	var payload model.ChainRules
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	draft, err := r.uc.Generate(c.Param("id"), payload, c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, draft, "OK")
+}
+
+func (r *CalibrationDraftController) listHandler(c *gin.Context) {
+	drafts, err := r.uc.FindByProject(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, drafts, "OK")
+}
+
+func (r *CalibrationDraftController) getByIdHandler(c *gin.Context) {
+	draft, err := r.uc.FindById(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, "Draft not found")
+		return
+	}
+	r.NewSuccessSingleResponse(c, draft, "OK")
+}
+
+// exportHandler downloads the draft in the calibration upload layout, ?format= takes xlsx, csv, json or ndjson
+func (r *CalibrationDraftController) exportHandler(c *gin.Context) {
+	format, err := exporter.ParseFormat(c.Query("format"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	r.NewDownloadResponse(c, "calibration-draft."+format, func(w io.Writer) error {
+		return r.uc.Export(w, c.Param("id"), format)
+	})
+}
+
+func (r *CalibrationDraftController) replaceHandler(c *gin.Context) {
+	file, err := r.ParseUploadFile(c)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	report, err := r.uc.Replace(c.Request.Context(), c.Param("id"), file)
+	if err != nil {
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
+		} else {
+			r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func (r *CalibrationDraftController) commitHandler(c *gin.Context) {
+	dryRun := c.Query("dryRun") == "true"
+	report, err := r.uc.Commit(c.Request.Context(), c.Param("id"), dryRun)
+	if err != nil {
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
+		} else {
+			r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func NewCalibrationDraftController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.CalibrationDraftUsecase) *CalibrationDraftController {
+	controller := CalibrationDraftController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	auth.POST("/projects/:id/calibration-drafts", admin, controller.generateHandler)
+	auth.GET("/projects/:id/calibration-drafts", admin, controller.listHandler)
+	auth.GET("/calibration-drafts/:id", admin, controller.getByIdHandler)
+	auth.GET("/calibration-drafts/:id/export", admin, controller.exportHandler)
+	auth.PUT("/calibration-drafts/:id", admin, controller.replaceHandler)
+	auth.POST("/calibration-drafts/:id/commit", admin, controller.commitHandler)
+	return &controller
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type DelegationController struct {
+	router *gin.Engine
+	uc     usecase.DelegationUsecase
+	api.BaseApi
+}
+
+// createHandler delegates the caller's worksheets, admins can set CalibratorID to delegate for someone else
+func (r *DelegationController) createHandler(c *gin.Context) {
+	var payload model.Delegation
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := r.uc.Create(&payload, c.GetString("ID"), isAdmin(c)); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, payload, "OK")
+}
+
+func (r *DelegationController) listHandler(c *gin.Context) {
+	delegations, err := r.uc.FindAll(c.Query("calibratorID"), c.Query("delegateID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, delegations, "OK")
+}
+
+func (r *DelegationController) listMineHandler(c *gin.Context) {
+	delegations, err := r.uc.FindByUser(c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, delegations, "OK")
+}
+
+func (r *DelegationController) revokeHandler(c *gin.Context) {
+	if err := r.uc.Revoke(c.Param("id"), c.GetString("ID"), isAdmin(c)); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+// actionsHandler lists what was done on a delegation, for the calibrator, the delegate and admins
+func (r *DelegationController) actionsHandler(c *gin.Context) {
+	delegation, err := r.uc.FindById(c.Param("id"))
+	userID := c.GetString("ID")
+	if err != nil || (userID != delegation.CalibratorID && userID != delegation.DelegateID && !isAdmin(c)) {
+		r.NewFailedResponse(c, http.StatusNotFound, "Delegation not found")
+		return
+	}
+
+	actions, err := r.uc.FindActions(delegation.ID)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, actions, "OK")
+}
+
+func NewDelegationController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.DelegationUsecase) *DelegationController {
+	controller := DelegationController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	auth.POST("/delegations", controller.createHandler)
+	auth.GET("/delegations", admin, controller.listHandler)
+	auth.GET("/me/delegations", controller.listMineHandler)
+	auth.DELETE("/delegations/:id", controller.revokeHandler)
+	auth.GET("/delegations/:id/actions", controller.actionsHandler)
+	return &controller
+}
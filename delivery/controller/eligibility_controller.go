@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type EligibilityController struct {
+	router *gin.Engine
+	uc     usecase.EligibilityUsecase
+	api.BaseApi
+}
+
+func (r *EligibilityController) reportHandler(c *gin.Context) {
+	report, err := r.uc.FindReport(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func (r *EligibilityController) listRulesHandler(c *gin.Context) {
+	rules, err := r.uc.FindRules(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, rules, "OK")
+}
+
+// saveRulesHandler takes the whole rule set of the project in evaluation order
+func (r *EligibilityController) saveRulesHandler(c *gin.Context) {
+	var payload []model.EligibilityRule
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rules, err := r.uc.SaveRules(c.Param("id"), payload)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, rules, "OK")
+}
+
+func (r *EligibilityController) listOverridesHandler(c *gin.Context) {
+	overrides, err := r.uc.FindOverrides(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, overrides, "OK")
+}
+
+func (r *EligibilityController) overrideHandler(c *gin.Context) {
+	var payload model.EligibilityOverride
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	payload.EmployeeID = c.Param("employeeID")
+	if err := r.uc.Override(c.Param("id"), &payload, c.GetString("ID")); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, payload, "OK")
+}
+
+func (r *EligibilityController) removeOverrideHandler(c *gin.Context) {
+	if err := r.uc.RemoveOverride(c.Param("id"), c.Param("employeeID")); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func NewEligibilityController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.EligibilityUsecase) *EligibilityController {
+	controller := EligibilityController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	auth.GET("/projects/:id/eligibility", admin, controller.reportHandler)
+	auth.GET("/projects/:id/eligibility/rules", admin, controller.listRulesHandler)
+	auth.PUT("/projects/:id/eligibility/rules", admin, controller.saveRulesHandler)
+	auth.GET("/projects/:id/eligibility/overrides", admin, controller.listOverridesHandler)
+	auth.PUT("/projects/:id/eligibility/overrides/:employeeID", admin, controller.overrideHandler)
+	auth.DELETE("/projects/:id/eligibility/overrides/:employeeID", admin, controller.removeOverrideHandler)
+	return &controller
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type EncryptionController struct {
+	router *gin.Engine
+	uc     usecase.EncryptionUsecase
+	api.BaseApi
+}
+
+func (r *EncryptionController) reencryptHandler(c *gin.Context) {
+	result, err := r.uc.Reencrypt()
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, result, "OK")
+}
+
+func NewEncryptionController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.EncryptionUsecase) *EncryptionController {
+	controller := EncryptionController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	auth.POST("/encryption/reencrypt", admin, twoFactor, controller.reencryptHandler)
+	return &controller
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type HrisSyncController struct {
+	router *gin.Engine
+	uc     usecase.HrisSyncUsecase
+	api.BaseApi
+}
+
+func (r *HrisSyncController) listHandler(c *gin.Context) {
+	syncs, err := r.uc.FindAll()
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, syncs, "OK")
+}
+
+func (r *HrisSyncController) getByIdHandler(c *gin.Context) {
+	sync, err := r.uc.FindById(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, sync, "OK")
+}
+
+// submitHandler takes the HRIS feed as an upload or as the raw body, service accounts push it through the same route
+func (r *HrisSyncController) submitHandler(c *gin.Context) {
+	file, err := r.ParseUploadFile(c)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	source := "upload"
+	if serviceAccountID := c.GetString("ServiceAccountID"); serviceAccountID != "" {
+		source = "api:" + serviceAccountID
+	}
+
+	sync, report, err := r.uc.Submit(c.Request.Context(), file, c.Query("mode"), source, c.GetString("ID"))
+	if err != nil {
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
+		} else {
+			r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	r.NewSuccessSingleResponse(c, sync, "Waiting for approval")
+}
+
+func (r *HrisSyncController) approveHandler(c *gin.Context) {
+	sync, err := r.uc.Approve(c.Param("id"), c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, sync, "OK")
+}
+
+func (r *HrisSyncController) rejectHandler(c *gin.Context) {
+	if err := r.uc.Reject(c.Param("id"), c.GetString("ID")); err != nil {
+		r.NewFailedResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func NewHrisSyncController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.HrisSyncUsecase) *HrisSyncController {
+	controller := HrisSyncController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	roleValidator := middleware.NewRoleValidator()
+	admin := roleValidator.RequireRole(model.RoleAdmin)
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	auth.GET("/hris-syncs", admin, controller.listHandler)
+	auth.GET("/hris-syncs/:id", admin, controller.getByIdHandler)
+	auth.POST("/hris-syncs", roleValidator.RequireRole(model.RoleAdmin, model.RoleServiceAccount), controller.submitHandler)
+	auth.POST("/hris-syncs/:id/approve", admin, twoFactor, controller.approveHandler)
+	auth.POST("/hris-syncs/:id/reject", admin, controller.rejectHandler)
+	return &controller
+}
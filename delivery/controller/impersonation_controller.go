@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/api/request"
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationController struct {
+	router       *gin.Engine
+	uc           usecase.ImpersonationUsecase
+	tokenService authenticator.AccessToken
+	api.BaseApi
+}
+
+func (r *ImpersonationController) startHandler(c *gin.Context) {
+	if c.GetString("ActorID") != "" {
+		r.NewFailedResponse(c, http.StatusForbidden, "Stop the current impersonation first")
+		return
+	}
+
+	actor, subject, err := r.uc.Start(c.GetString("ID"), c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var actorRoles []string
+	for _, v := range actor.Roles {
+		actorRoles = append(actorRoles, v.Name)
+	}
+
+	var subjectRoles []string
+	for _, v := range subject.Roles {
+		subjectRoles = append(subjectRoles, v.Name)
+	}
+
+	actorCred := model.TokenModel{
+		Email: actor.Email,
+		Role:  actorRoles,
+		ID:    actor.ID,
+		Name:  actor.Name,
+	}
+	subjectCred := model.TokenModel{
+		Email: subject.Email,
+		Role:  subjectRoles,
+		ID:    subject.ID,
+		Name:  subject.Name,
+	}
+
+	tokenDetail, err := r.tokenService.CreateImpersonationToken(&actorCred, &subjectCred)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := r.tokenService.StoreAccessToken(subject.Email, tokenDetail); err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = r.uc.Record(&model.ImpersonationLog{
+		ActorID:    actor.ID,
+		SubjectID:  subject.ID,
+		AccessUUID: tokenDetail.AccessUUID,
+		Action:     "start",
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		StatusCode: http.StatusOK,
+		ClientIP:   c.ClientIP(),
+	})
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	r.NewSuccessSingleResponse(c, response.ImpersonationResponse{
+		AccessToken: tokenDetail.AccessToken,
+		TokenModel: model.ModifiedTokenModel{
+			Email:        subject.Email,
+			Role:         subjectRoles,
+			ID:           subject.ID,
+			Name:         subject.Name,
+			Nik:          subject.Nik,
+			Division:     subject.Division,
+			BusinessUnit: subject.BusinessUnit,
+		},
+		Actor:         actorCred,
+		ExpiresAt:     time.Unix(tokenDetail.AtExpired, 0),
+		Impersonating: true,
+		Banner:        fmt.Sprintf("%s is acting as %s", actor.Name, subject.Name),
+	}, "OK")
+}
+
+func (r *ImpersonationController) stopHandler(c *gin.Context) {
+	actorID := c.GetString("ActorID")
+	if actorID == "" {
+		r.NewFailedResponse(c, http.StatusBadRequest, "Current session is not an impersonation")
+		return
+	}
+
+	if err := r.tokenService.DeleteAccessToken(c.GetString("AccessUUID")); err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err := r.uc.Record(&model.ImpersonationLog{
+		ActorID:    actorID,
+		SubjectID:  c.GetString("ID"),
+		AccessUUID: c.GetString("AccessUUID"),
+		Action:     "stop",
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		StatusCode: http.StatusOK,
+		ClientIP:   c.ClientIP(),
+	})
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func (r *ImpersonationController) logsHandler(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	logs, pagination, err := r.uc.FindLogs(c.Query("actorID"), c.Query("subjectID"), request.PaginationParam{
+		Page:  page,
+		Limit: limit,
+	})
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var newLogs []interface{}
+	for _, v := range logs {
+		newLogs = append(newLogs, v)
+	}
+	r.NewSuccesPagedResponse(c, newLogs, "OK", pagination)
+}
+
+func NewImpersonationController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.ImpersonationUsecase) *ImpersonationController {
+	controller := ImpersonationController{
+		router:       r,
+		tokenService: tokenService,
+		uc:           uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	auth.POST("/impersonation/stop", controller.stopHandler)
+	auth.GET("/impersonation/logs", admin, controller.logsHandler)
+	auth.POST("/impersonation/:id", admin, twoFactor, controller.startHandler)
+	return &controller
+}
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type ImportTemplateController struct {
+	router *gin.Engine
+	uc     usecase.ImportTemplateUsecase
+	api.BaseApi
+}
+
+func (r *ImportTemplateController) downloadHandler(c *gin.Context) {
+	file, fileName, err := r.uc.Generate(c.Param("kind"), c.Query("projectID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", file.Bytes())
+}
+
+func NewImportTemplateController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.ImportTemplateUsecase) *ImportTemplateController {
+	controller := ImportTemplateController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	auth.GET("/import-templates/:kind", controller.downloadHandler)
+	return &controller
+}
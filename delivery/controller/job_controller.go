@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"calibration-system.com/utils/exporter"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type JobController struct {
+	router   *gin.Engine
+	uc       usecase.JobUsecase
+	upgrader websocket.Upgrader
+	api.BaseApi
+}
+
+func (r *JobController) listHandler(c *gin.Context) {
+	jobs, err := r.uc.FindByCreator(c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, jobs, "OK")
+}
+
+// importHandler takes the same form as the synchronous upload routes and queues it
+func (r *JobController) importHandler(c *gin.Context) {
+	file, err := r.ParseUploadFile(c)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	input, err := io.ReadAll(file)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	payload := map[string]string{
+		"projectID": c.Request.FormValue("projectID"),
+		"dryRun":    c.Query("dryRun"),
+	}
+
+	job, err := r.uc.Enqueue("import:"+c.Param("kind"), c.GetString("ID"), payload, input)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, job, "Queued")
+}
+
+// reportHandler queues a report, the query string is the same as the synchronous report routes
+func (r *JobController) reportHandler(c *gin.Context) {
+	payload := map[string]string{}
+	for key, values := range c.Request.URL.Query() {
+		if len(values) > 0 {
+			payload[key] = values[0]
+		}
+	}
+
+	job, err := r.uc.Enqueue("report:"+c.Param("kind"), c.GetString("ID"), payload, nil)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, job, "Queued")
+}
+
+func (r *JobController) getHandler(c *gin.Context) {
+	job, ok := r.findOwnJob(c)
+	if !ok {
+		return
+	}
+	r.NewSuccessSingleResponse(c, job, "OK")
+}
+
+func (r *JobController) cancelHandler(c *gin.Context) {
+	job, ok := r.findOwnJob(c)
+	if !ok {
+		return
+	}
+
+	if err := r.uc.Cancel(job.ID); err != nil {
+		r.NewFailedResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func (r *JobController) resultHandler(c *gin.Context) {
+	job, ok := r.findOwnJob(c)
+	if !ok {
+		return
+	}
+
+	result, err := r.uc.FindResult(job.ID)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+result.ResultName)
+	c.Data(http.StatusOK, exporter.ContentType(strings.TrimPrefix(path.Ext(result.ResultName), ".")), result.ResultFile)
+}
+
+// progressHandler pushes the job every second until it finishes, browsers cannot set headers on a websocket so the token comes in ?token=
+func (r *JobController) progressHandler(c *gin.Context) {
+	job, ok := r.findOwnJob(c)
+	if !ok {
+		return
+	}
+
+	conn, err := r.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if err := conn.WriteJSON(job); err != nil || job.Finished() {
+			return
+		}
+
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		job, err = r.uc.FindById(job.ID)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// findOwnJob hides other users' jobs behind a 404, admins can see every job
+func (r *JobController) findOwnJob(c *gin.Context) (*model.Job, bool) {
+	job, err := r.uc.FindById(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, "Job not found")
+		return nil, false
+	}
+
+	if job.CreatedBy == c.GetString("ID") {
+		return job, true
+	}
+	for _, role := range c.GetStringSlice("Roles") {
+		if strings.EqualFold(role, model.RoleAdmin) {
+			return job, true
+		}
+	}
+
+	r.NewFailedResponse(c, http.StatusNotFound, "Job not found")
+	return nil, false
+}
+
+func NewJobController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.JobUsecase, upgrader websocket.Upgrader) *JobController {
+	controller := JobController{
+		router:   r,
+		uc:       uc,
+		upgrader: upgrader,
+	}
+	tokenValidator := middleware.NewTokenValidator(tokenService)
+	auth := r.Group("/auth").Use(tokenValidator.RequireToken())
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	// same as the synchronous routes, user uploads need a recent second factor
+	userImportTwoFactor := func(c *gin.Context) {
+		if kind := c.Param("kind"); kind == "users" || kind == "user-passwords" {
+			twoFactor(c)
+			return
+		}
+		c.Next()
+	}
+	auth.GET("/jobs", controller.listHandler)
+	auth.POST("/jobs/imports/:kind", userImportTwoFactor, controller.importHandler)
+	auth.POST("/jobs/reports/:kind", controller.reportHandler)
+	auth.GET("/jobs/:id", controller.getHandler)
+	auth.POST("/jobs/:id/cancel", controller.cancelHandler)
+	auth.GET("/jobs/:id/result", controller.resultHandler)
+	r.GET("/auth/jobs/:id/ws", middleware.TokenFromQuery(), tokenValidator.RequireToken(), controller.progressHandler)
+	return &controller
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type OrgHierarchyController struct {
+	router *gin.Engine
+	uc     usecase.OrgHierarchyUsecase
+	api.BaseApi
+}
+
+func (r *OrgHierarchyController) reportHandler(c *gin.Context) {
+	report, err := r.uc.Report()
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func (r *OrgHierarchyController) rebuildHandler(c *gin.Context) {
+	report, err := r.uc.Rebuild()
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+// subordinatesHandler returns the whole subtree, ?depth=1 limits it to direct reports, ?depth=2 to N-2 and so on
+func (r *OrgHierarchyController) subordinatesHandler(c *gin.Context) {
+	if !r.canView(c) {
+		return
+	}
+
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "0"))
+	if err != nil || depth < 0 {
+		r.NewFailedResponse(c, http.StatusBadRequest, "depth must be a positive number")
+		return
+	}
+
+	nodes, err := r.uc.FindSubordinates(c.Param("id"), depth)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, nodes, "OK")
+}
+
+func (r *OrgHierarchyController) managersHandler(c *gin.Context) {
+	if !r.canView(c) {
+		return
+	}
+
+	nodes, err := r.uc.FindManagers(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, nodes, "OK")
+}
+
+func (r *OrgHierarchyController) spanHandler(c *gin.Context) {
+	if !r.canView(c) {
+		return
+	}
+
+	span, err := r.uc.FindSpan(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, "Employee not found")
+		return
+	}
+	r.NewSuccessSingleResponse(c, span, "OK")
+}
+
+// canView lets employees look at their own place in the tree, anything else needs admin
+func (r *OrgHierarchyController) canView(c *gin.Context) bool {
+	if c.Param("id") == c.GetString("ID") {
+		return true
+	}
+	for _, role := range c.GetStringSlice("Roles") {
+		if strings.EqualFold(role, model.RoleAdmin) {
+			return true
+		}
+	}
+
+	r.NewFailedResponse(c, http.StatusForbidden, "Forbidden")
+	return false
+}
+
+func NewOrgHierarchyController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.OrgHierarchyUsecase) *OrgHierarchyController {
+	controller := OrgHierarchyController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	auth.GET("/org-hierarchy/report", admin, controller.reportHandler)
+	auth.POST("/org-hierarchy/rebuild", admin, controller.rebuildHandler)
+	auth.GET("/org-hierarchy/:id/subordinates", controller.subordinatesHandler)
+	auth.GET("/org-hierarchy/:id/managers", controller.managersHandler)
+	auth.GET("/org-hierarchy/:id/span", controller.spanHandler)
+	return &controller
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type PhaseLifecycleController struct {
+	router *gin.Engine
+	uc     usecase.PhaseLifecycleUsecase
+	api.BaseApi
+}
+
+func (r *PhaseLifecycleController) grantExtensionHandler(c *gin.Context) {
+	var payload model.PhaseExtension
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := r.uc.GrantExtension(c.Param("id"), &payload, c.GetString("ID")); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, payload, "OK")
+}
+
+func (r *PhaseLifecycleController) listExtensionHandler(c *gin.Context) {
+	extensions, err := r.uc.FindExtensions(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, extensions, "OK")
+}
+
+// runHandler runs the lifecycle now instead of waiting for the next tick
+func (r *PhaseLifecycleController) runHandler(c *gin.Context) {
+	run, err := r.uc.RunOnce(time.Now())
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, run, "OK")
+}
+
+func NewPhaseLifecycleController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.PhaseLifecycleUsecase) *PhaseLifecycleController {
+	controller := PhaseLifecycleController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	auth.POST("/project-phases/:id/extensions", admin, controller.grantExtensionHandler)
+	auth.GET("/project-phases/:id/extensions", admin, controller.listExtensionHandler)
+	auth.POST("/phase-lifecycle/run", admin, controller.runHandler)
+	return &controller
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type ProjectBundleController struct {
+	router *gin.Engine
+	uc     usecase.ProjectBundleUsecase
+	api.BaseApi
+}
+
+// exportHandler downloads the project as a zip archive, ?scope=config leaves out scores and calibrations
+func (r *ProjectBundleController) exportHandler(c *gin.Context) {
+	scope := c.DefaultQuery("scope", model.BundleScopeFull)
+	if scope != model.BundleScopeConfig && scope != model.BundleScopeFull {
+		r.NewFailedResponse(c, http.StatusBadRequest, fmt.Sprintf("Scope must be %s or %s", model.BundleScopeConfig, model.BundleScopeFull))
+		return
+	}
+
+	name := fmt.Sprintf("project-%s-%s-%s.zip", c.Param("id"), scope, time.Now().Format("20060102"))
+	r.NewDownloadResponse(c, name, func(w io.Writer) error {
+		return r.uc.Export(w, c.Param("id"), scope)
+	})
+}
+
+func (r *ProjectBundleController) importHandler(c *gin.Context) {
+	file, err := r.ParseUploadFile(c)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	dryRun := c.Query("dryRun") == "true"
+	skipConflicts := c.Query("skipConflicts") == "true"
+	report, err := r.uc.Import(c.Request.Context(), file, dryRun, skipConflicts)
+	if err != nil {
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
+		} else {
+			r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func NewProjectBundleController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.ProjectBundleUsecase) *ProjectBundleController {
+	controller := ProjectBundleController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	auth.GET("/projects/:id/export", admin, twoFactor, controller.exportHandler)
+	auth.POST("/projects/import", admin, twoFactor, controller.importHandler)
+	return &controller
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type ProjectCloseOutController struct {
+	router *gin.Engine
+	uc     usecase.ProjectCloseOutUsecase
+	api.BaseApi
+}
+
+func (r *ProjectCloseOutController) reportHandler(c *gin.Context) {
+	report, err := r.uc.FindReport(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func (r *ProjectCloseOutController) startHandler(c *gin.Context) {
+	report, err := r.uc.Start(c.Param("id"))
+	if err != nil {
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
+		} else {
+			r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func (r *ProjectCloseOutController) signOffHandler(c *gin.Context) {
+	var payload model.ProjectSignOff
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := r.uc.SignOff(c.Param("id"), &payload, c.GetString("ID")); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, payload, "OK")
+}
+
+func (r *ProjectCloseOutController) cancelHandler(c *gin.Context) {
+	if err := r.uc.Cancel(c.Param("id")); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func (r *ProjectCloseOutController) finalizeHandler(c *gin.Context) {
+	report, err := r.uc.Finalize(c.Param("id"))
+	if err != nil {
+		if report != nil {
+			r.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
+		} else {
+			r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	r.NewSuccessSingleResponse(c, report, "OK")
+}
+
+func (r *ProjectCloseOutController) finalResultsHandler(c *gin.Context) {
+	results, err := r.uc.FindFinalResults(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, results, "OK")
+}
+
+// myResultsHandler returns the released results of the logged in employee
+func (r *ProjectCloseOutController) myResultsHandler(c *gin.Context) {
+	results, err := r.uc.FindEmployeeResults(c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, results, "OK")
+}
+
+func NewProjectCloseOutController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.ProjectCloseOutUsecase) *ProjectCloseOutController {
+	controller := ProjectCloseOutController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	roleValidator := middleware.NewRoleValidator()
+	admin := roleValidator.RequireRole(model.RoleAdmin)
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	auth.GET("/projects/:id/close-out", admin, controller.reportHandler)
+	auth.POST("/projects/:id/close-out/start", admin, controller.startHandler)
+	// business unit heads sign off themselves, the usecase checks the caller is the head
+	auth.POST("/projects/:id/close-out/sign-off", controller.signOffHandler)
+	auth.POST("/projects/:id/close-out/cancel", admin, controller.cancelHandler)
+	auth.POST("/projects/:id/close-out/finalize", admin, twoFactor, controller.finalizeHandler)
+	auth.GET("/projects/:id/final-results", roleValidator.RequireRole(model.RoleAdmin, model.RoleServiceAccount), controller.finalResultsHandler)
+	auth.GET("/me/results", controller.myResultsHandler)
+	return &controller
+}
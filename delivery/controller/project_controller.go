@@ -2,8 +2,8 @@ package controller
 
 import (
 	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
@@ -13,6 +13,7 @@ import (
 	"calibration-system.com/model"
 	"calibration-system.com/usecase"
 	"calibration-system.com/utils/authenticator"
+	"calibration-system.com/utils/exporter"
 	"github.com/gin-gonic/gin"
 )
 
@@ -81,6 +82,21 @@ func (r *ProjectController) createHandler(c *gin.Context) {
 	r.NewSuccessSingleResponse(c, payload, "OK")
 }
 
+func (r *ProjectController) cloneHandler(c *gin.Context) {
+	var payload model.ProjectCloneOptions
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	project, err := r.uc.Clone(c.Param("id"), payload)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, project, "OK")
+}
+
 func (r *ProjectController) publishHandler(c *gin.Context) {
 	id := c.Param("id")
 	if err := r.uc.PublishProject(id); err != nil {
@@ -176,7 +192,10 @@ func (r *ProjectController) getActiveHandlerBySpmoID(c *gin.Context) {
 
 func (r *ProjectController) getScoreDistributionHandlerByID(c *gin.Context) {
 	id := c.Query("businessUnit")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindScoreDistributionByCalibratorID(id, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -191,7 +210,10 @@ func (r *ProjectController) getRatingQuotaHandlerByID(c *gin.Context) {
 	businessUnit := c.Query("businessUnit")
 	types := c.Query("type")
 	countCurrentUser := c.Query("countCurrentUser")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	countUser, err := strconv.Atoi(countCurrentUser)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -210,7 +232,10 @@ func (r *ProjectController) getTotalActualScoreHandlerByID(c *gin.Context) {
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
 	types := c.Query("type")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindTotalActualScoreByCalibratorID(id, prevCalibrator, businessUnit, types, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -224,7 +249,10 @@ func (r *ProjectController) getTotalCalibratedHandlerByID(c *gin.Context) {
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
 	types := c.Query("type")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindTotalCalibratedByCalibratorID(id, prevCalibrator, businessUnit, types, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -238,7 +266,10 @@ func (r *ProjectController) getAverageScoreHandlerByID(c *gin.Context) {
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
 	types := c.Query("type")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindAverageScoreByCalibratorID(id, prevCalibrator, businessUnit, types, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -252,7 +283,10 @@ func (r *ProjectController) getEmployeeNameHandler(c *gin.Context) {
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
 	types := c.Query("type")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindAllEmployeeName(id, prevCalibrator, businessUnit, types, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -266,7 +300,10 @@ func (r *ProjectController) getSupervisorNameHandler(c *gin.Context) {
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
 	types := c.Query("type")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindAllSupervisorName(id, prevCalibrator, businessUnit, types, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -280,7 +317,10 @@ func (r *ProjectController) getGradeHandler(c *gin.Context) {
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
 	types := c.Query("type")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindAllGrade(id, prevCalibrator, businessUnit, types, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -291,7 +331,10 @@ func (r *ProjectController) getGradeHandler(c *gin.Context) {
 
 func (r *ProjectController) getSummaryProjectByCalibratorID(c *gin.Context) {
 	id := c.Query("calibratorID")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	prevCalibratorIDs := c.Query("prevCalibratorIDs")
 	var idStrings []string
 	if prevCalibratorIDs != "" {
@@ -310,7 +353,10 @@ func (r *ProjectController) getCalibrationsByPrevCalibratorBusinessUnit(c *gin.C
 	calibratorID := c.Query("calibratorID")
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 
 	page, err := strconv.Atoi(c.Query("page"))
 	if err != nil {
@@ -397,7 +443,10 @@ func (r *ProjectController) getCalibrationsByPrevCalibratorBusinessUnit(c *gin.C
 func (r *ProjectController) getCalibrationsByBusinessUnit(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
 	businessUnit := c.Query("businessUnit")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 
 	page, err := strconv.Atoi(c.Query("page"))
 	if err != nil {
@@ -505,7 +554,10 @@ func (r *ProjectController) getNumberOneCalibrationsByPrevCalibratorBusinessUnit
 func (r *ProjectController) getNMinusOneCalibrationsByPrevCalibratorBusinessUnit(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
 	businessUnit := c.Query("businessUnit")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 
 	page, err := strconv.Atoi(c.Query("page"))
 	if err != nil {
@@ -594,7 +646,10 @@ func (r *ProjectController) getCalibrationsByPrevCalibratorBusinessUnitAndRating
 	prevCalibrator := c.Query("prevCalibrator")
 	businessUnit := c.Query("businessUnit")
 	rating := c.Query("rating")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 
 	page, err := strconv.Atoi(c.Query("page"))
 	if err != nil {
@@ -630,7 +685,10 @@ func (r *ProjectController) getCalibrationsByPrevCalibratorBusinessUnitAndRating
 func (r *ProjectController) getCalibrationsByBusinessUnitAndRating(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
 	businessUnit := c.Query("businessUnit")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	rating := c.Query("rating")
 
 	page, err := strconv.Atoi(c.Query("page"))
@@ -667,7 +725,10 @@ func (r *ProjectController) getCalibrationsByBusinessUnitAndRating(c *gin.Contex
 
 func (r *ProjectController) getCalibrationsByRating(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	rating := c.Query("rating")
 
 	page, err := strconv.Atoi(c.Query("page"))
@@ -704,7 +765,10 @@ func (r *ProjectController) getCalibrationsByRating(c *gin.Context) {
 
 func (r *ProjectController) getSummaryTotalProjectByCalibrator(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindSummaryProjectTotalByCalibratorID(calibratorID, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -715,7 +779,10 @@ func (r *ProjectController) getSummaryTotalProjectByCalibrator(c *gin.Context) {
 
 func (r *ProjectController) getProjectPhaseByCalibratorId(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindCalibratorPhase(calibratorID, projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -725,7 +792,10 @@ func (r *ProjectController) getProjectPhaseByCalibratorId(c *gin.Context) {
 }
 
 func (r *ProjectController) getProjectPhaseHandler(c *gin.Context) {
-	projectID := c.Query("projectID")
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
 	projects, err := r.uc.FindActiveProjectPhase(projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
@@ -735,7 +805,11 @@ func (r *ProjectController) getProjectPhaseHandler(c *gin.Context) {
 }
 
 func (r *ProjectController) getActiveManagerPhaseHandler(c *gin.Context) {
-	projects, err := r.uc.FindActiveManagerPhase()
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
+	projects, err := r.uc.FindActiveManagerPhase(projectID)
 	if err != nil {
 		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -749,51 +823,52 @@ func (r *ProjectController) getReportCalibrations(c *gin.Context) {
 	businessUnit := c.Param("businessUnit")
 	prevCalibrator := c.Param("prevCalibrator")
 	projectID := c.Param("projectID")
-	file, err := r.uc.ReportCalibrations(types, calibratorID, businessUnit, prevCalibrator, projectID, c)
+	format, err := exporter.ParseFormat(c.Query("format"))
 	if err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	defer func() {
-		// Clean up: Remove the file after it has been served
-		err := os.Remove(file)
+	if format != exporter.FormatXLSX {
+		table, err := r.uc.ReportCalibrationsTable(types, calibratorID, businessUnit, prevCalibrator, projectID)
 		if err != nil {
-			fmt.Println("Error removing file:", err)
+			r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+			return
 		}
-	}()
-
-	// Set the response headers for downloading
-	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Header("Content-Disposition", "attachment; filename="+file)
+		r.NewExportResponse(c, format, table)
+		return
+	}
 
-	// Serve the file
-	c.File(file)
+	r.NewDownloadResponse(c, "report.xlsx", func(w io.Writer) error {
+		return r.uc.ReportCalibrations(w, types, calibratorID, businessUnit, prevCalibrator, projectID)
+	})
 }
 
 func (r *ProjectController) getSummaryReportCalibrations(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
-	projectID := c.Query("projectID")
-	file, err := r.uc.SummaryReportCalibrations(calibratorID, projectID, c)
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
+	format, err := exporter.ParseFormat(c.Query("format"))
 	if err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	defer func() {
-		// Clean up: Remove the file after it has been served
-		err := os.Remove(file)
+	if format != exporter.FormatXLSX {
+		table, err := r.uc.SummaryReportCalibrationsTable(calibratorID, projectID)
 		if err != nil {
-			fmt.Println("Error removing file:", err)
+			r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+			return
 		}
-	}()
-
-	// Set the response headers for downloading
-	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Header("Content-Disposition", "attachment; filename="+file)
+		r.NewExportResponse(c, format, table)
+		return
+	}
 
-	// Serve the file
-	c.File(file)
+	r.NewDownloadResponse(c, "report.xlsx", func(w io.Writer) error {
+		return r.uc.SummaryReportCalibrations(w, calibratorID, projectID)
+	})
 }
 
 func (r *ProjectController) getReportAllCalibrations(c *gin.Context) {
@@ -801,27 +876,29 @@ func (r *ProjectController) getReportAllCalibrations(c *gin.Context) {
 	calibratorID := c.Query("calibratorID")
 	businessUnit := c.Query("businessUnit")
 	prevCalibrator := c.Query("prevCalibrator")
-	projectID := c.Query("projectID")
-	file, err := r.uc.ReportCalibrations(types, calibratorID, businessUnit, prevCalibrator, projectID, c)
+	projectID, ok := r.ParseProjectID(c)
+	if !ok {
+		return
+	}
+	format, err := exporter.ParseFormat(c.Query("format"))
 	if err != nil {
-		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	defer func() {
-		// Clean up: Remove the file after it has been served
-		err := os.Remove(file)
+	if format != exporter.FormatXLSX {
+		table, err := r.uc.ReportCalibrationsTable(types, calibratorID, businessUnit, prevCalibrator, projectID)
 		if err != nil {
-			fmt.Println("Error removing file:", err)
+			r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+			return
 		}
-	}()
-
-	// Set the response headers for downloading
-	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Header("Content-Disposition", "attachment; filename="+file)
+		r.NewExportResponse(c, format, table)
+		return
+	}
 
-	// Serve the file
-	c.File(file)
+	r.NewDownloadResponse(c, "report.xlsx", func(w io.Writer) error {
+		return r.uc.ReportCalibrations(w, types, calibratorID, businessUnit, prevCalibrator, projectID)
+	})
 }
 
 func NewProjectController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.ProjectUsecase) *ProjectController {
@@ -831,6 +908,7 @@ func NewProjectController(r *gin.Engine, tokenService authenticator.AccessToken,
 		uc:           uc,
 	}
 	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
 	auth.GET("/projects", controller.listHandler)
 	auth.GET("/projects/active", controller.getActiveHandler)
 	auth.GET("/projects/calibrator", controller.getActiveHandlerByCalibratorID)
@@ -858,7 +936,8 @@ func NewProjectController(r *gin.Engine, tokenService authenticator.AccessToken,
 	auth.GET("/projects/:id", controller.getByIdHandler)
 	auth.PUT("/projects", controller.updateHandler)
 	auth.POST("/projects", controller.createHandler)
-	auth.POST("/projects/publish/:id", controller.publishHandler)
+	auth.POST("/projects/:id/clone", middleware.NewRoleValidator().RequireRole(model.RoleAdmin), controller.cloneHandler)
+	auth.POST("/projects/publish/:id", twoFactor, controller.publishHandler)
 	auth.POST("/projects/deactive/:id", controller.deactivateHandler)
 	auth.DELETE("/projects/:id", controller.deleteHandler)
 	auth.GET("/projects-report/:type/:calibratorID/:businessUnit/:prevCalibrator/:projectID", controller.getReportCalibrations)
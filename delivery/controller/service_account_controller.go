@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/api/request"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceAccountController struct {
+	router *gin.Engine
+	uc     usecase.ApiKeyUsecase
+	api.BaseApi
+}
+
+func (r *ServiceAccountController) listHandler(c *gin.Context) {
+	serviceAccounts, err := r.uc.FindAllServiceAccount()
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, serviceAccounts, "OK")
+}
+
+func (r *ServiceAccountController) getByIdHandler(c *gin.Context) {
+	serviceAccount, err := r.uc.FindServiceAccountById(c.Param("id"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, serviceAccount, "OK")
+}
+
+func (r *ServiceAccountController) createHandler(c *gin.Context) {
+	var payload request.CreateServiceAccount
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	serviceAccount, err := r.uc.CreateServiceAccount(payload, c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, serviceAccount, "OK")
+}
+
+func (r *ServiceAccountController) deactivateHandler(c *gin.Context) {
+	if err := r.uc.DeactivateServiceAccount(c.Param("id")); err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func (r *ServiceAccountController) createKeyHandler(c *gin.Context) {
+	var payload request.CreateApiKey
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiKey, err := r.uc.CreateKey(c.Param("id"), payload)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, apiKey, "Store the key now, it will not be shown again")
+}
+
+func (r *ServiceAccountController) rotateKeyHandler(c *gin.Context) {
+	var payload request.RotateApiKey
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiKey, err := r.uc.RotateKey(c.Param("id"), payload)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, apiKey, "Store the key now, it will not be shown again")
+}
+
+func (r *ServiceAccountController) revokeKeyHandler(c *gin.Context) {
+	if err := r.uc.RevokeKey(c.Param("id")); err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func NewServiceAccountController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.ApiKeyUsecase) *ServiceAccountController {
+	controller := ServiceAccountController{
+		router: r,
+		uc:     uc,
+	}
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	admin := middleware.NewRoleValidator().RequireRole(model.RoleAdmin)
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
+	auth.GET("/service-accounts", admin, controller.listHandler)
+	auth.GET("/service-accounts/:id", admin, controller.getByIdHandler)
+	auth.POST("/service-accounts", admin, controller.createHandler)
+	auth.DELETE("/service-accounts/:id", admin, controller.deactivateHandler)
+	auth.POST("/service-accounts/:id/api-keys", admin, twoFactor, controller.createKeyHandler)
+	auth.POST("/api-keys/:id/rotate", admin, twoFactor, controller.rotateKeyHandler)
+	auth.DELETE("/api-keys/:id", admin, twoFactor, controller.revokeKeyHandler)
+	return &controller
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+
+	"calibration-system.com/delivery/api"
+	"calibration-system.com/delivery/api/request"
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/delivery/middleware"
+	"calibration-system.com/usecase"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct {
+	router       *gin.Engine
+	uc           usecase.TwoFactorUsecase
+	tokenService authenticator.AccessToken
+	api.BaseApi
+}
+
+func (r *TwoFactorController) statusHandler(c *gin.Context) {
+	status, err := r.uc.FindStatus(c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, status, "OK")
+}
+
+func (r *TwoFactorController) enrollHandler(c *gin.Context) {
+	enrollment, err := r.uc.Enroll(c.GetString("ID"))
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, enrollment, "OK")
+}
+
+func (r *TwoFactorController) confirmHandler(c *gin.Context) {
+	var payload request.TwoFactorCode
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	recoveryCodes, err := r.uc.Confirm(c.GetString("ID"), payload.Code)
+	if err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// an enrollment token has done its job, the user logs in again through the challenge
+	if c.GetString("TokenScope") == authenticator.EnrollmentScope {
+		if err := r.tokenService.DeleteAccessToken(c.GetString("AccessUUID")); err != nil {
+			r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else if err := r.tokenService.StoreTwoFactorVerified(c.GetString("AccessUUID")); err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, recoveryCodes, "OK")
+}
+
+func (r *TwoFactorController) verifyHandler(c *gin.Context) {
+	var payload request.TwoFactorCode
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !r.checkCode(c, http.StatusUnauthorized, func() error {
+		return r.uc.Verify(c.GetString("ID"), payload.Code)
+	}) {
+		return
+	}
+
+	if err := r.tokenService.StoreTwoFactorVerified(c.GetString("AccessUUID")); err != nil {
+		r.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.NewSuccessSingleResponse(c, "", "OK")
+}
+
+func (r *TwoFactorController) regenerateRecoveryCodesHandler(c *gin.Context) {
+	var payload request.TwoFactorCode
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var recoveryCodes *response.TwoFactorRecoveryCodes
+	if !r.checkCode(c, http.StatusUnauthorized, func() (err error) {
+		recoveryCodes, err = r.uc.RegenerateRecoveryCodes(c.GetString("ID"), payload.Code)
+		return err
+	}) {
+		return
+	}
+	r.NewSuccessSingleResponse(c, recoveryCodes, "OK")
+}
+
+func (r *TwoFactorController) disableHandler(c *gin.Context) {
+	var payload request.TwoFactorCode
+	if err := r.ParseRequestBody(c, &payload); err != nil {
+		r.NewFailedResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !r.checkCode(c, http.StatusBadRequest, func() error {
+		return r.uc.Disable(c.GetString("ID"), payload.Code)
+	}) {
+		return
+	}
+	c.String(http.StatusNoContent, "")
+}
+
+// checkCode runs a check of a signed-in user's code. Wrong codes are counted per user and once there are as many as
+// a login challenge takes the code routes answer 429 until the count expires.
+func (r *TwoFactorController) checkCode(c *gin.Context, failedStatus int, check func() error) bool {
+	userID := c.GetString("ID")
+	if r.tokenService.IsTwoFactorLocked(userID) {
+		r.NewFailedResponse(c, http.StatusTooManyRequests, "Too many invalid codes, try again later")
+		return false
+	}
+	if err := check(); err != nil {
+		if err := r.tokenService.CountTwoFactorFailure(userID); err != nil {
+			log.Printf("Failed to count two factor failure of %s: %v", userID, err)
+		}
+		r.NewFailedResponse(c, failedStatus, err.Error())
+		return false
+	}
+	return true
+}
+
+func NewTwoFactorController(r *gin.Engine, tokenService authenticator.AccessToken, uc usecase.TwoFactorUsecase) *TwoFactorController {
+	controller := TwoFactorController{
+		router:       r,
+		tokenService: tokenService,
+		uc:           uc,
+	}
+	ownSession := middleware.NewTwoFactorValidator(tokenService).RejectImpersonation()
+	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	auth.GET("/2fa", controller.statusHandler)
+	auth.POST("/2fa/enroll", ownSession, controller.enrollHandler)
+	auth.POST("/2fa/confirm", ownSession, controller.confirmHandler)
+	auth.POST("/2fa/verify", ownSession, controller.verifyHandler)
+	auth.POST("/2fa/recovery-codes", ownSession, controller.regenerateRecoveryCodesHandler)
+	auth.DELETE("/2fa", ownSession, controller.disableHandler)
+	return &controller
+}
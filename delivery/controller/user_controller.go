@@ -3,7 +3,6 @@ package controller
 import (
 	"net/http"
 	"strconv"
-	"strings"
 
 	"calibration-system.com/delivery/api"
 	"calibration-system.com/delivery/api/request"
@@ -147,6 +146,10 @@ func (u *UserController) createHandler(c *gin.Context) {
 		Position:         payload.Position,
 		ScoringMethod:    payload.ScoringMethod,
 	}
+	if isServiceAccount(c) && len(payload.Roles) > 0 {
+		u.NewFailedResponse(c, http.StatusForbidden, "Roles can't be assigned by a service account")
+		return
+	}
 	if err := u.uc.CreateUser(user, payload.Roles); err != nil {
 		u.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -181,6 +184,23 @@ func (u *UserController) updateHandler(c *gin.Context) {
 		PhoneNumber:      payload.PhoneNumber,
 		ScoringMethod:    payload.ScoringMethod,
 	}
+	if isServiceAccount(c) {
+		if len(payload.Roles) > 0 {
+			u.NewFailedResponse(c, http.StatusForbidden, "Roles can't be assigned by a service account")
+			return
+		}
+		// accounts with a role are kept out of reach of an api key, a changed email would take over the account
+		// through forgot password
+		existing, err := u.uc.FindById(payload.ID)
+		if err != nil {
+			u.NewFailedResponse(c, http.StatusNotFound, "User Not Found")
+			return
+		}
+		if len(existing.Roles) > 0 {
+			u.NewFailedResponse(c, http.StatusForbidden, "Users with a role can't be changed by a service account")
+			return
+		}
+	}
 	if err := u.uc.SaveUser(user, payload.Roles); err != nil {
 		u.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -199,43 +219,47 @@ func (u *UserController) deleteHandler(c *gin.Context) {
 }
 
 func (u *UserController) uploadHandler(c *gin.Context) {
-	file, err := c.FormFile("excelFile")
+	file, err := u.ParseUploadFile(c)
 	if err != nil {
 		u.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	defer file.Close()
 
-	logs, err := u.uc.BulkInsert(file)
+	dryRun := c.Query("dryRun") == "true"
+	report, err := u.uc.BulkInsert(c.Request.Context(), file, dryRun, !isServiceAccount(c))
 	if err != nil {
-		if len(logs) > 0 {
-			u.NewFailedResponse(c, http.StatusInternalServerError, strings.Join(logs, ","))
+		if report != nil {
+			u.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
 		} else {
 			u.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	u.NewSuccessSingleResponse(c, "", "OK")
+	u.NewSuccessSingleResponse(c, report, "OK")
 }
 
 func (u *UserController) uploadPasswordHandler(c *gin.Context) {
-	file, err := c.FormFile("excelFile")
+	file, err := u.ParseUploadFile(c)
 	if err != nil {
 		u.NewFailedResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	defer file.Close()
 
-	logs, err := u.uc.BulkChangePassword(file)
+	dryRun := c.Query("dryRun") == "true"
+	report, err := u.uc.BulkChangePassword(c.Request.Context(), file, dryRun)
 	if err != nil {
-		if len(logs) > 0 {
-			u.NewFailedResponse(c, http.StatusInternalServerError, strings.Join(logs, ","))
+		if report != nil {
+			u.NewFailedDataResponse(c, http.StatusUnprocessableEntity, report, err.Error())
 		} else {
 			u.NewFailedResponse(c, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	u.NewSuccessSingleResponse(c, "", "OK")
+	u.NewSuccessSingleResponse(c, report, "OK")
 }
 
 func (u *UserController) generatePasswordHandler(c *gin.Context) {
@@ -248,7 +272,12 @@ func (u *UserController) generatePasswordHandler(c *gin.Context) {
 	u.NewSuccessSingleResponse(c, "Success Generate Password", "OK")
 }
 
-func NewUserController(u *gin.Engine, tokenService authenticator.AccessToken, uc usecase.UserUsecase) *UserController {
+// isServiceAccount tells api key callers apart, they write employee data but never roles or users that have one
+func isServiceAccount(c *gin.Context) bool {
+	return c.GetString("ServiceAccountID") != ""
+}
+
+func NewUserController(u *gin.Engine, tokenService authenticator.AccessToken, limiter middleware.RateLimiter, uc usecase.UserUsecase) *UserController {
 	controller := UserController{
 		router:       u,
 		tokenService: tokenService,
@@ -256,18 +285,19 @@ func NewUserController(u *gin.Engine, tokenService authenticator.AccessToken, uc
 	}
 
 	auth := u.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
+	twoFactor := middleware.NewTwoFactorValidator(tokenService).RequireRecentTwoFactor()
 	auth.GET("/users", controller.listHandler)
 	auth.GET("/users/all", controller.allListHandler)
 	auth.GET("/users/all-admin", controller.listUserAdminHandler)
 	auth.GET("/users/:id", controller.getByIdHandler)
-	auth.GET("/users-switch/:id", controller.getByIdSwitchHandler)
+	auth.GET("/users-switch/:id", middleware.NewRoleValidator().RequireRole(model.RoleAdmin), controller.getByIdSwitchHandler)
 	auth.GET("/users/project/:projectId", controller.getByProjectId)
 	auth.PUT("/users", controller.updateHandler)
-	u.POST("/users", controller.createHandler)
+	u.POST("/users", limiter.Limit("public"), controller.createHandler)
 	auth.POST("/users", controller.createHandler)
 	auth.POST("/users/generate-password/:id", controller.generatePasswordHandler)
-	auth.POST("/users/upload", controller.uploadHandler)
-	auth.POST("/users/upload/password", controller.uploadPasswordHandler)
+	auth.POST("/users/upload", twoFactor, controller.uploadHandler)
+	auth.POST("/users/upload/password", twoFactor, controller.uploadPasswordHandler)
 	auth.DELETE("/users/:id", controller.deleteHandler)
 	return &controller
 }
@@ -0,0 +1,37 @@
+package middleware
+
+import "calibration-system.com/model"
+
+// Routes reachable with an api key, keyed by "<METHOD> <route path>". Anything not listed is denied to service accounts.
+var apiKeyRouteScopes = map[string]string{
+	"GET /auth/users":                                model.ScopeUsersRead,
+	"GET /auth/users/all":                            model.ScopeUsersRead,
+	"GET /auth/users/:id":                            model.ScopeUsersRead,
+	"POST /auth/users":                               model.ScopeUsersWrite,
+	"PUT /auth/users":                                model.ScopeUsersWrite,
+	"POST /auth/users/upload":                        model.ScopeUsersWrite,
+	"POST /auth/hris-syncs":                          model.ScopeUsersWrite,
+	"GET /auth/actual-scores":                        model.ScopeActualScoresRead,
+	"GET /auth/actual-scores/:projectId/:employeeId": model.ScopeActualScoresRead,
+	"POST /auth/actual-scores":                       model.ScopeActualScoresWrite,
+	"PUT /auth/actual-scores":                        model.ScopeActualScoresWrite,
+	"POST /auth/actual-scores/upload":                model.ScopeActualScoresWrite,
+	"GET /auth/projects":                             model.ScopeReportsRead,
+	"GET /auth/projects/:id":                         model.ScopeReportsRead,
+	"GET /auth/projects-report-summary":              model.ScopeReportsRead,
+	"GET /auth/projects-report/:type/:calibratorID/:businessUnit/:prevCalibrator/:projectID": model.ScopeReportsRead,
+	"GET /auth/projects/:id/final-results":                                                   model.ScopeResultsRead,
+}
+
+func apiKeyAllowed(method, path string, scopes []string) bool {
+	scope, ok := apiKeyRouteScopes[method+" "+path]
+	if !ok {
+		return false
+	}
+	for _, v := range scopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
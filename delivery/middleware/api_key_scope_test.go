@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"testing"
+
+	"calibration-system.com/model"
+)
+
+func TestApiKeyAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		scopes []string
+		want   bool
+	}{
+		{"scope of the route", "GET", "/auth/users", []string{model.ScopeUsersRead}, true},
+		{"one of several scopes", "PUT", "/auth/users", []string{model.ScopeUsersRead, model.ScopeUsersWrite}, true},
+		{"route path with params", "GET", "/auth/users/:id", []string{model.ScopeUsersRead}, true},
+		{"other scope", "PUT", "/auth/users", []string{model.ScopeUsersRead}, false},
+		{"read scope on a write method", "POST", "/auth/actual-scores", []string{model.ScopeActualScoresRead}, false},
+		{"no scopes", "GET", "/auth/users", nil, false},
+		{"unlisted route", "DELETE", "/auth/users/:id", []string{model.ScopeUsersWrite}, false},
+		{"unlisted method", "DELETE", "/auth/users", []string{model.ScopeUsersRead, model.ScopeUsersWrite}, false},
+		{"concrete path instead of the route", "GET", "/auth/users/42", []string{model.ScopeUsersRead}, false},
+		{"empty route", "", "", []string{model.ScopeUsersRead}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apiKeyAllowed(tt.method, tt.path, tt.scopes); got != tt.want {
+				t.Errorf("apiKeyAllowed(%s %s, %v) = %v, want %v", tt.method, tt.path, tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log"
+
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type DelegationAuditMiddleware interface {
+	RecordAction() gin.HandlerFunc
+}
+
+type delegationAuditMiddleware struct {
+	uc usecase.DelegationUsecase
+}
+
+// RecordAction is registered on the engine and records the requests a handler let through on a delegation, the
+// handler sets DelegationID and OnBehalfOf once it has matched the caller to an active delegation
+func (d *delegationAuditMiddleware) RecordAction() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		delegationID := ctx.GetString("DelegationID")
+		if delegationID == "" {
+			return
+		}
+
+		err := d.uc.Record(&model.DelegatedAction{
+			DelegationID: delegationID,
+			DelegateID:   ctx.GetString("ID"),
+			CalibratorID: ctx.GetString("OnBehalfOf"),
+			Method:       ctx.Request.Method,
+			Path:         ctx.Request.URL.Path,
+			StatusCode:   ctx.Writer.Status(),
+			ClientIP:     ctx.ClientIP(),
+		})
+		if err != nil {
+			log.Printf("Failed to record delegated action: %v", err)
+		}
+	}
+}
+
+func NewDelegationAudit(uc usecase.DelegationUsecase) DelegationAuditMiddleware {
+	return &delegationAuditMiddleware{
+		uc: uc,
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"calibration-system.com/model"
+	"calibration-system.com/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationAuditMiddleware interface {
+	RecordMutation() gin.HandlerFunc
+}
+
+type impersonationAuditMiddleware struct {
+	uc usecase.ImpersonationUsecase
+}
+
+// RecordMutation is registered on the engine, so it runs after the group's RequireToken has set ActorID
+func (i *impersonationAuditMiddleware) RecordMutation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		actorID := ctx.GetString("ActorID")
+		if actorID == "" {
+			return
+		}
+
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+
+		err := i.uc.Record(&model.ImpersonationLog{
+			ActorID:    actorID,
+			SubjectID:  ctx.GetString("ID"),
+			AccessUUID: ctx.GetString("AccessUUID"),
+			Action:     "request",
+			Method:     ctx.Request.Method,
+			Path:       ctx.Request.URL.Path,
+			StatusCode: ctx.Writer.Status(),
+			ClientIP:   ctx.ClientIP(),
+		})
+		if err != nil {
+			log.Printf("Failed to record impersonation log: %v", err)
+		}
+	}
+}
+
+func NewImpersonationAudit(uc usecase.ImpersonationUsecase) ImpersonationAuditMiddleware {
+	return &impersonationAuditMiddleware{
+		uc: uc,
+	}
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"calibration-system.com/config"
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// Token bucket kept as a redis hash so every instance shares the same budget.
+// Returns {allowed, remaining, retryAfterMs}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(data[1]) or capacity
+local ts = tonumber(data[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity / rate))
+return {allowed, math.floor(tokens), retry}
+`)
+
+type RateLimiter interface {
+	Limit(group string) gin.HandlerFunc
+	LimitPrefix(prefix, group string) gin.HandlerFunc
+}
+
+type rateLimiter struct {
+	client   *redis.Client
+	accToken authenticator.AccessToken
+	cfg      config.RateLimitConfig
+}
+
+func (r *rateLimiter) Limit(group string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		r.take(ctx, group)
+	}
+}
+
+func (r *rateLimiter) LimitPrefix(prefix, group string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !strings.HasPrefix(ctx.Request.URL.Path, prefix) {
+			ctx.Next()
+			return
+		}
+		r.take(ctx, group)
+	}
+}
+
+func (r *rateLimiter) take(ctx *gin.Context, group string) {
+	rule, ok := r.cfg.RateLimitRules[group]
+	if !r.cfg.RateLimitEnabled || !ok {
+		ctx.Next()
+		return
+	}
+
+	keys := []string{fmt.Sprintf("ratelimit:%s:ip:%s", group, ctx.ClientIP())}
+	if userID := r.userID(ctx); userID != "" {
+		keys = append(keys, fmt.Sprintf("ratelimit:%s:user:%s", group, userID))
+	}
+
+	rate := float64(rule.Capacity) / float64(rule.Period.Milliseconds())
+	remaining := int64(rule.Capacity)
+	for _, key := range keys {
+		result, err := tokenBucketScript.Run(context.Background(), r.client, []string{key}, rule.Capacity, rate, time.Now().UnixMilli()).Int64Slice()
+		if err != nil {
+			// Fail open, an unavailable redis should not take the API down
+			log.Printf("Failed to check rate limit %s: %v", key, err)
+			ctx.Next()
+			return
+		}
+
+		if result[1] < remaining {
+			remaining = result[1]
+		}
+
+		if result[0] == 0 {
+			retryAfter := int(math.Ceil(float64(result[2]) / 1000))
+			ctx.Header("X-RateLimit-Limit", strconv.Itoa(rule.Capacity))
+			ctx.Header("X-RateLimit-Remaining", "0")
+			ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"message": "Too many requests",
+			})
+			ctx.Abort()
+			return
+		}
+	}
+
+	ctx.Header("X-RateLimit-Limit", strconv.Itoa(rule.Capacity))
+	ctx.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	ctx.Next()
+}
+
+func (r *rateLimiter) userID(ctx *gin.Context) string {
+	if id := ctx.GetString("ID"); id != "" {
+		return id
+	}
+
+	header := ctx.GetHeader("Authorization")
+	if header == "" {
+		return ""
+	}
+
+	accessDetail, err := r.accToken.VerifyAccessToken(strings.Replace(header, "Bearer ", "", -1))
+	if err != nil {
+		return ""
+	}
+	return accessDetail.ID
+}
+
+func NewRateLimiter(client *redis.Client, acctToken authenticator.AccessToken, cfg config.RateLimitConfig) RateLimiter {
+	return &rateLimiter{
+		client:   client,
+		accToken: acctToken,
+		cfg:      cfg,
+	}
+}
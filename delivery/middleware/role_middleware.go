@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleMiddleware interface {
+	RequireRole(roles ...string) gin.HandlerFunc
+}
+
+type roleMiddleware struct{}
+
+// RequireRole must be chained after RequireToken, it reads the Roles set there
+func (r *roleMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userRoles := ctx.GetStringSlice("Roles")
+		for _, userRole := range userRoles {
+			for _, role := range roles {
+				if strings.EqualFold(userRole, role) {
+					ctx.Next()
+					return
+				}
+			}
+		}
+
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": "Forbidden",
+		})
+		ctx.Abort()
+	}
+}
+
+func NewRoleValidator() RoleMiddleware {
+	return &roleMiddleware{}
+}
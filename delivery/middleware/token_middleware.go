@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
+	"calibration-system.com/utils"
 	"calibration-system.com/utils/authenticator"
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +23,11 @@ type authTokenMiddleware struct {
 
 func (a *authTokenMiddleware) RequireToken() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		if apiKey := bindApiKey(ctx); apiKey != "" {
+			a.requireApiKey(ctx, apiKey)
+			return
+		}
+
 		tokenString, err := authenticator.BindAuthHeader(ctx)
 		if err != nil {
 			ctx.JSON(http.StatusUnauthorized, gin.H{
@@ -45,15 +52,79 @@ func (a *authTokenMiddleware) RequireToken() gin.HandlerFunc {
 			ctx.Abort()
 			return
 		}
+		if accessDetail.Scope == authenticator.EnrollmentScope && !enrollmentRoutes[ctx.Request.Method+" "+ctx.FullPath()] {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"message":                     "Two factor enrollment required",
+				"twoFactorEnrollmentRequired": true,
+			})
+			ctx.Abort()
+			return
+		}
 		ctx.Set("Roles", accessDetail.Roles)
 		ctx.Set("Email", accessDetail.Email)
 		ctx.Set("ID", accessDetail.ID)
+		ctx.Set("AccessUUID", accessDetail.AccessUUID)
+		ctx.Set("TokenScope", accessDetail.Scope)
+		if accessDetail.ActorID != "" {
+			ctx.Set("ActorID", accessDetail.ActorID)
+		}
 		ctx.Next()
 	}
 }
 
+func (a *authTokenMiddleware) requireApiKey(ctx *gin.Context, apiKey string) {
+	accessDetail, err := a.accToken.VerifyApiKey(apiKey, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		ctx.Abort()
+		return
+	}
+
+	if !apiKeyAllowed(ctx.Request.Method, ctx.FullPath(), accessDetail.Scopes) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": "Api key scope does not allow this route",
+		})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("Roles", accessDetail.Roles)
+	ctx.Set("Email", accessDetail.Email)
+	ctx.Set("ID", accessDetail.ID)
+	ctx.Set("AccessUUID", accessDetail.AccessUUID)
+	ctx.Set("ServiceAccountID", accessDetail.ServiceAccountID)
+	ctx.Set("Scopes", accessDetail.Scopes)
+	ctx.Next()
+}
+
+// bindApiKey accepts either X-API-Key or a bearer value carrying the api key prefix
+func bindApiKey(ctx *gin.Context) string {
+	if apiKey := ctx.GetHeader("X-API-Key"); apiKey != "" {
+		return apiKey
+	}
+
+	bearer := strings.Replace(ctx.GetHeader("Authorization"), "Bearer ", "", -1)
+	if strings.HasPrefix(bearer, utils.ApiKeyPrefix) {
+		return bearer
+	}
+	return ""
+}
+
 func NewTokenValidator(acctToken authenticator.AccessToken) AuthTokenMiddleware {
 	return &authTokenMiddleware{
 		accToken: acctToken,
 	}
 }
+
+// TokenFromQuery copies ?token= into the Authorization header for clients that cannot set headers, like browser websockets.
+// Chain it before RequireToken.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if token := ctx.Query("token"); token != "" && ctx.GetHeader("Authorization") == "" {
+			ctx.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+
+	"calibration-system.com/utils/authenticator"
+	"github.com/gin-gonic/gin"
+)
+
+// Routes an enrollment token reaches, keyed by "<METHOD> <route path>"
+var enrollmentRoutes = map[string]bool{
+	"GET /auth/2fa":          true,
+	"POST /auth/2fa/enroll":  true,
+	"POST /auth/2fa/confirm": true,
+	"POST /auth/logout":      true,
+}
+
+type TwoFactorMiddleware interface {
+	RequireRecentTwoFactor() gin.HandlerFunc
+	RejectImpersonation() gin.HandlerFunc
+}
+
+type twoFactorMiddleware struct {
+	accToken authenticator.AccessToken
+}
+
+// RequireRecentTwoFactor must be chained after RequireToken, it reads the AccessUUID set there
+func (t *twoFactorMiddleware) RequireRecentTwoFactor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// Service accounts have no second factor, the api key scope already guards the route
+		if ctx.GetString("ServiceAccountID") != "" {
+			ctx.Next()
+			return
+		}
+
+		// the second factor is the subject's, an impersonating admin can't give it for them
+		if ctx.GetString("ActorID") != "" {
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "Two factor verification can't be given while impersonating"})
+			ctx.Abort()
+			return
+		}
+
+		accessUUID := ctx.GetString("AccessUUID")
+		if accessUUID == "" || !t.accToken.IsTwoFactorVerified(accessUUID) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"message":           "Two factor verification required",
+				"twoFactorRequired": true,
+			})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RejectImpersonation keeps an impersonating admin away from the subject's second factor, it must be chained after
+// RequireToken
+func (t *twoFactorMiddleware) RejectImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString("ActorID") != "" {
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "Two factor settings can't be changed while impersonating"})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func NewTwoFactorValidator(acctToken authenticator.AccessToken) TwoFactorMiddleware {
+	return &twoFactorMiddleware{
+		accToken: acctToken,
+	}
+}
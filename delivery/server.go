@@ -1,6 +1,7 @@
 package delivery
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -9,6 +10,7 @@ import (
 	"calibration-system.com/delivery/middleware"
 	"calibration-system.com/manager"
 	"calibration-system.com/model"
+	"calibration-system.com/utils"
 	"calibration-system.com/utils/authenticator"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -22,21 +24,22 @@ type Server struct {
 	authRoute    gin.IRoutes
 	host         string
 	tokenService authenticator.AccessToken
+	rateLimiter  middleware.RateLimiter
 	cfg          config.Config
 	websocket.Upgrader
 }
 
 func (s *Server) initController() {
 	controller.NewRoleController(s.engine, s.tokenService, s.ucManager.RoleUc())
-	controller.NewUserController(s.engine, s.tokenService, s.ucManager.UserUc())
-	controller.NewAuthController(s.engine, s.ucManager.AuthUc(), s.tokenService, s.cfg)
+	controller.NewUserController(s.engine, s.tokenService, s.rateLimiter, s.ucManager.UserUc())
+	controller.NewAuthController(s.engine, s.ucManager.AuthUc(), s.ucManager.TwoFactorUc(), s.tokenService, s.rateLimiter, s.cfg)
 	controller.NewGroupBusinessUnitController(s.engine, s.tokenService, s.ucManager.GroupBusinessUnitUc())
 	controller.NewBusinessUnitController(s.engine, s.tokenService, s.ucManager.BusinessUnitUc())
 	controller.NewPhaseController(s.engine, s.tokenService, s.ucManager.PhaseUc())
 	controller.NewProjectController(s.engine, s.tokenService, s.ucManager.ProjectUc())
 	controller.NewProjectPhaseController(s.engine, s.tokenService, s.ucManager.ProjectPhaseUc())
 	controller.NewActualScoreController(s.engine, s.tokenService, s.ucManager.ActualScoreUc())
-	controller.NewCalibrationController(s.engine, s.tokenService, s.ucManager.CalibrationUc())
+	controller.NewCalibrationController(s.engine, s.tokenService, s.ucManager.CalibrationUc(), s.ucManager.DelegationUc())
 	controller.NewRatingQuotaController(s.engine, s.tokenService, s.ucManager.RatingQuotaUc())
 	controller.NewScoreDistributionController(s.engine, s.tokenService, s.ucManager.ScoreDistributionUc())
 	controller.NewRemarkSettingController(s.engine, s.tokenService, s.ucManager.RemarkSettingUc())
@@ -44,10 +47,28 @@ func (s *Server) initController() {
 	controller.NewBottomRemarkController(s.engine, s.tokenService, s.ucManager.BottomRemarkUc())
 	controller.NewAnnouncementController(s.engine, s.tokenService, s.ucManager.AnnouncementUc())
 	controller.NewFaqController(s.engine, s.tokenService, s.ucManager.FaqUc())
+	controller.NewTwoFactorController(s.engine, s.tokenService, s.ucManager.TwoFactorUc())
+	controller.NewImpersonationController(s.engine, s.tokenService, s.ucManager.ImpersonationUc())
+	controller.NewServiceAccountController(s.engine, s.tokenService, s.ucManager.ApiKeyUc())
+	controller.NewEncryptionController(s.engine, s.tokenService, s.ucManager.EncryptionUc())
+	controller.NewImportTemplateController(s.engine, s.tokenService, s.ucManager.ImportTemplateUc())
+	controller.NewJobController(s.engine, s.tokenService, s.ucManager.JobUc(), s.Upgrader)
+	controller.NewHrisSyncController(s.engine, s.tokenService, s.ucManager.HrisSyncUc())
+	controller.NewOrgHierarchyController(s.engine, s.tokenService, s.ucManager.OrgHierarchyUc())
+	controller.NewCalibrationDraftController(s.engine, s.tokenService, s.ucManager.CalibrationDraftUc())
+	controller.NewProjectBundleController(s.engine, s.tokenService, s.ucManager.ProjectBundleUc())
+	controller.NewPhaseLifecycleController(s.engine, s.tokenService, s.ucManager.PhaseLifecycleUc())
+	controller.NewProjectCloseOutController(s.engine, s.tokenService, s.ucManager.ProjectCloseOutUc())
+	controller.NewAppealController(s.engine, s.tokenService, s.ucManager.AppealUc())
+	controller.NewDelegationController(s.engine, s.tokenService, s.ucManager.DelegationUc())
+	controller.NewCalibrationCommentController(s.engine, s.tokenService, s.ucManager.CalibrationCommentUc())
+	controller.NewEligibilityController(s.engine, s.tokenService, s.ucManager.EligibilityUc())
 }
 
 func (s *Server) Run() {
 	s.initController()
+	s.ucManager.JobUc().Run(context.Background())
+	s.ucManager.PhaseLifecycleUc().Run(context.Background())
 
 	err := s.engine.Run(s.host)
 	if err != nil {
@@ -61,6 +82,11 @@ func NewServer() *Server {
 		panic(err)
 	}
 
+	err = utils.InitFieldEncryption(cfg.EncryptionConfig)
+	if err != nil {
+		panic(err)
+	}
+
 	infra, err := manager.NewInfraManager(cfg)
 	if err != nil {
 		panic(err)
@@ -72,11 +98,12 @@ func NewServer() *Server {
 		// Username: "username",
 	})
 
-	tokenService := authenticator.NewTokenService(*cfg, client)
-
 	repo := manager.NewRepoManager(infra)
 	uc := manager.NewUsecaseManager(repo, cfg)
 
+	tokenService := authenticator.NewTokenService(*cfg, client, uc.ApiKeyUc())
+	rateLimiter := middleware.NewRateLimiter(client, tokenService, cfg.RateLimitConfig)
+
 	// c := cron.New()
 	// _, err = c.AddFunc("2 6 * * *", func() {
 	// 	err = uc.NotificationUc().NotifyCalibrator()
@@ -90,6 +117,10 @@ func NewServer() *Server {
 	// c.Start()
 
 	r := gin.Default()
+	// rate limits, api key allowlists and audit rows key on the client ip, only trusted proxies may forward it
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic(err)
+	}
 	r.GET("/migration", func(ctx *gin.Context) {
 		infra.Migrate(
 			&model.Role{},
@@ -108,16 +139,49 @@ func NewServer() *Server {
 			&model.ScoreDistribution{},
 			&model.Announcement{},
 			&model.Faq{},
+			&model.TwoFactor{},
+			&model.TwoFactorRecoveryCode{},
+			&model.ImpersonationLog{},
+			&model.ServiceAccount{},
+			&model.ApiKey{},
+			&model.Job{},
+			&model.HrisSync{},
+			&model.HrisSyncChange{},
+			&model.OrgPath{},
+			&model.CalibrationDraft{},
+			&model.CalibrationDraftRow{},
+			&model.PhaseExtension{},
+			&model.ProjectSignOff{},
+			&model.FinalResult{},
+			&model.Appeal{},
+			&model.AppealAttachment{},
+			&model.AppealEvent{},
+			&model.Delegation{},
+			&model.DelegatedAction{},
+			&model.CalibrationReassignment{},
+			&model.CalibrationApproval{},
+			&model.CalibrationComment{},
+			&model.CommentMention{},
+			&model.CommentRevision{},
+			&model.CommentRead{},
+			&model.EligibilityRule{},
+			&model.EligibilityOverride{},
+			&model.EligibilityResult{},
 		)
 	})
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"},
+		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With", "X-API-Key"},
+		ExposeHeaders:    []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"},
 		AllowCredentials: true,
 	}))
 
+	r.Use(middleware.NewImpersonationAudit(uc.ImpersonationUc()).RecordMutation())
+	r.Use(middleware.NewDelegationAudit(uc.DelegationUc()).RecordAction())
+	r.Use(rateLimiter.LimitPrefix("/auth", "auth"))
+
 	auth := r.Group("/auth").Use(middleware.NewTokenValidator(tokenService).RequireToken())
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
@@ -132,6 +196,7 @@ func NewServer() *Server {
 		authRoute:    auth,
 		host:         fmt.Sprintf("%s:%s", cfg.ApiHost, cfg.ApiPort),
 		tokenService: tokenService,
+		rateLimiter:  rateLimiter,
 		cfg:          *cfg,
 		Upgrader:     upgrader,
 	}
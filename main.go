@@ -1,6 +1,10 @@
 package main
 
-import "calibration-system.com/delivery"
+import (
+	"calibration-system.com/delivery"
+	// project timezones are loaded by name, the alpine image has no zoneinfo
+	_ "time/tzdata"
+)
 
 func main() {
 	delivery.NewServer().Run()
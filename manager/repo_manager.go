@@ -20,6 +20,21 @@ type RepoManager interface {
 	NotificationRepo() repository.NotificationRepo
 	AnnouncementRepo() repository.AnnouncementRepo
 	FaqRepo() repository.FaqRepo
+	TwoFactorRepo() repository.TwoFactorRepo
+	ImpersonationLogRepo() repository.ImpersonationLogRepo
+	ApiKeyRepo() repository.ApiKeyRepo
+	EncryptionRepo() repository.EncryptionRepo
+	JobRepo() repository.JobRepo
+	HrisSyncRepo() repository.HrisSyncRepo
+	OrgHierarchyRepo() repository.OrgHierarchyRepo
+	CalibrationDraftRepo() repository.CalibrationDraftRepo
+	ProjectBundleRepo() repository.ProjectBundleRepo
+	PhaseLifecycleRepo() repository.PhaseLifecycleRepo
+	ProjectCloseOutRepo() repository.ProjectCloseOutRepo
+	AppealRepo() repository.AppealRepo
+	DelegationRepo() repository.DelegationRepo
+	CalibrationCommentRepo() repository.CalibrationCommentRepo
+	EligibilityRepo() repository.EligibilityRepo
 }
 
 type repoManager struct {
@@ -94,6 +109,66 @@ func (r *repoManager) FaqRepo() repository.FaqRepo {
 	return repository.NewFaqRepo(r.infra.Conn())
 }
 
+func (r *repoManager) TwoFactorRepo() repository.TwoFactorRepo {
+	return repository.NewTwoFactorRepo(r.infra.Conn())
+}
+
+func (r *repoManager) ImpersonationLogRepo() repository.ImpersonationLogRepo {
+	return repository.NewImpersonationLogRepo(r.infra.Conn())
+}
+
+func (r *repoManager) ApiKeyRepo() repository.ApiKeyRepo {
+	return repository.NewApiKeyRepo(r.infra.Conn())
+}
+
+func (r *repoManager) EncryptionRepo() repository.EncryptionRepo {
+	return repository.NewEncryptionRepo(r.infra.Conn())
+}
+
+func (r *repoManager) JobRepo() repository.JobRepo {
+	return repository.NewJobRepo(r.infra.Conn())
+}
+
+func (r *repoManager) HrisSyncRepo() repository.HrisSyncRepo {
+	return repository.NewHrisSyncRepo(r.infra.Conn())
+}
+
+func (r *repoManager) OrgHierarchyRepo() repository.OrgHierarchyRepo {
+	return repository.NewOrgHierarchyRepo(r.infra.Conn())
+}
+
+func (r *repoManager) CalibrationDraftRepo() repository.CalibrationDraftRepo {
+	return repository.NewCalibrationDraftRepo(r.infra.Conn())
+}
+
+func (r *repoManager) ProjectBundleRepo() repository.ProjectBundleRepo {
+	return repository.NewProjectBundleRepo(r.infra.Conn())
+}
+
+func (r *repoManager) PhaseLifecycleRepo() repository.PhaseLifecycleRepo {
+	return repository.NewPhaseLifecycleRepo(r.infra.Conn())
+}
+
+func (r *repoManager) ProjectCloseOutRepo() repository.ProjectCloseOutRepo {
+	return repository.NewProjectCloseOutRepo(r.infra.Conn())
+}
+
+func (r *repoManager) AppealRepo() repository.AppealRepo {
+	return repository.NewAppealRepo(r.infra.Conn())
+}
+
+func (r *repoManager) DelegationRepo() repository.DelegationRepo {
+	return repository.NewDelegationRepo(r.infra.Conn())
+}
+
+func (r *repoManager) CalibrationCommentRepo() repository.CalibrationCommentRepo {
+	return repository.NewCalibrationCommentRepo(r.infra.Conn())
+}
+
+func (r *repoManager) EligibilityRepo() repository.EligibilityRepo {
+	return repository.NewEligibilityRepo(r.infra.Conn())
+}
+
 func NewRepoManager(infra InfraManager) RepoManager {
 	return &repoManager{
 		infra: infra,
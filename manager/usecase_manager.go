@@ -24,6 +24,22 @@ type UsecaseManager interface {
 	NotificationUc() usecase.NotificationUsecase
 	AnnouncementUc() usecase.AnnouncementUsecase
 	FaqUc() usecase.FaqUsecase
+	TwoFactorUc() usecase.TwoFactorUsecase
+	ImpersonationUc() usecase.ImpersonationUsecase
+	ApiKeyUc() usecase.ApiKeyUsecase
+	EncryptionUc() usecase.EncryptionUsecase
+	ImportTemplateUc() usecase.ImportTemplateUsecase
+	JobUc() usecase.JobUsecase
+	HrisSyncUc() usecase.HrisSyncUsecase
+	OrgHierarchyUc() usecase.OrgHierarchyUsecase
+	CalibrationDraftUc() usecase.CalibrationDraftUsecase
+	ProjectBundleUc() usecase.ProjectBundleUsecase
+	PhaseLifecycleUc() usecase.PhaseLifecycleUsecase
+	ProjectCloseOutUc() usecase.ProjectCloseOutUsecase
+	AppealUc() usecase.AppealUsecase
+	DelegationUc() usecase.DelegationUsecase
+	CalibrationCommentUc() usecase.CalibrationCommentUsecase
+	EligibilityUc() usecase.EligibilityUsecase
 }
 
 type usecaseManager struct {
@@ -36,7 +52,7 @@ func (u *usecaseManager) RoleUc() usecase.RoleUsecase {
 }
 
 func (u *usecaseManager) UserUc() usecase.UserUsecase {
-	return usecase.NewUserUseCase(u.repo.UserRepo(), u.RoleUc(), u.BusinessUnitUc(), u.cfg)
+	return usecase.NewUserUseCase(u.repo.UserRepo(), u.RoleUc(), u.BusinessUnitUc(), u.OrgHierarchyUc(), u.cfg)
 }
 
 func (u *usecaseManager) AuthUc() usecase.AuthUsecase {
@@ -68,7 +84,7 @@ func (u *usecaseManager) ActualScoreUc() usecase.ActualScoreUsecase {
 }
 
 func (u *usecaseManager) CalibrationUc() usecase.CalibrationUsecase {
-	return usecase.NewCalibrationUsecase(u.repo.CalibrationRepo(), u.UserUc(), u.ProjectUc(), u.ProjectPhaseUc(), u.NotificationUc(), u.ActualScoreUc())
+	return usecase.NewCalibrationUsecase(u.repo.CalibrationRepo(), u.UserUc(), u.ProjectUc(), u.ProjectPhaseUc(), u.NotificationUc(), u.ActualScoreUc(), u.PhaseLifecycleUc(), u.DelegationUc(), u.EligibilityUc())
 }
 
 func (u *usecaseManager) RatingQuotaUc() usecase.RatingQuotaUsecase {
@@ -92,7 +108,7 @@ func (u *usecaseManager) BottomRemarkUc() usecase.BottomRemarkUsecase {
 }
 
 func (u *usecaseManager) NotificationUc() usecase.NotificationUsecase {
-	return usecase.NewNotificationUsecase(u.repo.NotificationRepo(), u.UserUc(), u.ProjectUc(), *u.cfg)
+	return usecase.NewNotificationUsecase(u.repo.NotificationRepo(), u.UserUc(), u.ProjectUc(), u.DelegationUc(), *u.cfg)
 }
 
 func (u *usecaseManager) AnnouncementUc() usecase.AnnouncementUsecase {
@@ -103,6 +119,74 @@ func (u *usecaseManager) FaqUc() usecase.FaqUsecase {
 	return usecase.NewFaqUsecase(u.repo.FaqRepo())
 }
 
+func (u *usecaseManager) TwoFactorUc() usecase.TwoFactorUsecase {
+	return usecase.NewTwoFactorUsecase(u.repo.TwoFactorRepo(), u.UserUc(), u.cfg)
+}
+
+func (u *usecaseManager) ImpersonationUc() usecase.ImpersonationUsecase {
+	return usecase.NewImpersonationUsecase(u.repo.ImpersonationLogRepo(), u.UserUc())
+}
+
+func (u *usecaseManager) ApiKeyUc() usecase.ApiKeyUsecase {
+	return usecase.NewApiKeyUsecase(u.repo.ApiKeyRepo())
+}
+
+func (u *usecaseManager) EncryptionUc() usecase.EncryptionUsecase {
+	return usecase.NewEncryptionUsecase(u.repo.EncryptionRepo())
+}
+
+func (u *usecaseManager) ImportTemplateUc() usecase.ImportTemplateUsecase {
+	return usecase.NewImportTemplateUsecase(u.RoleUc(), u.BusinessUnitUc(), u.ProjectUc())
+}
+
+func (u *usecaseManager) JobUc() usecase.JobUsecase {
+	handlers := usecase.ImportJobHandlers(u.UserUc(), u.ActualScoreUc(), u.RatingQuotaUc(), u.BusinessUnitUc(), u.CalibrationUc())
+	for kind, handler := range usecase.ReportJobHandlers(u.ProjectUc()) {
+		handlers[kind] = handler
+	}
+	return usecase.NewJobUsecase(u.repo.JobRepo(), handlers, u.cfg)
+}
+
+func (u *usecaseManager) HrisSyncUc() usecase.HrisSyncUsecase {
+	return usecase.NewHrisSyncUsecase(u.repo.HrisSyncRepo(), u.repo.UserRepo(), u.BusinessUnitUc(), u.OrgHierarchyUc())
+}
+
+func (u *usecaseManager) OrgHierarchyUc() usecase.OrgHierarchyUsecase {
+	return usecase.NewOrgHierarchyUsecase(u.repo.OrgHierarchyRepo())
+}
+
+func (u *usecaseManager) CalibrationDraftUc() usecase.CalibrationDraftUsecase {
+	return usecase.NewCalibrationDraftUsecase(u.repo.CalibrationDraftRepo(), u.repo.UserRepo(), u.ProjectUc(), u.BusinessUnitUc(), u.ActualScoreUc(), u.CalibrationUc())
+}
+
+func (u *usecaseManager) ProjectBundleUc() usecase.ProjectBundleUsecase {
+	return usecase.NewProjectBundleUsecase(u.repo.ProjectBundleRepo(), u.repo.UserRepo(), u.PhaseUc(), u.BusinessUnitUc(), u.GroupBusinessUnitUc())
+}
+
+func (u *usecaseManager) PhaseLifecycleUc() usecase.PhaseLifecycleUsecase {
+	return usecase.NewPhaseLifecycleUsecase(u.repo.PhaseLifecycleRepo(), u.NotificationUc(), u.cfg)
+}
+
+func (u *usecaseManager) ProjectCloseOutUc() usecase.ProjectCloseOutUsecase {
+	return usecase.NewProjectCloseOutUsecase(u.repo.ProjectCloseOutRepo(), u.repo.UserRepo())
+}
+
+func (u *usecaseManager) AppealUc() usecase.AppealUsecase {
+	return usecase.NewAppealUsecase(u.repo.AppealRepo(), u.repo.UserRepo(), u.ProjectUc())
+}
+
+func (u *usecaseManager) DelegationUc() usecase.DelegationUsecase {
+	return usecase.NewDelegationUsecase(u.repo.DelegationRepo(), u.repo.UserRepo(), u.ProjectUc())
+}
+
+func (u *usecaseManager) CalibrationCommentUc() usecase.CalibrationCommentUsecase {
+	return usecase.NewCalibrationCommentUsecase(u.repo.CalibrationCommentRepo(), u.repo.UserRepo(), u.DelegationUc(), u.NotificationUc())
+}
+
+func (u *usecaseManager) EligibilityUc() usecase.EligibilityUsecase {
+	return usecase.NewEligibilityUsecase(u.repo.EligibilityRepo(), u.repo.UserRepo(), u.ProjectUc())
+}
+
 func NewUsecaseManager(repo RepoManager, cfg *config.Config) UsecaseManager {
 	return &usecaseManager{
 		repo: repo,
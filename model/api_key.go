@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+const (
+	RoleServiceAccount = "service-account"
+
+	ScopeUsersRead         = "users:read"
+	ScopeUsersWrite        = "users:write"
+	ScopeActualScoresRead  = "actual-scores:read"
+	ScopeActualScoresWrite = "actual-scores:write"
+	ScopeReportsRead       = "reports:read"
+	ScopeResultsRead       = "results:read"
+)
+
+var ApiKeyScopes = []string{
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeActualScoresRead,
+	ScopeActualScoresWrite,
+	ScopeReportsRead,
+	ScopeResultsRead,
+}
+
+type ServiceAccount struct {
+	BaseModel
+	Name        string `gorm:"unique"`
+	Description string
+	Active      bool `gorm:"default:true"`
+	CreatedBy   string
+	ApiKeys     []ApiKey `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type ApiKey struct {
+	BaseModel
+	ServiceAccount   ServiceAccount `json:"-"`
+	ServiceAccountID string
+	Name             string
+	Prefix           string     `gorm:"uniqueIndex"`
+	KeyHash          string     `json:"-"`
+	Scopes           []string   `gorm:"serializer:json"`
+	ExpiresAt        *time.Time `gorm:"type:timestamp without time zone"`
+	LastUsedAt       *time.Time `gorm:"type:timestamp without time zone"`
+	LastUsedIP       string
+	RevokedAt        *time.Time `gorm:"type:timestamp without time zone"`
+	RotatedFromID    *string
+}
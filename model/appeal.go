@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+const (
+	AppealSubmitted   = "submitted"
+	AppealUnderReview = "under-review"
+	AppealUpheld      = "upheld"
+	AppealAmended     = "amended"
+)
+
+// Appeal is an employee contesting the published rating of a project, it is reviewed by their HRBP and the
+// calibrator of their final phase. An employee appeals a project once.
+type Appeal struct {
+	BaseModel
+	ProjectID      string `gorm:"uniqueIndex:idx_appeal_employee"`
+	EmployeeID     string `gorm:"uniqueIndex:idx_appeal_employee"`
+	Reason         string
+	Status         string `gorm:"index;default:submitted"`
+	HrbpID         string `gorm:"index"`
+	CalibratorID   string `gorm:"index"`
+	PreviousRating string
+	PreviousScore  float64
+	AmendedRating  string
+	AmendedScore   float64
+	Decision       string
+	ReviewedBy     string
+	ReviewedAt     *time.Time
+	Attachments    []AppealAttachment `gorm:"constraint:OnDelete:CASCADE" json:",omitempty"`
+	Events         []AppealEvent      `gorm:"constraint:OnDelete:CASCADE" json:",omitempty"`
+}
+
+type AppealAttachment struct {
+	BaseModel
+	AppealID string `gorm:"index"`
+	Name     string
+	Content  []byte `gorm:"serializer:encrypted" json:"-"`
+}
+
+// AppealEvent is the audit trail of an appeal, one row per step with the rating before and after it
+type AppealEvent struct {
+	BaseModel
+	AppealID   string `gorm:"index"`
+	Action     string
+	ActorID    string
+	FromStatus string
+	ToStatus   string
+	FromRating string
+	ToRating   string
+	FromScore  float64
+	ToScore    float64
+	Comment    string
+}
+
+// AppealDecision is the body of uphold and amend, Rating and Score are only read when amending. An amendment
+// without a Score keeps the final score.
+type AppealDecision struct {
+	Rating  string
+	Score   *float64
+	Comment string
+}
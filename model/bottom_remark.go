@@ -17,7 +17,7 @@ type BottomRemark struct {
 	ProjectPhase   ProjectPhase   `gorm:"foreignKey:ProjectPhaseID" json:"-"`
 	ProjectPhaseID string         `gorm:"primaryKey"`
 	LowPerformance string
-	Indisipliner   string
-	Attitude       string
-	WarningLetter  string
+	Indisipliner   string `gorm:"serializer:encrypted"`
+	Attitude       string `gorm:"serializer:encrypted"`
+	WarningLetter  string `gorm:"serializer:encrypted"`
 }
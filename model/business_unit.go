@@ -17,4 +17,9 @@ type BusinessUnit struct {
 	GroupBusinessUnitId string
 	Pillar              string
 	RatingQuotas        []RatingQuota `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	// defaults for the calibrator chain generator, niks of the unit head and its SPMOs
+	HeadNik  string
+	SpmoNik  string
+	Spmo2Nik string
+	Spmo3Nik string
 }
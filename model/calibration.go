@@ -38,6 +38,12 @@ type Calibration struct {
 	BottomRemark              BottomRemark `gorm:"foreignKey:ProjectID,EmployeeID,ProjectPhaseID;references:ProjectID,EmployeeID,ProjectPhaseID;constraint:OnDelete:CASCADE"`
 	TopRemarks                []TopRemark  `gorm:"foreignKey:ProjectID,EmployeeID,ProjectPhaseID;references:ProjectID,EmployeeID,ProjectPhaseID;constraint:OnDelete:CASCADE"`
 	FilledTopBottomMark       bool
+	// EmployeeLeft is set by the HRIS sync when the employee leaves while the project is active
+	EmployeeLeft bool `gorm:"default:false"`
+	// SendBackEscalatedAt is set once an overdue send-back has been escalated
+	SendBackEscalatedAt *time.Time
+	// Approvals are the SPMO levels reviewing the calibration, SpmoStatus is the outcome of the chain
+	Approvals []CalibrationApproval `gorm:"foreignKey:ProjectID,EmployeeID,ProjectPhaseID;references:ProjectID,EmployeeID,ProjectPhaseID;constraint:OnDelete:CASCADE" json:",omitempty"`
 }
 
 type SeeCalibrationJustification struct {
@@ -89,6 +95,8 @@ type CalibrationForm struct {
 	BottomRemark              BottomRemark `gorm:"foreignKey:ProjectID,EmployeeID,ProjectPhaseID;references:ProjectID,EmployeeID,ProjectPhaseID;constraint:OnDelete:CASCADE"`
 	TopRemarks                []TopRemark  `gorm:"foreignKey:ProjectID,EmployeeID,ProjectPhaseID;references:ProjectID,EmployeeID,ProjectPhaseID;constraint:OnDelete:CASCADE"`
 	FilledTopBottomMark       bool
+	// EmployeeLeft is set by the HRIS sync when the employee leaves while the project is active
+	EmployeeLeft bool `gorm:"default:false"`
 }
 
 func (CalibrationForm) TableName() string {
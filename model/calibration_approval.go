@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+const (
+	SpmoChainParallel   = "parallel"
+	SpmoChainSequential = "sequential"
+
+	SpmoQuorumAny = "any"
+	SpmoQuorumAll = "all"
+
+	// ApprovalPending is a sequential level whose turn hasn't come, ApprovalSkipped a level the chain was decided
+	// without
+	ApprovalPending  = "Pending"
+	ApprovalWaiting  = "Waiting"
+	ApprovalAccepted = "Accepted"
+	ApprovalRejected = "Rejected"
+	ApprovalSkipped  = "Skipped"
+)
+
+// CalibrationApproval is the decision of one SPMO level on a submitted calibration. Level 1 is SpmoID, 2 Spmo2ID and
+// 3 Spmo3ID.
+type CalibrationApproval struct {
+	CreatedAt      time.Time `gorm:"<-:create" json:"-"`
+	UpdatedAt      time.Time `json:"-"`
+	ProjectID      string    `gorm:"primaryKey"`
+	ProjectPhaseID string    `gorm:"primaryKey"`
+	EmployeeID     string    `gorm:"primaryKey"`
+	Level          int       `gorm:"primaryKey;autoIncrement:false"`
+	SpmoID         string    `gorm:"index"`
+	Status         string
+	Comment        string
+	DecidedAt      *time.Time
+}
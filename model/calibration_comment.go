@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+const (
+	CommentVisibleAll         = "all"
+	CommentVisibleCalibrators = "calibrators"
+	CommentVisibleSpmo        = "spmo"
+)
+
+// CalibrationComment is a message in the discussion on the calibration of an employee in a project. It is written in
+// the context of one phase and can answer another comment through ParentID.
+type CalibrationComment struct {
+	BaseModel
+	ProjectID      string       `gorm:"index:idx_calibration_comment_thread"`
+	EmployeeID     string       `gorm:"index:idx_calibration_comment_thread"`
+	ProjectPhaseID string       `json:",omitempty"`
+	ProjectPhase   ProjectPhase `json:"-"`
+	ParentID       *string      `json:",omitempty"`
+	AuthorID       string
+	Author         User `json:"-"`
+	Body           string
+	Visibility     string `gorm:"default:all"`
+	EditedAt       *time.Time
+	Mentions       []CommentMention `gorm:"constraint:OnDelete:CASCADE" json:",omitempty"`
+}
+
+// CommentMention is a user @mentioned by nik in a comment
+type CommentMention struct {
+	CalibrationCommentID string `gorm:"primaryKey"`
+	UserID               string `gorm:"primaryKey"`
+}
+
+// CommentRevision keeps the body a comment had before an edit
+type CommentRevision struct {
+	BaseModel
+	CalibrationCommentID string `gorm:"index"`
+	Body                 string
+	EditedBy             string
+}
+
+// CommentRead is how far a user has read the thread of an employee
+type CommentRead struct {
+	ProjectID  string `gorm:"primaryKey"`
+	EmployeeID string `gorm:"primaryKey"`
+	UserID     string `gorm:"primaryKey"`
+	LastReadAt time.Time
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+const (
+	CalibrationDraftOpen      = "draft"
+	CalibrationDraftCommitted = "committed"
+
+	// calibrator rules, one per project phase
+	ChainRuleSupervisor = "supervisor"
+	ChainRuleGrade      = "grade"
+	ChainRuleBuHead     = "bu-head"
+	ChainRuleFixed      = "fixed"
+	ChainRuleSkip       = "skip"
+)
+
+// ChainPhaseRule picks the calibrator of one phase from the employee's reporting line
+type ChainPhaseRule struct {
+	PhaseOrder int
+	Rule       string
+	// Level is the manager level for the supervisor rule, 1 is the direct supervisor
+	Level int
+	// MinGrade is the lowest grade the grade rule accepts, the nearest manager at or above it is picked
+	MinGrade string
+	// Nik is the calibrator for the fixed rule
+	Nik string
+}
+
+type ChainRules struct {
+	Phases []ChainPhaseRule
+	// SpmoNik overrides the business unit SPMO for every employee, SPMO 2 and 3 always come from the business unit
+	SpmoNik string
+}
+
+// CalibrationDraft is a generated calibrator chain for a project, it is only turned into calibrations on commit
+type CalibrationDraft struct {
+	BaseModel
+	ProjectID   string     `gorm:"index"`
+	Rules       ChainRules `gorm:"type:text;serializer:json"`
+	Status      string     `gorm:"default:draft"`
+	CreatedBy   string
+	CommittedAt *time.Time `gorm:"type:timestamp without time zone"`
+	Employees   int
+	Warnings    int
+	Rows        []CalibrationDraftRow `gorm:"foreignKey:DraftID;constraint:OnDelete:CASCADE" json:",omitempty"`
+}
+
+// CalibrationDraftRow is one line of the calibration upload, Calibrators is keyed by phase order
+type CalibrationDraftRow struct {
+	BaseModel
+	DraftID        string `gorm:"index"`
+	EmployeeID     string
+	Nik            string
+	Name           string
+	BusinessUnitId string
+	Calibrators    map[int]string `gorm:"type:text;serializer:json"`
+	SpmoNik        string
+	Spmo2Nik       string
+	Spmo3Nik       string
+	Warnings       []string `gorm:"type:text;serializer:json"`
+}
@@ -0,0 +1,21 @@
+package model
+
+const (
+	ReassignCalibrator = "calibrator"
+	ReassignSpmo       = "spmo"
+	ReassignSpmo2      = "spmo2"
+	ReassignSpmo3      = "spmo3"
+)
+
+// CalibrationReassignment records one phase of a mid-project move of employees from one calibrator or SPMO to another
+type CalibrationReassignment struct {
+	BaseModel
+	ProjectID      string `gorm:"index"`
+	ProjectPhaseID string
+	Role           string
+	FromID         string `gorm:"index"`
+	ToID           string `gorm:"index"`
+	EmployeeCount  int
+	Reason         string
+	ReassignedBy   string
+}
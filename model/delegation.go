@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Delegation lets a delegate act on the worksheets of a calibrator from StartDate until EndDate, in one project or in
+// every project when ProjectID is empty
+type Delegation struct {
+	BaseModel
+	CalibratorID string `gorm:"index"`
+	Calibrator   User   `json:"-"`
+	DelegateID   string `gorm:"index"`
+	Delegate     User   `json:"-"`
+	ProjectID    *string
+	StartDate    time.Time
+	EndDate      time.Time
+	Reason       string
+	CreatedBy    string
+	RevokedAt    *time.Time
+	RevokedBy    string
+}
+
+// DelegatedAction is a write a delegate made on behalf of a calibrator
+type DelegatedAction struct {
+	BaseModel
+	DelegationID string `gorm:"index"`
+	DelegateID   string
+	CalibratorID string `gorm:"index"`
+	Method       string
+	Path         string
+	StatusCode   int
+	ClientIP     string
+}
@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+// what a rule or override does with a matching employee, flagged employees are calibrated and listed for review
+const (
+	EligibilityInclude = "include"
+	EligibilityExclude = "exclude"
+	EligibilityFlag    = "flag"
+)
+
+// the User fields an eligibility rule can test
+const (
+	EligibilityJoinDate      = "join_date"
+	EligibilityGrade         = "grade"
+	EligibilityBusinessUnit  = "business_unit"
+	EligibilityDepartment    = "department"
+	EligibilityPosition      = "position"
+	EligibilityScoringMethod = "scoring_method"
+)
+
+// EligibilityRule decides whether an employee of a calibration upload is calibrated. The rules of a project are
+// evaluated in Priority order and the first match wins, employees no rule matches are included.
+type EligibilityRule struct {
+	BaseModel
+	ProjectID string `gorm:"index"`
+	Priority  int
+	Field     string
+	// Operator is eq, neq, in or not_in, join_date takes before or after
+	Operator string
+	// Value is comma separated for in and not_in, a date as 2006-01-02 for join_date
+	Value       string
+	Action      string
+	Description string
+}
+
+// EligibilityOverride includes or excludes one employee of a project whatever the rules say
+type EligibilityOverride struct {
+	CreatedAt  time.Time `gorm:"<-:create"`
+	UpdatedAt  time.Time
+	ProjectID  string `gorm:"primaryKey"`
+	EmployeeID string `gorm:"primaryKey"`
+	Action     string
+	Reason     string
+	CreatedBy  string
+}
+
+// EligibilityResult is the decision taken on an employee at the last calibration import that had them
+type EligibilityResult struct {
+	ProjectID    string `gorm:"primaryKey"`
+	EmployeeID   string `gorm:"primaryKey"`
+	EmployeeNik  string
+	EmployeeName string
+	Action       string
+	RuleID       *string
+	Reason       string
+	Overridden   bool
+	EvaluatedAt  time.Time
+}
+
+// EligibilityReportRow is an employee of the import population as the current rules and overrides decide, next to
+// what was decided when they were imported
+type EligibilityReportRow struct {
+	EligibilityResult
+	BusinessUnitID string
+	Grade          string
+	Department     string
+	Position       string
+	ScoringMethod  string
+	JoinDate       time.Time
+	ImportedAction string
+	// Changed is set when the rules or overrides changed since the import, a new upload applies them
+	Changed bool
+}
+
+type EligibilityReport struct {
+	ProjectID string
+	Included  int
+	Excluded  int
+	Flagged   int
+	Changed   int
+	Rules     []EligibilityRule
+	Overrides []EligibilityOverride
+	Rows      []EligibilityReportRow
+}
@@ -0,0 +1,39 @@
+package model
+
+// EncryptedTable lists the columns sealed by the encrypted serializer, used by the re-encryption job
+type EncryptedTable struct {
+	Name    string
+	Keys    []string
+	Columns []string
+	// BlindIndexes maps an index column to the encrypted column it is computed from
+	BlindIndexes map[string]string
+}
+
+var EncryptedTables = []EncryptedTable{
+	{
+		Name:         "users",
+		Keys:         []string{"id"},
+		Columns:      []string{"email", "date_of_birth", "phone_number"},
+		BlindIndexes: map[string]string{"email_index": "email"},
+	},
+	{
+		Name:    "bottom_remarks",
+		Keys:    []string{"project_id", "employee_id", "project_phase_id"},
+		Columns: []string{"indisipliner", "attitude", "warning_letter"},
+	},
+	{
+		Name:    "top_remarks",
+		Keys:    []string{"id"},
+		Columns: []string{"evidence"},
+	},
+	{
+		Name:    "two_factors",
+		Keys:    []string{"id"},
+		Columns: []string{"secret"},
+	},
+	{
+		Name:    "hris_sync_changes",
+		Keys:    []string{"id"},
+		Columns: []string{"record"},
+	},
+}
@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+const (
+	HrisSyncFull  = "full"
+	HrisSyncDelta = "delta"
+
+	HrisSyncPending  = "pending"
+	HrisSyncApplied  = "applied"
+	HrisSyncRejected = "rejected"
+
+	HrisChangeJoiner = "joiner"
+	HrisChangeLeaver = "leaver"
+	HrisChangeUpdate = "update"
+)
+
+// HrisSync is one employee feed from HRIS, it only touches users once an admin approves the computed changes
+type HrisSync struct {
+	BaseModel
+	Mode                string
+	Source              string
+	Status              string `gorm:"index;default:pending"`
+	CreatedBy           string
+	ReviewedBy          string
+	ReviewedAt          *time.Time `gorm:"type:timestamp without time zone"`
+	AppliedAt           *time.Time `gorm:"type:timestamp without time zone"`
+	TotalRows           int
+	Joiners             int
+	Leavers             int
+	Transfers           int
+	SupervisorChanges   int
+	Updates             int
+	Unchanged           int
+	FlaggedCalibrations int64
+	Message             string
+	Changes             []HrisSyncChange `gorm:"foreignKey:SyncID;constraint:OnDelete:CASCADE" json:",omitempty"`
+}
+
+// HrisSyncChange is one employee whose record differs from the feed, unchanged employees are only counted
+type HrisSyncChange struct {
+	BaseModel
+	SyncID           string `gorm:"index"`
+	EmployeeID       *string
+	Nik              string `gorm:"index"`
+	Name             string
+	Type             string
+	Transfer         bool
+	SupervisorChange bool
+	Fields           []HrisFieldChange `gorm:"type:text;serializer:json"`
+	// Record keeps the incoming row as json, it carries email and phone number so it is stored encrypted
+	Record []byte `gorm:"type:text;serializer:encrypted" json:"-"`
+}
+
+// HrisFieldChange shows one differing column, values of encrypted columns are left out
+type HrisFieldChange struct {
+	Field string
+	From  string
+	To    string
+}
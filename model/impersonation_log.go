@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type ImpersonationLog struct {
+	ID         string    `gorm:"primaryKey;unique;type:uuid;default:gen_random_uuid()"`
+	CreatedAt  time.Time `gorm:"<-:create"`
+	Actor      User      `json:"-"`
+	ActorID    string    `gorm:"index"`
+	Subject    User      `json:"-"`
+	SubjectID  string    `gorm:"index"`
+	AccessUUID string
+	Action     string // start/stop/request
+	Method     string
+	Path       string
+	StatusCode int
+	ClientIP   string
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+
+	JobImportUsers         = "import:users"
+	JobImportUserPasswords = "import:user-passwords"
+	JobImportActualScores  = "import:actual-scores"
+	JobImportRatingQuotas  = "import:rating-quotas"
+	JobImportBusinessUnits = "import:business-units"
+	JobImportCalibrations  = "import:calibrations"
+	JobReportCalibrations  = "report:calibrations"
+	JobReportSummary       = "report:summary"
+)
+
+// Job is a queued import or report, workers claim queued rows with SKIP LOCKED so every instance can share the queue
+type Job struct {
+	BaseModel
+	Kind            string            `gorm:"index"`
+	Status          string            `gorm:"index;default:queued"`
+	Progress        int               `gorm:"default:0"`
+	Payload         map[string]string `gorm:"serializer:json"`
+	Input           []byte            `json:"-"`
+	CreatedBy       string            `gorm:"index"`
+	CancelRequested bool              `gorm:"default:false"`
+	Message         string
+	// Result is the import report or any other json the job produces
+	Result     interface{} `gorm:"serializer:json"`
+	ResultName string
+	ResultFile []byte     `json:"-"`
+	Attempts   int        `gorm:"default:0"`
+	StartedAt  *time.Time `gorm:"type:timestamp without time zone"`
+	FinishedAt *time.Time `gorm:"type:timestamp without time zone"`
+	ExpiresAt  *time.Time `gorm:"type:timestamp without time zone"`
+}
+
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
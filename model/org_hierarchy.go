@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+const (
+	OrgIssueOrphan    = "orphan"
+	OrgIssueCycle     = "cycle"
+	OrgIssueDuplicate = "duplicate"
+)
+
+// OrgPath is the closure of the reporting lines built from SupervisorNik, every employee has a depth 0 row to itself
+type OrgPath struct {
+	AncestorID   string `gorm:"primaryKey;type:uuid"`
+	DescendantID string `gorm:"primaryKey;type:uuid;index"`
+	Depth        int    `gorm:"index"`
+}
+
+// OrgNode is an employee seen from another employee in the tree, Depth is the distance between the two
+type OrgNode struct {
+	ID             string
+	Nik            string
+	Name           string
+	Position       string
+	Grade          string
+	BusinessUnitId *string
+	SupervisorNik  string
+	Depth          int
+}
+
+type OrgSpan struct {
+	ID     string
+	Nik    string
+	Name   string
+	Direct int64
+	Total  int64
+	Levels int
+}
+
+// OrgIssue is an employee whose reporting line cannot be followed to a root
+type OrgIssue struct {
+	Type          string
+	ID            string
+	Nik           string
+	Name          string
+	SupervisorNik string
+	// Chain lists the niks of a cycle in reporting order
+	Chain []string `json:",omitempty"`
+}
+
+type OrgHierarchyReport struct {
+	Employees int
+	Roots     int
+	Paths     int
+	MaxDepth  int
+	Issues    []OrgIssue
+	BuiltAt   time.Time
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+const (
+	PhaseScheduled = "scheduled"
+	PhaseOpen      = "open"
+	PhaseClosed    = "closed"
+
+	// SendBackAutoAdvance submits an overdue send-back as it stands, SendBackEscalate leaves it and reports it
+	SendBackAutoAdvance = "auto-advance"
+	SendBackEscalate    = "escalate"
+
+	DefaultProjectTimezone = "Asia/Jakarta"
+)
+
+// PhaseExtension lets one calibrator keep writing in a project phase after it closed
+type PhaseExtension struct {
+	BaseModel
+	ProjectPhase   ProjectPhase `json:"-"`
+	ProjectPhaseID string
+	Calibrator     User `json:"-"`
+	CalibratorID   string
+	Deadline       time.Time
+	Reason         string
+	GrantedBy      string
+}
+
+type SendBackEscalation struct {
+	ProjectID      string
+	ProjectPhaseID string
+	EmployeeID     string
+	CalibratorID   string
+	SpmoID         string
+	Deadline       time.Time
+}
+
+// PhaseLifecycleRun is what one pass of the lifecycle changed
+type PhaseLifecycleRun struct {
+	Opened       int
+	Closed       int
+	AutoAdvanced int
+	Escalations  []SendBackEscalation
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Project struct {
 	BaseModel
 	Name               string
@@ -17,4 +19,27 @@ type Project struct {
 	BExcess            bool            `gorm:"default:false"`
 	CExcess            bool            `gorm:"default:false"`
 	DExcess            bool            `gorm:"default:false"`
+	// Timezone decides the day phases open and close on, SendBackPolicy what happens to overdue send-backs
+	Timezone       string `gorm:"default:Asia/Jakarta"`
+	SendBackPolicy string `gorm:"default:escalate"`
+	// Status moves open, closing, closed through close-out, calibration data is read-only once it leaves open
+	Status           string `gorm:"default:open"`
+	ClosedAt         *time.Time
+	ResultVisibility ResultVisibility `gorm:"embedded;embeddedPrefix:result_"`
+	// AppealWindowDays is how long employees can appeal once the results are released, zero turns appeals off
+	AppealWindowDays int `gorm:"default:0"`
+}
+
+// ProjectCloneOptions picks what POST /projects/:id/clone copies, nothing is copied unless asked for. Phase dates
+// move by YearIncrement years plus ShiftDays days.
+type ProjectCloneOptions struct {
+	Name               string
+	YearIncrement      int
+	ShiftDays          int
+	Phases             bool
+	RatingQuotas       bool
+	ScoreDistributions bool
+	RemarkSettings     bool
+	// CalibratorChains copies employee, calibrator and spmo assignments without scores, it needs Phases
+	CalibratorChains bool
 }
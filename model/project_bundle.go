@@ -0,0 +1,176 @@
+package model
+
+import "time"
+
+const (
+	// ProjectBundleVersion is bumped whenever the manifest changes shape, imports refuse newer versions
+	ProjectBundleVersion = 1
+
+	BundleScopeConfig = "config"
+	BundleScopeFull   = "full"
+)
+
+// ProjectBundle is the manifest.json of a project archive. Nothing in it refers to database ids: employees are niks,
+// phases are orders, business units keep their ids and group business units go by name.
+type ProjectBundle struct {
+	Version            int
+	Scope              string
+	ExportedAt         time.Time
+	Project            BundleProject
+	Phases             []BundlePhase
+	RemarkSettings     []BundleRemarkSetting
+	RatingQuotas       []BundleRatingQuota
+	ScoreDistributions []BundleScoreDistribution
+	ActualScores       []BundleActualScore `json:",omitempty"`
+	Calibrations       []BundleCalibration `json:",omitempty"`
+}
+
+type BundleProject struct {
+	Name           string
+	Year           int
+	APlusExcess    bool
+	AExcess        bool
+	BPlusExcess    bool
+	BExcess        bool
+	CExcess        bool
+	DExcess        bool
+	Timezone       string `json:",omitempty"`
+	SendBackPolicy string `json:",omitempty"`
+	// ResultVisibility is missing from bundles written before it existed, the zero value shows everything
+	ResultVisibility ResultVisibility
+	AppealWindowDays int `json:",omitempty"`
+}
+
+type BundlePhase struct {
+	Order      int
+	Name       string
+	ReviewSpmo bool
+	SpmoChain  string `json:",omitempty"`
+	SpmoQuorum string `json:",omitempty"`
+	StartDate  time.Time
+	EndDate    time.Time
+	Guideline  bool
+	ShowChart  bool
+}
+
+type BundleRemarkSetting struct {
+	JustificationType string
+	ScoringType       string
+	Level             int
+	From              string
+	To                string
+}
+
+type BundleRatingQuota struct {
+	BusinessUnitID string
+	APlusQuota     float64
+	AQuota         float64
+	BPlusQuota     float64
+	BQuota         float64
+	CQuota         float64
+	DQuota         float64
+	Remaining      string
+	Excess         string
+}
+
+type BundleScoreDistribution struct {
+	GroupBusinessUnitName string
+	APlusUpperLimit       float64
+	APlusLowerLimit       float64
+	AUpperLimit           float64
+	ALowerLimit           float64
+	BPlusUpperLimit       float64
+	BPlusLowerLimit       float64
+	BUpperLimit           float64
+	BLowerLimit           float64
+	CUpperLimit           float64
+	CLowerLimit           float64
+	DUpperLimit           float64
+	DLowerLimit           float64
+}
+
+type BundleActualScore struct {
+	EmployeeNik  string
+	ActualScore  float64
+	ActualRating string
+	Y1Rating     string
+	Y2Rating     string
+	PTTScore     float64
+	PATScore     float64
+	Score360     float64
+}
+
+type BundleCalibration struct {
+	EmployeeNik               string
+	PhaseOrder                int
+	CalibratorNik             string
+	SpmoNik                   string
+	Spmo2Nik                  string `json:",omitempty"`
+	Spmo3Nik                  string `json:",omitempty"`
+	CalibrationScore          float64
+	CalibrationRating         string
+	Status                    string
+	SpmoStatus                string
+	Comment                   string
+	SpmoComment               string
+	JustificationType         string
+	JustificationReviewStatus bool
+	SendBackDeadline          time.Time
+	FilledTopBottomMark       bool
+	EmployeeLeft              bool
+	BottomRemark              *BundleBottomRemark `json:",omitempty"`
+	TopRemarks                []BundleTopRemark   `json:",omitempty"`
+}
+
+type BundleBottomRemark struct {
+	LowPerformance string
+	Indisipliner   string
+	Attitude       string
+	WarningLetter  string
+}
+
+type BundleTopRemark struct {
+	Initiative   string
+	Description  string
+	Result       string
+	StartDate    *time.Time
+	EndDate      *time.Time
+	Comment      string
+	EvidenceName string
+	// EvidenceFile is the path of the attachment inside the archive
+	EvidenceFile string `json:",omitempty"`
+	IsProject    bool
+	IsInitiative bool
+	EvidenceLink string
+}
+
+type BundleConflict struct {
+	Section string
+	Key     string
+	Message string
+}
+
+// BundleImportReport is returned for every import, on dry-run it shows what would be created and what conflicts
+type BundleImportReport struct {
+	Version       int
+	Scope         string
+	DryRun        bool
+	Committed     bool
+	ProjectID     string `json:",omitempty"`
+	Phases        int
+	RatingQuotas  int
+	Distributions int
+	ActualScores  int
+	Calibrations  int
+	Attachments   int
+	Skipped       int
+	Conflicts     []BundleConflict
+}
+
+func (r *BundleImportReport) AddConflict(section, key, message string) {
+	r.Conflicts = append(r.Conflicts, BundleConflict{
+		Section: section,
+		Key:     key,
+		Message: message,
+	})
+}
@@ -0,0 +1,108 @@
+package model
+
+import "time"
+
+// project status, a project is open until close-out starts and read-only from then on
+const (
+	ProjectOpen    = "open"
+	ProjectClosing = "closing"
+	ProjectClosed  = "closed"
+)
+
+// ProjectSignOff is the approval of a business unit head on the final ratings of their unit
+type ProjectSignOff struct {
+	BaseModel
+	ProjectID      string `gorm:"uniqueIndex:idx_project_sign_off"`
+	BusinessUnitID string `gorm:"uniqueIndex:idx_project_sign_off"`
+	SignedBy       string
+	SignedAt       time.Time
+	Comment        string
+}
+
+// ResultVisibility decides what employees see of their published result and from when. The final rating is always
+// shown once the results are released.
+type ResultVisibility struct {
+	// ReleaseAt holds the results back after the project is finalized, empty releases them right away
+	ReleaseAt      *time.Time
+	HideScore      bool `gorm:"default:false"`
+	HideComponents bool `gorm:"default:false"`
+	HideHistory    bool `gorm:"default:false"`
+}
+
+// FinalResult is the published rating of an employee, written once when the project is finalized and read by
+// downstream systems instead of the calibration tables
+type FinalResult struct {
+	CreatedAt      time.Time `gorm:"<-:create" json:"-"`
+	ProjectID      string    `gorm:"primaryKey"`
+	EmployeeID     string    `gorm:"primaryKey"`
+	EmployeeNik    string
+	EmployeeName   string
+	BusinessUnitID string
+	ActualScore    float64
+	ActualRating   string
+	PTTScore       float64
+	PATScore       float64
+	Score360       float64
+	Y1Rating       string
+	Y2Rating       string
+	FinalScore     float64
+	FinalRating    string
+	FinalPhase     int
+	CalibratorID   string
+	PublishedAt    time.Time
+	// AmendedAt is set when an appeal changed the rating after publication
+	AmendedAt *time.Time
+	Project   Project `json:"-"`
+}
+
+// CloseOutRow is an employee of a project with their last calibration and actual score
+type CloseOutRow struct {
+	EmployeeID        string
+	EmployeeNik       string
+	EmployeeName      string
+	BusinessUnitID    string
+	ProjectPhaseID    string
+	PhaseOrder        int
+	ReviewSpmo        bool
+	Status            string
+	SpmoStatus        string
+	CalibrationScore  float64
+	CalibrationRating string
+	CalibratorID      string
+	ActualScore       float64
+	ActualRating      string
+	PTTScore          float64
+	PATScore          float64
+	Score360          float64
+	Y1Rating          string
+	Y2Rating          string
+}
+
+// CloseOutPending is an employee holding up close-out and why
+type CloseOutPending struct {
+	EmployeeID  string
+	EmployeeNik string
+	Name        string
+	PhaseOrder  int
+	Reason      string
+}
+
+type CloseOutSignOff struct {
+	BusinessUnitID   string
+	BusinessUnitName string
+	HeadNik          string
+	SignedBy         string
+	SignedAt         *time.Time
+	Comment          string
+}
+
+// ProjectCloseOutReport is what GET /projects/:id/close-out shows, the project can be finalized once nothing is
+// pending and every business unit signed off
+type ProjectCloseOutReport struct {
+	ProjectID   string
+	Status      string
+	Employees   int
+	Pending     []CloseOutPending
+	SignOffs    []CloseOutSignOff
+	CanFinalize bool
+}
@@ -13,4 +13,10 @@ type ProjectPhase struct {
 	EndDate    time.Time
 	Guideline  bool
 	ShowChart  bool
+	// Status is kept in step with the dates by the phase lifecycle
+	Status string `gorm:"default:scheduled"`
+	// SpmoChain is whether the SPMO levels review one after the other or at once, SpmoQuorum whether any or all of
+	// them have to accept
+	SpmoChain  string `gorm:"default:parallel"`
+	SpmoQuorum string `gorm:"default:any"`
 }
@@ -23,3 +23,5 @@ type RatingQuota struct {
 	Remaining      string
 	Excess         string
 }
+
+var Ratings = []string{"A+", "A", "B+", "B", "C", "D"}
@@ -5,3 +5,8 @@ type Role struct {
 	Name        string
 	Description string
 }
+
+const (
+	RoleAdmin = "admin"
+	RoleSpmo  = "spmo"
+)
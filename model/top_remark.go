@@ -19,7 +19,7 @@ type TopRemark struct {
 	EndDate        *time.Time
 	Comment        string
 	EvidenceName   string
-	Evidence       []byte `json:"-"`
+	Evidence       []byte `gorm:"serializer:encrypted" json:"-"`
 	IsProject      bool
 	IsInitiative   bool
 	EvidenceLink   string
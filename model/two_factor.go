@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type TwoFactor struct {
+	BaseModel
+	User          User                    `json:"-"`
+	UserID        string                  `gorm:"unique"`
+	Secret        string                  `gorm:"serializer:encrypted" json:"-"`
+	Enabled       bool                    `gorm:"default:false"`
+	EnabledAt     time.Time               `gorm:"type:timestamp without time zone"`
+	LastUsedStep  int64                   `json:"-"`
+	RecoveryCodes []TwoFactorRecoveryCode `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type TwoFactorRecoveryCode struct {
+	BaseModel
+	TwoFactorID string
+	CodeHash    string     `json:"-"`
+	UsedAt      *time.Time `gorm:"type:timestamp without time zone"`
+}
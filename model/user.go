@@ -8,10 +8,11 @@ type User struct {
 	BaseModel
 	CreatedBy              string `gorm:"default:admin" json:"-"`
 	UpdatedBy              string `gorm:"default:admin" json:"-"`
-	Email                  string
+	Email                  string `gorm:"serializer:encrypted"`
+	EmailIndex             string `gorm:"index" json:"-"`
 	Name                   string
 	Nik                    string
-	DateOfBirth            time.Time `gorm:"type:timestamp without time zone"`
+	DateOfBirth            time.Time `gorm:"type:text;serializer:encrypted"`
 	SupervisorNik          string
 	BusinessUnit           BusinessUnit
 	BusinessUnitId         *string
@@ -23,7 +24,7 @@ type User struct {
 	Grade                  string
 	HRBP                   string
 	Position               string
-	PhoneNumber            string
+	PhoneNumber            string `gorm:"serializer:encrypted"`
 	GeneratePassword       bool   `gorm:"default:false"`
 	Password               string `json:"-"`
 	Roles                  []Role `gorm:"many2many:user_roles"`
@@ -38,16 +39,19 @@ type User struct {
 	CalibratorCalibrations []Calibration `gorm:"foreignKey:CalibratorID"`
 	AccessTokenGenerate    string        `gorm:"unique;type:uuid;default:gen_random_uuid()"`
 	SupervisorNames        string
+	// Active is cleared by the HRIS sync for leavers, inactive users cannot log in
+	Active bool       `gorm:"default:true"`
+	LeftAt *time.Time `gorm:"type:timestamp without time zone"`
 }
 
 type UserCalibration struct {
 	BaseModel
 	CreatedBy              string `gorm:"default:admin" json:"-"`
 	UpdatedBy              string `gorm:"default:admin" json:"-"`
-	Email                  string `json:"-"`
+	Email                  string `gorm:"serializer:encrypted" json:"-"`
 	Name                   string
 	Nik                    string
-	DateOfBirth            time.Time    `gorm:"type:timestamp without time zone" json: "-"`
+	DateOfBirth            time.Time    `gorm:"type:text;serializer:encrypted" json:"-"`
 	SupervisorNik          string       `json:"-"`
 	BusinessUnit           BusinessUnit `json:"-"`
 	BusinessUnitId         *string      `json:"-"`
@@ -59,7 +63,7 @@ type UserCalibration struct {
 	Grade                  string
 	HRBP                   string `json:"-"`
 	Position               string
-	PhoneNumber            string        `json:"-"`
+	PhoneNumber            string        `gorm:"serializer:encrypted" json:"-"`
 	GeneratePassword       bool          `gorm:"default:false" json:"-"`
 	Password               string        `json:"-"`
 	Roles                  []Role        `gorm:"many2many:user_roles" json:"-"`
@@ -78,7 +82,7 @@ type UserCalibration struct {
 
 type UserChange struct {
 	ID               string
-	Email            string
+	Email            string `gorm:"serializer:encrypted"`
 	Division         string
 	Name             string
 	Nik              string
@@ -90,10 +94,10 @@ type UserShow struct {
 	BaseModel
 	CreatedBy         string `gorm:"default:admin" json:"-"`
 	UpdatedBy         string `gorm:"default:admin" json:"-"`
-	Email             string
+	Email             string `gorm:"serializer:encrypted"`
 	Name              string
 	Nik               string
-	DateOfBirth       time.Time `gorm:"type:timestamp without time zone"`
+	DateOfBirth       time.Time `gorm:"type:text;serializer:encrypted"`
 	SupervisorNik     string
 	BusinessUnit      BusinessUnit
 	BusinessUnitId    *string
@@ -105,7 +109,7 @@ type UserShow struct {
 	Grade             string
 	HRBP              string
 	Position          string
-	PhoneNumber       string
+	PhoneNumber       string            `gorm:"serializer:encrypted"`
 	ScoringMethod     string            `gorm:"default:Score"`
 	CalibrationScores []CalibrationForm `gorm:"foreignKey:EmployeeID"`
 	ActualScores      []ActualScore     `gorm:"foreignKey:EmployeeID"`
@@ -11,6 +11,7 @@ type ActualScoreRepo interface {
 	Save(payload *model.ActualScore) error
 	Get(projectId, employeeId string) (*model.ActualScoreTable, error)
 	List() ([]model.ActualScore, error)
+	ListByProject(projectId string) ([]model.ActualScore, error)
 	Delete(projectId, employeeId string) error
 	Bulksave(payload *[]model.ActualScore) error
 }
@@ -81,6 +82,15 @@ func (r *actualScoreRepo) List() ([]model.ActualScore, error) {
 	return actualScores, nil
 }
 
+func (r *actualScoreRepo) ListByProject(projectId string) ([]model.ActualScore, error) {
+	var actualScores []model.ActualScore
+	err := r.db.Where("project_id = ?", projectId).Find(&actualScores).Error
+	if err != nil {
+		return nil, err
+	}
+	return actualScores, nil
+}
+
 func (r *actualScoreRepo) Delete(projectId, employeeId string) error {
 	result := r.db.Delete(&model.ActualScore{
 		ProjectID:  projectId,
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+)
+
+type ApiKeyRepo interface {
+	SaveServiceAccount(payload *model.ServiceAccount) error
+	GetServiceAccount(id string) (*model.ServiceAccount, error)
+	ListServiceAccount() ([]model.ServiceAccount, error)
+	DeactivateServiceAccount(id string) error
+	Save(payload *model.ApiKey) error
+	Get(id string) (*model.ApiKey, error)
+	GetByPrefix(prefix string) (*model.ApiKey, error)
+	Rotate(oldKey *model.ApiKey, newKey *model.ApiKey) error
+	Revoke(id string) error
+	UpdateLastUsed(id, clientIP string) error
+}
+
+type apiKeyRepo struct {
+	db *gorm.DB
+}
+
+func (r *apiKeyRepo) SaveServiceAccount(payload *model.ServiceAccount) error {
+	err := r.db.Omit("ApiKeys").Save(&payload)
+	if err.Error != nil {
+		return err.Error
+	}
+	return nil
+}
+
+func (r *apiKeyRepo) GetServiceAccount(id string) (*model.ServiceAccount, error) {
+	var serviceAccount model.ServiceAccount
+	err := r.db.
+		Preload("ApiKeys", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at DESC")
+		}).
+		First(&serviceAccount, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &serviceAccount, nil
+}
+
+func (r *apiKeyRepo) ListServiceAccount() ([]model.ServiceAccount, error) {
+	var serviceAccounts []model.ServiceAccount
+	err := r.db.
+		Preload("ApiKeys", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at DESC")
+		}).
+		Order("name ASC").
+		Find(&serviceAccounts).Error
+	if err != nil {
+		return nil, err
+	}
+	return serviceAccounts, nil
+}
+
+func (r *apiKeyRepo) DeactivateServiceAccount(id string) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Model(&model.ServiceAccount{}).Where("id = ?", id).Update("active", false)
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("Service account not found!")
+	}
+
+	err := tx.Model(&model.ApiKey{}).
+		Where("service_account_id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *apiKeyRepo) Save(payload *model.ApiKey) error {
+	err := r.db.Omit("ServiceAccount").Save(&payload)
+	if err.Error != nil {
+		return err.Error
+	}
+	return nil
+}
+
+func (r *apiKeyRepo) Get(id string) (*model.ApiKey, error) {
+	var apiKey model.ApiKey
+	err := r.db.First(&apiKey, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *apiKeyRepo) GetByPrefix(prefix string) (*model.ApiKey, error) {
+	var apiKey model.ApiKey
+	err := r.db.
+		Preload("ServiceAccount").
+		First(&apiKey, "prefix = ?", prefix).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *apiKeyRepo) Rotate(oldKey *model.ApiKey, newKey *model.ApiKey) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Model(&model.ApiKey{}).Where("id = ?", oldKey.ID).Update("expires_at", oldKey.ExpiresAt).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Omit("ServiceAccount").Create(&newKey).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *apiKeyRepo) Revoke(id string) error {
+	result := r.db.Model(&model.ApiKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return fmt.Errorf("Api key not found!")
+	}
+	return nil
+}
+
+func (r *apiKeyRepo) UpdateLastUsed(id, clientIP string) error {
+	return r.db.Model(&model.ApiKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": clientIP,
+		}).Error
+}
+
+func NewApiKeyRepo(db *gorm.DB) ApiKeyRepo {
+	return &apiKeyRepo{
+		db: db,
+	}
+}
@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AppealRepo interface {
+	GetFinalResult(projectID, employeeID string) (*model.FinalResult, error)
+	CountResultGroup(projectID, calibratorID, businessUnitID string) (int, error)
+	Create(payload *model.Appeal, event *model.AppealEvent) error
+	Get(id string) (*model.Appeal, error)
+	ListByEmployee(employeeID string) ([]model.Appeal, error)
+	ListByReviewer(reviewerID, projectID, status string) ([]model.Appeal, error)
+	GetAttachment(appealID, attachmentID string) (*model.AppealAttachment, error)
+	UpdateStatus(payload *model.Appeal, from string, event *model.AppealEvent) error
+	Amend(payload *model.Appeal, event *model.AppealEvent, quota *response.RatingQuota) error
+}
+
+type appealRepo struct {
+	db *gorm.DB
+}
+
+func (r *appealRepo) GetFinalResult(projectID, employeeID string) (*model.FinalResult, error) {
+	var result model.FinalResult
+	err := r.db.
+		Joins("Project").
+		Where("final_results.project_id = ? AND final_results.employee_id = ?", projectID, employeeID).
+		First(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CountResultGroup counts the published results a calibrator gave in one business unit, the group a rating quota
+// applies to
+func (r *appealRepo) CountResultGroup(projectID, calibratorID, businessUnitID string) (int, error) {
+	var count int64
+	err := r.db.Model(&model.FinalResult{}).
+		Where("project_id = ? AND calibrator_id = ? AND business_unit_id = ?", projectID, calibratorID, businessUnitID).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (r *appealRepo) Create(payload *model.Appeal, event *model.AppealEvent) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var count int64
+	err := tx.Model(&model.Appeal{}).
+		Where("project_id = ? AND employee_id = ?", payload.ProjectID, payload.EmployeeID).
+		Count(&count).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return fmt.Errorf("The rating of this project was already appealed")
+	}
+
+	if err := tx.Create(payload).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	event.AppealID = payload.ID
+	if err := tx.Create(event).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Get loads the appeal with its audit trail, attachments come without their content
+func (r *appealRepo) Get(id string) (*model.Appeal, error) {
+	var appeal model.Appeal
+	err := r.db.
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "created_at", "appeal_id", "name")
+		}).
+		Preload("Events", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at")
+		}).
+		First(&appeal, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appeal, nil
+}
+
+func (r *appealRepo) ListByEmployee(employeeID string) ([]model.Appeal, error) {
+	var appeals []model.Appeal
+	err := r.db.Where("employee_id = ?", employeeID).Order("created_at DESC").Find(&appeals).Error
+	if err != nil {
+		return nil, err
+	}
+	return appeals, nil
+}
+
+// ListByReviewer returns the appeals routed to a reviewer, an empty reviewerID lists every appeal
+func (r *appealRepo) ListByReviewer(reviewerID, projectID, status string) ([]model.Appeal, error) {
+	var appeals []model.Appeal
+	query := r.db.Order("created_at")
+	if reviewerID != "" {
+		query = query.Where("hrbp_id = ? OR calibrator_id = ?", reviewerID, reviewerID)
+	}
+	if projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&appeals).Error; err != nil {
+		return nil, err
+	}
+	return appeals, nil
+}
+
+func (r *appealRepo) GetAttachment(appealID, attachmentID string) (*model.AppealAttachment, error) {
+	var attachment model.AppealAttachment
+	err := r.db.First(&attachment, "id = ? AND appeal_id = ?", attachmentID, appealID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// UpdateStatus moves an appeal that is still in the from status and records the step
+func (r *appealRepo) UpdateStatus(payload *model.Appeal, from string, event *model.AppealEvent) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Model(&model.Appeal{}).
+		Where("id = ? AND status = ?", payload.ID, from).
+		Updates(map[string]interface{}{
+			"status":      payload.Status,
+			"decision":    payload.Decision,
+			"reviewed_by": payload.ReviewedBy,
+			"reviewed_at": payload.ReviewedAt,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("Appeal is not %s", from)
+	}
+
+	if err := tx.Create(event).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Amend changes the published rating of the appealed employee. The results of the same calibrator and business unit
+// are locked and counted with the new rating so quota is checked the way a submission is. A nil quota skips it, for
+// phases without guideline.
+func (r *appealRepo) Amend(payload *model.Appeal, event *model.AppealEvent, quota *response.RatingQuota) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var result model.FinalResult
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&result, "project_id = ? AND employee_id = ?", payload.ProjectID, payload.EmployeeID).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if quota != nil {
+		var group []model.FinalResult
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ? AND calibrator_id = ? AND business_unit_id = ?", result.ProjectID, result.CalibratorID, result.BusinessUnitID).
+			Find(&group).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		var counted response.TotalCalibratedRating
+		for _, member := range group {
+			rating := member.FinalRating
+			if member.EmployeeID == result.EmployeeID {
+				rating = payload.AmendedRating
+			}
+			switch rating {
+			case "A+":
+				counted.APlus++
+			case "A":
+				counted.A++
+			case "B+":
+				counted.BPlus++
+			case "B":
+				counted.B++
+			case "C":
+				counted.C++
+			case "D":
+				counted.D++
+			}
+			counted.Total++
+		}
+
+		var project model.Project
+		if err := tx.First(&project, "id = ?", result.ProjectID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := checkRatingQuota(&project, counted, *quota); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	now := time.Now()
+	err = tx.Model(&model.FinalResult{}).
+		Where("project_id = ? AND employee_id = ?", result.ProjectID, result.EmployeeID).
+		Updates(map[string]interface{}{
+			"final_rating": payload.AmendedRating,
+			"final_score":  payload.AmendedScore,
+			"amended_at":   now,
+		}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	updated := tx.Model(&model.Appeal{}).
+		Where("id = ? AND status = ?", payload.ID, model.AppealUnderReview).
+		Updates(map[string]interface{}{
+			"status":          model.AppealAmended,
+			"previous_rating": result.FinalRating,
+			"previous_score":  result.FinalScore,
+			"amended_rating":  payload.AmendedRating,
+			"amended_score":   payload.AmendedScore,
+			"decision":        payload.Decision,
+			"reviewed_by":     payload.ReviewedBy,
+			"reviewed_at":     now,
+		})
+	if updated.Error != nil {
+		tx.Rollback()
+		return updated.Error
+	}
+	if updated.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("Appeal is not %s", model.AppealUnderReview)
+	}
+
+	event.FromRating = result.FinalRating
+	event.FromScore = result.FinalScore
+	if err := tx.Create(event).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func NewAppealRepo(db *gorm.DB) AppealRepo {
+	return &appealRepo{
+		db: db,
+	}
+}
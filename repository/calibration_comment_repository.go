@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CalibrationCommentRepo interface {
+	ListParticipants(projectID, employeeID string) ([]model.Calibration, error)
+	List(projectID, employeeID string) ([]model.CalibrationComment, error)
+	Get(id string) (*model.CalibrationComment, error)
+	Create(payload *model.CalibrationComment) error
+	Update(payload *model.CalibrationComment, revision *model.CommentRevision) error
+	ListRevisions(commentID string) ([]model.CommentRevision, error)
+	GetRead(projectID, employeeID, userID string) (*model.CommentRead, error)
+	SaveRead(payload *model.CommentRead) error
+	CountUnread(projectID, userID string, calibratorIDs []string, all bool) ([]response.UnreadComments, error)
+}
+
+type calibrationCommentRepo struct {
+	db *gorm.DB
+}
+
+// ListParticipants returns the calibrator and SPMOs of each phase of the employee
+func (r *calibrationCommentRepo) ListParticipants(projectID, employeeID string) ([]model.Calibration, error) {
+	var calibrations []model.Calibration
+	err := r.db.
+		Select("project_id", "project_phase_id", "employee_id", "calibrator_id", "spmo_id", "spmo2_id", "spmo3_id").
+		Where("project_id = ? AND employee_id = ?", projectID, employeeID).
+		Find(&calibrations).Error
+	if err != nil {
+		return nil, err
+	}
+	return calibrations, nil
+}
+
+func (r *calibrationCommentRepo) List(projectID, employeeID string) ([]model.CalibrationComment, error) {
+	var comments []model.CalibrationComment
+	err := r.db.
+		Preload("Author").
+		Preload("ProjectPhase.Phase").
+		Preload("Mentions").
+		Where("project_id = ? AND employee_id = ?", projectID, employeeID).
+		Order("created_at").
+		Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *calibrationCommentRepo) Get(id string) (*model.CalibrationComment, error) {
+	var comment model.CalibrationComment
+	if err := r.db.Preload("Mentions").First(&comment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *calibrationCommentRepo) Create(payload *model.CalibrationComment) error {
+	return r.db.Create(payload).Error
+}
+
+// Update keeps the previous body as a revision and replaces the body and mentions of the comment
+func (r *calibrationCommentRepo) Update(payload *model.CalibrationComment, revision *model.CommentRevision) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(revision).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	err := tx.Model(&model.CalibrationComment{}).
+		Where("id = ?", payload.ID).
+		Updates(map[string]interface{}{
+			"body":      payload.Body,
+			"edited_at": payload.EditedAt,
+		}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("calibration_comment_id = ?", payload.ID).Delete(&model.CommentMention{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(payload.Mentions) > 0 {
+		if err := tx.Create(&payload.Mentions).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+func (r *calibrationCommentRepo) ListRevisions(commentID string) ([]model.CommentRevision, error) {
+	var revisions []model.CommentRevision
+	err := r.db.Where("calibration_comment_id = ?", commentID).Order("created_at DESC").Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetRead returns nil when the user never read the thread
+func (r *calibrationCommentRepo) GetRead(projectID, employeeID, userID string) (*model.CommentRead, error) {
+	var reads []model.CommentRead
+	err := r.db.
+		Where("project_id = ? AND employee_id = ? AND user_id = ?", projectID, employeeID, userID).
+		Limit(1).
+		Find(&reads).Error
+	if err != nil || len(reads) == 0 {
+		return nil, err
+	}
+	return &reads[0], nil
+}
+
+func (r *calibrationCommentRepo) SaveRead(payload *model.CommentRead) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "employee_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_read_at"}),
+	}).Create(payload).Error
+}
+
+// CountUnread counts per employee the comments of others the user hasn't read. Unless all is set only threads the
+// user takes part in are counted, as one of calibratorIDs or as an SPMO, and only comments visible to them.
+func (r *calibrationCommentRepo) CountUnread(projectID, userID string, calibratorIDs []string, all bool) ([]response.UnreadComments, error) {
+	query := r.db.Table("calibration_comments cc").
+		Select("cc.employee_id, COUNT(*) as unread").
+		Joins("LEFT JOIN comment_reads cr ON cr.project_id = cc.project_id AND cr.employee_id = cc.employee_id AND cr.user_id = ?", userID).
+		Where("cc.project_id = ? AND cc.author_id <> ? AND cc.deleted_at IS NULL", projectID, userID).
+		Where("cr.last_read_at IS NULL OR cc.created_at > cr.last_read_at")
+	if !all {
+		calibrator := r.db.Table("calibrations c").Select("1").
+			Where("c.project_id = cc.project_id AND c.employee_id = cc.employee_id AND c.deleted_at IS NULL AND c.calibrator_id IN ?", calibratorIDs)
+		spmo := r.db.Table("calibrations c").Select("1").
+			Where("c.project_id = cc.project_id AND c.employee_id = cc.employee_id AND c.deleted_at IS NULL").
+			Where("c.spmo_id = ? OR c.spmo2_id = ? OR c.spmo3_id = ?", userID, userID, userID)
+		query = query.Where(
+			r.db.Where("cc.visibility = ? AND (EXISTS (?) OR EXISTS (?))", model.CommentVisibleAll, calibrator, spmo).
+				Or("cc.visibility = ? AND EXISTS (?)", model.CommentVisibleCalibrators, calibrator).
+				Or("cc.visibility = ? AND EXISTS (?)", model.CommentVisibleSpmo, spmo),
+		)
+	}
+
+	var unread []response.UnreadComments
+	err := query.Group("cc.employee_id").Order("cc.employee_id").Scan(&unread).Error
+	if err != nil {
+		return nil, err
+	}
+	return unread, nil
+}
+
+func NewCalibrationCommentRepo(db *gorm.DB) CalibrationCommentRepo {
+	return &calibrationCommentRepo{
+		db: db,
+	}
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+)
+
+type CalibrationDraftRepo interface {
+	Save(payload *model.CalibrationDraft) error
+	Get(id string) (*model.CalibrationDraft, error)
+	ListByProject(projectID string) ([]model.CalibrationDraft, error)
+	ReplaceRows(payload *model.CalibrationDraft) error
+	MarkCommitted(id string) error
+}
+
+type calibrationDraftRepo struct {
+	db *gorm.DB
+}
+
+func (r *calibrationDraftRepo) Save(payload *model.CalibrationDraft) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Omit("Rows").Create(payload).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := createDraftRows(tx, payload); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *calibrationDraftRepo) Get(id string) (*model.CalibrationDraft, error) {
+	var draft model.CalibrationDraft
+	err := r.db.
+		Preload("Rows", func(db *gorm.DB) *gorm.DB {
+			return db.Order("nik ASC")
+		}).
+		First(&draft, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+func (r *calibrationDraftRepo) ListByProject(projectID string) ([]model.CalibrationDraft, error) {
+	var drafts []model.CalibrationDraft
+	err := r.db.
+		Where("project_id = ?", projectID).
+		Order("created_at DESC").
+		Find(&drafts).Error
+	if err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+// ReplaceRows swaps the rows of an open draft for an edited set
+func (r *calibrationDraftRepo) ReplaceRows(payload *model.CalibrationDraft) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Model(&model.CalibrationDraft{}).
+		Where("id = ? AND status = ?", payload.ID, model.CalibrationDraftOpen).
+		Updates(map[string]interface{}{
+			"employees": payload.Employees,
+			"warnings":  payload.Warnings,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("Draft has already been committed")
+	}
+
+	if err := tx.Unscoped().Where("draft_id = ?", payload.ID).Delete(&model.CalibrationDraftRow{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := createDraftRows(tx, payload); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *calibrationDraftRepo) MarkCommitted(id string) error {
+	result := r.db.Model(&model.CalibrationDraft{}).
+		Where("id = ? AND status = ?", id, model.CalibrationDraftOpen).
+		Updates(map[string]interface{}{
+			"status":       model.CalibrationDraftCommitted,
+			"committed_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("Draft has already been committed")
+	}
+	return nil
+}
+
+func createDraftRows(tx *gorm.DB, payload *model.CalibrationDraft) error {
+	for i := range payload.Rows {
+		payload.Rows[i].DraftID = payload.ID
+	}
+	if len(payload.Rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(&payload.Rows, 500).Error
+}
+
+func NewCalibrationDraftRepo(db *gorm.DB) CalibrationDraftRepo {
+	return &calibrationDraftRepo{
+		db: db,
+	}
+}
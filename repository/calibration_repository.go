@@ -2,11 +2,13 @@ package repository
 
 import (
 	"fmt"
+	"time"
 
 	"calibration-system.com/delivery/api/request"
 	"calibration-system.com/delivery/api/response"
 	"calibration-system.com/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CalibrationRepo interface {
@@ -16,28 +18,30 @@ type CalibrationRepo interface {
 	GetAllPreviousEmployeeCalibrationByActiveProject(employeeID, projectID string, phaseOrder int) ([]model.Calibration, error)
 	GetByProjectEmployeeID(projectID, employeeID string) ([]model.CalibrationForm, error)
 	List() ([]model.Calibration, error)
-	GetActiveUserBySPMOID(spmoID string) ([]model.UserChange, error)
-	GetAcceptedBySPMOID(spmoID string) ([]model.Calibration, error)
-	GetRejectedBySPMOID(spmoID string) ([]model.Calibration, error)
+	ListByProject(projectID string) ([]model.Calibration, error)
+	GetActiveUserBySPMOID(spmoID, projectID string) ([]model.UserChange, error)
+	GetAcceptedBySPMOID(spmoID, projectID string) ([]model.Calibration, error)
+	GetRejectedBySPMOID(spmoID, projectID string) ([]model.Calibration, error)
 	Delete(projectId, employeeId string) error
 	DeleteCalibrationPhase(projectId, projectPhaseId, employeeId string) error
+	ImportCalibrations(payload *[]model.Calibration, removed []model.Calibration, results []model.EligibilityResult) error
 	Bulksave(payload *[]model.Calibration) error
+	Reassign(payload *request.ReassignCalibrations, reassignedBy string) ([]model.CalibrationReassignment, error)
 	BulkUpdate(payload []response.UserResponse, projectPhase model.ProjectPhase, projectID string) ([]string, []*response.NotificationModel, error)
 	UpdateManagerCalibrations(payload []response.UserResponse, projectPhase model.ProjectPhase) ([]string, string, error)
 	UpdateCalibrationsOnePhaseBefore(payload []response.UserResponse, projectPhase model.ProjectPhase) ([]response.NotificationModel, error)
 	SaveChanges(payload *request.CalibrationRequest) error
 	SaveCommentCalibration(payload *model.Calibration) error
 	SaveScoreAndRating(payload *model.Calibration) error
-	AcceptCalibration(payload *request.AcceptJustification, phaseOrder int) error
-	AcceptMultipleCalibration(payload *request.AcceptMultipleJustification) error
-	RejectCalibration(payload *request.RejectJustification) error
+	AcceptCalibration(payload *request.AcceptJustification, spmoID string, admin bool) (*response.ApprovalResult, error)
+	AcceptMultipleCalibration(payload *request.AcceptMultipleJustification, spmoID string, admin bool) ([]response.ApprovalResult, error)
+	RejectCalibration(payload *request.RejectJustification, spmoID string, admin bool) (*response.ApprovalResult, error)
 	SubmitReview(payload *request.AcceptMultipleJustification) ([]response.NotificationModel, error)
 	GetSummaryBySPMOID(spmoID, projectID string) ([]response.SPMOSummaryResult, error)
-	GetAllDetailCalibrationBySPMOID(spmoID, calibratorID, businessUnitID, department string, order int) ([]response.UserResponse, error)
+	GetAllDetailCalibrationBySPMOID(spmoID, calibratorID, businessUnitID, department, projectID string, order int) ([]response.UserResponse, error)
 	GetAllDetailCalibration2BySPMOID(spmoID, calibratorID, businessUnitID, projectID string, order int) ([]response.UserResponse, error)
-	GetCalibrateCalibration() ([]model.Calibration, error)
 	GetCalibrateCalibrationByProjectID(projectID string) ([]model.Calibration, error)
-	GetAllCalibrationByCalibratorID(calibratorId string) ([]model.Calibration, error)
+	GetAllCalibrationByCalibratorID(calibratorID, projectID string) ([]model.Calibration, error)
 	GetLatestJustification(projectID, calibratorID, employeeID string) ([]model.SeeCalibrationJustification, error)
 	CheckConditionBeforeSubmitCalibration(projectID string, payload []response.UserResponse,
 		projectPhase model.ProjectPhase, countCalibrated response.TotalCalibratedRating, countRatingQuota response.RatingQuota,
@@ -80,28 +84,11 @@ func (r *calibrationRepo) GetLatestJustification(projectID, calibratorID, employ
 	return calibrations, nil
 }
 
-func (r *calibrationRepo) GetAllCalibrationByCalibratorID(calibratorId string) ([]model.Calibration, error) {
+func (r *calibrationRepo) GetAllCalibrationByCalibratorID(calibratorID, projectID string) ([]model.Calibration, error) {
 	var calibrations []model.Calibration
 	err := r.db.
 		Table("calibrations c").
-		Joins("JOIN projects pr ON pr.id = c.project_id AND pr.active = true").
-		Where("c.calibrator_id = ?", calibratorId).
-		Find(&calibrations).
-		Error
-	if err != nil {
-		return nil, err
-	}
-	return calibrations, nil
-}
-
-func (r *calibrationRepo) GetCalibrateCalibration() ([]model.Calibration, error) {
-	var calibrations []model.Calibration
-	err := r.db.
-		Table("calibrations c").
-		Preload("ProjectPhase").
-		Preload("ProjectPhase.Phase").
-		Joins("JOIN projects pr ON pr.id = c.project_id AND pr.active = true").
-		Where("c.status = ? AND c.spmo_status = ? ", "Calibrate", "-").
+		Where("c.calibrator_id = ? AND c.project_id = ?", calibratorID, projectID).
 		Find(&calibrations).
 		Error
 	if err != nil {
@@ -116,7 +103,6 @@ func (r *calibrationRepo) GetCalibrateCalibrationByProjectID(projectID string) (
 		Table("calibrations c").
 		Preload("ProjectPhase").
 		Preload("ProjectPhase.Phase").
-		Joins("JOIN projects pr ON pr.id = c.project_id AND pr.active = true").
 		Where("c.status = ? AND c.spmo_status = ? AND c.project_id = ?", "Calibrate", "-", projectID).
 		Find(&calibrations).
 		Error
@@ -281,6 +267,177 @@ func (r *calibrationRepo) Bulksave(payload *[]model.Calibration) error {
 	return nil
 }
 
+// reassignColumns are the calibration columns a reassignment can move
+var reassignColumns = map[string]string{
+	model.ReassignCalibrator: "calibrator_id",
+	model.ReassignSpmo:       "spmo_id",
+	model.ReassignSpmo2:      "spmo2_id",
+	model.ReassignSpmo3:      "spmo3_id",
+}
+
+// reassignLevels are the approval levels of the SPMO columns, undecided levels move with the column
+var reassignLevels = map[string]int{
+	model.ReassignSpmo:  1,
+	model.ReassignSpmo2: 2,
+	model.ReassignSpmo3: 3,
+}
+
+// Reassign moves the calibrations of the from user to the to user phase by phase. Only the calibrator or SPMO
+// column changes, so scores, comments and remarks stay with the employee. Worksheets the calibrator already
+// submitted and reviews the SPMO already submitted can't be moved.
+func (r *calibrationRepo) Reassign(payload *request.ReassignCalibrations, reassignedBy string) ([]model.CalibrationReassignment, error) {
+	column, ok := reassignColumns[payload.Role]
+	if !ok {
+		return nil, fmt.Errorf("Role must be calibrator, spmo, spmo2 or spmo3")
+	}
+
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var reassignments []model.CalibrationReassignment
+	for _, projectPhaseID := range payload.ProjectPhaseIDs {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ? AND project_phase_id = ?", payload.ProjectID, projectPhaseID).
+			Where(column+" = ?", payload.FromID)
+		if len(payload.EmployeeIDs) > 0 {
+			query = query.Where("employee_id IN ?", payload.EmployeeIDs)
+		}
+		var calibrations []model.Calibration
+		if err := query.Find(&calibrations).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if len(payload.EmployeeIDs) > 0 && len(calibrations) != len(payload.EmployeeIDs) {
+			tx.Rollback()
+			return nil, fmt.Errorf("%d of the employees are not assigned to the %s in phase %s", len(payload.EmployeeIDs)-len(calibrations), payload.Role, projectPhaseID)
+		}
+		if len(calibrations) == 0 {
+			continue
+		}
+
+		employeeIDs := make([]string, 0, len(calibrations))
+		for _, calibration := range calibrations {
+			if payload.Role == model.ReassignCalibrator && calibration.Status == "Complete" {
+				tx.Rollback()
+				return nil, fmt.Errorf("Employee %s was already submitted by the calibrator in phase %s", calibration.EmployeeID, projectPhaseID)
+			}
+			if payload.Role != model.ReassignCalibrator && calibration.JustificationReviewStatus {
+				tx.Rollback()
+				return nil, fmt.Errorf("Employee %s was already reviewed by the SPMO in phase %s", calibration.EmployeeID, projectPhaseID)
+			}
+			employeeIDs = append(employeeIDs, calibration.EmployeeID)
+		}
+
+		err := tx.Model(&model.Calibration{}).
+			Where("project_id = ? AND project_phase_id = ? AND employee_id IN ?", payload.ProjectID, projectPhaseID, employeeIDs).
+			Update(column, payload.ToID).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if level, ok := reassignLevels[payload.Role]; ok {
+			err := tx.Model(&model.CalibrationApproval{}).
+				Where("project_id = ? AND project_phase_id = ? AND employee_id IN ? AND level = ?", payload.ProjectID, projectPhaseID, employeeIDs, level).
+				Where("status IN ?", []string{model.ApprovalPending, model.ApprovalWaiting}).
+				Update("spmo_id", payload.ToID).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		reassignment := model.CalibrationReassignment{
+			ProjectID:      payload.ProjectID,
+			ProjectPhaseID: projectPhaseID,
+			Role:           payload.Role,
+			FromID:         payload.FromID,
+			ToID:           payload.ToID,
+			EmployeeCount:  len(employeeIDs),
+			Reason:         payload.Reason,
+			ReassignedBy:   reassignedBy,
+		}
+		if err := tx.Create(&reassignment).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		reassignments = append(reassignments, reassignment)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	go func() {
+		err := r.db.Exec("REFRESH MATERIALIZED VIEW materialized_user_view;").Error
+		if err != nil {
+			fmt.Printf("Failed to refresh materialized view: %v", err)
+		}
+	}()
+	return reassignments, nil
+}
+
+// ImportCalibrations removes the skipped phases, saves the uploaded calibrations and the eligibility decisions taken
+// on them in one transaction. Employees left out of an upload keep their previous decision.
+func (r *calibrationRepo) ImportCalibrations(payload *[]model.Calibration, removed []model.Calibration, results []model.EligibilityResult) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, calibration := range removed {
+		err := tx.Unscoped().
+			Where("project_id = ? AND employee_id = ? AND project_phase_id = ?", calibration.ProjectID, calibration.EmployeeID, calibration.ProjectPhaseID).
+			Delete(&model.Calibration{}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	batchSize := 100
+	for start := 0; start < len(*payload); start += batchSize {
+		end := start + batchSize
+		if end > len(*payload) {
+			end = len(*payload)
+		}
+		currentBatch := (*payload)[start:end]
+		err := tx.Save(&currentBatch).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(results) > 0 {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "project_id"}, {Name: "employee_id"}},
+			UpdateAll: true,
+		}).CreateInBatches(&results, batchSize).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	go func() {
+		err := r.db.Exec("REFRESH MATERIALIZED VIEW materialized_user_view;").Error
+		if err != nil {
+			fmt.Printf("Failed to refresh materialized view: %v", err)
+		}
+	}()
+	return nil
+}
+
 func (r *calibrationRepo) Get(projectID, projectPhaseID, employeeID string) (*model.Calibration, error) {
 	var calibration model.Calibration
 	err := r.db.
@@ -295,6 +452,15 @@ func (r *calibrationRepo) Get(projectID, projectPhaseID, employeeID string) (*mo
 	return &calibration, nil
 }
 
+func (r *calibrationRepo) ListByProject(projectID string) ([]model.Calibration, error) {
+	var calibrations []model.Calibration
+	err := r.db.Where("project_id = ?", projectID).Find(&calibrations).Error
+	if err != nil {
+		return nil, err
+	}
+	return calibrations, nil
+}
+
 func (r *calibrationRepo) GetAllPreviousEmployeeCalibrationByActiveProject(employeeID, projectID string, phaseOrder int) ([]model.Calibration, error) {
 	var calibrations []model.Calibration
 	err := r.db.
@@ -336,14 +502,13 @@ func (r *calibrationRepo) GetByProjectEmployeeID(projectID, employeeID string) (
 	return calibration, nil
 }
 
-func (r *calibrationRepo) GetActiveUserBySPMOID(spmoID string) ([]model.UserChange, error) {
+func (r *calibrationRepo) GetActiveUserBySPMOID(spmoID, projectID string) ([]model.UserChange, error) {
 	var calibration []model.UserChange
 	err := r.db.
 		Table("users u").
 		Select("u.id as id, u.email as email, u.name as name, u.division as division, u.nik as nik, b.name as business_unit_name").
 		Joins("JOIN business_units b on u.business_unit_id = b.id").
-		Joins("JOIN calibrations c1 ON (c1.employee_id = u.id OR c1.calibrator_id = u.id) AND (spmo_id = ? OR spmo2_id = ? OR spmo3_id = ?) AND c1.deleted_at IS NULL", spmoID, spmoID, spmoID).
-		Joins("JOIN projects pr ON pr.id = c1.project_id AND pr.active = true").
+		Joins("JOIN calibrations c1 ON (c1.employee_id = u.id OR c1.calibrator_id = u.id) AND (spmo_id = ? OR spmo2_id = ? OR spmo3_id = ?) AND c1.project_id = ? AND c1.deleted_at IS NULL", spmoID, spmoID, spmoID, projectID).
 		Joins("LEFT JOIN user_roles ur on u.id = ur.user_id").
 		Joins("LEFT JOIN roles r on ur.role_id = r.id").
 		Where("r.name != 'exclude' or r.id is NULL").
@@ -356,7 +521,7 @@ func (r *calibrationRepo) GetActiveUserBySPMOID(spmoID string) ([]model.UserChan
 	return calibration, nil
 }
 
-func (r *calibrationRepo) GetAcceptedBySPMOID(id string) ([]model.Calibration, error) {
+func (r *calibrationRepo) GetAcceptedBySPMOID(id, projectID string) ([]model.Calibration, error) {
 	var calibration []model.Calibration
 	err := r.db.
 		Table("calibrations c").
@@ -367,10 +532,9 @@ func (r *calibrationRepo) GetAcceptedBySPMOID(id string) ([]model.Calibration, e
 		Preload("BottomRemark").
 		Preload("TopRemarks").
 		Select("c.*").
-		Joins("JOIN projects pr ON pr.id = c.project_id AND pr.active = true").
 		Joins("JOIN project_phases pp ON pp.id = c.project_phase_id AND pp.review_spmo = true").
 		Joins("JOIN phases p ON p.id = pp.phase_id").
-		Where("c.spmo_id = ? AND c.spmo_status = 'Accepted' ", id).
+		Where("c.spmo_id = ? AND c.spmo_status = 'Accepted' AND c.project_id = ?", id, projectID).
 		Order("p.order ASC").
 		Find(&calibration).Error
 	if err != nil {
@@ -379,7 +543,7 @@ func (r *calibrationRepo) GetAcceptedBySPMOID(id string) ([]model.Calibration, e
 	return calibration, nil
 }
 
-func (r *calibrationRepo) GetRejectedBySPMOID(id string) ([]model.Calibration, error) {
+func (r *calibrationRepo) GetRejectedBySPMOID(id, projectID string) ([]model.Calibration, error) {
 	var calibration []model.Calibration
 	err := r.db.
 		Table("calibrations c").
@@ -390,10 +554,9 @@ func (r *calibrationRepo) GetRejectedBySPMOID(id string) ([]model.Calibration, e
 		Preload("BottomRemark").
 		Preload("TopRemarks").
 		Select("c.*").
-		Joins("JOIN projects pr ON pr.id = c.project_id AND pr.active = true").
 		Joins("JOIN project_phases pp ON pp.id = c.project_phase_id AND pp.review_spmo = true").
 		Joins("JOIN phases p ON p.id = pp.phase_id").
-		Where("c.spmo_id = ? AND c.spmo_status = 'Rejected' ", id).
+		Where("c.spmo_id = ? AND c.spmo_status = 'Rejected' AND c.project_id = ?", id, projectID).
 		Order("p.order ASC").
 		Find(&calibration).Error
 	if err != nil {
@@ -456,17 +619,20 @@ func (r *calibrationRepo) BulkUpdate(payload []response.UserResponse, projectPha
 			return nil, nil, err
 		}
 
-		spmoID = append(spmoID, getCalibration.SpmoID)
-		if getCalibration.Spmo2ID != nil {
-			spmoID = append(spmoID, *getCalibration.Spmo2ID)
-		}
-		if getCalibration.Spmo3ID != nil {
-			spmoID = append(spmoID, *getCalibration.Spmo3ID)
-		}
-
 		if projectPhase.ReviewSpmo {
 			getCalibration.SpmoStatus = "Waiting"
 			reviewSPMO = true
+
+			approvals, err := resetApprovals(tx, getCalibration, &projectPhase)
+			if err != nil {
+				tx.Rollback()
+				return nil, nil, err
+			}
+			for _, approval := range approvals {
+				if approval.Status == model.ApprovalWaiting {
+					spmoID = append(spmoID, approval.SpmoID)
+				}
+			}
 		}
 		getCalibration.Status = "Complete"
 		employeeCalibrationScore = append(employeeCalibrationScore, getCalibration)
@@ -997,7 +1163,7 @@ func (r *calibrationRepo) SaveScoreAndRating(payload *model.Calibration) error {
 	return nil
 }
 
-func (r *calibrationRepo) AcceptMultipleCalibration(payload *request.AcceptMultipleJustification) error {
+func (r *calibrationRepo) AcceptMultipleCalibration(payload *request.AcceptMultipleJustification, spmoID string, admin bool) ([]response.ApprovalResult, error) {
 	tx := r.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -1005,24 +1171,23 @@ func (r *calibrationRepo) AcceptMultipleCalibration(payload *request.AcceptMulti
 		}
 	}()
 
+	var results []response.ApprovalResult
 	for _, justification := range payload.ArrayOfAcceptsJustification {
-		err := tx.Updates(&model.Calibration{
-			ProjectID:      justification.ProjectID,
-			ProjectPhaseID: justification.ProjectPhaseID,
-			EmployeeID:     justification.EmployeeID,
-			SpmoStatus:     "Accepted",
-		}).Error
+		result, err := decideApproval(tx, justification.ProjectID, justification.ProjectPhaseID, justification.EmployeeID, spmoID, admin, model.ApprovalAccepted, "")
 		if err != nil {
 			tx.Rollback()
-			return err
+			return nil, err
 		}
+		results = append(results, *result)
 	}
 
-	tx.Commit()
-	return nil
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-func (r *calibrationRepo) AcceptCalibration(payload *request.AcceptJustification, phaseOrder int) error {
+func (r *calibrationRepo) AcceptCalibration(payload *request.AcceptJustification, spmoID string, admin bool) (*response.ApprovalResult, error) {
 	tx := r.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -1030,21 +1195,18 @@ func (r *calibrationRepo) AcceptCalibration(payload *request.AcceptJustification
 		}
 	}()
 
-	err := tx.Updates(&model.Calibration{
-		ProjectID:      payload.ProjectID,
-		ProjectPhaseID: payload.ProjectPhaseID,
-		EmployeeID:     payload.EmployeeID,
-		SpmoStatus:     "Accepted",
-	}).Error
+	result, err := decideApproval(tx, payload.ProjectID, payload.ProjectPhaseID, payload.EmployeeID, spmoID, admin, model.ApprovalAccepted, "")
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func (r *calibrationRepo) RejectCalibration(payload *request.RejectJustification) error {
+func (r *calibrationRepo) RejectCalibration(payload *request.RejectJustification, spmoID string, admin bool) (*response.ApprovalResult, error) {
 	tx := r.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -1052,23 +1214,199 @@ func (r *calibrationRepo) RejectCalibration(payload *request.RejectJustification
 		}
 	}()
 
-	err := tx.Model(&model.Calibration{}).
-		Where("project_id = ? AND project_phase_id = ? AND employee_id = ? AND calibrator_id = ?",
-			payload.ProjectID,
-			payload.ProjectPhaseID,
-			payload.EmployeeID,
-			payload.CalibratorID,
-		).Updates(map[string]interface{}{
-		"spmo_status":  "Rejected",
-		"spmo_comment": payload.Comment,
-	}).Error
+	result, err := decideApproval(tx, payload.ProjectID, payload.ProjectPhaseID, payload.EmployeeID, spmoID, admin, model.ApprovalRejected, payload.Comment)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	tx.Commit()
-	return nil
+// decideApproval records the decision of the caller's SPMO level on a calibration and settles the chain. An admin
+// who isn't one of the SPMOs decides for the first level that is waiting.
+func decideApproval(tx *gorm.DB, projectID, projectPhaseID, employeeID, spmoID string, admin bool, decision, comment string) (*response.ApprovalResult, error) {
+	var calibration model.Calibration
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&calibration, "project_id = ? AND project_phase_id = ? AND employee_id = ?", projectID, projectPhaseID, employeeID).Error
+	if err != nil {
+		return nil, fmt.Errorf("Calibration not found!")
+	}
+	if calibration.SpmoStatus != model.ApprovalWaiting {
+		return nil, fmt.Errorf("Calibration of employee %s is not waiting for SPMO review", employeeID)
+	}
+
+	var projectPhase model.ProjectPhase
+	if err := tx.Preload("Phase").First(&projectPhase, "id = ?", projectPhaseID).Error; err != nil {
+		return nil, err
+	}
+
+	var approvals []model.CalibrationApproval
+	err = tx.Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", projectID, projectPhaseID, employeeID).
+		Order("level").
+		Find(&approvals).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(approvals) == 0 {
+		// submitted before the phase had a chain
+		approvals = buildApprovals(&calibration, &projectPhase)
+	}
+
+	level := -1
+	for i := range approvals {
+		if approvals[i].Status == model.ApprovalWaiting && approvals[i].SpmoID == spmoID {
+			level = i
+			break
+		}
+	}
+	if level < 0 && admin {
+		for i := range approvals {
+			if approvals[i].Status == model.ApprovalWaiting {
+				level = i
+				break
+			}
+		}
+	}
+	if level < 0 {
+		return nil, fmt.Errorf("Calibration of employee %s is not waiting for your review", employeeID)
+	}
+
+	now := time.Now()
+	approvals[level].Status = decision
+	approvals[level].Comment = comment
+	approvals[level].DecidedAt = &now
+	status, next := settleApprovals(approvals, projectPhase.SpmoChain, projectPhase.SpmoQuorum)
+
+	if err := tx.Save(&approvals).Error; err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{"spmo_status": status}
+	if status == model.ApprovalRejected {
+		updates["spmo_comment"] = comment
+	}
+	err = tx.Model(&model.Calibration{}).
+		Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", projectID, projectPhaseID, employeeID).
+		Updates(updates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.ApprovalResult{
+		ProjectID:      projectID,
+		ProjectPhaseID: projectPhaseID,
+		PhaseOrder:     projectPhase.Phase.Order,
+		EmployeeID:     employeeID,
+		CalibratorID:   calibration.CalibratorID,
+		Level:          approvals[level].Level,
+		Status:         status,
+		NextSpmoID:     next,
+	}, nil
+}
+
+// buildApprovals opens the chain of a submitted calibration, every SPMO level at once or only the first one for a
+// sequential chain
+func buildApprovals(calibration *model.Calibration, projectPhase *model.ProjectPhase) []model.CalibrationApproval {
+	spmoIDs := []string{calibration.SpmoID, "", ""}
+	if calibration.Spmo2ID != nil {
+		spmoIDs[1] = *calibration.Spmo2ID
+	}
+	if calibration.Spmo3ID != nil {
+		spmoIDs[2] = *calibration.Spmo3ID
+	}
+
+	var approvals []model.CalibrationApproval
+	for i, spmoID := range spmoIDs {
+		if spmoID == "" {
+			continue
+		}
+		status := model.ApprovalWaiting
+		if projectPhase.SpmoChain == model.SpmoChainSequential && len(approvals) > 0 {
+			status = model.ApprovalPending
+		}
+		approvals = append(approvals, model.CalibrationApproval{
+			ProjectID:      calibration.ProjectID,
+			ProjectPhaseID: calibration.ProjectPhaseID,
+			EmployeeID:     calibration.EmployeeID,
+			Level:          i + 1,
+			SpmoID:         spmoID,
+			Status:         status,
+		})
+	}
+	return approvals
+}
+
+// settleApprovals works out the outcome of a chain after a decision. With an all quorum every level has to accept
+// and one rejection rejects, with an any quorum one acceptance accepts and it is rejected once every level rejected.
+// A sequential chain that is still undecided hands over to its next level, whose SPMO is returned.
+func settleApprovals(approvals []model.CalibrationApproval, chain, quorum string) (string, string) {
+	accepted, rejected := 0, 0
+	for _, approval := range approvals {
+		switch approval.Status {
+		case model.ApprovalAccepted:
+			accepted++
+		case model.ApprovalRejected:
+			rejected++
+		}
+	}
+
+	status := model.ApprovalWaiting
+	if quorum == model.SpmoQuorumAll {
+		if rejected > 0 {
+			status = model.ApprovalRejected
+		} else if accepted == len(approvals) {
+			status = model.ApprovalAccepted
+		}
+	} else {
+		if accepted > 0 {
+			status = model.ApprovalAccepted
+		} else if rejected == len(approvals) {
+			status = model.ApprovalRejected
+		}
+	}
+
+	if status != model.ApprovalWaiting {
+		for i := range approvals {
+			if approvals[i].Status == model.ApprovalPending || approvals[i].Status == model.ApprovalWaiting {
+				approvals[i].Status = model.ApprovalSkipped
+			}
+		}
+		return status, ""
+	}
+
+	if chain == model.SpmoChainSequential {
+		for i := range approvals {
+			if approvals[i].Status == model.ApprovalWaiting {
+				return status, ""
+			}
+		}
+		for i := range approvals {
+			if approvals[i].Status == model.ApprovalPending {
+				approvals[i].Status = model.ApprovalWaiting
+				return status, approvals[i].SpmoID
+			}
+		}
+	}
+	return status, ""
+}
+
+// resetApprovals starts the chain of a calibration over when its calibrator submits it for review
+func resetApprovals(tx *gorm.DB, calibration *model.Calibration, projectPhase *model.ProjectPhase) ([]model.CalibrationApproval, error) {
+	err := tx.Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", calibration.ProjectID, calibration.ProjectPhaseID, calibration.EmployeeID).
+		Delete(&model.CalibrationApproval{}).Error
+	if err != nil {
+		return nil, err
+	}
+	approvals := buildApprovals(calibration, projectPhase)
+	if len(approvals) == 0 {
+		return nil, nil
+	}
+	if err := tx.Create(&approvals).Error; err != nil {
+		return nil, err
+	}
+	return approvals, nil
 }
 
 func (r *calibrationRepo) SubmitReview(payload *request.AcceptMultipleJustification) ([]response.NotificationModel, error) {
@@ -1081,7 +1419,33 @@ func (r *calibrationRepo) SubmitReview(payload *request.AcceptMultipleJustificat
 
 	mapResult := make(map[string]response.NotificationModel)
 	for _, justification := range payload.ArrayOfAcceptsJustification {
-		err := tx.Updates(&model.Calibration{
+		// the calibration itself has to be accepted, it may predate the chain of its phase and have no approvals,
+		// then no level of the chain may still be undecided
+		var pending int64
+		err := tx.Model(&model.Calibration{}).
+			Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", justification.ProjectID, justification.ProjectPhaseID, justification.EmployeeID).
+			Where("spmo_status <> ?", model.ApprovalAccepted).
+			Count(&pending).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if pending == 0 {
+			err = tx.Model(&model.CalibrationApproval{}).
+				Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", justification.ProjectID, justification.ProjectPhaseID, justification.EmployeeID).
+				Where("status IN ?", []string{model.ApprovalPending, model.ApprovalWaiting}).
+				Count(&pending).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+		if pending > 0 {
+			tx.Rollback()
+			return nil, fmt.Errorf("The approval chain of employee %s is not complete", justification.EmployeeID)
+		}
+
+		err = tx.Updates(&model.Calibration{
 			ProjectID:                 justification.ProjectID,
 			ProjectPhaseID:            justification.ProjectPhaseID,
 			EmployeeID:                justification.EmployeeID,
@@ -1109,7 +1473,7 @@ func (r *calibrationRepo) SubmitReview(payload *request.AcceptMultipleJustificat
 			Joins("JOIN projects ON projects.id = calibrations.project_id").
 			Joins("JOIN project_phases ON project_phases.id = calibrations.project_phase_id").
 			Joins("JOIN phases ON phases.id = project_phases.phase_id").
-			Where("projects.active = true AND phases.order > ? AND calibrations.employee_id = ?", projectPhase.Phase.Order, justification.EmployeeID).
+			Where("calibrations.project_id = ? AND phases.order > ? AND calibrations.employee_id = ?", justification.ProjectID, projectPhase.Phase.Order, justification.EmployeeID).
 			Order("phases.order ASC").
 			Find(&calibrations).Error
 		if err != nil {
@@ -1214,7 +1578,8 @@ func (r *calibrationRepo) GetSummaryBySPMOID(spmoID, projectID string) ([]respon
 	// Assuming db is your GORM database instance
 	var results []response.SPMOSummaryResult
 	err := tx.Table("calibrations c").
-		Select("COUNT(c.*) as count, u.business_unit_id, b.name as business_unit_name, u2.name as calibrator_name, c.calibrator_id, c.project_phase_id, p.order, u2.last_login as last_login").
+		Select("COUNT(c.*) as count, u.business_unit_id, b.name as business_unit_name, u2.name as calibrator_name, c.calibrator_id, c.project_phase_id, p.order, u2.last_login as last_login, "+
+			"CASE WHEN c.spmo_id = ? THEN 1 WHEN c.spmo2_id = ? THEN 2 ELSE 3 END as spmo_level", spmoID, spmoID).
 		Joins("JOIN project_phases pp ON pp.id = c.project_phase_id").
 		Joins("JOIN phases p ON pp.phase_id = p.id").
 		Joins("JOIN users u ON c.employee_id = u.id").
@@ -1223,8 +1588,8 @@ func (r *calibrationRepo) GetSummaryBySPMOID(spmoID, projectID string) ([]respon
 		Joins("JOIN projects pr ON pr.id = c.project_id").
 		Where("pr.id = ? AND (spmo_id = ? OR spmo2_id = ? OR spmo3_id = ?)", projectID, spmoID, spmoID, spmoID).
 		Where("p.order < 6").
-		Group("u.business_unit_id, b.name, u2.name, c.calibrator_id, c.project_phase_id, p.order, u2.last_login").
-		Order("p.order ASC").
+		Group("u.business_unit_id, b.name, u2.name, c.calibrator_id, c.project_phase_id, p.order, u2.last_login, spmo_level").
+		Order("p.order ASC, spmo_level ASC").
 		Scan(&results).Error
 
 	if err != nil {
@@ -1235,21 +1600,19 @@ func (r *calibrationRepo) GetSummaryBySPMOID(spmoID, projectID string) ([]respon
 	return results, nil
 }
 
-func (r *calibrationRepo) GetAllDetailCalibrationBySPMOID(spmoID, calibratorID, businessUnitID, department string, order int) ([]response.UserResponse, error) {
+func (r *calibrationRepo) GetAllDetailCalibrationBySPMOID(spmoID, calibratorID, businessUnitID, department, projectID string, order int) ([]response.UserResponse, error) {
 	var calibration []response.UserResponse
 	err := r.db.
 		Table("users u").
 		Preload("ActualScores", func(db *gorm.DB) *gorm.DB {
 			return db.
-				Joins("JOIN projects proj1 ON actual_scores.project_id = proj1.id").
-				Where("proj1.active = ?", true)
+				Where("actual_scores.project_id = ?", projectID)
 		}).
 		Preload("CalibrationScores", func(db *gorm.DB) *gorm.DB {
 			return db.
-				Joins("JOIN projects ON calibrations.project_id = projects.id").
 				Joins("JOIN project_phases pp ON pp.id = calibrations.project_phase_id").
 				Joins("JOIN phases p ON p.id = pp.phase_id ").
-				Where("projects.active = true AND p.order <= ?", order).
+				Where("calibrations.project_id = ? AND p.order <= ?", projectID, order).
 				Order("p.order ASC")
 		}).
 		Preload("CalibrationScores.ProjectPhase").
@@ -1258,8 +1621,7 @@ func (r *calibrationRepo) GetAllDetailCalibrationBySPMOID(spmoID, calibratorID,
 		Preload("CalibrationScores.BottomRemark").
 		Select("u.*, u2.name as supervisor_names").
 		Joins("JOIN business_units b ON u.business_unit_id = b.id AND b.id = ?", businessUnitID).
-		Joins("JOIN calibrations c1 ON c1.employee_id = u.id AND (spmo_id = ? OR spmo2_id = ? OR spmo3_id = ?) AND c1.calibrator_id = ?", spmoID, spmoID, spmoID, calibratorID).
-		Joins("JOIN projects pr ON pr.id = c1.project_id AND pr.active = true").
+		Joins("JOIN calibrations c1 ON c1.employee_id = u.id AND (spmo_id = ? OR spmo2_id = ? OR spmo3_id = ?) AND c1.calibrator_id = ? AND c1.project_id = ?", spmoID, spmoID, spmoID, calibratorID, projectID).
 		Joins("LEFT JOIN users u2 ON u.supervisor_nik = u2.nik").
 		Where("u.department = ?", department).
 		Find(&calibration).Error
@@ -1285,6 +1647,9 @@ func (r *calibrationRepo) GetAllDetailCalibration2BySPMOID(spmoID, calibratorID,
 				Order("p.order ASC")
 		}).
 		Preload("CalibrationScores.ProjectPhase.Phase").
+		Preload("CalibrationScores.Approvals", func(db *gorm.DB) *gorm.DB {
+			return db.Order("level")
+		}).
 		Select("m.*").
 		Distinct().
 		Where("m.phase_order = ? AND m.project_id = ? AND m.business_unit_id = ? AND m.calibrator_id = ? AND (m.spmo_id = ? OR m.spmo2_id = ? OR m.spmo3_id = ?)", order, projectID, businessUnitID, calibratorID, spmoID, spmoID, spmoID).
@@ -1448,23 +1813,8 @@ func (r *calibrationRepo) CheckConditionBeforeSubmitCalibration(projectID string
 			return false, err
 		}
 
-		if !project.APlusExcess && countCalibrated.APlus > countRatingQuota.APlus {
-			return false, fmt.Errorf("Calibration Score Exceeds Rating Quota.")
-		}
-		if !project.AExcess && countCalibrated.A > countRatingQuota.A {
-			return false, fmt.Errorf("Calibration Score Exceeds Rating Quota.")
-		}
-		if !project.BPlusExcess && countCalibrated.BPlus > countRatingQuota.BPlus {
-			return false, fmt.Errorf("Calibration Score Exceeds Rating Quota.")
-		}
-		if !project.BExcess && countCalibrated.B > countRatingQuota.B {
-			return false, fmt.Errorf("Calibration Score Exceeds Rating Quota.")
-		}
-		if !project.CExcess && countCalibrated.C > countRatingQuota.C {
-			return false, fmt.Errorf("Calibration Score Exceeds Rating Quota.")
-		}
-		if !project.DExcess && countCalibrated.D > countRatingQuota.D {
-			return false, fmt.Errorf("Calibration Score Exceeds Rating Quota.")
+		if err := checkRatingQuota(project, countCalibrated, countRatingQuota); err != nil {
+			return false, err
 		}
 	}
 
@@ -1472,6 +1822,29 @@ func (r *calibrationRepo) CheckConditionBeforeSubmitCalibration(projectID string
 	return finalValue, nil
 }
 
+// checkRatingQuota fails when a rating is given more often than its quota and the project doesn't allow the excess
+func checkRatingQuota(project *model.Project, countCalibrated response.TotalCalibratedRating, countRatingQuota response.RatingQuota) error {
+	if !project.APlusExcess && countCalibrated.APlus > countRatingQuota.APlus {
+		return fmt.Errorf("Calibration Score Exceeds Rating Quota.")
+	}
+	if !project.AExcess && countCalibrated.A > countRatingQuota.A {
+		return fmt.Errorf("Calibration Score Exceeds Rating Quota.")
+	}
+	if !project.BPlusExcess && countCalibrated.BPlus > countRatingQuota.BPlus {
+		return fmt.Errorf("Calibration Score Exceeds Rating Quota.")
+	}
+	if !project.BExcess && countCalibrated.B > countRatingQuota.B {
+		return fmt.Errorf("Calibration Score Exceeds Rating Quota.")
+	}
+	if !project.CExcess && countCalibrated.C > countRatingQuota.C {
+		return fmt.Errorf("Calibration Score Exceeds Rating Quota.")
+	}
+	if !project.DExcess && countCalibrated.D > countRatingQuota.D {
+		return fmt.Errorf("Calibration Score Exceeds Rating Quota.")
+	}
+	return nil
+}
+
 func NewCalibrationRepo(db *gorm.DB) CalibrationRepo {
 	return &calibrationRepo{
 		db: db,
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"calibration-system.com/model"
+)
+
+func TestSettleApprovals(t *testing.T) {
+	const (
+		accepted = model.ApprovalAccepted
+		rejected = model.ApprovalRejected
+		waiting  = model.ApprovalWaiting
+		pending  = model.ApprovalPending
+		skipped  = model.ApprovalSkipped
+	)
+
+	tests := []struct {
+		name       string
+		chain      string
+		quorum     string
+		statuses   []string
+		wantStatus string
+		wantNext   string
+		wantLevels []string
+	}{
+		{
+			name: "all accepted", chain: model.SpmoChainParallel, quorum: model.SpmoQuorumAll,
+			statuses:   []string{accepted, accepted, accepted},
+			wantStatus: accepted, wantLevels: []string{accepted, accepted, accepted},
+		},
+		{
+			name: "all quorum still waiting", chain: model.SpmoChainParallel, quorum: model.SpmoQuorumAll,
+			statuses:   []string{accepted, waiting, accepted},
+			wantStatus: waiting, wantLevels: []string{accepted, waiting, accepted},
+		},
+		{
+			name: "all quorum rejected by one", chain: model.SpmoChainParallel, quorum: model.SpmoQuorumAll,
+			statuses:   []string{accepted, rejected, waiting},
+			wantStatus: rejected, wantLevels: []string{accepted, rejected, skipped},
+		},
+		{
+			name: "any quorum accepted by one", chain: model.SpmoChainParallel, quorum: model.SpmoQuorumAny,
+			statuses:   []string{waiting, accepted, waiting},
+			wantStatus: accepted, wantLevels: []string{skipped, accepted, skipped},
+		},
+		{
+			name: "any quorum rejected by one", chain: model.SpmoChainParallel, quorum: model.SpmoQuorumAny,
+			statuses:   []string{rejected, waiting},
+			wantStatus: waiting, wantLevels: []string{rejected, waiting},
+		},
+		{
+			name: "any quorum rejected by all", chain: model.SpmoChainParallel, quorum: model.SpmoQuorumAny,
+			statuses:   []string{rejected, rejected},
+			wantStatus: rejected, wantLevels: []string{rejected, rejected},
+		},
+		{
+			name: "sequential hands over to the next level", chain: model.SpmoChainSequential, quorum: model.SpmoQuorumAll,
+			statuses:   []string{accepted, pending, pending},
+			wantStatus: waiting, wantNext: "spmo-2", wantLevels: []string{accepted, waiting, pending},
+		},
+		{
+			name: "sequential waits for the current level", chain: model.SpmoChainSequential, quorum: model.SpmoQuorumAll,
+			statuses:   []string{accepted, waiting, pending},
+			wantStatus: waiting, wantLevels: []string{accepted, waiting, pending},
+		},
+		{
+			name: "sequential any quorum goes on after a rejection", chain: model.SpmoChainSequential, quorum: model.SpmoQuorumAny,
+			statuses:   []string{rejected, pending},
+			wantStatus: waiting, wantNext: "spmo-2", wantLevels: []string{rejected, waiting},
+		},
+		{
+			name: "sequential all quorum stops at a rejection", chain: model.SpmoChainSequential, quorum: model.SpmoQuorumAll,
+			statuses:   []string{rejected, pending, pending},
+			wantStatus: rejected, wantLevels: []string{rejected, skipped, skipped},
+		},
+		{
+			name: "sequential accepted by the last level", chain: model.SpmoChainSequential, quorum: model.SpmoQuorumAll,
+			statuses:   []string{accepted, accepted},
+			wantStatus: accepted, wantLevels: []string{accepted, accepted},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approvals := make([]model.CalibrationApproval, len(tt.statuses))
+			for i, status := range tt.statuses {
+				approvals[i] = model.CalibrationApproval{Level: i + 1, SpmoID: fmt.Sprintf("spmo-%d", i+1), Status: status}
+			}
+
+			status, next := settleApprovals(approvals, tt.chain, tt.quorum)
+			if status != tt.wantStatus || next != tt.wantNext {
+				t.Errorf("settleApprovals = %s, %q, want %s, %q", status, next, tt.wantStatus, tt.wantNext)
+			}
+
+			var levels []string
+			for _, approval := range approvals {
+				levels = append(levels, approval.Status)
+			}
+			if !reflect.DeepEqual(levels, tt.wantLevels) {
+				t.Errorf("levels after settleApprovals = %v, want %v", levels, tt.wantLevels)
+			}
+		})
+	}
+}
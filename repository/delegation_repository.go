@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+)
+
+// delegationLock keeps two overlapping delegations of one calibrator from being created at once
+const delegationLock = 7310005
+
+type DelegationRepo interface {
+	Create(payload *model.Delegation) error
+	Get(id string) (*model.Delegation, error)
+	List(calibratorID, delegateID string) ([]model.Delegation, error)
+	ListActive(calibratorIDs []string, delegateID, projectID string, at time.Time) ([]model.Delegation, error)
+	Revoke(id, revokedBy string, at time.Time) error
+	SaveAction(payload *model.DelegatedAction) error
+	ListActions(delegationID string) ([]model.DelegatedAction, error)
+}
+
+type delegationRepo struct {
+	db *gorm.DB
+}
+
+// Create saves a delegation unless the calibrator already delegates an overlapping range of the same project, a
+// delegation without project overlaps every project
+func (r *delegationRepo) Create(payload *model.Delegation) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", delegationLock).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query := tx.Model(&model.Delegation{}).
+		Where("calibrator_id = ? AND revoked_at IS NULL", payload.CalibratorID).
+		Where("start_date < ? AND end_date > ?", payload.EndDate, payload.StartDate)
+	if payload.ProjectID != nil {
+		query = query.Where("project_id IS NULL OR project_id = ?", *payload.ProjectID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return fmt.Errorf("The calibrator already has a delegation in this period")
+	}
+
+	if err := tx.Create(payload).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (r *delegationRepo) Get(id string) (*model.Delegation, error) {
+	var delegation model.Delegation
+	err := r.db.Preload("Calibrator").Preload("Delegate").First(&delegation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delegation, nil
+}
+
+// List returns the delegations given by calibratorID and received by delegateID, empty filters are left out
+func (r *delegationRepo) List(calibratorID, delegateID string) ([]model.Delegation, error) {
+	var delegations []model.Delegation
+	query := r.db.Preload("Calibrator").Preload("Delegate").Order("start_date DESC")
+	if calibratorID != "" {
+		query = query.Where("calibrator_id = ?", calibratorID)
+	}
+	if delegateID != "" {
+		query = query.Where("delegate_id = ?", delegateID)
+	}
+	if err := query.Find(&delegations).Error; err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+// ListActive returns the delegations running at the given time for any of the calibrators, or for every calibrator
+// when calibratorIDs is empty. A delegateID narrows it to one delegate and a projectID to delegations covering that
+// project.
+func (r *delegationRepo) ListActive(calibratorIDs []string, delegateID, projectID string, at time.Time) ([]model.Delegation, error) {
+	var delegations []model.Delegation
+	query := r.db.Preload("Delegate").
+		Where("revoked_at IS NULL AND start_date <= ? AND end_date > ?", at, at)
+	if len(calibratorIDs) > 0 {
+		query = query.Where("calibrator_id IN ?", calibratorIDs)
+	}
+	if delegateID != "" {
+		query = query.Where("delegate_id = ?", delegateID)
+	}
+	if projectID != "" {
+		query = query.Where("project_id IS NULL OR project_id = ?", projectID)
+	}
+	if err := query.Order("start_date").Find(&delegations).Error; err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+func (r *delegationRepo) Revoke(id, revokedBy string, at time.Time) error {
+	result := r.db.Model(&model.Delegation{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"revoked_at": at,
+			"revoked_by": revokedBy,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("Delegation is already revoked")
+	}
+	return nil
+}
+
+func (r *delegationRepo) SaveAction(payload *model.DelegatedAction) error {
+	return r.db.Create(payload).Error
+}
+
+func (r *delegationRepo) ListActions(delegationID string) ([]model.DelegatedAction, error) {
+	var actions []model.DelegatedAction
+	err := r.db.Where("delegation_id = ?", delegationID).Order("created_at DESC").Find(&actions).Error
+	if err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+func NewDelegationRepo(db *gorm.DB) DelegationRepo {
+	return &delegationRepo{
+		db: db,
+	}
+}
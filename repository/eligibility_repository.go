@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EligibilityRepo interface {
+	ListRules(projectID string) ([]model.EligibilityRule, error)
+	ReplaceRules(projectID string, rules []model.EligibilityRule) error
+	ListOverrides(projectID string) ([]model.EligibilityOverride, error)
+	SaveOverride(payload *model.EligibilityOverride) error
+	DeleteOverride(projectID, employeeID string) (bool, error)
+	ListResults(projectID string) ([]model.EligibilityResult, error)
+}
+
+type eligibilityRepo struct {
+	db *gorm.DB
+}
+
+func (r *eligibilityRepo) ListRules(projectID string) ([]model.EligibilityRule, error) {
+	var rules []model.EligibilityRule
+	err := r.db.Where("project_id = ?", projectID).Order("priority").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ReplaceRules swaps the rule set of a project in one transaction
+func (r *eligibilityRepo) ReplaceRules(projectID string, rules []model.EligibilityRule) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Unscoped().Where("project_id = ?", projectID).Delete(&model.EligibilityRule{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(rules) > 0 {
+		if err := tx.Create(&rules).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+func (r *eligibilityRepo) ListOverrides(projectID string) ([]model.EligibilityOverride, error) {
+	var overrides []model.EligibilityOverride
+	err := r.db.Where("project_id = ?", projectID).Order("created_at").Find(&overrides).Error
+	if err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+func (r *eligibilityRepo) SaveOverride(payload *model.EligibilityOverride) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "employee_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "reason", "created_by", "updated_at"}),
+	}).Create(payload).Error
+}
+
+func (r *eligibilityRepo) DeleteOverride(projectID, employeeID string) (bool, error) {
+	result := r.db.Where("project_id = ? AND employee_id = ?", projectID, employeeID).Delete(&model.EligibilityOverride{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *eligibilityRepo) ListResults(projectID string) ([]model.EligibilityResult, error) {
+	var results []model.EligibilityResult
+	err := r.db.Where("project_id = ?", projectID).Order("employee_nik").Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func NewEligibilityRepo(db *gorm.DB) EligibilityRepo {
+	return &eligibilityRepo{
+		db: db,
+	}
+}
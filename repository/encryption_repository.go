@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"calibration-system.com/model"
+	"calibration-system.com/utils"
+	"gorm.io/gorm"
+)
+
+type EncryptionRepo interface {
+	Reencrypt(table model.EncryptedTable, batchSize int) (int, error)
+}
+
+type encryptionRepo struct {
+	db *gorm.DB
+}
+
+// Reencrypt seals plaintext values and values under a retired key with the active key, and fills missing blind indexes.
+// Columns are read and written through Table so the serializer does not touch the raw values.
+func (r *encryptionRepo) Reencrypt(table model.EncryptedTable, batchSize int) (int, error) {
+	var indexColumns []string
+	for indexColumn := range table.BlindIndexes {
+		indexColumns = append(indexColumns, indexColumn)
+	}
+
+	columns := append(append(append([]string{}, table.Keys...), table.Columns...), indexColumns...)
+	updated := 0
+
+	for offset := 0; ; offset += batchSize {
+		rows, err := r.db.
+			Table(table.Name).
+			Select(columns).
+			Order(strings.Join(table.Keys, ", ")).
+			Limit(batchSize).
+			Offset(offset).
+			Rows()
+		if err != nil {
+			return updated, err
+		}
+
+		var batch []map[string]interface{}
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				return updated, err
+			}
+
+			row := map[string]interface{}{}
+			for i, column := range columns {
+				row[column] = values[i]
+			}
+			batch = append(batch, row)
+		}
+		rows.Close()
+
+		for _, row := range batch {
+			changes, err := reencryptRow(table, row)
+			if err != nil {
+				return updated, fmt.Errorf("%s: %v", table.Name, err)
+			}
+			if len(changes) == 0 {
+				continue
+			}
+
+			query := r.db.Table(table.Name)
+			for _, key := range table.Keys {
+				query = query.Where(fmt.Sprintf("%s = ?", key), row[key])
+			}
+			if err := query.Updates(changes).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+
+		if len(batch) < batchSize {
+			return updated, nil
+		}
+	}
+}
+
+func reencryptRow(table model.EncryptedTable, row map[string]interface{}) (map[string]interface{}, error) {
+	changes := map[string]interface{}{}
+	plainTexts := map[string]string{}
+
+	for _, column := range table.Columns {
+		switch v := row[column].(type) {
+		case nil:
+		case string:
+			plainText, err := utils.DecryptField(v)
+			if err != nil {
+				return nil, err
+			}
+			plainTexts[column] = string(plainText)
+			if utils.NeedsReencryption(v) {
+				cipherText, err := utils.EncryptField(plainText)
+				if err != nil {
+					return nil, err
+				}
+				changes[column] = cipherText
+			}
+		case []byte:
+			plainText, err := utils.DecryptField(string(v))
+			if err != nil {
+				return nil, err
+			}
+			plainTexts[column] = string(plainText)
+			if utils.NeedsReencryption(string(v)) {
+				cipherText, err := utils.EncryptField(plainText)
+				if err != nil {
+					return nil, err
+				}
+				changes[column] = []byte(cipherText)
+			}
+		default:
+			return nil, fmt.Errorf("column %s has type %T, run the migration first", column, v)
+		}
+	}
+
+	for indexColumn, column := range table.BlindIndexes {
+		blindIndex := utils.BlindIndex(plainTexts[column])
+		if current, _ := row[indexColumn].(string); current != blindIndex {
+			changes[indexColumn] = blindIndex
+		}
+	}
+	return changes, nil
+}
+
+func NewEncryptionRepo(db *gorm.DB) EncryptionRepo {
+	return &encryptionRepo{
+		db: db,
+	}
+}
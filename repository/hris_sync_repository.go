@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/model"
+	"calibration-system.com/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// hrisSyncColumns are the user columns an HRIS sync may overwrite, everything else on the user is left alone
+var hrisSyncColumns = []string{
+	"name", "email", "email_index", "join_date", "date_of_birth", "supervisor_nik", "business_unit_id",
+	"organization_unit", "division", "directorate", "department", "position", "grade", "hrbp", "phone_number",
+	"active", "left_at",
+}
+
+// hrisSyncLock serializes approvals so two syncs computed from the same snapshot cannot both be applied
+const hrisSyncLock = 7310001
+
+type HrisSyncRepo interface {
+	Save(payload *model.HrisSync) error
+	Get(id string) (*model.HrisSync, error)
+	List(limit int) ([]model.HrisSync, error)
+	Apply(payload *model.HrisSync, joiners, updates []model.User, leaverIDs []string) error
+	Reject(id, reviewedBy string) error
+}
+
+type hrisSyncRepo struct {
+	db *gorm.DB
+}
+
+func (r *hrisSyncRepo) Save(payload *model.HrisSync) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Omit("Changes").Create(payload).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	for i := range payload.Changes {
+		payload.Changes[i].SyncID = payload.ID
+	}
+	if len(payload.Changes) > 0 {
+		if err := tx.CreateInBatches(&payload.Changes, 500).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *hrisSyncRepo) Get(id string) (*model.HrisSync, error) {
+	var sync model.HrisSync
+	err := r.db.
+		Preload("Changes", func(db *gorm.DB) *gorm.DB {
+			return db.Order("type ASC, nik ASC")
+		}).
+		First(&sync, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sync, nil
+}
+
+func (r *hrisSyncRepo) List(limit int) ([]model.HrisSync, error) {
+	var syncs []model.HrisSync
+	err := r.db.
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&syncs).Error
+	if err != nil {
+		return nil, err
+	}
+	return syncs, nil
+}
+
+// Apply writes the approved changes in one transaction. Leavers are deactivated and their calibrations in active
+// projects flagged, supervisor names are refreshed for every employee the sync touched.
+func (r *hrisSyncRepo) Apply(payload *model.HrisSync, joiners, updates []model.User, leaverIDs []string) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", hrisSyncLock).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var newer int64
+	err := tx.Model(&model.HrisSync{}).
+		Where("status = ? AND applied_at > ?", model.HrisSyncApplied, payload.CreatedAt).
+		Count(&newer).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if newer > 0 {
+		tx.Rollback()
+		return fmt.Errorf("A newer sync was applied after this one was computed, submit the feed again")
+	}
+
+	now := time.Now()
+	result := tx.Model(&model.HrisSync{}).
+		Where("id = ? AND status = ?", payload.ID, model.HrisSyncPending).
+		Updates(map[string]interface{}{
+			"status":      model.HrisSyncApplied,
+			"reviewed_by": payload.ReviewedBy,
+			"reviewed_at": now,
+			"applied_at":  now,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("Sync has already been reviewed")
+	}
+
+	var niks []string
+	for i := range joiners {
+		joiners[i].EmailIndex = utils.BlindIndex(joiners[i].Email)
+		niks = append(niks, joiners[i].Nik)
+	}
+	if len(joiners) > 0 {
+		if err := tx.Omit(clause.Associations).CreateInBatches(&joiners, 100).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for i := range updates {
+		updates[i].EmailIndex = utils.BlindIndex(updates[i].Email)
+		niks = append(niks, updates[i].Nik)
+		if err := tx.Model(&updates[i]).Select(hrisSyncColumns).Updates(&updates[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	var flagged int64
+	batchSize := 1000
+	for start := 0; start < len(leaverIDs); start += batchSize {
+		end := start + batchSize
+		if end > len(leaverIDs) {
+			end = len(leaverIDs)
+		}
+
+		err := tx.Model(&model.User{}).
+			Where("id IN ?", leaverIDs[start:end]).
+			Updates(map[string]interface{}{"active": false, "left_at": now}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		result := tx.Model(&model.Calibration{}).
+			Where("employee_id IN ?", leaverIDs[start:end]).
+			// projects in close-out are frozen, their leavers stay in the final results
+			Where("project_id IN (?)", tx.Model(&model.Project{}).Select("id").Where("active = ? AND status = ?", true, model.ProjectOpen)).
+			Update("employee_left", true)
+		if result.Error != nil {
+			tx.Rollback()
+			return result.Error
+		}
+		flagged += result.RowsAffected
+	}
+
+	for start := 0; start < len(niks); start += batchSize {
+		end := start + batchSize
+		if end > len(niks) {
+			end = len(niks)
+		}
+
+		err := tx.Exec(`
+			UPDATE users AS u SET supervisor_names = s.name
+			FROM users AS s
+			WHERE s.nik = u.supervisor_nik AND s.deleted_at IS NULL AND u.deleted_at IS NULL
+			AND (u.nik IN ? OR u.supervisor_nik IN ?)`, niks[start:end], niks[start:end]).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Model(&model.HrisSync{}).
+		Where("id = ?", payload.ID).
+		Update("flagged_calibrations", flagged).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	payload.Status = model.HrisSyncApplied
+	payload.ReviewedAt = &now
+	payload.AppliedAt = &now
+	payload.FlaggedCalibrations = flagged
+	return nil
+}
+
+func (r *hrisSyncRepo) Reject(id, reviewedBy string) error {
+	result := r.db.Model(&model.HrisSync{}).
+		Where("id = ? AND status = ?", id, model.HrisSyncPending).
+		Updates(map[string]interface{}{
+			"status":      model.HrisSyncRejected,
+			"reviewed_by": reviewedBy,
+			"reviewed_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("Sync has already been reviewed")
+	}
+	return nil
+}
+
+func NewHrisSyncRepo(db *gorm.DB) HrisSyncRepo {
+	return &hrisSyncRepo{
+		db: db,
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/utils"
+	"gorm.io/gorm"
+)
+
+type ImpersonationLogRepo interface {
+	Save(payload *model.ImpersonationLog) error
+	PaginateList(actorID, subjectID string, pagination model.PaginationQuery) ([]model.ImpersonationLog, response.Paging, error)
+}
+
+type impersonationLogRepo struct {
+	db *gorm.DB
+}
+
+func (r *impersonationLogRepo) Save(payload *model.ImpersonationLog) error {
+	err := r.db.Create(&payload)
+	if err.Error != nil {
+		return err.Error
+	}
+	return nil
+}
+
+func (r *impersonationLogRepo) PaginateList(actorID, subjectID string, pagination model.PaginationQuery) ([]model.ImpersonationLog, response.Paging, error) {
+	var logs []model.ImpersonationLog
+	query := r.db.Model(&model.ImpersonationLog{})
+	if actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if subjectID != "" {
+		query = query.Where("subject_id = ?", subjectID)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, response.Paging{}, err
+	}
+
+	err = query.
+		Order("created_at DESC").
+		Limit(pagination.Take).Offset(pagination.Skip).
+		Find(&logs).Error
+	if err != nil {
+		return nil, response.Paging{}, err
+	}
+
+	return logs, utils.Paginate(pagination.Page, pagination.Take, int(count)), nil
+}
+
+func NewImpersonationLogRepo(db *gorm.DB) ImpersonationLogRepo {
+	return &impersonationLogRepo{
+		db: db,
+	}
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepo interface {
+	Save(payload *model.Job) error
+	Get(id string) (*model.Job, error)
+	GetResult(id string) (*model.Job, error)
+	ListByCreator(createdBy string, limit int) ([]model.Job, error)
+	Claim() (*model.Job, error)
+	UpdateProgress(id string, progress int) (bool, error)
+	Finish(payload *model.Job) error
+	Cancel(id string, expiresAt time.Time) error
+	RequeueStale(before time.Time) (int64, error)
+	PurgeExpired(now time.Time) (int64, error)
+}
+
+type jobRepo struct {
+	db *gorm.DB
+}
+
+func (r *jobRepo) Save(payload *model.Job) error {
+	err := r.db.Save(&payload)
+	if err.Error != nil {
+		return err.Error
+	}
+	return nil
+}
+
+func (r *jobRepo) Get(id string) (*model.Job, error) {
+	var job model.Job
+	err := r.db.Omit("input", "result_file").First(&job, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepo) GetResult(id string) (*model.Job, error) {
+	var job model.Job
+	err := r.db.Omit("input").First(&job, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepo) ListByCreator(createdBy string, limit int) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.
+		Omit("input", "result_file").
+		Where("created_by = ?", createdBy).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Claim moves the oldest queued job to running, SKIP LOCKED lets several workers poll the table without blocking each other
+func (r *jobRepo) Claim() (*model.Job, error) {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var job model.Job
+	result := tx.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ?", model.JobQueued).
+		Order("created_at ASC").
+		Limit(1).
+		Find(&job)
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil, nil
+	}
+
+	now := time.Now()
+	job.Status = model.JobRunning
+	job.StartedAt = &now
+	job.Attempts++
+	err := tx.Model(&model.Job{}).
+		Where("id = ?", job.ID).
+		Updates(map[string]interface{}{
+			"status":     job.Status,
+			"started_at": job.StartedAt,
+			"attempts":   job.Attempts,
+		}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &job, tx.Commit().Error
+}
+
+// UpdateProgress doubles as the worker heartbeat, it reports whether the job owner asked to cancel
+func (r *jobRepo) UpdateProgress(id string, progress int) (bool, error) {
+	var job model.Job
+	err := r.db.Model(&job).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "cancel_requested"}}}).
+		Where("id = ? AND status = ?", id, model.JobRunning).
+		Updates(map[string]interface{}{
+			"progress":   progress,
+			"updated_at": time.Now(),
+		}).Error
+	if err != nil {
+		return false, err
+	}
+	return job.CancelRequested, nil
+}
+
+func (r *jobRepo) Finish(payload *model.Job) error {
+	// map updates skip the json serializer, so the result is encoded here
+	result, err := json.Marshal(payload.Result)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Model(&model.Job{}).
+		Where("id = ?", payload.ID).
+		Updates(map[string]interface{}{
+			"status":      payload.Status,
+			"progress":    payload.Progress,
+			"message":     payload.Message,
+			"result":      string(result),
+			"result_name": payload.ResultName,
+			"result_file": payload.ResultFile,
+			"finished_at": payload.FinishedAt,
+			"expires_at":  payload.ExpiresAt,
+			// the upload is not needed once the job is done
+			"input": nil,
+		}).Error
+}
+
+// Cancel drops a queued job right away, a running job is flagged and stops at its next progress update
+func (r *jobRepo) Cancel(id string, expiresAt time.Time) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var job model.Job
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Omit("input", "result_file").
+		First(&job, "id = ?", id).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	updates := map[string]interface{}{}
+	switch job.Status {
+	case model.JobQueued:
+		now := time.Now()
+		updates["status"] = model.JobCancelled
+		updates["finished_at"] = now
+		updates["expires_at"] = expiresAt
+		updates["input"] = nil
+	case model.JobRunning:
+		updates["cancel_requested"] = true
+	default:
+		tx.Rollback()
+		return fmt.Errorf("Job is already %s", job.Status)
+	}
+
+	err = tx.Model(&model.Job{}).Where("id = ?", id).Updates(updates).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *jobRepo) RequeueStale(before time.Time) (int64, error) {
+	result := r.db.Model(&model.Job{}).
+		Where("status = ? AND updated_at < ?", model.JobRunning, before).
+		Updates(map[string]interface{}{
+			"status":   model.JobQueued,
+			"progress": 0,
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *jobRepo) PurgeExpired(now time.Time) (int64, error) {
+	// hard delete, a soft deleted row would keep the result file around
+	result := r.db.Unscoped().
+		Where("expires_at < ?", now).
+		Delete(&model.Job{})
+	return result.RowsAffected, result.Error
+}
+
+func NewJobRepo(db *gorm.DB) JobRepo {
+	return &jobRepo{
+		db: db,
+	}
+}
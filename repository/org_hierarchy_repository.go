@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+)
+
+// orgHierarchyLock keeps two rebuilds from interleaving their delete and insert
+const orgHierarchyLock = 7310002
+
+type OrgHierarchyRepo interface {
+	ListEmployees() ([]model.User, error)
+	Rebuild(paths []model.OrgPath) error
+	Subordinates(employeeID string, depth int) ([]model.OrgNode, error)
+	Managers(employeeID string) ([]model.OrgNode, error)
+	Span(employeeID string) (*model.OrgSpan, error)
+}
+
+type orgHierarchyRepo struct {
+	db *gorm.DB
+}
+
+// ListEmployees returns the active employees with only the columns the tree is built from
+func (r *orgHierarchyRepo) ListEmployees() ([]model.User, error) {
+	var users []model.User
+	err := r.db.
+		Model(&model.User{}).
+		Select("id, nik, name, supervisor_nik").
+		Where("active = ?", true).
+		Order("nik ASC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Rebuild replaces the whole closure table, readers keep seeing the previous tree until the commit
+func (r *orgHierarchyRepo) Rebuild(paths []model.OrgPath) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", orgHierarchyLock).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Exec("DELETE FROM org_paths").Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(paths) > 0 {
+		if err := tx.CreateInBatches(&paths, 1000).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// Subordinates lists the employees below employeeID, depth 1 is the direct reports and depth 0 the whole subtree
+func (r *orgHierarchyRepo) Subordinates(employeeID string, depth int) ([]model.OrgNode, error) {
+	var nodes []model.OrgNode
+	query := r.orgNodes("descendant_id").Where("op.ancestor_id = ? AND op.depth > 0", employeeID)
+	if depth > 0 {
+		query = query.Where("op.depth = ?", depth)
+	}
+	err := query.Order("op.depth ASC, u.nik ASC").Scan(&nodes).Error
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Managers is the reporting line above employeeID, nearest manager first
+func (r *orgHierarchyRepo) Managers(employeeID string) ([]model.OrgNode, error) {
+	var nodes []model.OrgNode
+	err := r.orgNodes("ancestor_id").
+		Where("op.descendant_id = ? AND op.depth > 0", employeeID).
+		Order("op.depth ASC").
+		Scan(&nodes).Error
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (r *orgHierarchyRepo) Span(employeeID string) (*model.OrgSpan, error) {
+	var spans []model.OrgSpan
+	err := r.db.
+		Table("users u").
+		Select(`u.id, u.nik, u.name,
+			COUNT(op.descendant_id) FILTER (WHERE op.depth = 1) AS direct,
+			COUNT(op.descendant_id) FILTER (WHERE op.depth > 0) AS total,
+			COALESCE(MAX(op.depth), 0) AS levels`).
+		Joins("LEFT JOIN org_paths op ON op.ancestor_id = u.id").
+		Where("u.id = ? AND u.deleted_at IS NULL", employeeID).
+		Group("u.id, u.nik, u.name").
+		Scan(&spans).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &spans[0], nil
+}
+
+// orgNodes joins the employee on the given side of the path
+func (r *orgHierarchyRepo) orgNodes(side string) *gorm.DB {
+	return r.db.
+		Table("org_paths op").
+		Select("u.id, u.nik, u.name, u.position, u.grade, u.business_unit_id, u.supervisor_nik, op.depth").
+		Joins("JOIN users u ON u.id = op." + side + " AND u.deleted_at IS NULL")
+}
+
+func NewOrgHierarchyRepo(db *gorm.DB) OrgHierarchyRepo {
+	return &orgHierarchyRepo{
+		db: db,
+	}
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+)
+
+// phaseLifecycleLock keeps instances from advancing or escalating the same send-back twice
+const phaseLifecycleLock = 7310003
+
+type PhaseLifecycleRepo interface {
+	GetProjectPhase(id string) (*model.ProjectPhase, error)
+	ListActivePhases() ([]model.ProjectPhase, error)
+	UpdateStatus(id, status string) error
+	SaveExtension(payload *model.PhaseExtension) error
+	ListExtensions(projectPhaseID string) ([]model.PhaseExtension, error)
+	LatestExtension(projectPhaseID, calibratorID string) (*model.PhaseExtension, error)
+	HasOpenSendBack(projectPhaseID, calibratorID string, now time.Time) (bool, error)
+	ResolveOverdueSendBacks(now time.Time) (int, []model.SendBackEscalation, error)
+}
+
+type phaseLifecycleRepo struct {
+	db *gorm.DB
+}
+
+func (r *phaseLifecycleRepo) GetProjectPhase(id string) (*model.ProjectPhase, error) {
+	var projectPhase model.ProjectPhase
+	err := r.db.
+		Preload("Phase").
+		Preload("Project").
+		First(&projectPhase, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &projectPhase, nil
+}
+
+func (r *phaseLifecycleRepo) ListActivePhases() ([]model.ProjectPhase, error) {
+	var projectPhases []model.ProjectPhase
+	err := r.db.
+		Preload("Phase").
+		Preload("Project").
+		Joins("JOIN projects ON projects.id = project_phases.project_id").
+		Where("projects.active = ? AND projects.deleted_at IS NULL", true).
+		Find(&projectPhases).Error
+	if err != nil {
+		return nil, err
+	}
+	return projectPhases, nil
+}
+
+func (r *phaseLifecycleRepo) UpdateStatus(id, status string) error {
+	return r.db.Model(&model.ProjectPhase{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func (r *phaseLifecycleRepo) SaveExtension(payload *model.PhaseExtension) error {
+	return r.db.Create(payload).Error
+}
+
+func (r *phaseLifecycleRepo) ListExtensions(projectPhaseID string) ([]model.PhaseExtension, error) {
+	var extensions []model.PhaseExtension
+	err := r.db.
+		Where("project_phase_id = ?", projectPhaseID).
+		Order("created_at DESC").
+		Find(&extensions).Error
+	if err != nil {
+		return nil, err
+	}
+	return extensions, nil
+}
+
+// LatestExtension is the furthest deadline granted to the calibrator, nil when there is none
+func (r *phaseLifecycleRepo) LatestExtension(projectPhaseID, calibratorID string) (*model.PhaseExtension, error) {
+	var extensions []model.PhaseExtension
+	err := r.db.
+		Where("project_phase_id = ? AND calibrator_id = ?", projectPhaseID, calibratorID).
+		Order("deadline DESC").
+		Limit(1).
+		Find(&extensions).Error
+	if err != nil || len(extensions) == 0 {
+		return nil, err
+	}
+	return &extensions[0], nil
+}
+
+// HasOpenSendBack tells whether calibrations were sent back to the calibrator in this phase and are still in time
+func (r *phaseLifecycleRepo) HasOpenSendBack(projectPhaseID, calibratorID string, now time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Calibration{}).
+		Where("project_phase_id = ? AND calibrator_id = ? AND status = ? AND send_back_deadline > ?", projectPhaseID, calibratorID, "Calibrate", now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ResolveOverdueSendBacks applies the project's policy to send-backs past their deadline. Auto-advance submits the
+// calibration as it stands to the next phase, escalate marks it so it is only reported once.
+func (r *phaseLifecycleRepo) ResolveOverdueSendBacks(now time.Time) (int, []model.SendBackEscalation, error) {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", phaseLifecycleLock).Error; err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+
+	var overdue []model.Calibration
+	err := tx.
+		Preload("Project").
+		Preload("ProjectPhase.Phase").
+		Joins("JOIN projects ON projects.id = calibrations.project_id").
+		Where("projects.active = ? AND calibrations.status = ?", true, "Calibrate").
+		Where("calibrations.send_back_deadline > ? AND calibrations.send_back_deadline < ?", time.Time{}, now).
+		Where("calibrations.send_back_escalated_at IS NULL").
+		Find(&overdue).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+
+	advanced := 0
+	var escalations []model.SendBackEscalation
+	for _, calibration := range overdue {
+		var next []model.Calibration
+		err := tx.
+			Joins("JOIN project_phases ON project_phases.id = calibrations.project_phase_id").
+			Joins("JOIN phases ON phases.id = project_phases.phase_id").
+			Where("calibrations.project_id = ? AND calibrations.employee_id = ? AND phases.order > ?",
+				calibration.ProjectID, calibration.EmployeeID, calibration.ProjectPhase.Phase.Order).
+			Order("phases.order ASC").
+			Find(&next).Error
+		if err != nil {
+			tx.Rollback()
+			return 0, nil, err
+		}
+		if len(next) == 0 {
+			// the last phase is never a send-back, nothing to advance to
+			continue
+		}
+
+		if calibration.Project.SendBackPolicy != model.SendBackAutoAdvance {
+			err := tx.Model(&model.Calibration{}).
+				Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", calibration.ProjectID, calibration.ProjectPhaseID, calibration.EmployeeID).
+				Update("send_back_escalated_at", now).Error
+			if err != nil {
+				tx.Rollback()
+				return 0, nil, err
+			}
+			escalations = append(escalations, model.SendBackEscalation{
+				ProjectID:      calibration.ProjectID,
+				ProjectPhaseID: calibration.ProjectPhaseID,
+				EmployeeID:     calibration.EmployeeID,
+				CalibratorID:   calibration.CalibratorID,
+				SpmoID:         calibration.SpmoID,
+				Deadline:       calibration.SendBackDeadline,
+			})
+			continue
+		}
+
+		err = tx.Model(&model.Calibration{}).
+			Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", calibration.ProjectID, calibration.ProjectPhaseID, calibration.EmployeeID).
+			Update("status", "Complete").Error
+		if err != nil {
+			tx.Rollback()
+			return 0, nil, err
+		}
+		for i, c := range next {
+			status := "Waiting"
+			if i == 0 {
+				status = "Calibrate"
+			}
+			err := tx.Model(&model.Calibration{}).
+				Where("project_id = ? AND project_phase_id = ? AND employee_id = ?", c.ProjectID, c.ProjectPhaseID, c.EmployeeID).
+				Updates(map[string]interface{}{
+					"calibration_score":  calibration.CalibrationScore,
+					"calibration_rating": calibration.CalibrationRating,
+					"status":             status,
+				}).Error
+			if err != nil {
+				tx.Rollback()
+				return 0, nil, err
+			}
+		}
+		advanced++
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, nil, err
+	}
+
+	if advanced > 0 {
+		go func() {
+			err := r.db.Exec("REFRESH MATERIALIZED VIEW materialized_user_view;").Error
+			if err != nil {
+				fmt.Printf("Failed to refresh materialized view: %v", err)
+			}
+		}()
+	}
+	return advanced, escalations, nil
+}
+
+func NewPhaseLifecycleRepo(db *gorm.DB) PhaseLifecycleRepo {
+	return &phaseLifecycleRepo{
+		db: db,
+	}
+}
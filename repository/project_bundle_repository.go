@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectBundleRepo interface {
+	Load(projectID string, full bool) (*model.Project, error)
+	CountByName(name string, year int) (int64, error)
+	Import(project *model.Project, bottomRemarks []model.BottomRemark, topRemarks []model.TopRemark) error
+}
+
+type projectBundleRepo struct {
+	db *gorm.DB
+}
+
+// Load reads everything a project archive carries, calibrations come with their remarks and evidence on a full export
+func (r *projectBundleRepo) Load(projectID string, full bool) (*model.Project, error) {
+	query := r.db.
+		Preload("ProjectPhases.Phase").
+		Preload("RemarkSettings", func(db *gorm.DB) *gorm.DB {
+			return db.Order("scoring_type ASC, level ASC")
+		}).
+		Preload("RatingQuotas", func(db *gorm.DB) *gorm.DB {
+			return db.Order("business_unit_id ASC")
+		}).
+		Preload("ScoreDistributions.GroupBusinessUnit")
+	if full {
+		query = query.
+			Preload("ActualScores").
+			Preload("Calibrations", func(db *gorm.DB) *gorm.DB {
+				return db.Order("employee_id ASC")
+			}).
+			Preload("Calibrations.BottomRemark").
+			Preload("Calibrations.TopRemarks")
+	}
+
+	var project model.Project
+	if err := query.First(&project, "id = ?", projectID).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *projectBundleRepo) CountByName(name string, year int) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Project{}).Where("name = ? AND year = ?", name, year).Count(&count).Error
+	return count, err
+}
+
+// Import creates the project and everything under it in one transaction, ids are assigned by the caller
+func (r *projectBundleRepo) Import(project *model.Project, bottomRemarks []model.BottomRemark, topRemarks []model.TopRemark) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Omit(clause.Associations).Create(project).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	batches := []interface{}{
+		&project.ProjectPhases,
+		&project.RemarkSettings,
+		&project.RatingQuotas,
+		&project.ScoreDistributions,
+		&project.ActualScores,
+		&project.Calibrations,
+		&bottomRemarks,
+		&topRemarks,
+	}
+	for _, batch := range batches {
+		if err := createBatch(tx, batch); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	go func() {
+		err := r.db.Exec("REFRESH MATERIALIZED VIEW materialized_user_view;").Error
+		if err != nil {
+			fmt.Printf("Failed to refresh materialized view: %v", err)
+		}
+	}()
+	return nil
+}
+
+// createBatch inserts a pointer to a slice without touching associations, empty slices are skipped
+func createBatch(tx *gorm.DB, batch interface{}) error {
+	if reflect.ValueOf(batch).Elem().Len() == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).CreateInBatches(batch, 100).Error
+}
+
+func NewProjectBundleRepo(db *gorm.DB) ProjectBundleRepo {
+	return &projectBundleRepo{
+		db: db,
+	}
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+)
+
+// projectCloseOutLock keeps two admins from publishing the same project at once
+const projectCloseOutLock = 7310004
+
+type ProjectCloseOutRepo interface {
+	GetProject(id string) (*model.Project, error)
+	ListRows(projectID string) ([]model.CloseOutRow, error)
+	ListBusinessUnits(ids []string) ([]model.BusinessUnit, error)
+	ListSignOffs(projectID string) ([]model.ProjectSignOff, error)
+	SaveSignOff(payload *model.ProjectSignOff) error
+	UpdateStatus(projectID, from, to string) error
+	Cancel(projectID string) error
+	Finalize(projectID string, results []model.FinalResult) error
+	ListFinalResults(projectID string) ([]model.FinalResult, error)
+	ListEmployeeResults(employeeID string) ([]model.FinalResult, error)
+}
+
+type projectCloseOutRepo struct {
+	db *gorm.DB
+}
+
+func (r *projectCloseOutRepo) GetProject(id string) (*model.Project, error) {
+	var project model.Project
+	err := r.db.First(&project, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListRows returns every employee of the project with the calibration of the last phase in their chain. Employees
+// who left are skipped, employees with an actual score but no calibration come back without a phase.
+func (r *projectCloseOutRepo) ListRows(projectID string) ([]model.CloseOutRow, error) {
+	var rows []model.CloseOutRow
+	err := r.db.Raw(`
+		SELECT u.id AS employee_id, u.nik AS employee_nik, u.name AS employee_name,
+			COALESCE(u.business_unit_id, '') AS business_unit_id,
+			COALESCE(c.project_phase_id, '') AS project_phase_id, COALESCE(c.phase_order, 0) AS phase_order,
+			COALESCE(c.review_spmo, false) AS review_spmo, COALESCE(c.status, '') AS status,
+			COALESCE(c.spmo_status, '') AS spmo_status, COALESCE(c.calibration_score, 0) AS calibration_score,
+			COALESCE(c.calibration_rating, '') AS calibration_rating, COALESCE(c.calibrator_id, '') AS calibrator_id,
+			COALESCE(a.actual_score, 0) AS actual_score, COALESCE(a.actual_rating, '') AS actual_rating,
+			COALESCE(a.ptt_score, 0) AS ptt_score, COALESCE(a.pat_score, 0) AS pat_score,
+			COALESCE(a.score360, 0) AS score360, COALESCE(a.y1_rating, '') AS y1_rating,
+			COALESCE(a.y2_rating, '') AS y2_rating
+		FROM (
+			SELECT employee_id FROM calibrations
+			WHERE project_id = @project AND deleted_at IS NULL AND employee_left = false
+			UNION
+			SELECT employee_id FROM actual_scores
+			WHERE project_id = @project AND deleted_at IS NULL AND employee_id NOT IN (
+				SELECT employee_id FROM calibrations WHERE project_id = @project AND employee_left = true
+			)
+		) employees
+		JOIN users u ON u.id = employees.employee_id AND u.deleted_at IS NULL
+		LEFT JOIN LATERAL (
+			SELECT c.project_phase_id, p.order AS phase_order, pp.review_spmo, c.status, c.spmo_status,
+				c.calibration_score, c.calibration_rating, c.calibrator_id
+			FROM calibrations c
+			JOIN project_phases pp ON pp.id = c.project_phase_id
+			JOIN phases p ON p.id = pp.phase_id
+			WHERE c.project_id = @project AND c.employee_id = u.id AND c.deleted_at IS NULL
+			ORDER BY p.order DESC
+			LIMIT 1
+		) c ON true
+		LEFT JOIN actual_scores a ON a.project_id = @project AND a.employee_id = u.id AND a.deleted_at IS NULL
+		ORDER BY u.nik`, map[string]interface{}{"project": projectID}).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *projectCloseOutRepo) ListBusinessUnits(ids []string) ([]model.BusinessUnit, error) {
+	var businessUnits []model.BusinessUnit
+	if len(ids) == 0 {
+		return businessUnits, nil
+	}
+	err := r.db.Where("id IN ?", ids).Order("name").Find(&businessUnits).Error
+	if err != nil {
+		return nil, err
+	}
+	return businessUnits, nil
+}
+
+func (r *projectCloseOutRepo) ListSignOffs(projectID string) ([]model.ProjectSignOff, error) {
+	var signOffs []model.ProjectSignOff
+	err := r.db.Where("project_id = ?", projectID).Find(&signOffs).Error
+	if err != nil {
+		return nil, err
+	}
+	return signOffs, nil
+}
+
+func (r *projectCloseOutRepo) SaveSignOff(payload *model.ProjectSignOff) error {
+	var count int64
+	err := r.db.Model(&model.ProjectSignOff{}).
+		Where("project_id = ? AND business_unit_id = ?", payload.ProjectID, payload.BusinessUnitID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("Business unit %s already signed off", payload.BusinessUnitID)
+	}
+	return r.db.Create(payload).Error
+}
+
+// UpdateStatus only moves a project that is still in the from status, a concurrent change makes it fail
+func (r *projectCloseOutRepo) UpdateStatus(projectID, from, to string) error {
+	result := r.db.Model(&model.Project{}).
+		Where("id = ? AND COALESCE(NULLIF(status, ''), ?) = ?", projectID, model.ProjectOpen, from).
+		Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("Project is not %s", from)
+	}
+	return nil
+}
+
+// Cancel reopens a project that is closing, sign-offs are dropped since the ratings can change again
+func (r *projectCloseOutRepo) Cancel(projectID string) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Model(&model.Project{}).
+		Where("id = ? AND status = ?", projectID, model.ProjectClosing).
+		Update("status", model.ProjectOpen)
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("Project is not %s", model.ProjectClosing)
+	}
+
+	if err := tx.Unscoped().Where("project_id = ?", projectID).Delete(&model.ProjectSignOff{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Finalize publishes the final results and closes the project in one transaction
+func (r *projectCloseOutRepo) Finalize(projectID string, results []model.FinalResult) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", projectCloseOutLock).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	now := time.Now()
+	result := tx.Model(&model.Project{}).
+		Where("id = ? AND status = ?", projectID, model.ProjectClosing).
+		Updates(map[string]interface{}{"status": model.ProjectClosed, "active": false, "closed_at": now})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("Project is not %s", model.ProjectClosing)
+	}
+
+	if err := tx.Where("project_id = ?", projectID).Delete(&model.FinalResult{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	for i := range results {
+		results[i].PublishedAt = now
+	}
+	if err := createBatch(tx, &results); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	go func() {
+		err := r.db.Exec("REFRESH MATERIALIZED VIEW materialized_user_view;").Error
+		if err != nil {
+			fmt.Printf("Failed to refresh materialized view: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (r *projectCloseOutRepo) ListFinalResults(projectID string) ([]model.FinalResult, error) {
+	var results []model.FinalResult
+	err := r.db.Where("project_id = ?", projectID).Order("employee_nik").Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListEmployeeResults returns the results of an employee in closed projects, newest year first
+func (r *projectCloseOutRepo) ListEmployeeResults(employeeID string) ([]model.FinalResult, error) {
+	var results []model.FinalResult
+	err := r.db.
+		Joins("Project").
+		Where("final_results.employee_id = ? AND \"Project\".status = ?", employeeID, model.ProjectClosed).
+		Order("\"Project\".year DESC").
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func NewProjectCloseOutRepo(db *gorm.DB) ProjectCloseOutRepo {
+	return &projectCloseOutRepo{
+		db: db,
+	}
+}
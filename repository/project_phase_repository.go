@@ -9,7 +9,7 @@ import (
 
 type ProjectPhaseRepo interface {
 	BaseRepository[model.ProjectPhase]
-	ListActive() ([]model.ProjectPhase, error)
+	ListActive(projectID string) ([]model.ProjectPhase, error)
 	ListActiveProjectPhaseHigherThanID(id string) ([]model.ProjectPhase, error)
 }
 
@@ -43,13 +43,13 @@ func (r *projectProjectPhaseRepo) List() ([]model.ProjectPhase, error) {
 	return projectProjectPhases, nil
 }
 
-func (r *projectProjectPhaseRepo) ListActive() ([]model.ProjectPhase, error) {
+func (r *projectProjectPhaseRepo) ListActive(projectID string) ([]model.ProjectPhase, error) {
 	var projectProjectPhases []model.ProjectPhase
 	err := r.db.
 		Table("project_phases pp").
 		Preload("Phase").
 		Preload("Project").
-		Joins("JOIN projects pr ON pr.id = pp.project_id AND pr.active = true").
+		Joins("JOIN projects pr ON pr.id = pp.project_id AND pr.id = ?", projectID).
 		Joins("JOIN phases p ON p.id = pp.phase_id").
 		Order("p.order ASC").
 		Find(&projectProjectPhases).
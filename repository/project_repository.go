@@ -10,6 +10,7 @@ import (
 	"calibration-system.com/model"
 	"calibration-system.com/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProjectRepo interface {
@@ -18,12 +19,11 @@ type ProjectRepo interface {
 	GetTotalRows(name string) (int, error)
 	ActivateByID(id string) error
 	NonactivateByID(id string) error
-	DeactivateAllExceptID(id string) error
 	GetProjectPhaseOrder(calibratorID, projectID string) (int, error)
 	GetProjectPhase(calibratorID, projectID string) (*model.ProjectPhase, error)
 	GetActiveProject() ([]model.Project, error)
 	GetActiveProjectPhase(projectID string) ([]model.ProjectPhase, error)
-	GetActiveManagerPhase() (model.ProjectPhase, error)
+	GetActiveManagerPhase(projectID string) (model.ProjectPhase, error)
 	GetScoreDistributionByCalibratorID(businessUnitID, projectID string) (*model.Project, error)
 	GetRatingQuotaByCalibratorID(businessUnitID, projectID string) (*model.Project, error)
 	GetNumberOneUserWhoCalibrator(calibratorID, businessUnit, projectID string, calibratorPhase int) ([]string, error)
@@ -32,7 +32,7 @@ type ProjectRepo interface {
 	GetCalibrationsByBusinessUnitPaginate(calibratorID, businessUnit, projectID string, phase int, pagination model.PaginationQuery) (response.UserCalibrationNew, response.Paging, error)
 	GetCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string, phase int) (response.UserCalibration, error)
 	GetCalibrationsByPrevCalibratorBusinessUnitPaginate(calibratorID, prevCalibrator, businessUnit, projectID string, phase int, pagination model.PaginationQuery) (response.UserCalibrationNew, response.Paging, error)
-	GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit string, phase int, exceptUsers []string) (response.UserCalibration, error)
+	GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string, phase int, exceptUsers []string) (response.UserCalibration, error)
 	GetNMinusOneCalibrationsByBusinessUnit(businessUnit string, phase int, calibratorID, projectID string) (response.UserCalibration, error)
 	GetNMinusOneCalibrationsByBusinessUnitPaginate(businessUnit string, phase int, calibratorID, projectID string, pagination model.PaginationQuery) (response.UserCalibrationNew, response.Paging, error)
 	GetCalibrationsByPrevCalibratorBusinessUnitAndRating(calibratorID, prevCalibrator, businessUnit, rating, projectID string, phase int, pagination model.PaginationQuery) (response.UserCalibrationNew, response.Paging, error)
@@ -49,9 +49,11 @@ type ProjectRepo interface {
 	GetTotalRowsCalibration(calibratorID, prevCalibrator, businessUnitName, types, projectID, rating string, pagination model.PaginationQuery) (int, error)
 	GetCalibratedRating(calibratorID, prevCalibrator, businessUnitName, types, projectID string) (*response.TotalCalibratedRating, error)
 	GetAverageScore(calibratorID, prevCalibrator, businessUnitName, types, projectID string) (float32, error)
-	GetAllDataNMinusOneCalibrationsByBusinessUnit(businessUnit string, phase int, calibratorID, projectID string) (response.UserCalibration, error)
-	GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string, phase int) (response.UserCalibration, error)
-	GetAllDataCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID string, phase int) (response.UserCalibration, error)
+	GetAllDataNMinusOneCalibrationsByBusinessUnit(businessUnit string, phase int, calibratorID, projectID string, pagination model.PaginationQuery) (response.UserCalibration, error)
+	GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string, phase int, pagination model.PaginationQuery) (response.UserCalibration, error)
+	GetAllDataCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID string, phase int, pagination model.PaginationQuery) (response.UserCalibration, error)
+	GetCloneSource(id string, chains bool) (*model.Project, error)
+	Clone(payload *model.Project) error
 }
 
 type projectRepo struct {
@@ -59,57 +61,74 @@ type projectRepo struct {
 }
 
 func (r *projectRepo) Save(payload *model.Project) error {
-	// err := r.db.Save(&payload)
-	// if err.Error != nil {
-	// 	return err.Error
-	// }
-
-	if payload.ID == "" {
-		var project model.Project
-		_ = r.db.
-			Preload("ScoreDistributions").
-			Preload("ScoreDistributions.GroupBusinessUnit").
-			Preload("RemarkSettings").
-			Order("created_at DESC").
-			First(&project).Error
-		// if err != nil {
-		// 	return err
-		// }
-
-		for _, scoreD := range project.ScoreDistributions {
-			payload.ScoreDistributions = append(payload.ScoreDistributions, model.ScoreDistribution{
-				ProjectID:           payload.ID,
-				GroupBusinessUnitID: scoreD.GroupBusinessUnitID,
-				APlusUpperLimit:     scoreD.APlusUpperLimit,
-				APlusLowerLimit:     scoreD.APlusLowerLimit,
-				AUpperLimit:         scoreD.AUpperLimit,
-				ALowerLimit:         scoreD.ALowerLimit,
-				BPlusUpperLimit:     scoreD.BPlusUpperLimit,
-				BPlusLowerLimit:     scoreD.BLowerLimit,
-				BUpperLimit:         scoreD.BUpperLimit,
-				BLowerLimit:         scoreD.BLowerLimit,
-				CUpperLimit:         scoreD.CUpperLimit,
-				CLowerLimit:         scoreD.CLowerLimit,
-				DUpperLimit:         scoreD.DUpperLimit,
-				DLowerLimit:         scoreD.DLowerLimit,
-			})
+	err := r.db.Save(&payload)
+	if err.Error != nil {
+		return err.Error
+	}
+
+	go func() {
+		err := r.db.Exec("REFRESH MATERIALIZED VIEW materialized_user_view;").Error
+		if err != nil {
+			fmt.Printf("Failed to refresh materialized view: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// GetCloneSource loads the configuration a clone can copy, calibrations only carry the chain columns and skip leavers
+func (r *projectRepo) GetCloneSource(id string, chains bool) (*model.Project, error) {
+	query := r.db.
+		Preload("ProjectPhases.Phase").
+		Preload("ScoreDistributions").
+		Preload("RemarkSettings").
+		Preload("RatingQuotas")
+	if chains {
+		query = query.Preload("Calibrations", func(db *gorm.DB) *gorm.DB {
+			return db.
+				Select("project_id", "project_phase_id", "employee_id", "calibrator_id", "spmo_id", "spmo2_id", "spmo3_id").
+				Where("employee_left = ?", false).
+				Order("employee_id ASC")
+		})
+	}
+
+	var project model.Project
+	if err := query.First(&project, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// Clone creates the project and the configuration copied into it in one transaction, ids are assigned by the caller
+func (r *projectRepo) Clone(payload *model.Project) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
 		}
+	}()
 
-		for _, remarks := range project.RemarkSettings {
-			payload.RemarkSettings = append(payload.RemarkSettings, model.RemarkSetting{
-				ProjectID:         payload.ID,
-				JustificationType: remarks.JustificationType,
-				ScoringType:       remarks.ScoringType,
-				Level:             remarks.Level,
-				From:              remarks.From,
-				To:                remarks.To,
-			})
+	if err := tx.Omit(clause.Associations).Create(payload).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	batches := []interface{}{
+		&payload.ProjectPhases,
+		&payload.RemarkSettings,
+		&payload.RatingQuotas,
+		&payload.ScoreDistributions,
+		&payload.Calibrations,
+	}
+	for _, batch := range batches {
+		if err := createBatch(tx, batch); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
 
-	err := r.db.Save(&payload)
-	if err.Error != nil {
-		return err.Error
+	if err := tx.Commit().Error; err != nil {
+		return err
 	}
 
 	go func() {
@@ -118,7 +137,6 @@ func (r *projectRepo) Save(payload *model.Project) error {
 			fmt.Printf("Failed to refresh materialized view: %v", err)
 		}
 	}()
-
 	return nil
 }
 
@@ -260,16 +278,6 @@ func (r *projectRepo) NonactivateByID(id string) error {
 	return nil
 }
 
-func (r *projectRepo) DeactivateAllExceptID(id string) error {
-	// Update all rows where 'id' is not equal to the specified 'id'
-	result := r.db.Model(&model.Project{}).Where("id <> ?", id).Update("active", false)
-	if result.Error != nil {
-		return result.Error
-	}
-
-	return nil
-}
-
 func (r *projectRepo) GetActiveProject() ([]model.Project, error) {
 	var project []model.Project
 	err := r.db.
@@ -300,7 +308,8 @@ func (r *projectRepo) GetActiveProjectPhase(projectID string) ([]model.ProjectPh
 	return project.ProjectPhases, nil
 }
 
-func (r *projectRepo) GetActiveManagerPhase() (model.ProjectPhase, error) {
+// GetActiveManagerPhase is the first phase of the project, it has to be active
+func (r *projectRepo) GetActiveManagerPhase(projectID string) (model.ProjectPhase, error) {
 	var project model.Project
 	err := r.db.
 		Preload("ProjectPhases", func(db *gorm.DB) *gorm.DB {
@@ -309,21 +318,31 @@ func (r *projectRepo) GetActiveManagerPhase() (model.ProjectPhase, error) {
 				Order("p.order ASC")
 		}).
 		Preload("ProjectPhases.Phase").
-		First(&project, "active = ?", true).
+		First(&project, "id = ? AND active = ?", projectID, true).
 		Error
 	if err != nil {
 		return model.ProjectPhase{}, err
 	}
+	if len(project.ProjectPhases) == 0 {
+		return model.ProjectPhase{}, fmt.Errorf("Project has no phases")
+	}
 	return project.ProjectPhases[0], nil
 }
 
+// GetProjectPhase is the earliest phase the calibrator calibrates in within the project
 func (r *projectRepo) GetProjectPhase(calibratorID, projectID string) (*model.ProjectPhase, error) {
+	if projectID == "" {
+		return nil, fmt.Errorf("projectID is required")
+	}
+
 	var calibration model.Calibration
 	err := r.db.
 		Preload("ProjectPhase").
 		Preload("ProjectPhase.Phase").
-		// Joins("JOIN projects ON projects.id = calibrations.project_id").
-		Where("project_id = ? AND calibrator_id = ? ", projectID, calibratorID).
+		Joins("JOIN project_phases pp ON pp.id = calibrations.project_phase_id").
+		Joins("JOIN phases p ON p.id = pp.phase_id").
+		Where("calibrations.project_id = ? AND calibrations.calibrator_id = ?", projectID, calibratorID).
+		Order("p.order ASC").
 		First(&calibration).Error
 	if err != nil {
 		return nil, err
@@ -333,17 +352,12 @@ func (r *projectRepo) GetProjectPhase(calibratorID, projectID string) (*model.Pr
 }
 
 func (r *projectRepo) GetProjectPhaseOrder(calibratorID, projectID string) (int, error) {
-	var calibration model.Calibration
-	err := r.db.
-		Preload("ProjectPhase").
-		Preload("ProjectPhase.Phase").
-		Where("project_id = ? AND calibrator_id = ? ", projectID, calibratorID).
-		First(&calibration).Error
+	projectPhase, err := r.GetProjectPhase(calibratorID, projectID)
 	if err != nil {
 		return -1, err
 	}
 
-	return calibration.ProjectPhase.Phase.Order, nil
+	return projectPhase.Phase.Order, nil
 }
 
 func (r *projectRepo) GetScoreDistributionByCalibratorID(businessUnitID, projectID string) (*model.Project, error) {
@@ -1168,7 +1182,7 @@ func (r *projectRepo) GetCalibrationsByBusinessUnitPaginate(calibratorID, busine
 	}, utils.Paginate(pagination.Page, pagination.Take, totalRows), nil
 }
 
-func (r *projectRepo) GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit string, phase int, exceptUsers []string) (response.UserCalibration, error) {
+func (r *projectRepo) GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string, phase int, exceptUsers []string) (response.UserCalibration, error) {
 	var users []model.User
 	var resultUsers []response.UserResponse
 
@@ -1178,12 +1192,12 @@ func (r *projectRepo) GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calib
 		Distinct().
 		Joins("JOIN business_units b ON u.business_unit_id = b.id").
 		Joins("JOIN calibrations c1 ON c1.employee_id = u.id AND c1.deleted_at IS NULL").
-		Joins("JOIN projects pr ON pr.id = c1.project_id AND pr.active = true").
+		Joins("JOIN projects pr ON pr.id = c1.project_id AND pr.id = ?", projectID).
 		Joins("JOIN project_phases pp ON pp.id = c1.project_phase_id").
 		Joins("JOIN phases p ON p.id = pp.phase_id").
 		Joins("JOIN users u2 ON c1.calibrator_id = u2.id").
 		Joins("JOIN calibrations c2 ON c2.employee_id = u.id").
-		Joins("JOIN projects pr2 ON pr2.id = c2.project_id AND pr2.active = true").
+		Joins("JOIN projects pr2 ON pr2.id = c2.project_id AND pr2.id = ?", projectID).
 		Joins("JOIN project_phases pp2 ON pp2.id = c2.project_phase_id").
 		Joins("JOIN phases p2 ON p2.id = pp2.phase_id").
 		Joins("JOIN users u3 ON c2.calibrator_id = u3.id").
@@ -1202,14 +1216,14 @@ func (r *projectRepo) GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calib
 		Preload("ActualScores", func(db *gorm.DB) *gorm.DB {
 			return db.
 				Joins("JOIN projects proj1 ON actual_scores.project_id = proj1.id").
-				Where("proj1.active = ?", true)
+				Where("proj1.id = ?", projectID)
 		}).
 		Preload("CalibrationScores", func(db *gorm.DB) *gorm.DB {
 			return db.
 				Joins("JOIN projects proj2 ON calibrations.project_id = proj2.id").
 				Joins("JOIN project_phases pp ON pp.id = calibrations.project_phase_id").
 				Joins("JOIN phases p ON p.id = pp.phase_id ").
-				Where("proj2.active = ? AND p.order <= ?", true, phase).
+				Where("proj2.id = ? AND p.order <= ?", projectID, phase).
 				Order("p.order")
 		}).
 		Preload("CalibrationScores.Calibrator").
@@ -1220,13 +1234,13 @@ func (r *projectRepo) GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calib
 		Preload("BusinessUnit").
 		Select("u.*, COUNT(u.id) AS calibration_count").
 		Joins("INNER JOIN calibrations c1 ON c1.employee_id = u.id AND c1.deleted_at IS NULL").
-		Joins("INNER JOIN projects pr ON pr.id = c1.project_id AND pr.active = true").
+		Joins("INNER JOIN projects pr ON pr.id = c1.project_id AND pr.id = ?", projectID).
 		Joins("INNER JOIN project_phases pp ON pp.id = c1.project_phase_id").
 		Joins("INNER JOIN phases p ON p.id = pp.phase_id").
 		Joins("INNER JOIN business_units b ON u.business_unit_id = b.id").
 		Joins("INNER JOIN users u2 ON c1.calibrator_id = u2.id").
 		Joins("INNER JOIN calibrations c2 ON c2.employee_id = u.id").
-		Joins("INNER JOIN projects pr2 ON pr2.id = c2.project_id AND pr2.active = true").
+		Joins("INNER JOIN projects pr2 ON pr2.id = c2.project_id AND pr2.id = ?", projectID).
 		Joins("INNER JOIN project_phases pp2 ON pp2.id = c2.project_phase_id").
 		Joins("INNER JOIN phases p2 ON p2.id = pp2.phase_id").
 		Joins("INNER JOIN users u3 ON c2.calibrator_id = u3.id").
@@ -3395,7 +3409,7 @@ func getOrder(pagination model.PaginationQuery) string {
 	return orderBy
 }
 
-func (r *projectRepo) GetAllDataCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID string, phase int) (response.UserCalibration, error) {
+func (r *projectRepo) GetAllDataCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID string, phase int, pagination model.PaginationQuery) (response.UserCalibration, error) {
 	var users []response.UserResponse
 
 	err := r.db.Debug().
@@ -3409,6 +3423,9 @@ func (r *projectRepo) GetAllDataCalibrationsByBusinessUnit(calibratorID, busines
 				Where("calibrations.project_id = ? AND p.order <= ?", projectID, phase).
 				Order("p.order")
 		}).
+		Preload("CalibrationScores.ProjectPhase.Phase").
+		Preload("CalibrationScores.TopRemarks").
+		Preload("CalibrationScores.BottomRemark").
 		Preload("BusinessUnit").
 		Table("materialized_user_view m").
 		Joins("JOIN users u2 on u2.id = m.id").
@@ -3416,26 +3433,13 @@ func (r *projectRepo) GetAllDataCalibrationsByBusinessUnit(calibratorID, busines
 		Where("(m.phase_order = ? AND m.calibrator_id = ?) AND m.project_id = ? AND m.business_unit_id = ?", phase, calibratorID, projectID, businessUnit).
 		Group("u2.id").
 		Order("calibration_count ASC").
+		Order("u2.id").
+		Scopes(reportPage(pagination)).
 		Find(&users).Error
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
 
-	for i := range users {
-		// r.db.Model(&users[i]).Association("CalibrationScores").Find(&users[i].CalibrationScores)
-		for j := range users[i].CalibrationScores {
-			r.db.Model(&users[i].CalibrationScores[j]).Association("ProjectPhase").Find(&users[i].CalibrationScores[j].ProjectPhase)
-			r.db.Model(&users[i].CalibrationScores[j].ProjectPhase).Association("Phase").Find(&users[i].CalibrationScores[j].ProjectPhase.Phase)
-			if (&users[i]).CalibrationScores[j].JustificationType == "top" {
-				r.db.Model(&users[i].CalibrationScores[j]).Association("TopRemarks").Find(&users[i].CalibrationScores[j].TopRemarks)
-
-			}
-
-			if (&users[i]).CalibrationScores[j].JustificationType == "bottom" {
-				r.db.Model(&users[i].CalibrationScores[j]).Association("BottomRemark").Find(&users[i].CalibrationScores[j].BottomRemark)
-			}
-		}
-	}
 	return response.UserCalibration{
 		NPlusOneManager:     false,
 		SendToManager:       false,
@@ -3444,7 +3448,7 @@ func (r *projectRepo) GetAllDataCalibrationsByBusinessUnit(calibratorID, busines
 	}, nil
 }
 
-func (r *projectRepo) GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string, phase int) (response.UserCalibration, error) {
+func (r *projectRepo) GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string, phase int, pagination model.PaginationQuery) (response.UserCalibration, error) {
 	// var users []model.UserCalibration
 	var resultUsers []response.UserResponse
 
@@ -3477,32 +3481,22 @@ func (r *projectRepo) GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibra
 				Where("calibrations.project_id = ? AND p.order <= ?", projectID, phase).
 				Order("p.order")
 		}).
+		Preload("CalibrationScores.ProjectPhase.Phase").
+		Preload("CalibrationScores.TopRemarks").
+		Preload("CalibrationScores.BottomRemark").
 		Preload("BusinessUnit").
 		Table("materialized_user_view m").
 		Select("m.*, sq.calibration_count").
 		Joins("LEFT JOIN (?) as sq ON sq.employee_id = m.id", subQueryCount).
 		Where("m.phase_order = ? AND m.id IN (?) AND m.project_id = ? AND m.deleted_at is NULL", phase, subqueryResults, projectID).
 		Order("calibration_count ASC").
+		Order("m.id").
+		Scopes(reportPage(pagination)).
 		Find(&resultUsers).Error
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
 
-	for i := range resultUsers {
-		// r.db.Model(&resultUsers[i]).Association("CalibrationScores").Find(&resultUsers[i].CalibrationScores)
-		for j := range resultUsers[i].CalibrationScores {
-			r.db.Model(&resultUsers[i].CalibrationScores[j]).Association("ProjectPhase").Find(&resultUsers[i].CalibrationScores[j].ProjectPhase)
-			r.db.Model(&resultUsers[i].CalibrationScores[j].ProjectPhase).Association("Phase").Find(&resultUsers[i].CalibrationScores[j].ProjectPhase.Phase)
-			if (&resultUsers[i]).CalibrationScores[j].JustificationType == "top" {
-				r.db.Model(&resultUsers[i].CalibrationScores[j]).Association("TopRemarks").Find(&resultUsers[i].CalibrationScores[j].TopRemarks)
-
-			}
-			if (&resultUsers[i]).CalibrationScores[j].JustificationType == "bottom" {
-				r.db.Model(&resultUsers[i].CalibrationScores[j]).Association("BottomRemark").Find(&resultUsers[i].CalibrationScores[j].BottomRemark)
-			}
-		}
-	}
-
 	NPlusOneManagerFlag := false
 	SendToManagerFlag := false
 	SendBackFlag := false
@@ -3515,7 +3509,7 @@ func (r *projectRepo) GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibra
 	}, nil
 }
 
-func (r *projectRepo) GetAllDataNMinusOneCalibrationsByBusinessUnit(businessUnit string, phase int, calibratorID, projectID string) (response.UserCalibration, error) {
+func (r *projectRepo) GetAllDataNMinusOneCalibrationsByBusinessUnit(businessUnit string, phase int, calibratorID, projectID string, pagination model.PaginationQuery) (response.UserCalibration, error) {
 	var users []response.UserResponse
 
 	// prev calibrator
@@ -3569,33 +3563,22 @@ func (r *projectRepo) GetAllDataNMinusOneCalibrationsByBusinessUnit(businessUnit
 				Where("calibrations.project_id = ? AND p.order <= ?", projectID, phase).
 				Order("p.order")
 		}).
+		Preload("CalibrationScores.ProjectPhase.Phase").
+		Preload("CalibrationScores.TopRemarks").
+		Preload("CalibrationScores.BottomRemark").
 		Preload("BusinessUnit").
 		Table("materialized_user_view m").
 		Select("m.*, sq.calibration_count").
 		Joins("LEFT JOIN (?) as sq ON sq.employee_id = m.id", subQuery).
 		Where("m.calibrator_id = ? AND m.project_id = ? and m.phase_order = ? AND m.business_unit_id = ? AND m.id NOT IN (?) AND m.id NOT IN (?) AND m.deleted_at is NULL",
 			calibratorID, projectID, phase, businessUnit, queryPrevCalibrator, subqueryResults).
+		Order("m.id").
+		Scopes(reportPage(pagination)).
 		Find(&users).Error
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
 
-	for i := range users {
-		// r.db.Model(&users[i]).Association("CalibrationScores").Find(&users[i].CalibrationScores)
-		for j := range users[i].CalibrationScores {
-			r.db.Model(&users[i].CalibrationScores[j]).Association("ProjectPhase").Find(&users[i].CalibrationScores[j].ProjectPhase)
-			r.db.Model(&users[i].CalibrationScores[j].ProjectPhase).Association("Phase").Find(&users[i].CalibrationScores[j].ProjectPhase.Phase)
-			if (&users[i]).CalibrationScores[j].JustificationType == "top" {
-				r.db.Model(&users[i].CalibrationScores[j]).Association("TopRemarks").Find(&users[i].CalibrationScores[j].TopRemarks)
-
-			}
-
-			if (&users[i]).CalibrationScores[j].JustificationType == "bottom" {
-				r.db.Model(&users[i].CalibrationScores[j]).Association("BottomRemark").Find(&users[i].CalibrationScores[j].BottomRemark)
-			}
-		}
-	}
-
 	return response.UserCalibration{
 		NPlusOneManager:     false,
 		SendToManager:       false,
@@ -3609,3 +3592,13 @@ func NewProjectRepo(db *gorm.DB) ProjectRepo {
 		db: db,
 	}
 }
+
+// reportPage limits a report query to one page, a zero Take loads every row
+func reportPage(pagination model.PaginationQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if pagination.Take <= 0 {
+			return db
+		}
+		return db.Limit(pagination.Take).Offset(pagination.Skip)
+	}
+}
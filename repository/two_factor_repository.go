@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"time"
+
+	"calibration-system.com/model"
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepo interface {
+	Save(payload *model.TwoFactor) error
+	GetByUserID(userID string) (*model.TwoFactor, error)
+	ReplaceRecoveryCodes(twoFactorID string, codes []model.TwoFactorRecoveryCode) error
+	MarkRecoveryCodeUsed(id string) error
+	DeleteByUserID(userID string) error
+}
+
+type twoFactorRepo struct {
+	db *gorm.DB
+}
+
+func (r *twoFactorRepo) Save(payload *model.TwoFactor) error {
+	err := r.db.Omit("RecoveryCodes").Save(&payload)
+	if err.Error != nil {
+		return err.Error
+	}
+	return nil
+}
+
+func (r *twoFactorRepo) GetByUserID(userID string) (*model.TwoFactor, error) {
+	var twoFactor model.TwoFactor
+	err := r.db.
+		Preload("RecoveryCodes", "used_at IS NULL").
+		First(&twoFactor, "user_id = ?", userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r *twoFactorRepo) ReplaceRecoveryCodes(twoFactorID string, codes []model.TwoFactorRecoveryCode) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Unscoped().Where("two_factor_id = ?", twoFactorID).Delete(&model.TwoFactorRecoveryCode{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for i := range codes {
+		codes[i].TwoFactorID = twoFactorID
+	}
+
+	if len(codes) > 0 {
+		err = tx.Create(&codes).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *twoFactorRepo) MarkRecoveryCodeUsed(id string) error {
+	return r.db.Model(&model.TwoFactorRecoveryCode{}).
+		Where("id = ?", id).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *twoFactorRepo) DeleteByUserID(userID string) error {
+	var twoFactor model.TwoFactor
+	err := r.db.First(&twoFactor, "user_id = ?", userID).Error
+	if err != nil {
+		return err
+	}
+
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = tx.Unscoped().Where("two_factor_id = ?", twoFactor.ID).Delete(&model.TwoFactorRecoveryCode{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Unscoped().Delete(&twoFactor).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func NewTwoFactorRepo(db *gorm.DB) TwoFactorRepo {
+	return &twoFactorRepo{
+		db: db,
+	}
+}
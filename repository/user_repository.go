@@ -16,6 +16,8 @@ type UserRepo interface {
 	Delete(id string) error
 	SearchByEmail(email string) (*model.User, error)
 	SearchByNik(nik string) (*model.User, error)
+	SearchByNiks(niks []string) ([]model.User, error)
+	SearchAll() ([]model.User, error)
 	SearchByGenerateToken(generateToken string) (*model.User, error)
 	Update(payload *model.User) error
 	Bulksave(payload *[]model.User) error
@@ -39,6 +41,36 @@ func (u *userRepo) SearchByNik(nik string) (*model.User, error) {
 	return &user, nil
 }
 
+func (u *userRepo) SearchByNiks(niks []string) ([]model.User, error) {
+	var users []model.User
+	// keep the IN list well under the postgres bind parameter limit
+	batchSize := 1000
+	for start := 0; start < len(niks); start += batchSize {
+		end := start + batchSize
+		if end > len(niks) {
+			end = len(niks)
+		}
+
+		var batch []model.User
+		err := u.db.Preload("Roles").Where("nik IN ?", niks[start:end]).Find(&batch).Error
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, batch...)
+	}
+	return users, nil
+}
+
+// SearchAll loads every employee including inactive ones, used to diff a full HRIS feed
+func (u *userRepo) SearchAll() ([]model.User, error) {
+	var users []model.User
+	err := u.db.Preload("Roles").Order("nik ASC").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (u *userRepo) SearchByGenerateToken(generateToken string) (*model.User, error) {
 	var user model.User
 	err := u.db.Preload("Roles").First(&user, "access_token_generate = ?", generateToken).Error
@@ -50,7 +82,8 @@ func (u *userRepo) SearchByGenerateToken(generateToken string) (*model.User, err
 
 func (u *userRepo) SearchByEmail(email string) (*model.User, error) {
 	var user model.User
-	err := u.db.Preload("Roles").First(&user, "email = ?", email).Error
+	// rows written before the email was encrypted are matched on the plaintext column until re-encrypted
+	err := u.db.Preload("Roles").First(&user, "email_index = ? OR email = ?", utils.BlindIndex(email), email).Error
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +91,7 @@ func (u *userRepo) SearchByEmail(email string) (*model.User, error) {
 }
 
 func (u *userRepo) Save(payload *model.User) error {
+	payload.EmailIndex = utils.BlindIndex(payload.Email)
 	err := u.db.Save(&payload)
 	if err.Error != nil {
 		return fmt.Errorf(err.Error.Error() + payload.ID)
@@ -66,6 +100,10 @@ func (u *userRepo) Save(payload *model.User) error {
 }
 
 func (u *userRepo) Bulksave(payload *[]model.User) error {
+	for i := range *payload {
+		(*payload)[i].EmailIndex = utils.BlindIndex((*payload)[i].Email)
+	}
+
 	tx := u.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -268,6 +306,7 @@ func (u *userRepo) Delete(id string) error {
 }
 
 func (u *userRepo) Update(payload *model.User) error {
+	payload.EmailIndex = utils.BlindIndex(payload.Email)
 	payloadRole := payload.Roles
 	err := u.db.Model(&payload).Association("Roles").Clear()
 	if err != nil {
@@ -353,14 +392,14 @@ func (u *userRepo) PaginateByProjectId(pagination model.PaginationQuery, project
 			Preload("ActualScores", func(db *gorm.DB) *gorm.DB {
 				return db.
 					Joins("JOIN projects proj2 ON actual_scores.project_id = proj2.id").
-					Where("proj2.active = ?", true)
+					Where("proj2.id = ?", projectId)
 			}).
 			Preload("CalibrationScores", func(db *gorm.DB) *gorm.DB {
 				return db.
 					Joins("JOIN projects proj2 ON calibrations.project_id = proj2.id").
 					Joins("JOIN project_phases pp ON pp.id = calibrations.project_phase_id").
 					Joins("JOIN phases p ON p.id = pp.phase_id ").
-					Where("proj2.active = ?", true).
+					Where("proj2.id = ?", projectId).
 					Order("p.order ASC")
 			}).
 			Preload("CalibrationScores.Calibrator").
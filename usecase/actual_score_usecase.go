@@ -1,21 +1,22 @@
 package usecase
 
 import (
+	"context"
 	"fmt"
-	"mime/multipart"
-	"strconv"
+	"io"
 
 	"calibration-system.com/model"
 	"calibration-system.com/repository"
-	"github.com/360EntSecGroup-Skylar/excelize"
+	"calibration-system.com/utils/importer"
 )
 
 type ActualScoreUsecase interface {
 	FindAll() ([]model.ActualScore, error)
 	FindById(projectId, employeeId string) (*model.ActualScoreTable, error)
+	FindByProjectId(projectId string) ([]model.ActualScore, error)
 	SaveData(payload *model.ActualScore) error
 	DeleteData(projectId, employeeId string) error
-	BulkInsert(file *multipart.FileHeader, projectId string) ([]string, error)
+	BulkInsert(ctx context.Context, file io.Reader, projectId string, dryRun bool) (*importer.Report, error)
 }
 
 type actualScoreUsecase struct {
@@ -32,12 +33,19 @@ func (r *actualScoreUsecase) FindById(projectId, employeeId string) (*model.Actu
 	return r.repo.Get(projectId, employeeId)
 }
 
+func (r *actualScoreUsecase) FindByProjectId(projectId string) ([]model.ActualScore, error) {
+	return r.repo.ListByProject(projectId)
+}
+
 func (r *actualScoreUsecase) SaveData(payload *model.ActualScore) error {
 	if payload.ProjectID != "" {
 		_, err := r.project.FindById(payload.ProjectID)
 		if err != nil {
 			return fmt.Errorf("Project Not Found")
 		}
+		if err := r.project.CheckEditable(payload.ProjectID); err != nil {
+			return err
+		}
 	}
 
 	if payload.EmployeeID != "" {
@@ -50,115 +58,95 @@ func (r *actualScoreUsecase) SaveData(payload *model.ActualScore) error {
 }
 
 func (r *actualScoreUsecase) DeleteData(projectId, employeeId string) error {
+	if err := r.project.CheckEditable(projectId); err != nil {
+		return err
+	}
 	return r.repo.Delete(projectId, employeeId)
 }
 
-func (r *actualScoreUsecase) BulkInsert(file *multipart.FileHeader, projectId string) ([]string, error) {
-	logs := map[string]string{}
-	var actualScores []model.ActualScore
-
+func (r *actualScoreUsecase) BulkInsert(ctx context.Context, file io.Reader, projectId string, dryRun bool) (*importer.Report, error) {
 	_, err := r.project.FindById(projectId)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.project.CheckEditable(projectId); err != nil {
+		return nil, err
+	}
 
-	// Membuka file Excel yang diunggah
-	excelFile, err := file.Open()
+	report, rows, err := importer.Read(file, actualScoreImportSchema, dryRun)
 	if err != nil {
 		return nil, err
 	}
-	defer excelFile.Close()
 
-	xlsFile, err := excelize.OpenReader(excelFile)
+	employees, err := r.employee.FindByNiks(columnValues(rows, "Employee NIK"))
 	if err != nil {
 		return nil, err
 	}
 
-	sheetName := xlsFile.GetSheetName(3)
-	rows := xlsFile.GetRows(sheetName)
+	existing := map[string]bool{}
+	scores, err := r.repo.ListByProject(projectId)
+	if err != nil {
+		return nil, err
+	}
+	for _, score := range scores {
+		existing[score.EmployeeID] = true
+	}
 
+	var actualScores []model.ActualScore
+	niks := map[string]int{}
 	for i, row := range rows {
-		passed := true
-		if i == 0 {
-			continue
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
+			return nil, err
 		}
 
-		nik := row[0]
-		y1rating := row[1]
-		y2rating := row[2]
-		actualRating := row[7]
-
-		employee, err := r.employee.FindByNik(nik)
-		if err != nil {
-			if _, ok := logs[nik]; !ok {
-				logs[nik] = nik
-			}
-			passed = false
-		}
-
-		ptt, err := strconv.ParseFloat(row[3], 64)
-		if err != nil {
-			if _, ok := logs[nik]; !ok {
-				logs[nik] = nik
-			}
-			passed = false
-		}
-
-		pat, err := strconv.ParseFloat(row[4], 64)
-		if err != nil {
-			if _, ok := logs[nik]; !ok {
-				logs[nik] = nik
-			}
-			passed = false
+		nik := row.Get("Employee NIK")
+		if previous, ok := niks[nik]; ok {
+			report.AddError(row.Number, "Employee NIK", nik, fmt.Sprintf("Duplicate of row %d", previous))
+			continue
 		}
+		niks[nik] = row.Number
 
-		score360, err := strconv.ParseFloat(row[5], 64)
-		if err != nil {
-			if _, ok := logs[nik]; !ok {
-				logs[nik] = nik
-			}
-			passed = false
+		employee := employees[nik]
+		if employee == nil {
+			report.AddError(row.Number, "Employee NIK", nik, "Employee not found")
+			continue
 		}
 
-		actualScore, err := strconv.ParseFloat(row[6], 64)
-		if err != nil {
-			if _, ok := logs[nik]; !ok {
-				logs[nik] = nik
-			}
-			passed = false
+		action := importer.ActionCreate
+		if existing[employee.ID] {
+			action = importer.ActionUpdate
 		}
 
-		if passed {
-			actualScore := model.ActualScore{
-				ProjectID:    projectId,
-				EmployeeID:   employee.ID,
-				ActualScore:  actualScore,
-				ActualRating: actualRating,
-				Y1Rating:     y1rating,
-				Y2Rating:     y2rating,
-				PTTScore:     ptt,
-				PATScore:     pat,
-				Score360:     score360,
-			}
-			actualScores = append(actualScores, actualScore)
-		}
+		actualScores = append(actualScores, model.ActualScore{
+			ProjectID:    projectId,
+			EmployeeID:   employee.ID,
+			ActualScore:  row.Float("Actual Score"),
+			ActualRating: row.Get("Actual Rating"),
+			Y1Rating:     row.Get("Y1 Rating"),
+			Y2Rating:     row.Get("Y2 Rating"),
+			PTTScore:     row.Float("PTT Score"),
+			PATScore:     row.Float("PAT Score"),
+			Score360:     row.Float("360 Score"),
+		})
+		report.AddRow(row.Number, nik, action)
 	}
 
-	var dataError []string
-	for _, key := range logs {
-		dataError = append(dataError, key)
+	if report.HasErrors() {
+		return report, report.Err()
 	}
-
-	if len(dataError) > 0 {
-		return dataError, fmt.Errorf("Error when insert data")
+	if dryRun {
+		return report, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	err = r.repo.Bulksave(&actualScores)
 	if err != nil {
 		return nil, err
 	}
-
-	return dataError, nil
+	report.Committed = true
+	return report, nil
 }
 
 func NewActualScoreUsecase(repo repository.ActualScoreRepo, employee UserUsecase, project ProjectUsecase) ActualScoreUsecase {
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"calibration-system.com/delivery/api/request"
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+	"calibration-system.com/utils"
+)
+
+const apiKeyLastUsedInterval = time.Minute
+
+type ApiKeyUsecase interface {
+	FindAllServiceAccount() ([]model.ServiceAccount, error)
+	FindServiceAccountById(id string) (*model.ServiceAccount, error)
+	CreateServiceAccount(payload request.CreateServiceAccount, createdBy string) (*model.ServiceAccount, error)
+	DeactivateServiceAccount(id string) error
+	CreateKey(serviceAccountID string, payload request.CreateApiKey) (*response.ApiKeyCreated, error)
+	RotateKey(id string, payload request.RotateApiKey) (*response.ApiKeyCreated, error)
+	RevokeKey(id string) error
+	Authenticate(key, clientIP string) (*model.ApiKey, error)
+}
+
+type apiKeyUsecase struct {
+	repo repository.ApiKeyRepo
+}
+
+func (r *apiKeyUsecase) FindAllServiceAccount() ([]model.ServiceAccount, error) {
+	return r.repo.ListServiceAccount()
+}
+
+func (r *apiKeyUsecase) FindServiceAccountById(id string) (*model.ServiceAccount, error) {
+	return r.repo.GetServiceAccount(id)
+}
+
+func (r *apiKeyUsecase) CreateServiceAccount(payload request.CreateServiceAccount, createdBy string) (*model.ServiceAccount, error) {
+	if payload.Name == "" {
+		return nil, fmt.Errorf("Service account name is required")
+	}
+
+	serviceAccount := model.ServiceAccount{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Active:      true,
+		CreatedBy:   createdBy,
+	}
+	if err := r.repo.SaveServiceAccount(&serviceAccount); err != nil {
+		return nil, err
+	}
+	return &serviceAccount, nil
+}
+
+func (r *apiKeyUsecase) DeactivateServiceAccount(id string) error {
+	return r.repo.DeactivateServiceAccount(id)
+}
+
+func (r *apiKeyUsecase) CreateKey(serviceAccountID string, payload request.CreateApiKey) (*response.ApiKeyCreated, error) {
+	serviceAccount, err := r.repo.GetServiceAccount(serviceAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("Service account not found")
+	}
+	if !serviceAccount.Active {
+		return nil, fmt.Errorf("Service account is not active")
+	}
+
+	if len(payload.Scopes) == 0 {
+		return nil, fmt.Errorf("At least one scope is required")
+	}
+	for _, scope := range payload.Scopes {
+		valid := false
+		for _, apiKeyScope := range model.ApiKeyScopes {
+			if scope == apiKeyScope {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("Unknown scope %s", scope)
+		}
+	}
+
+	apiKey := model.ApiKey{
+		ServiceAccountID: serviceAccountID,
+		Name:             payload.Name,
+		Scopes:           payload.Scopes,
+	}
+	if payload.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, payload.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	key, err := r.fillKey(&apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.repo.Save(&apiKey); err != nil {
+		return nil, err
+	}
+
+	return &response.ApiKeyCreated{
+		ApiKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+func (r *apiKeyUsecase) RotateKey(id string, payload request.RotateApiKey) (*response.ApiKeyCreated, error) {
+	oldKey, err := r.repo.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("Api key not found")
+	}
+	if oldKey.RevokedAt != nil {
+		return nil, fmt.Errorf("Api key already revoked")
+	}
+
+	newKey := model.ApiKey{
+		ServiceAccountID: oldKey.ServiceAccountID,
+		Name:             oldKey.Name,
+		Scopes:           oldKey.Scopes,
+		RotatedFromID:    &oldKey.ID,
+	}
+
+	// Keep the original lifetime for the new key
+	if oldKey.ExpiresAt != nil {
+		expiresAt := time.Now().Add(oldKey.ExpiresAt.Sub(oldKey.CreatedAt))
+		newKey.ExpiresAt = &expiresAt
+	}
+
+	key, err := r.fillKey(&newKey)
+	if err != nil {
+		return nil, err
+	}
+
+	graceUntil := time.Now().Add(time.Duration(payload.GracePeriodHours) * time.Hour)
+	if oldKey.ExpiresAt == nil || graceUntil.Before(*oldKey.ExpiresAt) {
+		oldKey.ExpiresAt = &graceUntil
+	}
+
+	if err := r.repo.Rotate(oldKey, &newKey); err != nil {
+		return nil, err
+	}
+
+	return &response.ApiKeyCreated{
+		ApiKey: newKey,
+		Key:    key,
+	}, nil
+}
+
+func (r *apiKeyUsecase) RevokeKey(id string) error {
+	return r.repo.Revoke(id)
+}
+
+func (r *apiKeyUsecase) Authenticate(key, clientIP string) (*model.ApiKey, error) {
+	prefix, _, err := utils.SplitApiKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := r.repo.GetByPrefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("Api key invalid")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(utils.HashApiKey(key))) != 1 {
+		return nil, fmt.Errorf("Api key invalid")
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, fmt.Errorf("Api key revoked")
+	}
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		return nil, fmt.Errorf("Api key expired")
+	}
+	if !apiKey.ServiceAccount.Active {
+		return nil, fmt.Errorf("Service account is not active")
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > apiKeyLastUsedInterval || apiKey.LastUsedIP != clientIP {
+		if err := r.repo.UpdateLastUsed(apiKey.ID, clientIP); err != nil {
+			return nil, err
+		}
+	}
+	return apiKey, nil
+}
+
+func (r *apiKeyUsecase) fillKey(apiKey *model.ApiKey) (string, error) {
+	prefix, key, err := utils.GenerateApiKey()
+	if err != nil {
+		return "", err
+	}
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = utils.HashApiKey(key)
+	return key, nil
+}
+
+func NewApiKeyUsecase(repo repository.ApiKeyRepo) ApiKeyUsecase {
+	return &apiKeyUsecase{
+		repo: repo,
+	}
+}
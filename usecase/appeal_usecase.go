@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+type AppealUsecase interface {
+	File(employeeID string, payload *model.Appeal) error
+	FindById(id string) (*model.Appeal, error)
+	FindByEmployee(employeeID string) ([]model.Appeal, error)
+	FindByReviewer(reviewerID, projectID, status string) ([]model.Appeal, error)
+	FindAttachment(appealID, attachmentID string) (*model.AppealAttachment, error)
+	StartReview(id, reviewerID string) (*model.Appeal, error)
+	Uphold(id string, decision model.AppealDecision, reviewerID string) (*model.Appeal, error)
+	Amend(id string, decision model.AppealDecision, reviewerID string) (*model.Appeal, error)
+}
+
+// appealRatings are the ratings an amendment can give
+var appealRatings = map[string]bool{"A+": true, "A": true, "B+": true, "B": true, "C": true, "D": true}
+
+type appealUsecase struct {
+	repo    repository.AppealRepo
+	user    repository.UserRepo
+	project ProjectUsecase
+}
+
+// File opens an appeal on a released result while the project's appeal window runs. It is routed to the HRBP of
+// the employee, matched on nik, and the calibrator who gave the final rating.
+func (u *appealUsecase) File(employeeID string, payload *model.Appeal) error {
+	if payload.Reason == "" {
+		return fmt.Errorf("Reason is required")
+	}
+
+	result, err := u.repo.GetFinalResult(payload.ProjectID, employeeID)
+	if err != nil || result.Project.Status != model.ProjectClosed {
+		return fmt.Errorf("There is no published result to appeal")
+	}
+
+	now := time.Now()
+	project := result.Project
+	releasedAt := resultsReleasedAt(&project)
+	if releasedAt == nil || now.Before(*releasedAt) {
+		return fmt.Errorf("There is no published result to appeal")
+	}
+	if project.AppealWindowDays == 0 {
+		return fmt.Errorf("Project %s doesn't take appeals", project.Name)
+	}
+	closesAt := releasedAt.AddDate(0, 0, project.AppealWindowDays)
+	if !now.Before(closesAt) {
+		return fmt.Errorf("Appeals for project %s closed on %s", project.Name, closesAt.Format("2 January 2006"))
+	}
+
+	employee, err := u.user.Get(employeeID)
+	if err != nil {
+		return fmt.Errorf("Employee Not Found")
+	}
+	if employee.HRBP != "" {
+		if hrbp, err := u.user.SearchByNik(employee.HRBP); err == nil {
+			payload.HrbpID = hrbp.ID
+		} else {
+			log.Printf("Failed to find HRBP %s of employee %s: %v", employee.HRBP, employee.Nik, err)
+		}
+	}
+
+	payload.ID = ""
+	payload.EmployeeID = employeeID
+	payload.Status = model.AppealSubmitted
+	payload.CalibratorID = result.CalibratorID
+	payload.PreviousRating = result.FinalRating
+	payload.PreviousScore = result.FinalScore
+	payload.AmendedRating = ""
+	payload.AmendedScore = 0
+	payload.Decision = ""
+	payload.ReviewedBy = ""
+	payload.ReviewedAt = nil
+	payload.Events = nil
+	return u.repo.Create(payload, &model.AppealEvent{
+		Action:     model.AppealSubmitted,
+		ActorID:    employeeID,
+		ToStatus:   model.AppealSubmitted,
+		FromRating: result.FinalRating,
+		ToRating:   result.FinalRating,
+		FromScore:  result.FinalScore,
+		ToScore:    result.FinalScore,
+		Comment:    payload.Reason,
+	})
+}
+
+func (u *appealUsecase) FindById(id string) (*model.Appeal, error) {
+	return u.repo.Get(id)
+}
+
+func (u *appealUsecase) FindByEmployee(employeeID string) ([]model.Appeal, error) {
+	return u.repo.ListByEmployee(employeeID)
+}
+
+func (u *appealUsecase) FindByReviewer(reviewerID, projectID, status string) ([]model.Appeal, error) {
+	return u.repo.ListByReviewer(reviewerID, projectID, status)
+}
+
+func (u *appealUsecase) FindAttachment(appealID, attachmentID string) (*model.AppealAttachment, error) {
+	return u.repo.GetAttachment(appealID, attachmentID)
+}
+
+func (u *appealUsecase) StartReview(id, reviewerID string) (*model.Appeal, error) {
+	return u.decide(id, model.AppealSubmitted, model.AppealUnderReview, "", reviewerID)
+}
+
+func (u *appealUsecase) Uphold(id string, decision model.AppealDecision, reviewerID string) (*model.Appeal, error) {
+	if decision.Comment == "" {
+		return nil, fmt.Errorf("Comment is required")
+	}
+	return u.decide(id, model.AppealUnderReview, model.AppealUpheld, decision.Comment, reviewerID)
+}
+
+// Amend gives the employee a new final rating. When the final phase follows the guideline the rating quota of the
+// calibrator's group is checked as on submit.
+func (u *appealUsecase) Amend(id string, decision model.AppealDecision, reviewerID string) (*model.Appeal, error) {
+	if !appealRatings[decision.Rating] {
+		return nil, fmt.Errorf("Rating must be one of A+, A, B+, B, C or D")
+	}
+	if decision.Comment == "" {
+		return nil, fmt.Errorf("Comment is required")
+	}
+
+	appeal, err := u.repo.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("Appeal Not Found")
+	}
+	if appeal.Status != model.AppealUnderReview {
+		return nil, fmt.Errorf("Appeal is not %s", model.AppealUnderReview)
+	}
+
+	result, err := u.repo.GetFinalResult(appeal.ProjectID, appeal.EmployeeID)
+	if err != nil {
+		return nil, fmt.Errorf("Final result Not Found")
+	}
+	project, err := u.project.FindById(appeal.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+
+	var quota *response.RatingQuota
+	for _, projectPhase := range project.ProjectPhases {
+		if projectPhase.Phase.Order != result.FinalPhase || !projectPhase.Guideline {
+			continue
+		}
+		count, err := u.repo.CountResultGroup(result.ProjectID, result.CalibratorID, result.BusinessUnitID)
+		if err != nil {
+			return nil, err
+		}
+		quota, err = u.project.FindRatingQuotaByCalibratorID(result.CalibratorID, "", result.BusinessUnitID, "all", result.ProjectID, count)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	score := result.FinalScore
+	if decision.Score != nil {
+		score = *decision.Score
+	}
+
+	appeal.AmendedRating = decision.Rating
+	appeal.AmendedScore = score
+	appeal.Decision = decision.Comment
+	appeal.ReviewedBy = reviewerID
+	err = u.repo.Amend(appeal, &model.AppealEvent{
+		AppealID:   appeal.ID,
+		Action:     model.AppealAmended,
+		ActorID:    reviewerID,
+		FromStatus: model.AppealUnderReview,
+		ToStatus:   model.AppealAmended,
+		ToRating:   decision.Rating,
+		ToScore:    score,
+		Comment:    decision.Comment,
+	}, quota)
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.Get(id)
+}
+
+func (u *appealUsecase) decide(id, from, to, comment, reviewerID string) (*model.Appeal, error) {
+	appeal, err := u.repo.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("Appeal Not Found")
+	}
+
+	now := time.Now()
+	appeal.Status = to
+	appeal.ReviewedBy = reviewerID
+	appeal.ReviewedAt = &now
+	if comment != "" {
+		appeal.Decision = comment
+	}
+	err = u.repo.UpdateStatus(appeal, from, &model.AppealEvent{
+		AppealID:   appeal.ID,
+		Action:     to,
+		ActorID:    reviewerID,
+		FromStatus: from,
+		ToStatus:   to,
+		FromRating: appeal.PreviousRating,
+		ToRating:   appeal.PreviousRating,
+		FromScore:  appeal.PreviousScore,
+		ToScore:    appeal.PreviousScore,
+		Comment:    comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.Get(id)
+}
+
+// resultsReleasedAt is when employees get to see the results of a closed project, nil while it isn't closed
+func resultsReleasedAt(project *model.Project) *time.Time {
+	if project.Status != model.ProjectClosed || project.ClosedAt == nil {
+		return nil
+	}
+	if project.ResultVisibility.ReleaseAt != nil && project.ResultVisibility.ReleaseAt.After(*project.ClosedAt) {
+		return project.ResultVisibility.ReleaseAt
+	}
+	return project.ClosedAt
+}
+
+func NewAppealUsecase(repo repository.AppealRepo, user repository.UserRepo, project ProjectUsecase) AppealUsecase {
+	return &appealUsecase{
+		repo:    repo,
+		user:    user,
+		project: project,
+	}
+}
@@ -16,6 +16,7 @@ type AuthUsecase interface {
 	// ChangePassword(email string, requestData request.ChangePassword) error
 	ForgetPassword(email string, resetToken string) error
 	GetUserByEmail(email string) (*model.User, error)
+	GetUserByID(id string) (*model.User, error)
 	ResetPassword(email string, resetToken string, newPassword string, confirmPassword string) error
 	CheckToken(token string) (*model.User, error)
 }
@@ -92,7 +93,8 @@ func (a *authUsecase) Login(payload request.Login) (*model.User, error) {
 	if err != nil {
 		return nil, err
 	}
-	if !utils.ComparePassword(user.Password, []byte(payload.Password)) {
+	// leavers get the same answer as a wrong password
+	if !user.Active || !utils.ComparePassword(user.Password, []byte(payload.Password)) {
 		return nil, fmt.Errorf("Email/Password invalid")
 	}
 
@@ -107,11 +109,18 @@ func (a *authUsecase) GetUserByEmail(email string) (*model.User, error) {
 	return a.user.SearchEmail(email)
 }
 
+func (a *authUsecase) GetUserByID(id string) (*model.User, error) {
+	return a.user.FindById(id)
+}
+
 func (a *authUsecase) CheckToken(token string) (*model.User, error) {
 	user, err := a.user.FindByGenerateToken(token)
-	if err == gorm.ErrRecordNotFound {
+	if err == gorm.ErrRecordNotFound || (err == nil && !user.Active) {
 		return nil, fmt.Errorf("Token invalid")
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	user.LastLogin = time.Now()
 	if err := a.user.UpdateData(user); err != nil {
@@ -35,6 +35,9 @@ func (r *bottomRemarkUsecase) SaveData(payload *model.BottomRemark) error {
 		if err != nil {
 			return fmt.Errorf("Project Not Found")
 		}
+		if err := r.project.CheckEditable(payload.ProjectID); err != nil {
+			return err
+		}
 	}
 
 	if payload.EmployeeID != "" {
@@ -60,6 +63,9 @@ func (r *bottomRemarkUsecase) SaveData(payload *model.BottomRemark) error {
 }
 
 func (r *bottomRemarkUsecase) DeleteData(projectID, employeeID, projectPhaseID string) error {
+	if err := r.project.CheckEditable(projectID); err != nil {
+		return err
+	}
 	return r.repo.Delete(projectID, employeeID, projectPhaseID)
 }
 
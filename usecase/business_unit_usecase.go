@@ -1,20 +1,21 @@
 package usecase
 
 import (
+	"context"
 	"fmt"
-	"mime/multipart"
+	"io"
 
 	"calibration-system.com/delivery/api/request"
 	"calibration-system.com/delivery/api/response"
 	"calibration-system.com/model"
 	"calibration-system.com/repository"
 	"calibration-system.com/utils"
-	"github.com/360EntSecGroup-Skylar/excelize"
+	"calibration-system.com/utils/importer"
 )
 
 type BusinessUnitUsecase interface {
 	BaseUsecase[model.BusinessUnit]
-	BulkInsert(file *multipart.FileHeader) ([]string, error)
+	BulkInsert(ctx context.Context, file io.Reader, dryRun bool) (*importer.Report, error)
 	FindPagination(param request.PaginationParam) ([]model.BusinessUnit, response.Paging, error)
 }
 
@@ -50,78 +51,83 @@ func (r *businessUnitUsecase) DeleteData(id string) error {
 	return r.repo.Delete(id)
 }
 
-func (r *businessUnitUsecase) BulkInsert(file *multipart.FileHeader) ([]string, error) {
-	var logs []string
-	var businessUnits []model.BusinessUnit
-	var groupBu []model.GroupBusinessUnit
-
-	// Membuka file Excel yang diunggah
-	excelFile, err := file.Open()
+func (r *businessUnitUsecase) BulkInsert(ctx context.Context, file io.Reader, dryRun bool) (*importer.Report, error) {
+	report, rows, err := importer.Read(file, businessUnitImportSchema, dryRun)
 	if err != nil {
 		return nil, err
 	}
-	defer excelFile.Close()
 
-	xlsFile, err := excelize.OpenReader(excelFile)
-	if err != nil {
-		return nil, err
+	columns := map[string]bool{}
+	for _, column := range report.Columns {
+		columns[column] = true
 	}
 
-	sheetName := xlsFile.GetSheetName(1)
-	rows := xlsFile.GetRows(sheetName)
-
+	var businessUnits []model.BusinessUnit
+	groupBu := map[string]*model.GroupBusinessUnit{}
+	buIds := map[string]int{}
 	for i, row := range rows {
-		passed := true
-		if i == 0 {
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
+			return nil, err
+		}
+
+		buID := row.Get("Business Unit ID")
+		if previous, ok := buIds[buID]; ok {
+			report.AddError(row.Number, "Business Unit ID", buID, fmt.Sprintf("Duplicate of row %d", previous))
 			continue
 		}
+		buIds[buID] = row.Number
 
-		buID := row[0]
-		buName := row[1]
-		gbuName := row[3]
-
-		var found bool
-		var gbuid string
-		for _, gb := range groupBu {
-			if gb.GroupName == gbuName {
-				gbuid = gb.ID
-				found = true
-				break
-			}
+		gbuName := row.Get("Group Business Unit Name")
+		if _, ok := groupBu[gbuName]; !ok {
+			groupBu[gbuName], _ = r.groupBu.FindByName(gbuName)
+		}
+		if groupBu[gbuName] == nil {
+			report.AddError(row.Number, "Group Business Unit Name", gbuName, "Group business unit not found")
+			continue
 		}
 
-		if !found {
-			gbu, err := r.groupBu.FindByName(gbuName)
-			if err != nil {
-				logs = append(logs, fmt.Sprintf("Error Group Business Unit Name on Row %d ", i))
-				passed = false
-			}
-			gbuid = gbu.ID
-			groupBu = append(groupBu, *gbu)
+		// existing units keep the generator settings unless the sheet carries those columns
+		businessUnit := model.BusinessUnit{ID: buID}
+		action := importer.ActionCreate
+		if existing, err := r.repo.Get(buID); err == nil {
+			businessUnit = *existing
+			action = importer.ActionUpdate
 		}
 
-		if passed {
-			bu := model.BusinessUnit{
-				ID:                  buID,
-				Status:              true,
-				Name:                buName,
-				GroupBusinessUnitId: gbuid,
+		businessUnit.Status = true
+		businessUnit.Name = row.Get("Business Unit Name")
+		businessUnit.GroupBusinessUnitId = groupBu[gbuName].ID
+		for header, field := range map[string]*string{
+			"Head NIK":   &businessUnit.HeadNik,
+			"SPMO NIK":   &businessUnit.SpmoNik,
+			"SPMO 2 NIK": &businessUnit.Spmo2Nik,
+			"SPMO 3 NIK": &businessUnit.Spmo3Nik,
+		} {
+			if columns[header] {
+				*field = row.Get(header)
 			}
-
-			businessUnits = append(businessUnits, bu)
 		}
+
+		businessUnits = append(businessUnits, businessUnit)
+		report.AddRow(row.Number, buID, action)
 	}
 
-	if len(logs) > 0 {
-		return logs, fmt.Errorf("Error when insert data")
+	if report.HasErrors() {
+		return report, report.Err()
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	err = r.repo.Bulksave(&businessUnits)
 	if err != nil {
 		return nil, err
 	}
-
-	return logs, nil
+	report.Committed = true
+	return report, nil
 }
 
 func NewBusinessUnitUsecase(repo repository.BusinessUnitRepo, groupBu GroupBusinessUnitUsecase) BusinessUnitUsecase {
@@ -0,0 +1,364 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"calibration-system.com/delivery/api/request"
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+type CalibrationCommentUsecase interface {
+	FindThread(projectID, employeeID, userID string, admin bool) (*response.CommentThread, error)
+	Post(projectID, employeeID string, payload *request.CommentRequest, userID string, admin bool) (*model.CalibrationComment, error)
+	Edit(projectID, employeeID, id, body, userID string, admin bool) (*model.CalibrationComment, error)
+	FindRevisions(projectID, employeeID, id, userID string, admin bool) ([]model.CommentRevision, error)
+	MarkRead(projectID, employeeID, userID string, admin bool) error
+	FindUnread(projectID, userID string, admin bool) ([]response.UnreadComments, error)
+}
+
+// mentionPattern matches an @mention of a user by nik at the start of the body or after a character that can't be
+// part of a nik, so the domain of an email address is not a mention
+var mentionPattern = regexp.MustCompile(`(?:^|[^0-9A-Za-z._@-])@([0-9A-Za-z._-]+)`)
+
+// commentAudience is who a user is in the discussion on an employee, admins are both
+type commentAudience struct {
+	calibrator bool
+	spmo       bool
+}
+
+func (a commentAudience) sees(comment *model.CalibrationComment, userID string) bool {
+	switch comment.Visibility {
+	case model.CommentVisibleCalibrators:
+		return a.calibrator || comment.AuthorID == userID
+	case model.CommentVisibleSpmo:
+		return a.spmo || comment.AuthorID == userID
+	}
+	return true
+}
+
+type calibrationCommentUsecase struct {
+	repo         repository.CalibrationCommentRepo
+	user         repository.UserRepo
+	delegation   DelegationUsecase
+	notification NotificationUsecase
+}
+
+func (u *calibrationCommentUsecase) FindThread(projectID, employeeID, userID string, admin bool) (*response.CommentThread, error) {
+	audience, _, err := u.audience(projectID, employeeID, userID, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := u.repo.List(projectID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	read, err := u.repo.GetRead(projectID, employeeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &response.CommentThread{
+		ProjectID:  projectID,
+		EmployeeID: employeeID,
+		Comments:   []response.CommentEntry{},
+	}
+	if read != nil {
+		thread.LastReadAt = &read.LastReadAt
+	}
+	for i := range comments {
+		comment := &comments[i]
+		if !audience.sees(comment, userID) {
+			continue
+		}
+		unread := comment.AuthorID != userID && (read == nil || comment.CreatedAt.After(read.LastReadAt))
+		if unread {
+			thread.Unread++
+		}
+		thread.Comments = append(thread.Comments, response.CommentEntry{
+			CalibrationComment: *comment,
+			AuthorName:         comment.Author.Name,
+			PhaseOrder:         comment.ProjectPhase.Phase.Order,
+			Unread:             unread,
+		})
+	}
+	return thread, nil
+}
+
+// Post adds a comment to the thread of an employee. Calibrators can keep a comment among calibrators and SPMOs among
+// SPMOs, the users it @mentions must be able to read it and are notified.
+func (u *calibrationCommentUsecase) Post(projectID, employeeID string, payload *request.CommentRequest, userID string, admin bool) (*model.CalibrationComment, error) {
+	audience, participants, err := u.audience(projectID, employeeID, userID, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		return nil, fmt.Errorf("Body is required")
+	}
+	visibility := payload.Visibility
+	switch visibility {
+	case "":
+		visibility = model.CommentVisibleAll
+	case model.CommentVisibleAll:
+	case model.CommentVisibleCalibrators:
+		if !audience.calibrator {
+			return nil, fmt.Errorf("Only calibrators can post for calibrators only")
+		}
+	case model.CommentVisibleSpmo:
+		if !audience.spmo {
+			return nil, fmt.Errorf("Only SPMOs can post for SPMOs only")
+		}
+	default:
+		return nil, fmt.Errorf("Visibility must be all, calibrators or spmo")
+	}
+
+	phaseFound := false
+	for _, participant := range participants {
+		if participant.ProjectPhaseID == payload.ProjectPhaseID {
+			phaseFound = true
+			break
+		}
+	}
+	if !phaseFound {
+		return nil, fmt.Errorf("Project Phase Not Found")
+	}
+
+	if payload.ParentID != nil && *payload.ParentID != "" {
+		parent, err := u.repo.Get(*payload.ParentID)
+		if err != nil || parent.ProjectID != projectID || parent.EmployeeID != employeeID || !audience.sees(parent, userID) {
+			return nil, fmt.Errorf("Comment Not Found")
+		}
+	} else {
+		payload.ParentID = nil
+	}
+
+	comment := &model.CalibrationComment{
+		ProjectID:      projectID,
+		EmployeeID:     employeeID,
+		ProjectPhaseID: payload.ProjectPhaseID,
+		ParentID:       payload.ParentID,
+		AuthorID:       userID,
+		Body:           body,
+		Visibility:     visibility,
+	}
+	mentioned, err := u.mentions(comment, participants)
+	if err != nil {
+		return nil, err
+	}
+	for _, mentionedID := range mentioned {
+		comment.Mentions = append(comment.Mentions, model.CommentMention{UserID: mentionedID})
+	}
+
+	if err := u.repo.Create(comment); err != nil {
+		return nil, err
+	}
+	if len(mentioned) > 0 {
+		err = u.notification.NotifyMentionedInComment(mentioned, userID, employeeID, projectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+// Edit changes the body of the caller's own comment, the previous body is kept as a revision and users newly
+// mentioned are notified
+func (u *calibrationCommentUsecase) Edit(projectID, employeeID, id, body, userID string, admin bool) (*model.CalibrationComment, error) {
+	_, participants, err := u.audience(projectID, employeeID, userID, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := u.repo.Get(id)
+	if err != nil || comment.ProjectID != projectID || comment.EmployeeID != employeeID {
+		return nil, fmt.Errorf("Comment Not Found")
+	}
+	if comment.AuthorID != userID {
+		return nil, fmt.Errorf("Only the author can edit a comment")
+	}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("Body is required")
+	}
+	if body == comment.Body {
+		return comment, nil
+	}
+
+	revision := &model.CommentRevision{
+		CalibrationCommentID: comment.ID,
+		Body:                 comment.Body,
+		EditedBy:             userID,
+	}
+	previous := map[string]bool{}
+	for _, mention := range comment.Mentions {
+		previous[mention.UserID] = true
+	}
+
+	now := time.Now()
+	comment.Body = body
+	comment.EditedAt = &now
+	mentioned, err := u.mentions(comment, participants)
+	if err != nil {
+		return nil, err
+	}
+	comment.Mentions = nil
+	var added []string
+	for _, mentionedID := range mentioned {
+		comment.Mentions = append(comment.Mentions, model.CommentMention{CalibrationCommentID: comment.ID, UserID: mentionedID})
+		if !previous[mentionedID] {
+			added = append(added, mentionedID)
+		}
+	}
+
+	if err := u.repo.Update(comment, revision); err != nil {
+		return nil, err
+	}
+	if len(added) > 0 {
+		err = u.notification.NotifyMentionedInComment(added, userID, employeeID, projectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+func (u *calibrationCommentUsecase) FindRevisions(projectID, employeeID, id, userID string, admin bool) ([]model.CommentRevision, error) {
+	audience, _, err := u.audience(projectID, employeeID, userID, admin)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := u.repo.Get(id)
+	if err != nil || comment.ProjectID != projectID || comment.EmployeeID != employeeID || !audience.sees(comment, userID) {
+		return nil, fmt.Errorf("Comment Not Found")
+	}
+	return u.repo.ListRevisions(id)
+}
+
+func (u *calibrationCommentUsecase) MarkRead(projectID, employeeID, userID string, admin bool) error {
+	if _, _, err := u.audience(projectID, employeeID, userID, admin); err != nil {
+		return err
+	}
+	return u.repo.SaveRead(&model.CommentRead{
+		ProjectID:  projectID,
+		EmployeeID: employeeID,
+		UserID:     userID,
+		LastReadAt: time.Now(),
+	})
+}
+
+// FindUnread counts the unread comments per employee in the threads the user takes part in, including those of the
+// calibrators they are standing in for
+func (u *calibrationCommentUsecase) FindUnread(projectID, userID string, admin bool) ([]response.UnreadComments, error) {
+	actingFor, err := u.delegation.ActingFor(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.CountUnread(projectID, userID, append([]string{userID}, actingFor...), admin)
+}
+
+// audience works out whether the user is a calibrator or an SPMO of the employee in any phase, a delegate counts as
+// the calibrator they stand in for. Users who are neither can't see the thread.
+func (u *calibrationCommentUsecase) audience(projectID, employeeID, userID string, admin bool) (*commentAudience, []model.Calibration, error) {
+	participants, err := u.repo.ListParticipants(projectID, employeeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(participants) == 0 {
+		return nil, nil, fmt.Errorf("Calibration Not Found")
+	}
+
+	audience, err := u.audienceOf(participants, projectID, userID, admin)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !audience.calibrator && !audience.spmo {
+		return nil, nil, fmt.Errorf("Calibration Not Found")
+	}
+	return audience, participants, nil
+}
+
+func (u *calibrationCommentUsecase) audienceOf(participants []model.Calibration, projectID, userID string, admin bool) (*commentAudience, error) {
+	if admin {
+		return &commentAudience{calibrator: true, spmo: true}, nil
+	}
+
+	audience := &commentAudience{}
+	calibrators := map[string]bool{}
+	for _, participant := range participants {
+		calibrators[participant.CalibratorID] = true
+		if participant.SpmoID == userID ||
+			(participant.Spmo2ID != nil && *participant.Spmo2ID == userID) ||
+			(participant.Spmo3ID != nil && *participant.Spmo3ID == userID) {
+			audience.spmo = true
+		}
+	}
+	if calibrators[userID] {
+		audience.calibrator = true
+		return audience, nil
+	}
+
+	actingFor, err := u.delegation.ActingFor(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, calibratorID := range actingFor {
+		if calibrators[calibratorID] {
+			audience.calibrator = true
+		}
+	}
+	return audience, nil
+}
+
+// mentions resolves the @niks in a comment to users, each of them has to be able to read it
+func (u *calibrationCommentUsecase) mentions(comment *model.CalibrationComment, participants []model.Calibration) ([]string, error) {
+	seen := map[string]bool{}
+	var mentioned []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(comment.Body, -1) {
+		// punctuation closing a sentence is not part of the nik
+		nik := strings.TrimRight(match[1], "._-")
+		if nik == "" || seen[nik] {
+			continue
+		}
+		seen[nik] = true
+
+		// an @ that names nobody is plain text
+		user, err := u.user.SearchByNik(nik)
+		if err != nil {
+			continue
+		}
+		if user.ID == comment.AuthorID {
+			continue
+		}
+		admin := false
+		for _, role := range user.Roles {
+			if strings.EqualFold(role.Name, model.RoleAdmin) {
+				admin = true
+			}
+		}
+		audience, err := u.audienceOf(participants, comment.ProjectID, user.ID, admin)
+		if err != nil {
+			return nil, err
+		}
+		if !audience.sees(comment, user.ID) || (!audience.calibrator && !audience.spmo) {
+			return nil, fmt.Errorf("%s can't read this comment", user.Name)
+		}
+		mentioned = append(mentioned, user.ID)
+	}
+	return mentioned, nil
+}
+
+func NewCalibrationCommentUsecase(repo repository.CalibrationCommentRepo, user repository.UserRepo, delegation DelegationUsecase, notification NotificationUsecase) CalibrationCommentUsecase {
+	return &calibrationCommentUsecase{
+		repo:         repo,
+		user:         user,
+		delegation:   delegation,
+		notification: notification,
+	}
+}
@@ -0,0 +1,427 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+	"calibration-system.com/utils/exporter"
+	"calibration-system.com/utils/importer"
+)
+
+type CalibrationDraftUsecase interface {
+	Generate(projectID string, rules model.ChainRules, createdBy string) (*model.CalibrationDraft, error)
+	FindById(id string) (*model.CalibrationDraft, error)
+	FindByProject(projectID string) ([]model.CalibrationDraft, error)
+	Export(w io.Writer, id, format string) error
+	Replace(ctx context.Context, id string, file io.Reader) (*importer.Report, error)
+	Commit(ctx context.Context, id string, dryRun bool) (*importer.Report, error)
+}
+
+type calibrationDraftUsecase struct {
+	repo        repository.CalibrationDraftRepo
+	user        repository.UserRepo
+	project     ProjectUsecase
+	bu          BusinessUnitUsecase
+	actualScore ActualScoreUsecase
+	calibration CalibrationUsecase
+}
+
+// Generate builds a calibrator chain for every employee with an actual score in the project. Rows the rules cannot
+// fill are kept with a warning and an empty calibrator, the calibration import rejects them until they are edited.
+func (u *calibrationDraftUsecase) Generate(projectID string, rules model.ChainRules, createdBy string) (*model.CalibrationDraft, error) {
+	project, err := u.project.FindById(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+
+	phaseRules := map[int]model.ChainPhaseRule{}
+	for _, rule := range rules.Phases {
+		if err := validateChainRule(rule); err != nil {
+			return nil, err
+		}
+		phaseRules[rule.PhaseOrder] = rule
+	}
+	for _, projectPhase := range project.ProjectPhases {
+		if _, ok := phaseRules[projectPhase.Phase.Order]; !ok {
+			return nil, fmt.Errorf("No rule for phase %d", projectPhase.Phase.Order)
+		}
+	}
+
+	actualScores, err := u.actualScore.FindByProjectId(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(actualScores) == 0 {
+		return nil, fmt.Errorf("Project has no actual scores, the generator only covers employees with an actual score")
+	}
+
+	users, err := u.user.SearchAll()
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]*model.User{}
+	byNik := map[string]*model.User{}
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+		if _, ok := byNik[users[i].Nik]; !ok && users[i].Active {
+			byNik[users[i].Nik] = &users[i]
+		}
+	}
+
+	businessUnits, err := u.bu.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	buByID := map[string]*model.BusinessUnit{}
+	for i := range businessUnits {
+		buByID[businessUnits[i].ID] = &businessUnits[i]
+	}
+
+	draft := model.CalibrationDraft{
+		ProjectID: projectID,
+		Rules:     rules,
+		Status:    model.CalibrationDraftOpen,
+		CreatedBy: createdBy,
+	}
+	for _, score := range actualScores {
+		employee := byID[score.EmployeeID]
+		if employee == nil {
+			continue
+		}
+
+		var businessUnit *model.BusinessUnit
+		if employee.BusinessUnitId != nil {
+			businessUnit = buByID[*employee.BusinessUnitId]
+		}
+		chain, warning := managerChain(employee, byNik)
+
+		row := model.CalibrationDraftRow{
+			EmployeeID:  employee.ID,
+			Nik:         employee.Nik,
+			Name:        employee.Name,
+			Calibrators: map[int]string{},
+		}
+		if businessUnit != nil {
+			row.BusinessUnitId = businessUnit.ID
+		}
+		if !employee.Active {
+			row.Warnings = append(row.Warnings, "Employee is no longer active")
+		}
+		if warning != "" {
+			row.Warnings = append(row.Warnings, warning)
+		}
+
+		for _, projectPhase := range project.ProjectPhases {
+			order := projectPhase.Phase.Order
+			nik := pickCalibrator(phaseRules[order], employee, chain, businessUnit)
+			if nik == "" {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("No calibrator found for phase %d", order))
+			}
+			row.Calibrators[order] = nik
+		}
+
+		row.SpmoNik = rules.SpmoNik
+		row.Spmo2Nik = calibrationEmptyNik
+		row.Spmo3Nik = calibrationEmptyNik
+		if businessUnit != nil {
+			if row.SpmoNik == "" {
+				row.SpmoNik = businessUnit.SpmoNik
+			}
+			if businessUnit.Spmo2Nik != "" {
+				row.Spmo2Nik = businessUnit.Spmo2Nik
+			}
+			if businessUnit.Spmo3Nik != "" {
+				row.Spmo3Nik = businessUnit.Spmo3Nik
+			}
+		}
+		if row.SpmoNik == "" {
+			row.Warnings = append(row.Warnings, "No SPMO set for the business unit")
+		}
+
+		draft.Rows = append(draft.Rows, row)
+	}
+	sort.Slice(draft.Rows, func(i, j int) bool {
+		return draft.Rows[i].Nik < draft.Rows[j].Nik
+	})
+	countDraft(&draft)
+
+	if err := u.repo.Save(&draft); err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+func (u *calibrationDraftUsecase) FindById(id string) (*model.CalibrationDraft, error) {
+	return u.repo.Get(id)
+}
+
+func (u *calibrationDraftUsecase) FindByProject(projectID string) ([]model.CalibrationDraft, error) {
+	return u.repo.ListByProject(projectID)
+}
+
+// Export writes the draft in the calibration upload layout so it can be edited and uploaded back
+func (u *calibrationDraftUsecase) Export(w io.Writer, id, format string) error {
+	draft, project, err := u.findDraft(id)
+	if err != nil {
+		return err
+	}
+	return exporter.Write(w, format, draftTable(project, draft))
+}
+
+// Replace reads an edited export back into the draft, the checks that need the whole project wait for the commit dry-run
+func (u *calibrationDraftUsecase) Replace(ctx context.Context, id string, file io.Reader) (*importer.Report, error) {
+	draft, project, err := u.findDraft(id)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status != model.CalibrationDraftOpen {
+		return nil, fmt.Errorf("Draft has already been committed")
+	}
+
+	report, rows, err := importer.Read(file, calibrationImportSchema(project), true)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []string{"Employee NIK", "SPMO NIK", "SPMO 2 NIK", "SPMO 3 NIK"}
+	for _, projectPhase := range project.ProjectPhases {
+		headers = append(headers, calibratorHeader(projectPhase))
+	}
+	users, err := u.user.SearchByNiks(columnValues(rows, headers...))
+	if err != nil {
+		return nil, err
+	}
+	byNik := map[string]*model.User{}
+	for i := range users {
+		byNik[users[i].Nik] = &users[i]
+	}
+
+	draft.Rows = nil
+	niks := map[string]int{}
+	for i, row := range rows {
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
+			return nil, err
+		}
+
+		nik := row.Get("Employee NIK")
+		if previous, ok := niks[nik]; ok {
+			report.AddError(row.Number, "Employee NIK", nik, fmt.Sprintf("Duplicate of row %d", previous))
+			continue
+		}
+		niks[nik] = row.Number
+
+		employee := byNik[nik]
+		if employee == nil {
+			report.AddError(row.Number, "Employee NIK", nik, "Employee not found")
+			continue
+		}
+
+		draftRow := model.CalibrationDraftRow{
+			EmployeeID:  employee.ID,
+			Nik:         nik,
+			Name:        employee.Name,
+			Calibrators: map[int]string{},
+			SpmoNik:     row.Get("SPMO NIK"),
+			Spmo2Nik:    row.Get("SPMO 2 NIK"),
+			Spmo3Nik:    row.Get("SPMO 3 NIK"),
+		}
+		if employee.BusinessUnitId != nil {
+			draftRow.BusinessUnitId = *employee.BusinessUnitId
+		}
+		for _, projectPhase := range project.ProjectPhases {
+			calibratorNik := row.Get(calibratorHeader(projectPhase))
+			draftRow.Calibrators[projectPhase.Phase.Order] = calibratorNik
+			if calibratorNik != calibrationEmptyNik && byNik[calibratorNik] == nil {
+				draftRow.Warnings = append(draftRow.Warnings, fmt.Sprintf("Calibrator %s of phase %d not found", calibratorNik, projectPhase.Phase.Order))
+			}
+		}
+
+		draft.Rows = append(draft.Rows, draftRow)
+		report.AddRow(row.Number, nik, importer.ActionUpdate)
+	}
+
+	if report.HasErrors() {
+		return report, report.Err()
+	}
+
+	countDraft(draft)
+	if err := u.repo.ReplaceRows(draft); err != nil {
+		return nil, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// Commit runs the draft through the calibration import, dryRun previews the import report without saving
+func (u *calibrationDraftUsecase) Commit(ctx context.Context, id string, dryRun bool) (*importer.Report, error) {
+	draft, project, err := u.findDraft(id)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status != model.CalibrationDraftOpen {
+		return nil, fmt.Errorf("Draft has already been committed")
+	}
+
+	input, err := exporter.Bytes(exporter.FormatCSV, draftTable(project, draft))
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := u.calibration.BulkInsert(ctx, bytes.NewReader(input), project.ID, dryRun)
+	if err != nil || dryRun {
+		return report, err
+	}
+
+	if err := u.repo.MarkCommitted(draft.ID); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (u *calibrationDraftUsecase) findDraft(id string) (*model.CalibrationDraft, *model.Project, error) {
+	draft, err := u.repo.Get(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Draft not found")
+	}
+	project, err := u.project.FindById(draft.ProjectID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Project Not Found")
+	}
+	return draft, project, nil
+}
+
+func draftTable(project *model.Project, draft *model.CalibrationDraft) *exporter.Table {
+	table := &exporter.Table{
+		Sheet:   "Calibrations",
+		Headers: []string{"Employee NIK", "Employee Name", "Business Unit ID"},
+	}
+	for _, projectPhase := range project.ProjectPhases {
+		table.Headers = append(table.Headers, calibratorHeader(projectPhase))
+	}
+	table.Headers = append(table.Headers, "SPMO NIK", "SPMO 2 NIK", "SPMO 3 NIK", "Warnings")
+
+	for _, row := range draft.Rows {
+		values := []interface{}{row.Nik, row.Name, row.BusinessUnitId}
+		for _, projectPhase := range project.ProjectPhases {
+			values = append(values, row.Calibrators[projectPhase.Phase.Order])
+		}
+		values = append(values, row.SpmoNik, row.Spmo2Nik, row.Spmo3Nik, strings.Join(row.Warnings, "; "))
+		table.Rows = append(table.Rows, values)
+	}
+	return table
+}
+
+func countDraft(draft *model.CalibrationDraft) {
+	draft.Employees = len(draft.Rows)
+	draft.Warnings = 0
+	for _, row := range draft.Rows {
+		if len(row.Warnings) > 0 {
+			draft.Warnings++
+		}
+	}
+}
+
+func validateChainRule(rule model.ChainPhaseRule) error {
+	switch rule.Rule {
+	case model.ChainRuleSupervisor, model.ChainRuleBuHead, model.ChainRuleSkip:
+	case model.ChainRuleGrade:
+		if rule.MinGrade == "" {
+			return fmt.Errorf("Phase %d: the grade rule needs MinGrade", rule.PhaseOrder)
+		}
+	case model.ChainRuleFixed:
+		if rule.Nik == "" {
+			return fmt.Errorf("Phase %d: the fixed rule needs Nik", rule.PhaseOrder)
+		}
+	default:
+		return fmt.Errorf("Phase %d: unknown rule %s", rule.PhaseOrder, rule.Rule)
+	}
+	return nil
+}
+
+// managerChain follows SupervisorNik upwards over active employees, nearest manager first
+func managerChain(employee *model.User, byNik map[string]*model.User) ([]*model.User, string) {
+	var chain []*model.User
+	visited := map[string]bool{employee.ID: true}
+	current := employee
+	for current.SupervisorNik != "" {
+		supervisor := byNik[current.SupervisorNik]
+		if supervisor == nil {
+			return chain, fmt.Sprintf("Reporting line stops at supervisor %s who is not an active employee", current.SupervisorNik)
+		}
+		if visited[supervisor.ID] {
+			return chain, fmt.Sprintf("Reporting line loops back to %s", supervisor.Nik)
+		}
+		visited[supervisor.ID] = true
+		chain = append(chain, supervisor)
+		current = supervisor
+	}
+	return chain, ""
+}
+
+func pickCalibrator(rule model.ChainPhaseRule, employee *model.User, chain []*model.User, businessUnit *model.BusinessUnit) string {
+	switch rule.Rule {
+	case model.ChainRuleSkip:
+		return calibrationEmptyNik
+	case model.ChainRuleFixed:
+		return rule.Nik
+	case model.ChainRuleSupervisor:
+		level := rule.Level
+		if level < 1 {
+			level = 1
+		}
+		if level <= len(chain) {
+			return chain[level-1].Nik
+		}
+	case model.ChainRuleGrade:
+		for _, manager := range chain {
+			if gradeAtLeast(manager.Grade, rule.MinGrade) {
+				return manager.Nik
+			}
+		}
+	case model.ChainRuleBuHead:
+		if businessUnit != nil && businessUnit.HeadNik != "" && businessUnit.HeadNik != employee.Nik {
+			return businessUnit.HeadNik
+		}
+		// without a configured head the topmost manager still in the employee's unit stands in
+		var head string
+		for _, manager := range chain {
+			if manager.BusinessUnitId != nil && employee.BusinessUnitId != nil && *manager.BusinessUnitId == *employee.BusinessUnitId {
+				head = manager.Nik
+			}
+		}
+		return head
+	}
+	return ""
+}
+
+// gradeAtLeast compares the numbers in grades like "G7" or "10" and falls back to text order for anything else
+func gradeAtLeast(grade, min string) bool {
+	gradeNumber, gradeErr := strconv.Atoi(strings.TrimLeftFunc(grade, isNotDigit))
+	minNumber, minErr := strconv.Atoi(strings.TrimLeftFunc(min, isNotDigit))
+	if gradeErr == nil && minErr == nil {
+		return gradeNumber >= minNumber
+	}
+	return grade != "" && strings.ToUpper(grade) >= strings.ToUpper(min)
+}
+
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+func NewCalibrationDraftUsecase(repo repository.CalibrationDraftRepo, user repository.UserRepo, project ProjectUsecase, bu BusinessUnitUsecase, actualScore ActualScoreUsecase, calibration CalibrationUsecase) CalibrationDraftUsecase {
+	return &calibrationDraftUsecase{
+		repo:        repo,
+		user:        user,
+		project:     project,
+		bu:          bu,
+		actualScore: actualScore,
+		calibration: calibration,
+	}
+}
@@ -1,43 +1,45 @@
 package usecase
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math"
-	"mime/multipart"
 	"sort"
 
 	"calibration-system.com/delivery/api/request"
 	"calibration-system.com/delivery/api/response"
 	"calibration-system.com/model"
 	"calibration-system.com/repository"
-	"github.com/360EntSecGroup-Skylar/excelize"
+	"calibration-system.com/utils/importer"
 )
 
 type CalibrationUsecase interface {
 	FindAll() ([]model.Calibration, error)
-	FindActiveUserBySPMOID(spmoID string) ([]model.UserChange, error)
-	FindAcceptedBySPMOID(spmoID string) ([]model.Calibration, error)
-	FindRejectedBySPMOID(spmoID string) ([]model.Calibration, error)
+	FindActiveUserBySPMOID(spmoID, projectID string) ([]model.UserChange, error)
+	FindAcceptedBySPMOID(spmoID, projectID string) ([]model.Calibration, error)
+	FindRejectedBySPMOID(spmoID, projectID string) ([]model.Calibration, error)
 	FindById(projectID, projectPhaseID, employeeID string) (*model.Calibration, error)
 	FindByProjectEmployeeId(projectID, employeeID string) ([]model.CalibrationForm, error)
 	SaveData(payload *model.Calibration) error
 	SaveDataByUser(payload *request.CalibrationForm) error
 	DeleteData(projectId, employeeId string) error
-	CheckEmployee(file *multipart.FileHeader, projectId string) ([]string, error)
-	CheckCalibrator(file *multipart.FileHeader, projectId string) ([]string, error)
-	BulkInsert(file *multipart.FileHeader, projectId string) error
+	CheckEmployee(file io.Reader, projectId string) ([]string, error)
+	CheckCalibrator(file io.Reader, projectId string) ([]string, error)
+	BulkInsert(ctx context.Context, file io.Reader, projectId string, dryRun bool) (*importer.Report, error)
 	SubmitCalibrations(calibratorID, projectID, businessUnit string) error
-	SaveCalibrations(payload *request.CalibrationRequest) error
-	SaveCommentCalibration(payload *model.Calibration) error
-	SaveScoreAndRating(payload *model.Calibration) error
+	SaveCalibrations(payload *request.CalibrationRequest, principalID string) error
+	SaveCommentCalibration(payload *model.Calibration, principalID string) error
+	SaveScoreAndRating(payload *model.Calibration, principalID string) error
 	SendCalibrationsToManager(calibratorID, projectID, prevCalibrator, businessUnit string) error
 	SendBackCalibrationsToOnePhaseBefore(calibratorID, projectID, prevCalibrator, businessUnit string) error
-	SpmoAcceptApproval(payload *request.AcceptJustification) error
-	SpmoAcceptMultipleApproval(payload *request.AcceptMultipleJustification) error
-	SpmoRejectApproval(payload *request.RejectJustification) error
+	Reassign(payload *request.ReassignCalibrations, reassignedBy string) ([]model.CalibrationReassignment, error)
+	SpmoAcceptApproval(payload *request.AcceptJustification, spmoID string, admin bool) error
+	SpmoAcceptMultipleApproval(payload *request.AcceptMultipleJustification, spmoID string, admin bool) error
+	SpmoRejectApproval(payload *request.RejectJustification, spmoID string, admin bool) error
 	SpmoSubmit(payload *request.AcceptMultipleJustification) error
 	FindSummaryCalibrationBySPMOID(spmoID, projectID string) (response.SummarySPMO, error)
-	FindAllDetailCalibrationbySPMOID(spmoID, calibratorID, businessUnitID, department string, order int) ([]response.UserResponse, error)
+	FindAllDetailCalibrationbySPMOID(spmoID, calibratorID, businessUnitID, department, projectID string, order int) ([]response.UserResponse, error)
 	FindAllDetailCalibration2bySPMOID(spmoID, calibratorID, businessUnitID, projectID string, order int) ([]response.UserResponse, error)
 	SendNotificationToCurrentCalibrator(projectID string) ([]response.NotificationModel, error)
 	FindRatingQuotaSPMOByCalibratorID(spmoID, calibratorID, businessUnitID, projectID string, order int) (*response.RatingQuota, error)
@@ -51,6 +53,9 @@ type calibrationUsecase struct {
 	projectPhase ProjectPhaseUsecase
 	notification NotificationUsecase
 	actualScore  ActualScoreUsecase
+	lifecycle    PhaseLifecycleUsecase
+	delegation   DelegationUsecase
+	eligibility  EligibilityUsecase
 }
 
 func (r *calibrationUsecase) FindLatestJustification(projectID, calibratorID, employeeID string) ([]model.SeeCalibrationJustification, error) {
@@ -69,6 +74,7 @@ func (r *calibrationUsecase) SendNotificationToCurrentCalibrator(projectID strin
 			user, _ := r.user.FindById(data.CalibratorID)
 			uniqueCalibratorIDs[data.CalibratorID] = response.NotificationModel{
 				CalibratorID:   data.CalibratorID,
+				ProjectID:      projectID,
 				ProjectPhase:   data.ProjectPhase.Phase.Order,
 				Deadline:       data.ProjectPhase.EndDate,
 				NextCalibrator: user.Name,
@@ -83,7 +89,7 @@ func (r *calibrationUsecase) SendNotificationToCurrentCalibrator(projectID strin
 
 	var currentCalibrators []response.NotificationModel
 	for _, calibratorData := range uniqueCalibratorIDsSlice {
-		calibrations, err := r.repo.GetAllCalibrationByCalibratorID(calibratorData.CalibratorID)
+		calibrations, err := r.repo.GetAllCalibrationByCalibratorID(calibratorData.CalibratorID, projectID)
 		if err != nil {
 			return nil, err
 		}
@@ -113,16 +119,16 @@ func (r *calibrationUsecase) FindAll() ([]model.Calibration, error) {
 	return r.repo.List()
 }
 
-func (r *calibrationUsecase) FindActiveUserBySPMOID(spmoID string) ([]model.UserChange, error) {
-	return r.repo.GetActiveUserBySPMOID(spmoID)
+func (r *calibrationUsecase) FindActiveUserBySPMOID(spmoID, projectID string) ([]model.UserChange, error) {
+	return r.repo.GetActiveUserBySPMOID(spmoID, projectID)
 }
 
-func (r *calibrationUsecase) FindAcceptedBySPMOID(spmoID string) ([]model.Calibration, error) {
-	return r.repo.GetAcceptedBySPMOID(spmoID)
+func (r *calibrationUsecase) FindAcceptedBySPMOID(spmoID, projectID string) ([]model.Calibration, error) {
+	return r.repo.GetAcceptedBySPMOID(spmoID, projectID)
 }
 
-func (r *calibrationUsecase) FindRejectedBySPMOID(spmoID string) ([]model.Calibration, error) {
-	return r.repo.GetRejectedBySPMOID(spmoID)
+func (r *calibrationUsecase) FindRejectedBySPMOID(spmoID, projectID string) ([]model.Calibration, error) {
+	return r.repo.GetRejectedBySPMOID(spmoID, projectID)
 }
 
 func (r *calibrationUsecase) FindById(projectID, projectPhaseID, employeeID string) (*model.Calibration, error) {
@@ -139,6 +145,9 @@ func (r *calibrationUsecase) SaveData(payload *model.Calibration) error {
 		if err != nil {
 			return fmt.Errorf("Project Not Found")
 		}
+		if err := r.project.CheckEditable(payload.ProjectID); err != nil {
+			return err
+		}
 	}
 
 	if payload.ProjectPhaseID != "" {
@@ -176,6 +185,9 @@ func (r *calibrationUsecase) SaveDataByUser(payload *request.CalibrationForm) er
 	if err != nil {
 		return err
 	}
+	if err := r.project.CheckEditable(project.ID); err != nil {
+		return err
+	}
 
 	err = r.repo.SaveByUser(payload, project, payload.ActualScore, payload.ActualRating)
 	if err != nil {
@@ -201,6 +213,10 @@ func (r *calibrationUsecase) SaveDataByUser(payload *request.CalibrationForm) er
 }
 
 func (r *calibrationUsecase) DeleteData(projectId, employeeId string) error {
+	if err := r.project.CheckEditable(projectId); err != nil {
+		return err
+	}
+
 	err := r.repo.Delete(projectId, employeeId)
 	if err != nil {
 		return err
@@ -214,38 +230,30 @@ func (r *calibrationUsecase) DeleteData(projectId, employeeId string) error {
 	return nil
 }
 
-func (r *calibrationUsecase) CheckEmployee(file *multipart.FileHeader, projectId string) ([]string, error) {
+func (r *calibrationUsecase) CheckEmployee(file io.Reader, projectId string) ([]string, error) {
 	var logs []string
 
-	_, err := r.project.FindById(projectId)
+	project, err := r.project.FindById(projectId)
 	if err != nil {
 		return nil, err
 	}
 
-	// Membuka file Excel yang diunggah
-	excelFile, err := file.Open()
+	report, rows, err := importer.Read(file, calibrationImportSchema(project), true)
 	if err != nil {
 		return nil, err
 	}
-	defer excelFile.Close()
+	for _, rowError := range report.Errors {
+		logs = append(logs, fmt.Sprintf("Row %d %s: %s", rowError.Row, rowError.Column, rowError.Message))
+	}
 
-	xlsFile, err := excelize.OpenReader(excelFile)
+	employees, err := r.user.FindByNiks(columnValues(rows, "Employee NIK"))
 	if err != nil {
 		return nil, err
 	}
 
-	sheetName := xlsFile.GetSheetName(4)
-	rows := xlsFile.GetRows(sheetName)
-
-	for i, row := range rows {
-		if i == 0 {
-			continue
-		}
-
-		nik := row[0]
-
-		_, err = r.user.FindByNik(nik)
-		if err != nil {
+	for _, row := range rows {
+		nik := row.Get("Employee NIK")
+		if employees[nik] == nil {
 			logs = append(logs, fmt.Sprintf("Employee not available in database %s", nik))
 		}
 	}
@@ -257,60 +265,43 @@ func (r *calibrationUsecase) CheckEmployee(file *multipart.FileHeader, projectId
 	return logs, nil
 }
 
-func (r *calibrationUsecase) CheckCalibrator(file *multipart.FileHeader, projectId string) ([]string, error) {
+func (r *calibrationUsecase) CheckCalibrator(file io.Reader, projectId string) ([]string, error) {
 	logs := map[string]string{}
-	type EmployeeSupervisor struct {
-		EmployeeID    string
-		SupervisorNIK string
-	}
-	calibrators := map[string]EmployeeSupervisor{}
+	checked := map[string]bool{}
 
 	project, err := r.project.FindById(projectId)
 	if err != nil {
 		return nil, err
 	}
 
-	excelFile, err := file.Open()
+	_, rows, err := importer.Read(file, calibrationImportSchema(project), true)
 	if err != nil {
 		return nil, err
 	}
-	defer excelFile.Close()
 
-	xlsFile, err := excelize.OpenReader(excelFile)
+	var headers []string
+	for _, projectPhase := range project.ProjectPhases {
+		headers = append(headers, calibratorHeader(projectPhase))
+	}
+	calibrators, err := r.user.FindByNiks(columnValues(rows, headers...))
 	if err != nil {
 		return nil, err
 	}
 
-	sheetName := xlsFile.GetSheetName(4)
-	rows := xlsFile.GetRows(sheetName)
-
-	for i, row := range rows {
-		if i == 0 {
-			continue
-		}
+	for _, row := range rows {
+		for _, header := range headers {
+			calibratorNik := row.Get(header)
+			if calibratorNik == "" || calibratorNik == calibrationEmptyNik || checked[calibratorNik] {
+				continue
+			}
+			checked[calibratorNik] = true
 
-		lenProjectPhase := len(project.ProjectPhases)
-		var supervisorNIK string
-		for j := lenProjectPhase; j > 0; j-- {
-			calibratorNik := row[j]
-			_, exist := calibrators[calibratorNik]
-			if !exist && calibratorNik != "None" {
-				calibrator, err := r.user.FindByNik(calibratorNik)
-				if err != nil {
-					if _, ok := logs[calibratorNik]; !ok {
-						logs[calibratorNik] = calibratorNik
-					}
-				} else {
-					calibrators[calibratorNik] = EmployeeSupervisor{
-						EmployeeID:    calibrator.ID,
-						SupervisorNIK: supervisorNIK,
-					}
-				}
+			if calibrators[calibratorNik] == nil {
+				logs[calibratorNik] = calibratorNik
 			}
-			supervisorNIK = calibratorNik
 		}
 	}
-	// fmt.Println(calibrators)
+
 	var dataError []string
 	for _, key := range logs {
 		dataError = append(dataError, key)
@@ -323,159 +314,248 @@ func (r *calibrationUsecase) CheckCalibrator(file *multipart.FileHeader, project
 	return dataError, nil
 }
 
-func (r *calibrationUsecase) BulkInsert(file *multipart.FileHeader, projectId string) error {
-	type EmployeeSupervisor struct {
-		EmployeeID    string
-		SupervisorNIK string
-	}
-	calibrators := map[string]EmployeeSupervisor{}
-	calibrations := []model.Calibration{}
-	phases := []string{}
+func (r *calibrationUsecase) BulkInsert(ctx context.Context, file io.Reader, projectId string, dryRun bool) (*importer.Report, error) {
+	var calibrations []model.Calibration
+	var removed []model.Calibration
 
 	project, err := r.project.FindById(projectId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := r.project.CheckEditable(projectId); err != nil {
+		return nil, err
 	}
 
-	var phaseOne model.ProjectPhase
-	//Asc by phase order
-	for _, v := range project.ProjectPhases {
-		phases = append(phases, v.ID)
+	report, rows, err := importer.Read(file, calibrationImportSchema(project), dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	// walk from the last phase down to phase one, the lowest phase with a calibrator is the one that starts calibrating
+	var phaseOneID string
+	phases := append([]model.ProjectPhase{}, project.ProjectPhases...)
+	sort.Slice(phases, func(i, j int) bool {
+		return phases[i].Phase.Order > phases[j].Phase.Order
+	})
+	for _, v := range phases {
 		if v.Phase.Order == 1 {
-			phaseOne = v
+			phaseOneID = v.ID
 		}
 	}
 
-	// fmt.Println("phase: ", project.ProjectPhases)
+	// load every employee, calibrator, existing calibration and actual score up front instead of once per row and phase
+	headers := []string{"Employee NIK", "SPMO NIK", "SPMO 2 NIK", "SPMO 3 NIK"}
+	for _, projectPhase := range phases {
+		headers = append(headers, calibratorHeader(projectPhase))
+	}
+	users, err := r.user.FindByNiks(columnValues(rows, headers...))
+	if err != nil {
+		return nil, err
+	}
+	findUser := func(nik string) *model.User {
+		return users[nik]
+	}
 
-	excelFile, err := file.Open()
+	existingCalibrations, err := r.repo.ListByProject(projectId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	existing := map[string]*model.Calibration{}
+	for i, calibration := range existingCalibrations {
+		existing[calibration.ProjectPhaseID+"/"+calibration.EmployeeID] = &existingCalibrations[i]
 	}
-	defer excelFile.Close()
 
-	xlsFile, err := excelize.OpenReader(excelFile)
+	// the eligibility rules of the project decide who of the sheet is calibrated
+	var employees []*model.User
+	for _, nik := range columnValues(rows, "Employee NIK") {
+		if employee := findUser(nik); employee != nil {
+			employees = append(employees, employee)
+		}
+	}
+	eligible, err := r.eligibility.Evaluate(projectId, employees)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	sheetName := xlsFile.GetSheetName(4)
-	rows := xlsFile.GetRows(sheetName)
+	actualScores, err := r.actualScore.FindByProjectId(projectId)
+	if err != nil {
+		return nil, err
+	}
+	scores := map[string]*model.ActualScore{}
+	for i, score := range actualScores {
+		scores[score.EmployeeID] = &actualScores[i]
+	}
 
+	niks := map[string]int{}
 	for i, row := range rows {
-		if i == 0 {
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
+			return nil, err
+		}
+
+		nik := row.Get("Employee NIK")
+		if previous, ok := niks[nik]; ok {
+			report.AddError(row.Number, "Employee NIK", nik, fmt.Sprintf("Duplicate of row %d", previous))
 			continue
 		}
+		niks[nik] = row.Number
 
-		var supervisorNIK string
-		lenProjectPhase := len(project.ProjectPhases)
-		employee, err := r.user.FindByNik(row[0])
-		if err != nil {
-			return fmt.Errorf("Employee NIK not available in database %s", row[0])
-		}
-		for j := lenProjectPhase; j > 0; j-- {
-			calibratorNik := row[j]
-			calibratorEs, exist := calibrators[calibratorNik]
-			fmt.Sprintln(calibratorNik != "None", " NIK: ", calibratorNik)
-			if calibratorNik != "None" {
-				var calibratorId string
-				if !exist {
-					// fmt.Println("Not exist")
-					calibratorUser, err := r.user.FindByNik(calibratorNik)
-					if err != nil {
-						return fmt.Errorf("Calibrator not available in database %s", calibratorNik)
-					} else {
-						calibrators[calibratorNik] = EmployeeSupervisor{
-							EmployeeID:    calibratorUser.ID,
-							SupervisorNIK: supervisorNIK,
-						}
-						calibratorId = calibratorUser.ID
-					}
-				} else {
-					calibratorId = calibratorEs.EmployeeID
-				}
+		employee := findUser(nik)
+		if employee == nil {
+			report.AddError(row.Number, "Employee NIK", nik, "Employee not found")
+			continue
+		}
 
-				spmo, err := r.user.FindByNik(row[lenProjectPhase+1])
-				if err != nil {
-					return fmt.Errorf("SPMO ID not available in database %s", row[lenProjectPhase+1])
+		switch result := eligible[employee.ID]; result.Action {
+		case model.EligibilityExclude:
+			report.AddWarning(row.Number, "Employee NIK", nik, fmt.Sprintf("Not eligible: %s", result.Reason))
+			// a rule alone never throws away calibration work, only an explicit override does
+			if !result.Overridden && calibratedInAnyPhase(existing, phases, employee.ID, scores[employee.ID]) {
+				report.AddError(row.Number, "Employee NIK", nik, "Already calibrated, exclude the employee with an override to remove the calibration")
+				continue
+			}
+			for _, projectPhase := range phases {
+				if existingCalibration := existing[projectPhase.ID+"/"+employee.ID]; existingCalibration != nil {
+					removed = append(removed, *existingCalibration)
+					report.AddRow(row.Number, fmt.Sprintf("%s phase %d", nik, projectPhase.Phase.Order), importer.ActionDelete)
 				}
+			}
+			continue
+		case model.EligibilityFlag:
+			report.AddWarning(row.Number, "Employee NIK", nik, fmt.Sprintf("Flagged for review: %s", result.Reason))
+		}
 
-				// hrbp, err := r.user.FindByNik(row[lenProjectPhase+2])
-				// if err != nil {
-				// 	return fmt.Errorf("HRBP ID not available in database %s", row[lenProjectPhase+2])
-				// }
-				score, err := r.actualScore.FindById(projectId, employee.ID)
-				if err != nil {
-					return fmt.Errorf("Employee %s doesn't have actual score inputted", row[0])
-				}
+		spmo := findUser(row.Get("SPMO NIK"))
+		if spmo == nil {
+			report.AddError(row.Number, "SPMO NIK", row.Get("SPMO NIK"), "SPMO not found")
+		}
 
-				cali := model.Calibration{
-					ProjectID:           projectId,
-					ProjectPhaseID:      phases[j-1],
-					EmployeeID:          employee.ID,
-					CalibratorID:        calibratorId,
-					SpmoID:              spmo.ID,
-					CalibrationRating:   score.ActualRating,
-					CalibrationScore:    score.ActualScore,
-					FilledTopBottomMark: true,
-					// HrbpID:         hrbp.ID,
-				}
+		var spmo2ID, spmo3ID *string
+		for header, spmoID := range map[string]**string{"SPMO 2 NIK": &spmo2ID, "SPMO 3 NIK": &spmo3ID} {
+			spmoNik := row.Get(header)
+			if spmoNik == "" || spmoNik == calibrationEmptyNik {
+				continue
+			}
+			if user := findUser(spmoNik); user != nil {
+				*spmoID = &user.ID
+			} else {
+				report.AddError(row.Number, header, spmoNik, "SPMO not found")
+			}
+		}
 
-				if row[lenProjectPhase+2] != "None" {
-					spmo2, err := r.user.FindByNik(row[lenProjectPhase+2])
-					if err != nil {
-						return fmt.Errorf("SPMO ID not available in database %s", row[lenProjectPhase+2])
-					}
-					if spmo2 != nil {
-						cali.Spmo2ID = &spmo2.ID
-					}
-				}
+		var employeeCalibrations []model.Calibration
+		action := importer.ActionCreate
+		for _, projectPhase := range phases {
+			header := calibratorHeader(projectPhase)
+			calibratorNik := row.Get(header)
 
-				if row[lenProjectPhase+3] != "None" {
-					spmo3, err := r.user.FindByNik(row[lenProjectPhase+3])
-					if err != nil {
-						return fmt.Errorf("SPMO ID not available in database %s", row[lenProjectPhase+3])
-					}
+			existingCalibration := existing[projectPhase.ID+"/"+employee.ID]
+			if existingCalibration != nil {
+				action = importer.ActionUpdate
+			}
 
-					if spmo3 != nil {
-						cali.Spmo3ID = &spmo3.ID
-					}
+			if calibratorNik == calibrationEmptyNik {
+				if existingCalibration != nil {
+					removed = append(removed, *existingCalibration)
+					report.AddRow(row.Number, fmt.Sprintf("%s phase %d", nik, projectPhase.Phase.Order), importer.ActionDelete)
 				}
+				continue
+			}
 
-				calibrations = append(calibrations, cali)
-			} else {
-				cal, _ := r.repo.Get(projectId, phases[j-1], employee.ID)
-				if cal != nil {
-					err := r.repo.DeleteCalibrationPhase(projectId, phases[j-1], employee.ID)
-					if err != nil {
-						return err
-					}
-				}
+			calibrator := findUser(calibratorNik)
+			if calibrator == nil {
+				report.AddError(row.Number, header, calibratorNik, "Calibrator not found")
+				continue
 			}
-			supervisorNIK = calibratorNik
+
+			employeeCalibrations = append(employeeCalibrations, model.Calibration{
+				ProjectID:           projectId,
+				ProjectPhaseID:      projectPhase.ID,
+				EmployeeID:          employee.ID,
+				CalibratorID:        calibrator.ID,
+				Spmo2ID:             spmo2ID,
+				Spmo3ID:             spmo3ID,
+				FilledTopBottomMark: true,
+			})
 		}
 
-		if calibrations[len(calibrations)-1].ProjectPhaseID == phaseOne.ID {
-			justificationType := returnRemarkType(calibrations[len(calibrations)-2].CalibrationRating, project.RemarkSettings)
-			calibrations[len(calibrations)-2].Status = "Calibrate"
-			calibrations[len(calibrations)-2].JustificationType = justificationType
-			if justificationType != "default" {
-				calibrations[len(calibrations)-2].FilledTopBottomMark = false
-				calibrations[len(calibrations)-1].FilledTopBottomMark = false
-			}
-			fmt.Println("=========================ISI TOP BOTTOMNYA=====================", justificationType, calibrations[len(calibrations)-2].FilledTopBottomMark)
-		} else {
-			justificationType := returnRemarkType(calibrations[len(calibrations)-1].CalibrationRating, project.RemarkSettings)
-			calibrations[len(calibrations)-1].Status = "Calibrate"
-			calibrations[len(calibrations)-1].JustificationType = justificationType
-			if justificationType != "default" {
-				calibrations[len(calibrations)-1].FilledTopBottomMark = false
+		if len(employeeCalibrations) == 0 {
+			continue
+		}
+
+		score := scores[employee.ID]
+		if score == nil {
+			report.AddError(row.Number, "Employee NIK", nik, "Employee doesn't have actual score inputted")
+			continue
+		}
+
+		for i := range employeeCalibrations {
+			employeeCalibrations[i].CalibrationRating = score.ActualRating
+			employeeCalibrations[i].CalibrationScore = score.ActualScore
+			if spmo != nil {
+				employeeCalibrations[i].SpmoID = spmo.ID
 			}
-			fmt.Println("=========================ISI TOP BOTTOMNYA=====================", justificationType, calibrations[len(calibrations)-1].FilledTopBottomMark)
 		}
+
+		// phase one is filled by the employee's manager, so calibration starts on the phase above it
+		last := len(employeeCalibrations) - 1
+		current := last
+		if employeeCalibrations[last].ProjectPhaseID == phaseOneID && last > 0 {
+			current = last - 1
+		}
+
+		justificationType := returnRemarkType(employeeCalibrations[current].CalibrationRating, project.RemarkSettings)
+		employeeCalibrations[current].Status = "Calibrate"
+		employeeCalibrations[current].JustificationType = justificationType
+		if justificationType != "default" {
+			employeeCalibrations[current].FilledTopBottomMark = false
+			employeeCalibrations[last].FilledTopBottomMark = false
+		}
+
+		calibrations = append(calibrations, employeeCalibrations...)
+		report.AddRow(row.Number, nik, action)
 	}
 
-	return r.repo.Bulksave(&calibrations)
+	if report.HasErrors() {
+		return report, report.Err()
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []model.EligibilityResult
+	for _, result := range eligible {
+		results = append(results, result)
+	}
+	err = r.repo.ImportCalibrations(&calibrations, removed, results)
+	if err != nil {
+		return nil, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// calibratedInAnyPhase tells whether an existing calibration of the employee carries a calibrator's work, a score or
+// rating other than the actual one it was imported with, a comment or a completed phase
+func calibratedInAnyPhase(existing map[string]*model.Calibration, phases []model.ProjectPhase, employeeID string, score *model.ActualScore) bool {
+	for _, projectPhase := range phases {
+		calibration := existing[projectPhase.ID+"/"+employeeID]
+		switch {
+		case calibration == nil:
+			continue
+		case calibration.Status == "Complete" || calibration.Comment != "":
+			return true
+		case score == nil:
+			if calibration.CalibrationScore != 0 || calibration.CalibrationRating != "" {
+				return true
+			}
+		case calibration.CalibrationScore != score.ActualScore || calibration.CalibrationRating != score.ActualRating:
+			return true
+		}
+	}
+	return false
 }
 
 func returnRemarkType(score string, projectRemark []model.RemarkSetting) string {
@@ -505,6 +585,9 @@ func (r *calibrationUsecase) SubmitCalibrations(calibratorID, projectID, busines
 	if err != nil {
 		return err
 	}
+	if err := r.lifecycle.CheckWritable(projectPhase.ID, calibratorID); err != nil {
+		return err
+	}
 
 	payload, err := r.project.FindCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID)
 	if err != nil {
@@ -567,6 +650,7 @@ func (r *calibrationUsecase) SubmitCalibrations(calibratorID, projectID, busines
 			if _, ok := nCalibrator[requestData.CalibratorID]; !ok {
 				nCalibrator[requestData.CalibratorID] = response.NotificationModel{
 					CalibratorID:           requestData.CalibratorID,
+					ProjectID:              projectID,
 					ProjectPhase:           requestData.ProjectPhase,
 					Deadline:               requestData.Deadline,
 					PreviousCalibrator:     calibrator.Name,
@@ -583,6 +667,7 @@ func (r *calibrationUsecase) SubmitCalibrations(calibratorID, projectID, busines
 
 		err = r.notification.NotifySubmittedCalibrationToNextCalibratorsWithoutReview(response.NotificationModel{
 			CalibratorID: calibratorID,
+			ProjectID:    projectID,
 			// PreviousCalibratorID: ,
 		})
 		if err != nil {
@@ -598,6 +683,10 @@ func (r *calibrationUsecase) SubmitCalibrations(calibratorID, projectID, busines
 }
 
 func (r *calibrationUsecase) SendCalibrationsToManager(calibratorID, projectID, prevCalibrator, businessUnit string) error {
+	if err := r.project.CheckEditable(projectID); err != nil {
+		return err
+	}
+
 	projectPhase, err := r.project.FindCalibratorPhase(calibratorID, projectID)
 	if err != nil {
 		return err
@@ -619,7 +708,7 @@ func (r *calibrationUsecase) SendCalibrationsToManager(calibratorID, projectID,
 	}
 
 	uniqueCalibrator := removeDuplicates(managerCalibratorIDs)
-	err = r.notification.NotifyManager(uniqueCalibrator, projectPhaseNew.EndDate)
+	err = r.notification.NotifyManager(uniqueCalibrator, projectID, projectPhaseNew.EndDate)
 	if err != nil {
 		return err
 	}
@@ -631,6 +720,9 @@ func (r *calibrationUsecase) SendBackCalibrationsToOnePhaseBefore(calibratorID,
 	if err != nil {
 		return err
 	}
+	if err := r.lifecycle.CheckWritable(projectPhase.ID, calibratorID); err != nil {
+		return err
+	}
 
 	projectData, err := r.project.FindCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID)
 	if err != nil {
@@ -649,43 +741,157 @@ func (r *calibrationUsecase) SendBackCalibrationsToOnePhaseBefore(calibratorID,
 	return nil
 }
 
-func (r *calibrationUsecase) SaveCalibrations(payload *request.CalibrationRequest) error {
+// SaveCalibrations checks the phase window for principalID, the authenticated calibrator or the one a delegate acts
+// for, so an extension or send-back of someone else never opens it
+func (r *calibrationUsecase) SaveCalibrations(payload *request.CalibrationRequest, principalID string) error {
+	checked := map[string]bool{}
+	for _, calibration := range payload.RequestData {
+		if checked[calibration.ProjectPhaseID] {
+			continue
+		}
+		if err := r.lifecycle.CheckWritable(calibration.ProjectPhaseID, principalID); err != nil {
+			return err
+		}
+		checked[calibration.ProjectPhaseID] = true
+	}
 	return r.repo.SaveChanges(payload)
 }
 
-func (r *calibrationUsecase) SaveCommentCalibration(payload *model.Calibration) error {
+func (r *calibrationUsecase) SaveCommentCalibration(payload *model.Calibration, principalID string) error {
+	if err := r.lifecycle.CheckWritable(payload.ProjectPhaseID, principalID); err != nil {
+		return err
+	}
 	return r.repo.SaveCommentCalibration(payload)
 }
 
-func (r *calibrationUsecase) SaveScoreAndRating(payload *model.Calibration) error {
+func (r *calibrationUsecase) SaveScoreAndRating(payload *model.Calibration, principalID string) error {
+	if err := r.lifecycle.CheckWritable(payload.ProjectPhaseID, principalID); err != nil {
+		return err
+	}
 	return r.repo.SaveScoreAndRating(payload)
 }
 
-func (r *calibrationUsecase) SpmoAcceptApproval(payload *request.AcceptJustification) error {
-	projectPhase, err := r.project.FindCalibratorPhase(payload.CalibratorID, payload.ProjectID)
+// Reassign moves employees to another calibrator or SPMO mid-project without touching their calibration data. The
+// phases must belong to the project and still be open for it, and both users or their delegates are emailed.
+func (r *calibrationUsecase) Reassign(payload *request.ReassignCalibrations, reassignedBy string) ([]model.CalibrationReassignment, error) {
+	if payload.FromID == "" || payload.ToID == "" {
+		return nil, fmt.Errorf("FromID and ToID are required")
+	}
+	if payload.FromID == payload.ToID {
+		return nil, fmt.Errorf("FromID and ToID must differ")
+	}
+	if len(payload.ProjectPhaseIDs) == 0 {
+		return nil, fmt.Errorf("ProjectPhaseIDs is required")
+	}
+	if err := r.project.CheckEditable(payload.ProjectID); err != nil {
+		return nil, err
+	}
+	if _, err := r.user.FindById(payload.ToID); err != nil {
+		return nil, fmt.Errorf("User %s Not Found", payload.ToID)
+	}
+
+	for _, projectPhaseID := range payload.ProjectPhaseIDs {
+		projectPhase, err := r.projectPhase.FindById(projectPhaseID)
+		if err != nil || projectPhase.ProjectID != payload.ProjectID {
+			return nil, fmt.Errorf("Project Phase Not Found")
+		}
+		if projectPhase.Status == model.PhaseClosed {
+			return nil, &PhaseWindowError{fmt.Sprintf("Phase %d is closed", projectPhase.Phase.Order)}
+		}
+	}
+
+	seen := map[string]bool{}
+	employeeIDs := payload.EmployeeIDs[:0]
+	for _, employeeID := range payload.EmployeeIDs {
+		if !seen[employeeID] {
+			seen[employeeID] = true
+			employeeIDs = append(employeeIDs, employeeID)
+		}
+	}
+	payload.EmployeeIDs = employeeIDs
+
+	reassignments, err := r.repo.Reassign(payload, reassignedBy)
 	if err != nil {
+		return nil, err
+	}
+
+	moved := 0
+	for _, reassignment := range reassignments {
+		moved += reassignment.EmployeeCount
+	}
+	if moved > 0 {
+		err = r.notification.NotifyReassignedCalibrations(payload.FromID, payload.ToID, payload.Role, payload.ProjectID, moved)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reassignments, nil
+}
+
+func (r *calibrationUsecase) SpmoAcceptApproval(payload *request.AcceptJustification, spmoID string, admin bool) error {
+	if err := r.project.CheckEditable(payload.ProjectID); err != nil {
 		return err
 	}
 
-	err = r.repo.AcceptCalibration(payload, projectPhase.Phase.Order)
+	result, err := r.repo.AcceptCalibration(payload, spmoID, admin)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.notifyNextSpmo([]response.ApprovalResult{*result})
 }
 
-func (r *calibrationUsecase) SpmoAcceptMultipleApproval(payload *request.AcceptMultipleJustification) error {
-	err := r.repo.AcceptMultipleCalibration(payload)
+func (r *calibrationUsecase) SpmoAcceptMultipleApproval(payload *request.AcceptMultipleJustification, spmoID string, admin bool) error {
+	if err := r.checkEditable(payload.ArrayOfAcceptsJustification); err != nil {
+		return err
+	}
+
+	results, err := r.repo.AcceptMultipleCalibration(payload, spmoID, admin)
 	if err != nil {
 		return err
 	}
 
-	ids := []string{}
-	for _, acceptJustification := range payload.ArrayOfAcceptsJustification {
-		ids = append(ids, acceptJustification.CalibratorID)
+	return r.notifyNextSpmo(results)
+}
+
+// notifyNextSpmo tells the SPMOs a sequential chain moved on to about the worksheets now waiting for them
+func (r *calibrationUsecase) notifyNextSpmo(results []response.ApprovalResult) error {
+	notified := map[string]bool{}
+	for _, result := range results {
+		key := result.CalibratorID + "/" + result.NextSpmoID
+		if result.NextSpmoID == "" || notified[key] {
+			continue
+		}
+		notified[key] = true
+
+		calibrator, err := r.user.FindById(result.CalibratorID)
+		if err != nil {
+			return err
+		}
+		spmo, err := r.user.FindById(result.NextSpmoID)
+		if err != nil {
+			return err
+		}
+		err = r.notification.NotifySubmittedCalibrationToSpmo(calibrator, []*model.User{spmo}, result.PhaseOrder, result.ProjectID)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
+// checkEditable looks up each project of a batch of justifications once
+func (r *calibrationUsecase) checkEditable(justifications []request.AcceptJustification) error {
+	checked := map[string]bool{}
+	for _, justification := range justifications {
+		if checked[justification.ProjectID] {
+			continue
+		}
+		if err := r.project.CheckEditable(justification.ProjectID); err != nil {
+			return err
+		}
+		checked[justification.ProjectID] = true
+	}
 	return nil
 }
 
@@ -701,11 +907,20 @@ func removeDuplicates(s []string) []string {
 	return result
 }
 
-func (r *calibrationUsecase) SpmoRejectApproval(payload *request.RejectJustification) error {
-	err := r.repo.RejectCalibration(payload)
+// SpmoRejectApproval records the rejection of the caller's level, the calibrator hears about it once the chain is
+// rejected
+func (r *calibrationUsecase) SpmoRejectApproval(payload *request.RejectJustification, spmoID string, admin bool) error {
+	if err := r.project.CheckEditable(payload.ProjectID); err != nil {
+		return err
+	}
+
+	result, err := r.repo.RejectCalibration(payload, spmoID, admin)
 	if err != nil {
 		return err
 	}
+	if result.Status != model.ApprovalRejected {
+		return r.notifyNextSpmo([]response.ApprovalResult{*result})
+	}
 
 	employee, err := r.user.FindById(payload.EmployeeID)
 	if err != nil {
@@ -714,7 +929,7 @@ func (r *calibrationUsecase) SpmoRejectApproval(payload *request.RejectJustifica
 
 	employeeName := fmt.Sprintf("%s(%s) - %s - %s", employee.Name, employee.Nik, employee.BusinessUnit.Name, employee.OrganizationUnit)
 
-	err = r.notification.NotifyRejectedCalibrationToCalibrator(payload.CalibratorID, employeeName, payload.Comment, payload.ProjectID)
+	err = r.notification.NotifyRejectedCalibrationToCalibrator(result.CalibratorID, employeeName, payload.Comment, payload.ProjectID)
 	if err != nil {
 		return err
 	}
@@ -722,6 +937,10 @@ func (r *calibrationUsecase) SpmoRejectApproval(payload *request.RejectJustifica
 }
 
 func (r *calibrationUsecase) SpmoSubmit(payload *request.AcceptMultipleJustification) error {
+	if err := r.checkEditable(payload.ArrayOfAcceptsJustification); err != nil {
+		return err
+	}
+
 	nextCalibrator, err := r.repo.SubmitReview(payload)
 	if err != nil {
 		return err
@@ -732,6 +951,7 @@ func (r *calibrationUsecase) SpmoSubmit(payload *request.AcceptMultipleJustifica
 		if _, ok := prevCalibrator[requestData.CalibratorID]; !ok {
 			prevCalibrator[requestData.CalibratorID] = response.NotificationModel{
 				CalibratorID: requestData.CalibratorID,
+				ProjectID:    requestData.ProjectID,
 			}
 		}
 	}
@@ -759,6 +979,15 @@ func (r *calibrationUsecase) FindSummaryCalibrationBySPMOID(spmoID, projectID st
 		return response.SummarySPMO{}, err
 	}
 
+	var calibratorIDs []string
+	for _, d := range results {
+		calibratorIDs = append(calibratorIDs, d.CalibratorID)
+	}
+	delegations, err := r.delegation.ActiveForCalibrators(calibratorIDs, projectID)
+	if err != nil {
+		return response.SummarySPMO{}, err
+	}
+
 	groupedData := make(map[string]*response.BUPerformanceSummarySPMO)
 	for _, d := range results {
 		key := d.BusinessUnitID
@@ -770,10 +999,14 @@ func (r *calibrationUsecase) FindSummaryCalibrationBySPMOID(spmoID, projectID st
 			Status:         "Pending",
 			LastLogin:      d.LastLogin,
 		}
+		if delegation, ok := delegations[d.CalibratorID]; ok {
+			calibratorSummary.Delegation = delegationSummary(&delegation)
+		}
 
 		phaseSummary := response.ProjectPhaseSummarySPMO{
 			ProjectPhaseID:     d.ProjectPhaseID,
 			Order:              d.Order,
+			SpmoLevel:          d.SpmoLevel,
 			CalibratorSummarys: []*response.CalibratorSummary{&calibratorSummary},
 		}
 
@@ -787,7 +1020,7 @@ func (r *calibrationUsecase) FindSummaryCalibrationBySPMOID(spmoID, projectID st
 			data := groupedData[key]
 			found := false
 			for _, existingPhase := range data.ProjectPhaseSummary {
-				if existingPhase.ProjectPhaseID == phaseSummary.ProjectPhaseID {
+				if existingPhase.ProjectPhaseID == phaseSummary.ProjectPhaseID && existingPhase.SpmoLevel == phaseSummary.SpmoLevel {
 					existingPhase.CalibratorSummarys = append(existingPhase.CalibratorSummarys, &calibratorSummary)
 					found = true
 					break
@@ -828,7 +1061,7 @@ func (r *calibrationUsecase) FindSummaryCalibrationBySPMOID(spmoID, projectID st
 				allSubmitted := true
 				for _, user := range data {
 					// fmt.Println("====================================u", user.CalibrationScores, len(user.CalibrationScores)-1, user.Name, user.Nik, projectPhase.Order)
-					lastCalibrationStatus := user.CalibrationScores[len(user.CalibrationScores)-1].SpmoStatus
+					lastCalibrationStatus := approvalStatus(&user.CalibrationScores[len(user.CalibrationScores)-1], projectPhase.SpmoLevel)
 					if lastCalibrationStatus == "Waiting" || (lastCalibrationStatus == "Accepted" && user.CalibrationScores[len(user.CalibrationScores)-1].JustificationReviewStatus == false) {
 						status = "Pending"
 						allSubmitted = allSubmitted && false
@@ -864,8 +1097,26 @@ func (r *calibrationUsecase) FindSummaryCalibrationBySPMOID(spmoID, projectID st
 	return summary, nil
 }
 
-func (r *calibrationUsecase) FindAllDetailCalibrationbySPMOID(spmoID, calibratorID, businessUnitID, department string, order int) ([]response.UserResponse, error) {
-	return r.repo.GetAllDetailCalibrationBySPMOID(spmoID, calibratorID, businessUnitID, department, order)
+// approvalStatus is the SPMO status of a calibration as one level of its chain sees it. A level that isn't up yet
+// shows as not submitted and a skipped level follows the chain.
+func approvalStatus(calibration *model.Calibration, level int) string {
+	for _, approval := range calibration.Approvals {
+		if approval.Level != level {
+			continue
+		}
+		switch approval.Status {
+		case model.ApprovalPending:
+			return "-"
+		case model.ApprovalSkipped:
+			return calibration.SpmoStatus
+		}
+		return approval.Status
+	}
+	return calibration.SpmoStatus
+}
+
+func (r *calibrationUsecase) FindAllDetailCalibrationbySPMOID(spmoID, calibratorID, businessUnitID, department, projectID string, order int) ([]response.UserResponse, error) {
+	return r.repo.GetAllDetailCalibrationBySPMOID(spmoID, calibratorID, businessUnitID, department, projectID, order)
 }
 
 func (r *calibrationUsecase) FindAllDetailCalibration2bySPMOID(spmoID, calibratorID, businessUnitID, projectID string, order int) ([]response.UserResponse, error) {
@@ -944,7 +1195,7 @@ func (r *calibrationUsecase) FindRatingQuotaSPMOByCalibratorID(spmoID, calibrato
 	return &responses, nil
 }
 
-func NewCalibrationUsecase(repo repository.CalibrationRepo, user UserUsecase, project ProjectUsecase, projectPhase ProjectPhaseUsecase, notification NotificationUsecase, actualScore ActualScoreUsecase) CalibrationUsecase {
+func NewCalibrationUsecase(repo repository.CalibrationRepo, user UserUsecase, project ProjectUsecase, projectPhase ProjectPhaseUsecase, notification NotificationUsecase, actualScore ActualScoreUsecase, lifecycle PhaseLifecycleUsecase, delegation DelegationUsecase, eligibility EligibilityUsecase) CalibrationUsecase {
 	return &calibrationUsecase{
 		repo:         repo,
 		user:         user,
@@ -952,5 +1203,8 @@ func NewCalibrationUsecase(repo repository.CalibrationRepo, user UserUsecase, pr
 		projectPhase: projectPhase,
 		notification: notification,
 		actualScore:  actualScore,
+		lifecycle:    lifecycle,
+		delegation:   delegation,
+		eligibility:  eligibility,
 	}
 }
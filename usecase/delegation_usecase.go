@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+type DelegationUsecase interface {
+	Create(payload *model.Delegation, actorID string, admin bool) error
+	FindById(id string) (*model.Delegation, error)
+	FindAll(calibratorID, delegateID string) ([]response.DelegationSummary, error)
+	FindByUser(userID string) (response.UserDelegations, error)
+	Revoke(id, actorID string, admin bool) error
+	FindActive(calibratorID, delegateID, projectID string) (*model.Delegation, error)
+	ActiveForCalibrators(calibratorIDs []string, projectID string) (map[string]model.Delegation, error)
+	ActingFor(delegateID, projectID string) ([]string, error)
+	Record(payload *model.DelegatedAction) error
+	FindActions(delegationID string) ([]model.DelegatedAction, error)
+}
+
+type delegationUsecase struct {
+	repo    repository.DelegationRepo
+	user    repository.UserRepo
+	project ProjectUsecase
+}
+
+// Create lets a calibrator hand their worksheets to a delegate for a date range, admins can do it for any calibrator
+func (u *delegationUsecase) Create(payload *model.Delegation, actorID string, admin bool) error {
+	if payload.CalibratorID == "" {
+		payload.CalibratorID = actorID
+	}
+	if payload.CalibratorID != actorID && !admin {
+		return fmt.Errorf("Only admins can delegate for another calibrator")
+	}
+	if payload.DelegateID == "" {
+		return fmt.Errorf("DelegateID is required")
+	}
+	if payload.DelegateID == payload.CalibratorID {
+		return fmt.Errorf("A calibrator can't delegate to themselves")
+	}
+	if payload.StartDate.IsZero() || payload.EndDate.IsZero() {
+		return fmt.Errorf("StartDate and EndDate are required")
+	}
+	if !payload.EndDate.After(payload.StartDate) {
+		return fmt.Errorf("EndDate must be after StartDate")
+	}
+	if !payload.EndDate.After(time.Now()) {
+		return fmt.Errorf("EndDate must be in the future")
+	}
+
+	if _, err := u.user.Get(payload.CalibratorID); err != nil {
+		return fmt.Errorf("Calibrator Not Found")
+	}
+	if _, err := u.user.Get(payload.DelegateID); err != nil {
+		return fmt.Errorf("Delegate Not Found")
+	}
+	if payload.ProjectID != nil && *payload.ProjectID == "" {
+		payload.ProjectID = nil
+	}
+	if payload.ProjectID != nil {
+		if _, err := u.project.FindById(*payload.ProjectID); err != nil {
+			return fmt.Errorf("Project Not Found")
+		}
+	}
+
+	payload.ID = ""
+	payload.CreatedBy = actorID
+	payload.RevokedAt = nil
+	payload.RevokedBy = ""
+	return u.repo.Create(payload)
+}
+
+func (u *delegationUsecase) FindById(id string) (*model.Delegation, error) {
+	return u.repo.Get(id)
+}
+
+func (u *delegationUsecase) FindAll(calibratorID, delegateID string) ([]response.DelegationSummary, error) {
+	delegations, err := u.repo.List(calibratorID, delegateID)
+	if err != nil {
+		return nil, err
+	}
+	return delegationSummaries(delegations), nil
+}
+
+func (u *delegationUsecase) FindByUser(userID string) (response.UserDelegations, error) {
+	given, err := u.repo.List(userID, "")
+	if err != nil {
+		return response.UserDelegations{}, err
+	}
+	received, err := u.repo.List("", userID)
+	if err != nil {
+		return response.UserDelegations{}, err
+	}
+	return response.UserDelegations{
+		Given:    delegationSummaries(given),
+		Received: delegationSummaries(received),
+	}, nil
+}
+
+// Revoke ends a delegation early, the calibrator, the delegate and admins can revoke it
+func (u *delegationUsecase) Revoke(id, actorID string, admin bool) error {
+	delegation, err := u.repo.Get(id)
+	if err != nil {
+		return fmt.Errorf("Delegation Not Found")
+	}
+	if actorID != delegation.CalibratorID && actorID != delegation.DelegateID && !admin {
+		return fmt.Errorf("Delegation Not Found")
+	}
+	return u.repo.Revoke(id, actorID, time.Now())
+}
+
+// FindActive returns the delegation that currently lets the delegate act for the calibrator in a project
+func (u *delegationUsecase) FindActive(calibratorID, delegateID, projectID string) (*model.Delegation, error) {
+	delegations, err := u.repo.ListActive([]string{calibratorID}, delegateID, projectID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(delegations) == 0 {
+		return nil, fmt.Errorf("No active delegation")
+	}
+	return &delegations[0], nil
+}
+
+// ActiveForCalibrators maps each of the calibrators that is currently delegating in a project to its delegation
+func (u *delegationUsecase) ActiveForCalibrators(calibratorIDs []string, projectID string) (map[string]model.Delegation, error) {
+	active := map[string]model.Delegation{}
+	if len(calibratorIDs) == 0 {
+		return active, nil
+	}
+	delegations, err := u.repo.ListActive(calibratorIDs, "", projectID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, delegation := range delegations {
+		if _, ok := active[delegation.CalibratorID]; !ok {
+			active[delegation.CalibratorID] = delegation
+		}
+	}
+	return active, nil
+}
+
+// ActingFor returns the calibrators a delegate currently stands in for in a project
+func (u *delegationUsecase) ActingFor(delegateID, projectID string) ([]string, error) {
+	delegations, err := u.repo.ListActive(nil, delegateID, projectID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	calibratorIDs := make([]string, 0, len(delegations))
+	for _, delegation := range delegations {
+		calibratorIDs = append(calibratorIDs, delegation.CalibratorID)
+	}
+	return calibratorIDs, nil
+}
+
+func (u *delegationUsecase) Record(payload *model.DelegatedAction) error {
+	return u.repo.SaveAction(payload)
+}
+
+func (u *delegationUsecase) FindActions(delegationID string) ([]model.DelegatedAction, error) {
+	return u.repo.ListActions(delegationID)
+}
+
+func delegationSummary(delegation *model.Delegation) *response.DelegationSummary {
+	return &response.DelegationSummary{
+		DelegationID:   delegation.ID,
+		CalibratorID:   delegation.CalibratorID,
+		CalibratorName: delegation.Calibrator.Name,
+		DelegateID:     delegation.DelegateID,
+		DelegateName:   delegation.Delegate.Name,
+		ProjectID:      delegation.ProjectID,
+		StartDate:      delegation.StartDate,
+		EndDate:        delegation.EndDate,
+		Reason:         delegation.Reason,
+		RevokedAt:      delegation.RevokedAt,
+	}
+}
+
+func delegationSummaries(delegations []model.Delegation) []response.DelegationSummary {
+	summaries := make([]response.DelegationSummary, 0, len(delegations))
+	for i := range delegations {
+		summaries = append(summaries, *delegationSummary(&delegations[i]))
+	}
+	return summaries
+}
+
+// delegateRecipients swaps calibrators that are delegating in a project for their delegates, so notifications reach
+// whoever is doing the work. A failed lookup keeps the calibrators.
+func delegateRecipients(delegation DelegationUsecase, calibratorIDs []string, projectID string) []string {
+	active, err := delegation.ActiveForCalibrators(calibratorIDs, projectID)
+	if err != nil {
+		log.Printf("Failed to look up delegations of project %s: %v", projectID, err)
+		return calibratorIDs
+	}
+	recipients := make([]string, len(calibratorIDs))
+	for i, calibratorID := range calibratorIDs {
+		recipients[i] = calibratorID
+		if delegated, ok := active[calibratorID]; ok {
+			recipients[i] = delegated.DelegateID
+		}
+	}
+	return recipients
+}
+
+func NewDelegationUsecase(repo repository.DelegationRepo, user repository.UserRepo, project ProjectUsecase) DelegationUsecase {
+	return &delegationUsecase{
+		repo:    repo,
+		user:    user,
+		project: project,
+	}
+}
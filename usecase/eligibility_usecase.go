@@ -0,0 +1,326 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+type EligibilityUsecase interface {
+	FindRules(projectID string) ([]model.EligibilityRule, error)
+	SaveRules(projectID string, rules []model.EligibilityRule) ([]model.EligibilityRule, error)
+	FindOverrides(projectID string) ([]model.EligibilityOverride, error)
+	Override(projectID string, payload *model.EligibilityOverride, userID string) error
+	RemoveOverride(projectID, employeeID string) error
+	Evaluate(projectID string, employees []*model.User) (map[string]model.EligibilityResult, error)
+	FindReport(projectID string) (*model.EligibilityReport, error)
+}
+
+// eligibilityDateLayout is how a join_date rule writes its cut-off
+const eligibilityDateLayout = "2006-01-02"
+
+// eligibilityOperators are the operators each field takes
+var eligibilityOperators = map[string][]string{
+	model.EligibilityJoinDate:      {"before", "after"},
+	model.EligibilityGrade:         {"eq", "neq", "in", "not_in"},
+	model.EligibilityBusinessUnit:  {"eq", "neq", "in", "not_in"},
+	model.EligibilityDepartment:    {"eq", "neq", "in", "not_in"},
+	model.EligibilityPosition:      {"eq", "neq", "in", "not_in"},
+	model.EligibilityScoringMethod: {"eq", "neq", "in", "not_in"},
+}
+
+type eligibilityUsecase struct {
+	repo    repository.EligibilityRepo
+	user    repository.UserRepo
+	project ProjectUsecase
+}
+
+func (u *eligibilityUsecase) FindRules(projectID string) ([]model.EligibilityRule, error) {
+	return u.repo.ListRules(projectID)
+}
+
+// SaveRules replaces the rules of a project, their order in the payload is the order they are evaluated in
+func (u *eligibilityUsecase) SaveRules(projectID string, rules []model.EligibilityRule) ([]model.EligibilityRule, error) {
+	if err := u.project.CheckEditable(projectID); err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		rule.ID = ""
+		rule.ProjectID = projectID
+		rule.Priority = i + 1
+		rule.Field = strings.ToLower(strings.TrimSpace(rule.Field))
+		rule.Operator = strings.ToLower(strings.TrimSpace(rule.Operator))
+		rule.Action = strings.ToLower(strings.TrimSpace(rule.Action))
+		rule.Value = strings.TrimSpace(rule.Value)
+
+		operators, ok := eligibilityOperators[rule.Field]
+		if !ok {
+			return nil, fmt.Errorf("Rule %d: field must be one of join_date, grade, business_unit, department, position or scoring_method", i+1)
+		}
+		valid := false
+		for _, operator := range operators {
+			if rule.Operator == operator {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("Rule %d: %s takes operator %s", i+1, rule.Field, strings.Join(operators, ", "))
+		}
+		if rule.Value == "" {
+			return nil, fmt.Errorf("Rule %d: value is required", i+1)
+		}
+		if rule.Field == model.EligibilityJoinDate {
+			if _, err := time.Parse(eligibilityDateLayout, rule.Value); err != nil {
+				return nil, fmt.Errorf("Rule %d: date must use format %s", i+1, eligibilityDateLayout)
+			}
+		}
+		switch rule.Action {
+		case model.EligibilityInclude, model.EligibilityExclude, model.EligibilityFlag:
+		default:
+			return nil, fmt.Errorf("Rule %d: action must be include, exclude or flag", i+1)
+		}
+	}
+
+	if err := u.repo.ReplaceRules(projectID, rules); err != nil {
+		return nil, err
+	}
+	return u.repo.ListRules(projectID)
+}
+
+func (u *eligibilityUsecase) FindOverrides(projectID string) ([]model.EligibilityOverride, error) {
+	return u.repo.ListOverrides(projectID)
+}
+
+// Override includes or excludes an employee at the next calibration import whatever the rules say, the reason is
+// kept for audit
+func (u *eligibilityUsecase) Override(projectID string, payload *model.EligibilityOverride, userID string) error {
+	if err := u.project.CheckEditable(projectID); err != nil {
+		return err
+	}
+
+	payload.Action = strings.ToLower(strings.TrimSpace(payload.Action))
+	if payload.Action != model.EligibilityInclude && payload.Action != model.EligibilityExclude {
+		return fmt.Errorf("Action must be include or exclude")
+	}
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if payload.Reason == "" {
+		return fmt.Errorf("Reason is required")
+	}
+	if _, err := u.user.Get(payload.EmployeeID); err != nil {
+		return fmt.Errorf("Employee Not Found")
+	}
+
+	payload.ProjectID = projectID
+	payload.CreatedBy = userID
+	return u.repo.SaveOverride(payload)
+}
+
+func (u *eligibilityUsecase) RemoveOverride(projectID, employeeID string) error {
+	if err := u.project.CheckEditable(projectID); err != nil {
+		return err
+	}
+	removed, err := u.repo.DeleteOverride(projectID, employeeID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("Override Not Found")
+	}
+	return nil
+}
+
+// Evaluate decides on each employee of an upload with the rules and overrides of the project, keyed by employee ID
+func (u *eligibilityUsecase) Evaluate(projectID string, employees []*model.User) (map[string]model.EligibilityResult, error) {
+	rules, err := u.repo.ListRules(projectID)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := u.repo.ListOverrides(projectID)
+	if err != nil {
+		return nil, err
+	}
+	byEmployee := indexOverrides(overrides)
+
+	now := time.Now()
+	results := map[string]model.EligibilityResult{}
+	for _, employee := range employees {
+		result := evaluateEligibility(employee, rules, byEmployee[employee.ID])
+		result.ProjectID = projectID
+		result.EvaluatedAt = now
+		results[employee.ID] = result
+	}
+	return results, nil
+}
+
+// FindReport re-evaluates every employee seen by a calibration import of the project with the current rules and
+// overrides, so the admin sees who a new upload would bring in or leave out
+func (u *eligibilityUsecase) FindReport(projectID string) (*model.EligibilityReport, error) {
+	if _, err := u.project.FindById(projectID); err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+
+	rules, err := u.repo.ListRules(projectID)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := u.repo.ListOverrides(projectID)
+	if err != nil {
+		return nil, err
+	}
+	byEmployee := indexOverrides(overrides)
+	imported, err := u.repo.ListResults(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var niks []string
+	for _, result := range imported {
+		niks = append(niks, result.EmployeeNik)
+	}
+	users, err := u.user.SearchByNiks(niks)
+	if err != nil {
+		return nil, err
+	}
+	employees := map[string]*model.User{}
+	for i := range users {
+		employees[users[i].ID] = &users[i]
+	}
+
+	report := &model.EligibilityReport{
+		ProjectID: projectID,
+		Rules:     rules,
+		Overrides: overrides,
+		Rows:      []model.EligibilityReportRow{},
+	}
+	for _, result := range imported {
+		row := model.EligibilityReportRow{
+			EligibilityResult: result,
+			ImportedAction:    result.Action,
+		}
+		if employee := employees[result.EmployeeID]; employee != nil {
+			row.EligibilityResult = evaluateEligibility(employee, rules, byEmployee[employee.ID])
+			row.ProjectID = projectID
+			row.EvaluatedAt = result.EvaluatedAt
+			if employee.BusinessUnitId != nil {
+				row.BusinessUnitID = *employee.BusinessUnitId
+			}
+			row.Grade = employee.Grade
+			row.Department = employee.Department
+			row.Position = employee.Position
+			row.ScoringMethod = employee.ScoringMethod
+			row.JoinDate = employee.JoinDate
+		}
+		row.Changed = row.Action != row.ImportedAction
+
+		switch row.Action {
+		case model.EligibilityExclude:
+			report.Excluded++
+		case model.EligibilityFlag:
+			report.Flagged++
+		default:
+			report.Included++
+		}
+		if row.Changed {
+			report.Changed++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	return report, nil
+}
+
+func indexOverrides(overrides []model.EligibilityOverride) map[string]*model.EligibilityOverride {
+	byEmployee := map[string]*model.EligibilityOverride{}
+	for i := range overrides {
+		byEmployee[overrides[i].EmployeeID] = &overrides[i]
+	}
+	return byEmployee
+}
+
+// evaluateEligibility applies the override of an employee if there is one, else the first rule that matches them
+func evaluateEligibility(employee *model.User, rules []model.EligibilityRule, override *model.EligibilityOverride) model.EligibilityResult {
+	result := model.EligibilityResult{
+		EmployeeID:   employee.ID,
+		EmployeeNik:  employee.Nik,
+		EmployeeName: employee.Name,
+		Action:       model.EligibilityInclude,
+	}
+	if override != nil {
+		result.Action = override.Action
+		result.Reason = override.Reason
+		result.Overridden = true
+		return result
+	}
+
+	for _, rule := range rules {
+		if !matchEligibilityRule(employee, rule) {
+			continue
+		}
+		ruleID := rule.ID
+		result.Action = rule.Action
+		result.RuleID = &ruleID
+		result.Reason = rule.Description
+		if result.Reason == "" {
+			result.Reason = fmt.Sprintf("%s %s %s", rule.Field, rule.Operator, rule.Value)
+		}
+		break
+	}
+	return result
+}
+
+func matchEligibilityRule(employee *model.User, rule model.EligibilityRule) bool {
+	if rule.Field == model.EligibilityJoinDate {
+		cutOff, err := time.Parse(eligibilityDateLayout, rule.Value)
+		if err != nil || employee.JoinDate.IsZero() {
+			return false
+		}
+		if rule.Operator == "before" {
+			return employee.JoinDate.Before(cutOff)
+		}
+		return employee.JoinDate.After(cutOff)
+	}
+
+	var value string
+	switch rule.Field {
+	case model.EligibilityGrade:
+		value = employee.Grade
+	case model.EligibilityBusinessUnit:
+		if employee.BusinessUnitId != nil {
+			value = *employee.BusinessUnitId
+		}
+	case model.EligibilityDepartment:
+		value = employee.Department
+	case model.EligibilityPosition:
+		value = employee.Position
+	case model.EligibilityScoringMethod:
+		value = employee.ScoringMethod
+	default:
+		return false
+	}
+
+	switch rule.Operator {
+	case "eq":
+		return strings.EqualFold(value, rule.Value)
+	case "neq":
+		return !strings.EqualFold(value, rule.Value)
+	}
+	found := false
+	for _, option := range strings.Split(rule.Value, ",") {
+		if strings.EqualFold(value, strings.TrimSpace(option)) {
+			found = true
+		}
+	}
+	return found == (rule.Operator == "in")
+}
+
+func NewEligibilityUsecase(repo repository.EligibilityRepo, user repository.UserRepo, project ProjectUsecase) EligibilityUsecase {
+	return &eligibilityUsecase{
+		repo:    repo,
+		user:    user,
+		project: project,
+	}
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+const reencryptBatchSize = 500
+
+type EncryptionUsecase interface {
+	Reencrypt() (map[string]int, error)
+}
+
+type encryptionUsecase struct {
+	repo repository.EncryptionRepo
+}
+
+// Reencrypt rewrites every encrypted column with the active key, run it after adding a key version or on legacy plaintext rows
+func (r *encryptionUsecase) Reencrypt() (map[string]int, error) {
+	result := map[string]int{}
+	for _, table := range model.EncryptedTables {
+		updated, err := r.repo.Reencrypt(table, reencryptBatchSize)
+		result[table.Name] = updated
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func NewEncryptionUsecase(repo repository.EncryptionRepo) EncryptionUsecase {
+	return &encryptionUsecase{
+		repo: repo,
+	}
+}
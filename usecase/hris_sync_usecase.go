@@ -0,0 +1,480 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+	"calibration-system.com/utils"
+	"calibration-system.com/utils/importer"
+)
+
+const (
+	hrisStatusActive   = "Active"
+	hrisStatusInactive = "Inactive"
+
+	hrisDateLayout = "2006-01-02"
+)
+
+// hrisSensitiveColumns are compared like any other column but their values are kept out of the change log
+var hrisSensitiveColumns = map[string]bool{
+	"Email":         true,
+	"Phone Number":  true,
+	"Date Of Birth": true,
+}
+
+type HrisSyncUsecase interface {
+	Submit(ctx context.Context, file io.Reader, mode, source, createdBy string) (*model.HrisSync, *importer.Report, error)
+	FindAll() ([]model.HrisSync, error)
+	FindById(id string) (*model.HrisSync, error)
+	Approve(id, reviewedBy string) (*model.HrisSync, error)
+	Reject(id, reviewedBy string) error
+}
+
+type hrisSyncUsecase struct {
+	repo repository.HrisSyncRepo
+	user repository.UserRepo
+	bu   BusinessUnitUsecase
+	org  OrgHierarchyUsecase
+}
+
+// Submit diffs the feed against the current employees and stores the result for review, nothing is applied yet.
+// A full feed lists every employee so anyone missing from it is a leaver, a delta feed only carries what changed.
+func (u *hrisSyncUsecase) Submit(ctx context.Context, file io.Reader, mode, source, createdBy string) (*model.HrisSync, *importer.Report, error) {
+	if mode == "" {
+		mode = model.HrisSyncDelta
+	}
+	if mode != model.HrisSyncFull && mode != model.HrisSyncDelta {
+		return nil, nil, fmt.Errorf("Mode must be %s or %s", model.HrisSyncFull, model.HrisSyncDelta)
+	}
+
+	report, rows, err := importer.Read(file, hrisSyncSchema, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mode == model.HrisSyncFull && len(rows) == 0 && !report.HasErrors() {
+		report.AddError(1, "", "", "A full sync needs at least one employee")
+	}
+
+	users, err := u.user.SearchAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	existingUsers := map[string]*model.User{}
+	for i := range users {
+		existingUsers[users[i].Nik] = &users[i]
+	}
+
+	feedNiks := map[string]int{}
+	for _, row := range rows {
+		if _, ok := feedNiks[row.Get("Employee NIK")]; !ok {
+			feedNiks[row.Get("Employee NIK")] = row.Number
+		}
+	}
+
+	columns := map[string]bool{}
+	for _, column := range report.Columns {
+		columns[column] = true
+	}
+
+	sync := model.HrisSync{
+		Mode:      mode,
+		Source:    source,
+		Status:    model.HrisSyncPending,
+		CreatedBy: createdBy,
+		TotalRows: len(rows),
+	}
+	businessUnits := map[string]bool{}
+	seen := map[string]int{}
+
+	for i, row := range rows {
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
+			return nil, nil, err
+		}
+
+		nik := row.Get("Employee NIK")
+		if previous, ok := seen[nik]; ok {
+			report.AddError(row.Number, "Employee NIK", nik, fmt.Sprintf("Duplicate of row %d", previous))
+			continue
+		}
+		seen[nik] = row.Number
+		existing := existingUsers[nik]
+
+		if row.Get("Employment Status") == hrisStatusInactive {
+			if existing != nil && existing.Active {
+				sync.Changes = append(sync.Changes, hrisLeaver(existing))
+				report.AddRow(row.Number, nik, importer.ActionDelete)
+			} else {
+				sync.Unchanged++
+			}
+			continue
+		}
+
+		record := hrisRecord(row, report.Columns)
+		if buId := record["Business Unit ID"]; buId != "" {
+			if _, ok := businessUnits[buId]; !ok {
+				_, err := u.bu.FindById(buId)
+				businessUnits[buId] = err == nil
+			}
+			if !businessUnits[buId] {
+				report.AddError(row.Number, "Business Unit ID", buId, "Business unit not found")
+			}
+		}
+		if supervisorNik := record["Supervisor NIK"]; supervisorNik != "" {
+			if supervisorNik == nik {
+				report.AddError(row.Number, "Supervisor NIK", supervisorNik, "Employee cannot supervise themselves")
+			} else if _, ok := feedNiks[supervisorNik]; !ok && existingUsers[supervisorNik] == nil {
+				report.AddError(row.Number, "Supervisor NIK", supervisorNik, "Supervisor not found")
+			}
+		}
+
+		if existing == nil || !existing.Active {
+			for _, header := range []string{"Name", "Email", "Business Unit ID"} {
+				if existing == nil && record[header] == "" {
+					report.AddError(row.Number, header, "", "Value is required for a new employee")
+				}
+			}
+			change, err := hrisJoiner(existing, nik, record)
+			if err != nil {
+				return nil, nil, err
+			}
+			sync.Changes = append(sync.Changes, change)
+			report.AddRow(row.Number, nik, importer.ActionCreate)
+			continue
+		}
+
+		fields := hrisFieldChanges(existing, record, columns)
+		if len(fields) == 0 {
+			sync.Unchanged++
+			continue
+		}
+		change, err := hrisUpdate(existing, record, fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		sync.Changes = append(sync.Changes, change)
+		report.AddRow(row.Number, nik, importer.ActionUpdate)
+	}
+
+	if mode == model.HrisSyncFull {
+		for i := range users {
+			if _, ok := feedNiks[users[i].Nik]; ok || !users[i].Active || users[i].Nik == "" || isAdmin(&users[i]) {
+				continue
+			}
+			sync.Changes = append(sync.Changes, hrisLeaver(&users[i]))
+		}
+	}
+
+	if report.HasErrors() {
+		return nil, report, report.Err()
+	}
+
+	sort.SliceStable(sync.Changes, func(i, j int) bool {
+		return sync.Changes[i].Nik < sync.Changes[j].Nik
+	})
+	for _, change := range sync.Changes {
+		switch change.Type {
+		case model.HrisChangeJoiner:
+			sync.Joiners++
+		case model.HrisChangeLeaver:
+			sync.Leavers++
+		case model.HrisChangeUpdate:
+			sync.Updates++
+		}
+		if change.Transfer {
+			sync.Transfers++
+		}
+		if change.SupervisorChange {
+			sync.SupervisorChanges++
+		}
+	}
+
+	if err := u.repo.Save(&sync); err != nil {
+		return nil, nil, err
+	}
+	return &sync, report, nil
+}
+
+func (u *hrisSyncUsecase) FindAll() ([]model.HrisSync, error) {
+	return u.repo.List(100)
+}
+
+func (u *hrisSyncUsecase) FindById(id string) (*model.HrisSync, error) {
+	return u.repo.Get(id)
+}
+
+// Approve applies a pending sync. Joiners get the default password like the user upload, rehired employees are reactivated.
+func (u *hrisSyncUsecase) Approve(id, reviewedBy string) (*model.HrisSync, error) {
+	sync, err := u.repo.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("Sync not found")
+	}
+	if sync.Status != model.HrisSyncPending {
+		return nil, fmt.Errorf("Sync has already been reviewed")
+	}
+
+	var niks []string
+	for _, change := range sync.Changes {
+		niks = append(niks, change.Nik)
+	}
+	users, err := u.user.SearchByNiks(niks)
+	if err != nil {
+		return nil, err
+	}
+	existingUsers := map[string]*model.User{}
+	for i := range users {
+		existingUsers[users[i].Nik] = &users[i]
+	}
+
+	password, err := utils.SaltPassword([]byte("password"))
+	if err != nil {
+		return nil, err
+	}
+
+	var joiners, updates []model.User
+	var leaverIDs []string
+	for _, change := range sync.Changes {
+		existing := existingUsers[change.Nik]
+		if change.Type == model.HrisChangeLeaver {
+			if existing != nil {
+				leaverIDs = append(leaverIDs, existing.ID)
+			}
+			continue
+		}
+
+		record := map[string]string{}
+		if err := json.Unmarshal(change.Record, &record); err != nil {
+			return nil, fmt.Errorf("Failed to read the change for %s: %v", change.Nik, err)
+		}
+
+		if change.EmployeeID == nil {
+			if existing != nil {
+				return nil, fmt.Errorf("Employee %s was added after this sync was computed, submit the feed again", change.Nik)
+			}
+			user := model.User{
+				Nik:           change.Nik,
+				Password:      password,
+				ScoringMethod: "Score",
+			}
+			if err := applyHrisRecord(&user, record); err != nil {
+				return nil, err
+			}
+			joiners = append(joiners, user)
+			continue
+		}
+
+		if existing == nil {
+			return nil, fmt.Errorf("Employee %s was removed after this sync was computed, submit the feed again", change.Nik)
+		}
+		if err := applyHrisRecord(existing, record); err != nil {
+			return nil, err
+		}
+		existing.Active = true
+		existing.LeftAt = nil
+		updates = append(updates, *existing)
+	}
+
+	sync.ReviewedBy = reviewedBy
+	if err := u.repo.Apply(sync, joiners, updates, leaverIDs); err != nil {
+		return nil, err
+	}
+	rebuildOrgHierarchy(u.org)
+	return sync, nil
+}
+
+func (u *hrisSyncUsecase) Reject(id, reviewedBy string) error {
+	return u.repo.Reject(id, reviewedBy)
+}
+
+// hrisRecord keeps the columns present in the feed, dates are normalized so they compare with the stored values
+func hrisRecord(row importer.Row, columns []string) map[string]string {
+	record := map[string]string{}
+	for _, header := range columns {
+		switch header {
+		case "Employee NIK", "Employment Status":
+			continue
+		case "Join Date", "Date Of Birth":
+			record[header] = formatHrisDate(row.Date(header))
+		default:
+			record[header] = row.Get(header)
+		}
+	}
+	return record
+}
+
+// hrisUserValues is the user as the feed columns see it
+func hrisUserValues(user *model.User) map[string]string {
+	var buId string
+	if user.BusinessUnitId != nil {
+		buId = *user.BusinessUnitId
+	}
+	return map[string]string{
+		"Name":              user.Name,
+		"Email":             user.Email,
+		"Join Date":         formatHrisDate(user.JoinDate),
+		"Date Of Birth":     formatHrisDate(user.DateOfBirth),
+		"Supervisor NIK":    user.SupervisorNik,
+		"Business Unit ID":  buId,
+		"Organization Unit": user.OrganizationUnit,
+		"Division":          user.Division,
+		"Directorate":       user.Directorate,
+		"Department":        user.Department,
+		"Position":          user.Position,
+		"Grade":             user.Grade,
+		"HRBP":              user.HRBP,
+		"Phone Number":      user.PhoneNumber,
+	}
+}
+
+func applyHrisRecord(user *model.User, record map[string]string) error {
+	for header, value := range record {
+		switch header {
+		case "Name":
+			user.Name = value
+		case "Email":
+			user.Email = value
+		case "Join Date", "Date Of Birth":
+			var date time.Time
+			if value != "" {
+				parsed, err := time.Parse(hrisDateLayout, value)
+				if err != nil {
+					return fmt.Errorf("Invalid %s for %s: %v", header, user.Nik, err)
+				}
+				date = parsed
+			}
+			if header == "Join Date" {
+				user.JoinDate = date
+			} else {
+				user.DateOfBirth = date
+			}
+		case "Supervisor NIK":
+			user.SupervisorNik = value
+		case "Business Unit ID":
+			buId := value
+			user.BusinessUnitId = &buId
+			if value == "" {
+				user.BusinessUnitId = nil
+			}
+		case "Organization Unit":
+			user.OrganizationUnit = value
+		case "Division":
+			user.Division = value
+		case "Directorate":
+			user.Directorate = value
+		case "Department":
+			user.Department = value
+		case "Position":
+			user.Position = value
+		case "Grade":
+			user.Grade = value
+		case "HRBP":
+			user.HRBP = value
+		case "Phone Number":
+			user.PhoneNumber = value
+		}
+	}
+	return nil
+}
+
+func hrisFieldChanges(user *model.User, record map[string]string, columns map[string]bool) []model.HrisFieldChange {
+	current := hrisUserValues(user)
+	var fields []model.HrisFieldChange
+	for _, column := range hrisSyncSchema.Columns {
+		value, ok := record[column.Header]
+		if !ok || !columns[column.Header] || value == current[column.Header] {
+			continue
+		}
+		if column.Header == "Email" && strings.EqualFold(value, current[column.Header]) {
+			continue
+		}
+
+		field := model.HrisFieldChange{Field: column.Header}
+		if !hrisSensitiveColumns[column.Header] {
+			field.From = current[column.Header]
+			field.To = value
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func hrisJoiner(existing *model.User, nik string, record map[string]string) (model.HrisSyncChange, error) {
+	change := model.HrisSyncChange{
+		Nik:  nik,
+		Name: record["Name"],
+		Type: model.HrisChangeJoiner,
+	}
+	if existing != nil {
+		change.EmployeeID = &existing.ID
+		if change.Name == "" {
+			change.Name = existing.Name
+		}
+	}
+
+	var err error
+	change.Record, err = json.Marshal(record)
+	return change, err
+}
+
+func hrisUpdate(existing *model.User, record map[string]string, fields []model.HrisFieldChange) (model.HrisSyncChange, error) {
+	change := model.HrisSyncChange{
+		EmployeeID: &existing.ID,
+		Nik:        existing.Nik,
+		Name:       existing.Name,
+		Type:       model.HrisChangeUpdate,
+		Fields:     fields,
+	}
+	for _, field := range fields {
+		switch field.Field {
+		case "Business Unit ID":
+			change.Transfer = true
+		case "Supervisor NIK":
+			change.SupervisorChange = true
+		}
+	}
+
+	var err error
+	change.Record, err = json.Marshal(record)
+	return change, err
+}
+
+func hrisLeaver(existing *model.User) model.HrisSyncChange {
+	return model.HrisSyncChange{
+		EmployeeID: &existing.ID,
+		Nik:        existing.Nik,
+		Name:       existing.Name,
+		Type:       model.HrisChangeLeaver,
+	}
+}
+
+func formatHrisDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(hrisDateLayout)
+}
+
+// isAdmin keeps admin accounts out of full sync leavers, they are often not HRIS employees
+func isAdmin(user *model.User) bool {
+	for _, role := range user.Roles {
+		if strings.EqualFold(role.Name, model.RoleAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+func NewHrisSyncUsecase(repo repository.HrisSyncRepo, user repository.UserRepo, bu BusinessUnitUsecase, org OrgHierarchyUsecase) HrisSyncUsecase {
+	return &hrisSyncUsecase{
+		repo: repo,
+		user: user,
+		bu:   bu,
+		org:  org,
+	}
+}
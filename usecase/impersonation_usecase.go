@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"calibration-system.com/delivery/api/request"
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+	"calibration-system.com/utils"
+)
+
+type ImpersonationUsecase interface {
+	Start(actorID, subjectID string) (*model.User, *model.User, error)
+	Record(payload *model.ImpersonationLog) error
+	FindLogs(actorID, subjectID string, param request.PaginationParam) ([]model.ImpersonationLog, response.Paging, error)
+}
+
+type impersonationUsecase struct {
+	repo repository.ImpersonationLogRepo
+	user UserUsecase
+}
+
+func (r *impersonationUsecase) Start(actorID, subjectID string) (*model.User, *model.User, error) {
+	if actorID == subjectID {
+		return nil, nil, fmt.Errorf("Cannot impersonate yourself")
+	}
+
+	actor, err := r.user.FindById(actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	isAdmin := false
+	for _, role := range actor.Roles {
+		if strings.EqualFold(role.Name, model.RoleAdmin) {
+			isAdmin = true
+			break
+		}
+	}
+	if !isAdmin {
+		return nil, nil, fmt.Errorf("Only admin can impersonate other user")
+	}
+
+	subject, err := r.user.FindById(subjectID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("User Not Found")
+	}
+	return actor, subject, nil
+}
+
+func (r *impersonationUsecase) Record(payload *model.ImpersonationLog) error {
+	return r.repo.Save(payload)
+}
+
+func (r *impersonationUsecase) FindLogs(actorID, subjectID string, param request.PaginationParam) ([]model.ImpersonationLog, response.Paging, error) {
+	paginationQuery := utils.GetPaginationParams(param)
+	return r.repo.PaginateList(actorID, subjectID, paginationQuery)
+}
+
+func NewImpersonationUsecase(repo repository.ImpersonationLogRepo, user UserUsecase) ImpersonationUsecase {
+	return &impersonationUsecase{
+		repo: repo,
+		user: user,
+	}
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"fmt"
+
+	"calibration-system.com/model"
+	"calibration-system.com/utils/importer"
+)
+
+// calibrationEmptyNik marks a phase the employee skips in the calibration upload
+const calibrationEmptyNik = "None"
+
+var userImportSchema = importer.Schema{
+	Kind: "users",
+	Columns: []importer.Column{
+		{Header: "Employee NIK", Aliases: []string{"NIK"}, Required: true},
+		{Header: "Name", Aliases: []string{"Employee Name"}, Required: true},
+		{Header: "Join Date", Type: importer.Date, Layouts: []string{"01-02-06", "2006-01-02"}, Description: "MM-DD-YY"},
+		{Header: "Supervisor NIK"},
+		{Header: "Business Unit ID", Required: true},
+		{Header: "Organization Unit"},
+		{Header: "Division"},
+		{Header: "Department"},
+		{Header: "Position"},
+		{Header: "Grade"},
+		{Header: "Email", Required: true},
+		{Header: "Phone Number"},
+		{Header: "Scoring Method", Description: "Defaults to Score"},
+		{Header: "Role"},
+	},
+}
+
+// hrisSyncSchema is the HRIS employee feed, a delta feed only needs the columns it changes
+var hrisSyncSchema = importer.Schema{
+	Kind: "hris-employees",
+	Columns: []importer.Column{
+		{Header: "Employee NIK", Aliases: []string{"NIK"}, Required: true},
+		{Header: "Name", Aliases: []string{"Employee Name"}},
+		{Header: "Email"},
+		{Header: "Join Date", Type: importer.Date, Description: "YYYY-MM-DD"},
+		{Header: "Date Of Birth", Type: importer.Date, Description: "YYYY-MM-DD"},
+		{Header: "Supervisor NIK"},
+		{Header: "Business Unit ID"},
+		{Header: "Organization Unit"},
+		{Header: "Division"},
+		{Header: "Directorate"},
+		{Header: "Department"},
+		{Header: "Position"},
+		{Header: "Grade"},
+		{Header: "HRBP"},
+		{Header: "Phone Number"},
+		{Header: "Employment Status", Options: []string{hrisStatusActive, hrisStatusInactive}, Description: "Defaults to Active, Inactive marks a leaver"},
+	},
+}
+
+var userPasswordImportSchema = importer.Schema{
+	Kind: "user-passwords",
+	Columns: []importer.Column{
+		{Header: "Employee NIK", Aliases: []string{"NIK"}, Required: true},
+		{Header: "Password", Required: true},
+	},
+}
+
+var actualScoreImportSchema = importer.Schema{
+	Kind: "actual-scores",
+	Columns: []importer.Column{
+		{Header: "Employee NIK", Aliases: []string{"NIK"}, Required: true},
+		{Header: "Y1 Rating", Options: model.Ratings},
+		{Header: "Y2 Rating", Options: model.Ratings},
+		{Header: "PTT Score", Type: importer.Float, Required: true},
+		{Header: "PAT Score", Type: importer.Float, Required: true},
+		{Header: "360 Score", Aliases: []string{"Score 360"}, Type: importer.Float, Required: true},
+		{Header: "Actual Score", Type: importer.Float, Required: true},
+		{Header: "Actual Rating", Options: model.Ratings, Required: true},
+	},
+}
+
+var ratingQuotaImportSchema = importer.Schema{
+	Kind: "rating-quotas",
+	Columns: []importer.Column{
+		{Header: "Business Unit ID", Required: true},
+		{Header: "A+ Quota", Type: importer.Float, Required: true, Description: "Percentage"},
+		{Header: "A Quota", Type: importer.Float, Required: true, Description: "Percentage"},
+		{Header: "B+ Quota", Type: importer.Float, Required: true, Description: "Percentage"},
+		{Header: "B Quota", Type: importer.Float, Required: true, Description: "Percentage"},
+		{Header: "C Quota", Type: importer.Float, Required: true, Description: "Percentage"},
+		{Header: "D Quota", Type: importer.Float, Required: true, Description: "Percentage"},
+		{Header: "Remaining", Options: model.Ratings, Description: "Rating that takes the rounding remainder"},
+		{Header: "Excess", Options: model.Ratings, Description: "Rating that gives up the rounding excess"},
+	},
+}
+
+var businessUnitImportSchema = importer.Schema{
+	Kind: "business-units",
+	Columns: []importer.Column{
+		{Header: "Business Unit ID", Required: true},
+		{Header: "Business Unit Name", Required: true},
+		{Header: "Group Business Unit Name", Aliases: []string{"Group Name"}, Required: true},
+		{Header: "Head NIK", Description: "Used by the calibrator chain generator"},
+		{Header: "SPMO NIK", Description: "Used by the calibrator chain generator"},
+		{Header: "SPMO 2 NIK"},
+		{Header: "SPMO 3 NIK"},
+	},
+}
+
+// calibrationImportSchema has one calibrator column per project phase, ordered by phase order
+func calibrationImportSchema(project *model.Project) importer.Schema {
+	columns := []importer.Column{
+		{Header: "Employee NIK", Aliases: []string{"NIK"}, Required: true},
+	}
+	for _, projectPhase := range project.ProjectPhases {
+		columns = append(columns, importer.Column{
+			Header:      calibratorHeader(projectPhase),
+			Required:    true,
+			Description: fmt.Sprintf("Calibrator NIK, fill %s when the employee skips this phase", calibrationEmptyNik),
+		})
+	}
+	columns = append(columns,
+		importer.Column{Header: "SPMO NIK", Required: true},
+		importer.Column{Header: "SPMO 2 NIK", Description: fmt.Sprintf("Fill %s when not needed", calibrationEmptyNik)},
+		importer.Column{Header: "SPMO 3 NIK", Description: fmt.Sprintf("Fill %s when not needed", calibrationEmptyNik)},
+	)
+
+	return importer.Schema{
+		Kind:    "calibrations",
+		Columns: columns,
+	}
+}
+
+func calibratorHeader(projectPhase model.ProjectPhase) string {
+	return fmt.Sprintf("Phase %d Calibrator NIK", projectPhase.Phase.Order)
+}
+
+// columnValues collects the given columns of every row so lookups can be batched before the rows are checked one by one
+func columnValues(rows []importer.Row, headers ...string) []string {
+	var values []string
+	for _, row := range rows {
+		for _, header := range headers {
+			if value := row.Get(header); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
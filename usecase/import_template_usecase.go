@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"bytes"
+	"fmt"
+
+	"calibration-system.com/utils/importer"
+)
+
+type ImportTemplateUsecase interface {
+	Generate(kind, projectID string) (*bytes.Buffer, string, error)
+}
+
+type importTemplateUsecase struct {
+	role         RoleUsecase
+	businessUnit BusinessUnitUsecase
+	project      ProjectUsecase
+}
+
+func (r *importTemplateUsecase) Generate(kind, projectID string) (*bytes.Buffer, string, error) {
+	var schema importer.Schema
+	lists := map[string][]string{}
+
+	switch kind {
+	case userImportSchema.Kind:
+		schema = userImportSchema
+		businessUnits, err := r.businessUnitIds()
+		if err != nil {
+			return nil, "", err
+		}
+		lists["Business Unit ID"] = businessUnits
+
+		roles, err := r.role.FindAll()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, role := range roles {
+			lists["Role"] = append(lists["Role"], role.Name)
+		}
+	case hrisSyncSchema.Kind:
+		schema = hrisSyncSchema
+		businessUnits, err := r.businessUnitIds()
+		if err != nil {
+			return nil, "", err
+		}
+		lists["Business Unit ID"] = businessUnits
+	case userPasswordImportSchema.Kind:
+		schema = userPasswordImportSchema
+	case actualScoreImportSchema.Kind:
+		schema = actualScoreImportSchema
+	case ratingQuotaImportSchema.Kind:
+		schema = ratingQuotaImportSchema
+		businessUnits, err := r.businessUnitIds()
+		if err != nil {
+			return nil, "", err
+		}
+		lists["Business Unit ID"] = businessUnits
+	case businessUnitImportSchema.Kind:
+		schema = businessUnitImportSchema
+	case "calibrations":
+		if projectID == "" {
+			return nil, "", fmt.Errorf("projectID is required for the calibrations template")
+		}
+		project, err := r.project.FindById(projectID)
+		if err != nil {
+			return nil, "", fmt.Errorf("Project Not Found")
+		}
+		schema = calibrationImportSchema(project)
+	default:
+		return nil, "", fmt.Errorf("Unknown import template %s", kind)
+	}
+
+	file, err := importer.Template(schema, lists)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, fmt.Sprintf("%s-template.xlsx", schema.Kind), nil
+}
+
+func (r *importTemplateUsecase) businessUnitIds() ([]string, error) {
+	businessUnits, err := r.businessUnit.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, businessUnit := range businessUnits {
+		ids = append(ids, businessUnit.ID)
+	}
+	return ids, nil
+}
+
+func NewImportTemplateUsecase(role RoleUsecase, businessUnit BusinessUnitUsecase, project ProjectUsecase) ImportTemplateUsecase {
+	return &importTemplateUsecase{
+		role:         role,
+		businessUnit: businessUnit,
+		project:      project,
+	}
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"calibration-system.com/model"
+	"calibration-system.com/utils/exporter"
+	"calibration-system.com/utils/importer"
+)
+
+type importFunc func(ctx context.Context, file io.Reader, payload map[string]string, dryRun bool) (*importer.Report, error)
+
+// ImportJobHandlers runs the excel uploads as jobs, the job payload carries the same fields as the upload form
+func ImportJobHandlers(
+	user UserUsecase,
+	actualScore ActualScoreUsecase,
+	ratingQuota RatingQuotaUsecase,
+	businessUnit BusinessUnitUsecase,
+	calibration CalibrationUsecase,
+) map[string]JobHandler {
+	return map[string]JobHandler{
+		model.JobImportUsers: importJob(func(ctx context.Context, file io.Reader, payload map[string]string, dryRun bool) (*importer.Report, error) {
+			return user.BulkInsert(ctx, file, dryRun, true)
+		}),
+		model.JobImportUserPasswords: importJob(func(ctx context.Context, file io.Reader, payload map[string]string, dryRun bool) (*importer.Report, error) {
+			return user.BulkChangePassword(ctx, file, dryRun)
+		}),
+		model.JobImportActualScores: importJob(func(ctx context.Context, file io.Reader, payload map[string]string, dryRun bool) (*importer.Report, error) {
+			return actualScore.BulkInsert(ctx, file, payload["projectID"], dryRun)
+		}),
+		model.JobImportRatingQuotas: importJob(func(ctx context.Context, file io.Reader, payload map[string]string, dryRun bool) (*importer.Report, error) {
+			return ratingQuota.BulkInsert(ctx, file, payload["projectID"], dryRun)
+		}),
+		model.JobImportBusinessUnits: importJob(func(ctx context.Context, file io.Reader, payload map[string]string, dryRun bool) (*importer.Report, error) {
+			return businessUnit.BulkInsert(ctx, file, dryRun)
+		}),
+		model.JobImportCalibrations: importJob(func(ctx context.Context, file io.Reader, payload map[string]string, dryRun bool) (*importer.Report, error) {
+			return calibration.BulkInsert(ctx, file, payload["projectID"], dryRun)
+		}),
+	}
+}
+
+// ReportJobHandlers builds the reports as jobs, the file is kept on the job until it expires. ?format= picks csv or json instead of the workbook
+func ReportJobHandlers(project ProjectUsecase) map[string]JobHandler {
+	return map[string]JobHandler{
+		model.JobReportCalibrations: func(ctx context.Context, job *model.Job) (*JobResult, error) {
+			payload := job.Payload
+			return reportJob(payload["format"], func(w io.Writer, format string) error {
+				if format == exporter.FormatXLSX {
+					return project.ReportCalibrations(w, payload["type"], payload["calibratorID"], payload["businessUnit"], payload["prevCalibrator"], payload["projectID"])
+				}
+				table, err := project.ReportCalibrationsTable(payload["type"], payload["calibratorID"], payload["businessUnit"], payload["prevCalibrator"], payload["projectID"])
+				if err != nil {
+					return err
+				}
+				return exporter.Write(w, format, table)
+			})
+		},
+		model.JobReportSummary: func(ctx context.Context, job *model.Job) (*JobResult, error) {
+			payload := job.Payload
+			return reportJob(payload["format"], func(w io.Writer, format string) error {
+				if format == exporter.FormatXLSX {
+					return project.SummaryReportCalibrations(w, payload["calibratorID"], payload["projectID"])
+				}
+				table, err := project.SummaryReportCalibrationsTable(payload["calibratorID"], payload["projectID"])
+				if err != nil {
+					return err
+				}
+				return exporter.Write(w, format, table)
+			})
+		},
+	}
+}
+
+func importJob(run importFunc) JobHandler {
+	return func(ctx context.Context, job *model.Job) (*JobResult, error) {
+		report, err := run(ctx, bytes.NewReader(job.Input), job.Payload, job.Payload["dryRun"] == "true")
+		if report == nil {
+			return nil, err
+		}
+		return &JobResult{Result: report}, err
+	}
+}
+
+func reportJob(format string, write func(w io.Writer, format string) error) (*JobResult, error) {
+	format, err := exporter.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var file bytes.Buffer
+	err = write(&file, format)
+	if err != nil {
+		return nil, err
+	}
+	return &JobResult{
+		Name: "report." + format,
+		File: file.Bytes(),
+	}, nil
+}
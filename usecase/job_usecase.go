@@ -0,0 +1,264 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"calibration-system.com/config"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+// JobHandler runs one job kind, the result is kept even when an error is returned so failed imports still carry their report
+type JobHandler func(ctx context.Context, job *model.Job) (*JobResult, error)
+
+type JobResult struct {
+	Result interface{}
+	Name   string
+	File   []byte
+}
+
+type JobUsecase interface {
+	Enqueue(kind, createdBy string, payload map[string]string, input []byte) (*model.Job, error)
+	FindById(id string) (*model.Job, error)
+	FindResult(id string) (*model.Job, error)
+	FindByCreator(createdBy string) ([]model.Job, error)
+	Cancel(id string) error
+	Run(ctx context.Context)
+}
+
+type jobUsecase struct {
+	repo     repository.JobRepo
+	handlers map[string]JobHandler
+	cfg      *config.Config
+}
+
+type progressKey struct{}
+
+type progressFunc func(percent int) error
+
+// trackProgress reports done/total to the job running the context and stops the caller once the job is cancelled.
+// Outside a job it only checks the context.
+func trackProgress(ctx context.Context, done, total int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	progress, ok := ctx.Value(progressKey{}).(progressFunc)
+	if !ok || total <= 0 {
+		return nil
+	}
+	return progress(done * 100 / total)
+}
+
+func (u *jobUsecase) Enqueue(kind, createdBy string, payload map[string]string, input []byte) (*model.Job, error) {
+	if _, ok := u.handlers[kind]; !ok {
+		return nil, fmt.Errorf("Unknown job kind %s", kind)
+	}
+
+	job := model.Job{
+		Kind:      kind,
+		Status:    model.JobQueued,
+		Payload:   payload,
+		Input:     input,
+		CreatedBy: createdBy,
+	}
+	err := u.repo.Save(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (u *jobUsecase) FindById(id string) (*model.Job, error) {
+	return u.repo.Get(id)
+}
+
+func (u *jobUsecase) FindResult(id string) (*model.Job, error) {
+	job, err := u.repo.GetResult(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(job.ResultFile) == 0 {
+		return nil, fmt.Errorf("Job has no result file")
+	}
+	return job, nil
+}
+
+func (u *jobUsecase) FindByCreator(createdBy string) ([]model.Job, error) {
+	return u.repo.ListByCreator(createdBy, 50)
+}
+
+func (u *jobUsecase) Cancel(id string) error {
+	return u.repo.Cancel(id, time.Now().Add(u.cfg.JobRetention))
+}
+
+// Run starts the worker pool and the janitor, it returns immediately and the workers stop with ctx
+func (u *jobUsecase) Run(ctx context.Context) {
+	if u.cfg.JobWorkers <= 0 {
+		return
+	}
+
+	for i := 0; i < u.cfg.JobWorkers; i++ {
+		go u.work(ctx)
+	}
+	go u.janitor(ctx)
+}
+
+func (u *jobUsecase) work(ctx context.Context) {
+	for {
+		job, err := u.repo.Claim()
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(u.cfg.JobPollInterval):
+			}
+			continue
+		}
+
+		u.process(ctx, job)
+	}
+}
+
+func (u *jobUsecase) process(ctx context.Context, job *model.Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	lastProgress := 0
+	cancelled := false
+	heartbeat := func(progress int) error {
+		cancelRequested, err := u.repo.UpdateProgress(job.ID, progress)
+		if err != nil {
+			log.Printf("Failed to update job %s progress: %v", job.ID, err)
+		}
+		if cancelRequested {
+			cancelled = true
+			cancel()
+		}
+		return jobCtx.Err()
+	}
+
+	progress := func(percent int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if percent <= lastProgress {
+			return jobCtx.Err()
+		}
+		lastProgress = percent
+		return heartbeat(percent)
+	}
+
+	// reports do not call trackProgress, the ticker keeps them from looking stale and still picks up cancellation
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(u.cfg.JobStaleAfter / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				heartbeat(lastProgress)
+				mu.Unlock()
+			}
+		}
+	}()
+
+	result, err := u.runHandler(context.WithValue(jobCtx, progressKey{}, progressFunc(progress)), job)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !cancelled && ctx.Err() != nil {
+		// shutting down, the janitor of another instance queues the job again once it looks stale
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(u.cfg.JobRetention)
+	job.Progress = lastProgress
+	job.FinishedAt = &now
+	job.ExpiresAt = &expiresAt
+	switch {
+	case cancelled:
+		job.Status = model.JobCancelled
+		job.Message = "Cancelled by request"
+	case err != nil:
+		job.Status = model.JobFailed
+		job.Message = err.Error()
+	default:
+		job.Status = model.JobSucceeded
+		job.Progress = 100
+	}
+
+	if result != nil {
+		job.Result = result.Result
+		if job.Status == model.JobSucceeded {
+			job.ResultName = result.Name
+			job.ResultFile = result.File
+		}
+	}
+
+	err = u.repo.Finish(job)
+	if err != nil {
+		log.Printf("Failed to finish job %s: %v", job.ID, err)
+	}
+}
+
+func (u *jobUsecase) runHandler(ctx context.Context, job *model.Job) (result *JobResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("Job panicked: %v", r)
+		}
+	}()
+
+	handler, ok := u.handlers[job.Kind]
+	if !ok {
+		return nil, fmt.Errorf("Unknown job kind %s", job.Kind)
+	}
+	return handler(ctx, job)
+}
+
+func (u *jobUsecase) janitor(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		requeued, err := u.repo.RequeueStale(now.Add(-u.cfg.JobStaleAfter))
+		if err != nil {
+			log.Printf("Failed to requeue stale jobs: %v", err)
+		} else if requeued > 0 {
+			log.Printf("Requeued %d stale job(s)", requeued)
+		}
+
+		_, err = u.repo.PurgeExpired(now)
+		if err != nil {
+			log.Printf("Failed to purge expired jobs: %v", err)
+		}
+	}
+}
+
+func NewJobUsecase(repo repository.JobRepo, handlers map[string]JobHandler, cfg *config.Config) JobUsecase {
+	return &jobUsecase{
+		repo:     repo,
+		handlers: handlers,
+		cfg:      cfg,
+	}
+}
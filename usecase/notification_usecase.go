@@ -1,17 +1,20 @@
 package usecase
 
 import (
+	"fmt"
+	"log"
 	"time"
 
 	"calibration-system.com/config"
 	"calibration-system.com/delivery/api/response"
 	"calibration-system.com/model"
 	"calibration-system.com/repository"
+	"calibration-system.com/utils"
 )
 
 type NotificationUsecase interface {
 	NotifyCalibrator(projectID string) error
-	NotifyManager(ids []string, deadline time.Time) error                                                                 // Send to Manager
+	NotifyManager(ids []string, projectID string, deadline time.Time) error                                               // Send to Manager
 	NotifyFirstCurrentCalibrators(data []response.NotificationModel) error                                                // First Send Calibrator on Click in Project Active
 	NotifyNextCalibrators(data []response.NotificationModel) error                                                        // From Previous Phase
 	NotifyApprovedCalibrationToCalibrators(data []response.NotificationModel) error                                       // Spmo Submit
@@ -19,13 +22,26 @@ type NotificationUsecase interface {
 	NotifyRejectedCalibrationToCalibrator(id, employee, comment, projectID string) error                                  // Spmo Reject
 	NotifySubmittedCalibrationToSpmo(calibrator *model.User, listOfSpmo []*model.User, phase int, projectID string) error // Spmo When Submit
 	NotifySendBackCalibrators(data []response.NotificationModel) error                                                    // Send Back Calibration
+	NotifyReassignedCalibrations(fromID, toID, role, projectID string, count int) error                                   // Reassign Calibration
+	NotifyMentionedInComment(userIDs []string, authorID, employeeID, projectID string) error                              // Comment Mention
 }
 
 type notificationUsecase struct {
-	repo     repository.NotificationRepo
-	employee UserUsecase
-	project  ProjectUsecase
-	cfg      config.Config
+	repo       repository.NotificationRepo
+	employee   UserUsecase
+	project    ProjectUsecase
+	delegation DelegationUsecase
+	cfg        config.Config
+}
+
+// routeToDelegates addresses each notification to the delegate of its calibrator while a delegation runs
+func (n *notificationUsecase) routeToDelegates(data []response.NotificationModel) []response.NotificationModel {
+	routed := make([]response.NotificationModel, len(data))
+	copy(routed, data)
+	for i := range routed {
+		routed[i].CalibratorID = delegateRecipients(n.delegation, []string{routed[i].CalibratorID}, routed[i].ProjectID)[0]
+	}
+	return routed
 }
 
 func (n *notificationUsecase) NotifyCalibrator(projectID string) error {
@@ -75,7 +91,10 @@ func (n *notificationUsecase) NotifyCalibrator(projectID string) error {
 	return nil
 }
 
-func (n *notificationUsecase) NotifyManager(ids []string, deadline time.Time) error {
+// NotifyManager tells the managers filling phase one of a project. Only delegations of that project reroute them, a
+// manager delegating in another cycle is still written to.
+func (n *notificationUsecase) NotifyManager(ids []string, projectID string, deadline time.Time) error {
+	ids = delegateRecipients(n.delegation, ids, projectID)
 	// for _, calibratorID := range ids {
 	// 	employee, err := n.employee.FindById(calibratorID)
 	// 	if err != nil {
@@ -107,6 +126,7 @@ func (n *notificationUsecase) NotifyManager(ids []string, deadline time.Time) er
 }
 
 func (n *notificationUsecase) NotifyApprovedCalibrationToCalibrators(data []response.NotificationModel) error {
+	data = n.routeToDelegates(data)
 	// for _, dataX := range data {
 	// 	user, err := n.employee.FindById(dataX.CalibratorID)
 	// 	if err != nil {
@@ -137,6 +157,7 @@ func (n *notificationUsecase) NotifyApprovedCalibrationToCalibrators(data []resp
 }
 
 func (n *notificationUsecase) NotifySubmittedCalibrationToNextCalibratorsWithoutReview(data response.NotificationModel) error {
+	data = n.routeToDelegates([]response.NotificationModel{data})[0]
 	// user, err := n.employee.FindById(data.CalibratorID)
 	// if err != nil {
 	// 	return err
@@ -163,6 +184,7 @@ func (n *notificationUsecase) NotifySubmittedCalibrationToNextCalibratorsWithout
 }
 
 func (n *notificationUsecase) NotifyNextCalibrators(data []response.NotificationModel) error {
+	data = n.routeToDelegates(data)
 	// for _, calibratorData := range data {
 	// 	employee, err := n.employee.FindById(calibratorData.CalibratorID)
 	// 	if err != nil {
@@ -207,6 +229,7 @@ func (n *notificationUsecase) NotifyNextCalibrators(data []response.Notification
 }
 
 func (n *notificationUsecase) NotifySendBackCalibrators(data []response.NotificationModel) error {
+	data = n.routeToDelegates(data)
 	// for _, calibratorData := range data {
 	// 	employee, err := n.employee.FindById(calibratorData.CalibratorID)
 	// 	if err != nil {
@@ -250,6 +273,7 @@ func (n *notificationUsecase) NotifySendBackCalibrators(data []response.Notifica
 }
 
 func (n *notificationUsecase) NotifyFirstCurrentCalibrators(data []response.NotificationModel) error {
+	data = n.routeToDelegates(data)
 	// for _, calibratorData := range data {
 	// 	employee, err := n.employee.FindById(calibratorData.CalibratorID)
 	// 	if err != nil {
@@ -283,6 +307,7 @@ func (n *notificationUsecase) NotifyFirstCurrentCalibrators(data []response.Noti
 }
 
 func (n *notificationUsecase) NotifyRejectedCalibrationToCalibrator(id, employee, comment, projectID string) error {
+	id = delegateRecipients(n.delegation, []string{id}, projectID)[0]
 	// user, err := n.employee.FindById(id)
 	// if err != nil {
 	// 	return err
@@ -342,11 +367,81 @@ func (n *notificationUsecase) NotifySubmittedCalibrationToSpmo(calibrator *model
 	return nil
 }
 
-func NewNotificationUsecase(repo repository.NotificationRepo, employee UserUsecase, project ProjectUsecase, cfg config.Config) NotificationUsecase {
+// NotifyReassignedCalibrations tells the previous and the new calibrator or SPMO, or their delegates, that employees
+// moved between them
+func (n *notificationUsecase) NotifyReassignedCalibrations(fromID, toID, role, projectID string, count int) error {
+	from, err := n.employee.FindById(fromID)
+	if err != nil {
+		return err
+	}
+	to, err := n.employee.FindById(toID)
+	if err != nil {
+		return err
+	}
+
+	for _, recipientID := range delegateRecipients(n.delegation, []string{fromID, toID}, projectID) {
+		user, err := n.employee.FindById(recipientID)
+		if err != nil {
+			return err
+		}
+
+		emailData := utils.EmailData{
+			URL:       fmt.Sprintf("%s/#/autologin/%s", n.cfg.FrontEndApi, user.AccessTokenGenerate),
+			FirstName: user.Name,
+			Subject:   "Reassigned Employee Performance Rating Calibration",
+			Comment:   fmt.Sprintf("%d employees were moved from %s to %s as %s.", count, from.Name, to.Name, role),
+		}
+
+		err = utils.SendMail([]string{user.Email}, &emailData, "./utils/templates", "reassignedCalibrationEmail.html", n.cfg.SMTPConfig)
+		if err != nil {
+			log.Printf("Failed to email reassignment to %s: %v", user.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// NotifyMentionedInComment emails the users mentioned in a comment on an employee
+func (n *notificationUsecase) NotifyMentionedInComment(userIDs []string, authorID, employeeID, projectID string) error {
+	author, err := n.employee.FindById(authorID)
+	if err != nil {
+		return err
+	}
+	employee, err := n.employee.FindById(employeeID)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		user, err := n.employee.FindById(userID)
+		if err != nil {
+			return err
+		}
+
+		emailData := utils.EmailData{
+			URL:          fmt.Sprintf("%s/#/autologin/%s", n.cfg.FrontEndApi, user.AccessTokenGenerate),
+			FirstName:    user.Name,
+			Subject:      "You Were Mentioned in an Employee Performance Rating Calibration",
+			Calibrator:   author.Name,
+			EmployeeName: employee.Name,
+			Comment:      fmt.Sprintf("%s mentioned you in the discussion on %s.", author.Name, employee.Name),
+		}
+
+		err = utils.SendMail([]string{user.Email}, &emailData, "./utils/templates", "commentMentionEmail.html", n.cfg.SMTPConfig)
+		if err != nil {
+			log.Printf("Failed to email mention to %s: %v", user.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func NewNotificationUsecase(repo repository.NotificationRepo, employee UserUsecase, project ProjectUsecase, delegation DelegationUsecase, cfg config.Config) NotificationUsecase {
 	return &notificationUsecase{
-		repo:     repo,
-		employee: employee,
-		project:  project,
-		cfg:      cfg,
+		repo:       repo,
+		employee:   employee,
+		project:    project,
+		delegation: delegation,
+		cfg:        cfg,
 	}
 }
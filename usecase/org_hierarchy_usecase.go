@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+type OrgHierarchyUsecase interface {
+	Rebuild() (*model.OrgHierarchyReport, error)
+	Report() (*model.OrgHierarchyReport, error)
+	FindSubordinates(employeeID string, depth int) ([]model.OrgNode, error)
+	FindManagers(employeeID string) ([]model.OrgNode, error)
+	FindSpan(employeeID string) (*model.OrgSpan, error)
+}
+
+type orgHierarchyUsecase struct {
+	repo repository.OrgHierarchyRepo
+}
+
+// Rebuild recomputes the closure table from the active employees' SupervisorNik, it runs after every user import
+func (u *orgHierarchyUsecase) Rebuild() (*model.OrgHierarchyReport, error) {
+	employees, err := u.repo.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, report := buildOrgPaths(employees)
+	if err := u.repo.Rebuild(paths); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Report checks the reporting lines for cycles, orphans and duplicate niks without touching the stored tree
+func (u *orgHierarchyUsecase) Report() (*model.OrgHierarchyReport, error) {
+	employees, err := u.repo.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+
+	_, report := buildOrgPaths(employees)
+	return report, nil
+}
+
+func (u *orgHierarchyUsecase) FindSubordinates(employeeID string, depth int) ([]model.OrgNode, error) {
+	return u.repo.Subordinates(employeeID, depth)
+}
+
+func (u *orgHierarchyUsecase) FindManagers(employeeID string) ([]model.OrgNode, error) {
+	return u.repo.Managers(employeeID)
+}
+
+func (u *orgHierarchyUsecase) FindSpan(employeeID string) (*model.OrgSpan, error) {
+	return u.repo.Span(employeeID)
+}
+
+// buildOrgPaths walks every employee up their reporting line. A walk stops at a root, at a supervisor nik that matches
+// no active employee (orphan) or when it comes back to an employee it already passed (cycle).
+func buildOrgPaths(employees []model.User) ([]model.OrgPath, *model.OrgHierarchyReport) {
+	report := &model.OrgHierarchyReport{
+		Employees: len(employees),
+		BuiltAt:   time.Now(),
+	}
+
+	byNik := map[string]*model.User{}
+	for i := range employees {
+		employee := &employees[i]
+		if employee.Nik == "" {
+			continue
+		}
+		if _, ok := byNik[employee.Nik]; ok {
+			report.Issues = append(report.Issues, orgIssue(model.OrgIssueDuplicate, employee))
+			continue
+		}
+		byNik[employee.Nik] = employee
+	}
+
+	var paths []model.OrgPath
+	cycles := map[string]bool{}
+	for i := range employees {
+		employee := &employees[i]
+		paths = append(paths, model.OrgPath{AncestorID: employee.ID, DescendantID: employee.ID})
+
+		visited := map[string]int{employee.ID: 0}
+		chain := []*model.User{employee}
+		current := employee
+		for depth := 1; ; depth++ {
+			if current.SupervisorNik == "" {
+				if current == employee {
+					report.Roots++
+				}
+				break
+			}
+
+			supervisor := byNik[current.SupervisorNik]
+			if supervisor == nil {
+				if current == employee {
+					report.Issues = append(report.Issues, orgIssue(model.OrgIssueOrphan, employee))
+				}
+				break
+			}
+
+			if start, ok := visited[supervisor.ID]; ok {
+				cycle := orgCycle(chain[start:])
+				if key := cycle[0]; !cycles[key] {
+					cycles[key] = true
+					issue := orgIssue(model.OrgIssueCycle, supervisor)
+					issue.Chain = cycle
+					report.Issues = append(report.Issues, issue)
+				}
+				break
+			}
+
+			paths = append(paths, model.OrgPath{AncestorID: supervisor.ID, DescendantID: employee.ID, Depth: depth})
+			if depth > report.MaxDepth {
+				report.MaxDepth = depth
+			}
+			visited[supervisor.ID] = depth
+			chain = append(chain, supervisor)
+			current = supervisor
+		}
+	}
+	report.Paths = len(paths)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		if report.Issues[i].Type != report.Issues[j].Type {
+			return report.Issues[i].Type < report.Issues[j].Type
+		}
+		return report.Issues[i].Nik < report.Issues[j].Nik
+	})
+	return paths, report
+}
+
+// orgCycle rotates the cycle to start at its smallest nik so every walk into it reports the same chain
+func orgCycle(members []*model.User) []string {
+	start := 0
+	for i := range members {
+		if members[i].Nik < members[start].Nik {
+			start = i
+		}
+	}
+
+	var niks []string
+	for i := range members {
+		niks = append(niks, members[(start+i)%len(members)].Nik)
+	}
+	return niks
+}
+
+func orgIssue(issueType string, employee *model.User) model.OrgIssue {
+	return model.OrgIssue{
+		Type:          issueType,
+		ID:            employee.ID,
+		Nik:           employee.Nik,
+		Name:          employee.Name,
+		SupervisorNik: employee.SupervisorNik,
+	}
+}
+
+// rebuildOrgHierarchy is called once users have been committed, a failed rebuild keeps the previous tree and is only logged
+func rebuildOrgHierarchy(org OrgHierarchyUsecase) {
+	if org == nil {
+		return
+	}
+	if _, err := org.Rebuild(); err != nil {
+		log.Printf("Failed to rebuild org hierarchy: %v", err)
+	}
+}
+
+func NewOrgHierarchyUsecase(repo repository.OrgHierarchyRepo) OrgHierarchyUsecase {
+	return &orgHierarchyUsecase{
+		repo: repo,
+	}
+}
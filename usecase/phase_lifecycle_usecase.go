@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"calibration-system.com/config"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+type PhaseLifecycleUsecase interface {
+	CheckWritable(projectPhaseID, calibratorID string) error
+	GrantExtension(projectPhaseID string, payload *model.PhaseExtension, grantedBy string) error
+	FindExtensions(projectPhaseID string) ([]model.PhaseExtension, error)
+	RunOnce(now time.Time) (*model.PhaseLifecycleRun, error)
+	Run(ctx context.Context)
+}
+
+// PhaseWindowError is returned for writes outside the phase window so handlers can tell it from a failure
+type PhaseWindowError struct {
+	Message string
+}
+
+func (e *PhaseWindowError) Error() string {
+	return e.Message
+}
+
+type phaseLifecycleUsecase struct {
+	repo         repository.PhaseLifecycleRepo
+	notification NotificationUsecase
+	cfg          *config.Config
+}
+
+// CheckWritable rejects calibration writes outside the phase window. After the window a calibrator can still write
+// with an extension or while a send-back to them is in time.
+func (u *phaseLifecycleUsecase) CheckWritable(projectPhaseID, calibratorID string) error {
+	projectPhase, err := u.repo.GetProjectPhase(projectPhaseID)
+	if err != nil {
+		return fmt.Errorf("Project phase not found")
+	}
+	if err := checkProjectOpen(&projectPhase.Project); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	opensAt, closesAt := phaseWindow(projectPhase)
+	if now.Before(opensAt) {
+		return &PhaseWindowError{fmt.Sprintf("Phase %d opens on %s", projectPhase.Phase.Order, formatPhaseDay(opensAt))}
+	}
+	if now.Before(closesAt) {
+		return nil
+	}
+
+	if calibratorID != "" {
+		sentBack, err := u.repo.HasOpenSendBack(projectPhaseID, calibratorID, now)
+		if err != nil {
+			return err
+		}
+		if sentBack {
+			return nil
+		}
+
+		extension, err := u.repo.LatestExtension(projectPhaseID, calibratorID)
+		if err != nil {
+			return err
+		}
+		if extension != nil && now.Before(extension.Deadline) {
+			return nil
+		}
+	}
+	return &PhaseWindowError{fmt.Sprintf("Phase %d closed on %s, ask an admin for a deadline extension", projectPhase.Phase.Order, formatPhaseDay(closesAt.Add(-time.Nanosecond)))}
+}
+
+func (u *phaseLifecycleUsecase) GrantExtension(projectPhaseID string, payload *model.PhaseExtension, grantedBy string) error {
+	if payload.CalibratorID == "" {
+		return fmt.Errorf("CalibratorID is required")
+	}
+	if payload.Reason == "" {
+		return fmt.Errorf("Reason is required")
+	}
+	if !payload.Deadline.After(time.Now()) {
+		return fmt.Errorf("Deadline must be in the future")
+	}
+	if _, err := u.repo.GetProjectPhase(projectPhaseID); err != nil {
+		return fmt.Errorf("Project phase not found")
+	}
+
+	payload.ID = ""
+	payload.ProjectPhaseID = projectPhaseID
+	payload.GrantedBy = grantedBy
+	return u.repo.SaveExtension(payload)
+}
+
+func (u *phaseLifecycleUsecase) FindExtensions(projectPhaseID string) ([]model.PhaseExtension, error) {
+	return u.repo.ListExtensions(projectPhaseID)
+}
+
+// RunOnce moves the phases of active projects to the status their dates call for and applies the send-back policy
+func (u *phaseLifecycleUsecase) RunOnce(now time.Time) (*model.PhaseLifecycleRun, error) {
+	projectPhases, err := u.repo.ListActivePhases()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &model.PhaseLifecycleRun{}
+	opened := map[string]bool{}
+	for _, projectPhase := range projectPhases {
+		status := phaseStatus(&projectPhase, now)
+		if status == projectPhase.Status {
+			continue
+		}
+		if err := u.repo.UpdateStatus(projectPhase.ID, status); err != nil {
+			return nil, err
+		}
+
+		switch status {
+		case model.PhaseOpen:
+			run.Opened++
+			opened[projectPhase.ProjectID] = true
+		case model.PhaseClosed:
+			run.Closed++
+		}
+	}
+
+	for projectID := range opened {
+		if err := u.notification.NotifyCalibrator(projectID); err != nil {
+			log.Printf("Failed to notify calibrators of project %s: %v", projectID, err)
+		}
+	}
+
+	run.AutoAdvanced, run.Escalations, err = u.repo.ResolveOverdueSendBacks(now)
+	if err != nil {
+		return nil, err
+	}
+	for _, escalation := range run.Escalations {
+		log.Printf("Send-back of employee %s to calibrator %s in project %s is overdue since %s", escalation.EmployeeID, escalation.CalibratorID, escalation.ProjectID, escalation.Deadline.Format(time.RFC3339))
+	}
+	return run, nil
+}
+
+func (u *phaseLifecycleUsecase) Run(ctx context.Context) {
+	if u.cfg.PhaseLifecycleInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(u.cfg.PhaseLifecycleInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := u.RunOnce(time.Now()); err != nil {
+				log.Printf("Failed to run phase lifecycle: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// phaseWindow opens at the start of the StartDate day and closes at the end of the EndDate day, both in the
+// project's timezone. The dates are stored without a timezone so their wall clock day is the calendar day, converting
+// them would move it by the project's offset.
+func phaseWindow(projectPhase *model.ProjectPhase) (time.Time, time.Time) {
+	location := projectLocation(projectPhase.Project.Timezone)
+	start := projectPhase.StartDate
+	end := projectPhase.EndDate
+	opensAt := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location)
+	closesAt := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, location)
+	return opensAt, closesAt
+}
+
+func phaseStatus(projectPhase *model.ProjectPhase, now time.Time) string {
+	opensAt, closesAt := phaseWindow(projectPhase)
+	switch {
+	case now.Before(opensAt):
+		return model.PhaseScheduled
+	case now.Before(closesAt):
+		return model.PhaseOpen
+	default:
+		return model.PhaseClosed
+	}
+}
+
+func projectLocation(timezone string) *time.Location {
+	if timezone == "" {
+		timezone = model.DefaultProjectTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Printf("Failed to load timezone %s: %v", timezone, err)
+		return time.UTC
+	}
+	return location
+}
+
+func formatPhaseDay(value time.Time) string {
+	return value.Format("02 January 2006 (MST)")
+}
+
+func NewPhaseLifecycleUsecase(repo repository.PhaseLifecycleRepo, notification NotificationUsecase, cfg *config.Config) PhaseLifecycleUsecase {
+	return &phaseLifecycleUsecase{
+		repo:         repo,
+		notification: notification,
+		cfg:          cfg,
+	}
+}
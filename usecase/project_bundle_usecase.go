@@ -0,0 +1,606 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+	"github.com/google/uuid"
+)
+
+const bundleManifest = "manifest.json"
+
+type ProjectBundleUsecase interface {
+	Export(w io.Writer, projectID, scope string) error
+	Import(ctx context.Context, file io.Reader, dryRun, skipConflicts bool) (*model.BundleImportReport, error)
+}
+
+type projectBundleUsecase struct {
+	repo    repository.ProjectBundleRepo
+	user    repository.UserRepo
+	phase   PhaseUsecase
+	bu      BusinessUnitUsecase
+	groupBu GroupBusinessUnitUsecase
+}
+
+// Export writes a zip with manifest.json first and the top remark evidence after it. The config scope leaves out
+// actual scores and calibrations so a configured project can be copied without employee data.
+func (u *projectBundleUsecase) Export(w io.Writer, projectID, scope string) error {
+	if scope == "" {
+		scope = model.BundleScopeFull
+	}
+	if scope != model.BundleScopeConfig && scope != model.BundleScopeFull {
+		return fmt.Errorf("Scope must be %s or %s", model.BundleScopeConfig, model.BundleScopeFull)
+	}
+
+	project, err := u.repo.Load(projectID, scope == model.BundleScopeFull)
+	if err != nil {
+		return fmt.Errorf("Project Not Found")
+	}
+
+	niks := map[string]string{}
+	if scope == model.BundleScopeFull {
+		users, err := u.user.SearchAll()
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			niks[user.ID] = user.Nik
+		}
+	}
+
+	bundle, attachments := projectBundle(project, scope, niks)
+	archive := zip.NewWriter(w)
+	manifest, err := archive.Create(bundleManifest)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bundle); err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(attachments) {
+		entry, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(attachments[name]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// Import always creates a new inactive project. Anything that cannot be matched in this environment is reported as a
+// conflict, skipConflicts imports the rest and leaves those rows out.
+func (u *projectBundleUsecase) Import(ctx context.Context, file io.Reader, dryRun, skipConflicts bool) (*model.BundleImportReport, error) {
+	input, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(input), int64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("File is not a project archive: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, entry := range archive.File {
+		files[entry.Name] = entry
+	}
+
+	var bundle model.ProjectBundle
+	if err := readZipJSON(files[bundleManifest], &bundle); err != nil {
+		return nil, err
+	}
+	if bundle.Version < 1 || bundle.Version > model.ProjectBundleVersion {
+		return nil, fmt.Errorf("Unsupported project archive version %d", bundle.Version)
+	}
+
+	report := &model.BundleImportReport{
+		Version: bundle.Version,
+		Scope:   bundle.Scope,
+		DryRun:  dryRun,
+	}
+	if count, err := u.repo.CountByName(bundle.Project.Name, bundle.Project.Year); err != nil {
+		return nil, err
+	} else if count > 0 {
+		report.AddConflict("Project", bundle.Project.Name, fmt.Sprintf("A project named %s already exists for %d", bundle.Project.Name, bundle.Project.Year))
+	}
+
+	project := model.Project{
+		BaseModel:        model.BaseModel{ID: uuid.New().String()},
+		Name:             bundle.Project.Name,
+		Year:             bundle.Project.Year,
+		APlusExcess:      bundle.Project.APlusExcess,
+		AExcess:          bundle.Project.AExcess,
+		BPlusExcess:      bundle.Project.BPlusExcess,
+		BExcess:          bundle.Project.BExcess,
+		CExcess:          bundle.Project.CExcess,
+		DExcess:          bundle.Project.DExcess,
+		Timezone:         bundle.Project.Timezone,
+		SendBackPolicy:   bundle.Project.SendBackPolicy,
+		ResultVisibility: bundle.Project.ResultVisibility,
+		AppealWindowDays: bundle.Project.AppealWindowDays,
+	}
+
+	phases, err := u.phase.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	phaseByOrder := map[int]string{}
+	for _, phase := range phases {
+		phaseByOrder[phase.Order] = phase.ID
+	}
+	projectPhaseByOrder := map[int]string{}
+	for _, phase := range bundle.Phases {
+		phaseID, ok := phaseByOrder[phase.Order]
+		if !ok {
+			report.AddConflict("Phases", strconv.Itoa(phase.Order), fmt.Sprintf("No phase with order %d", phase.Order))
+			report.Skipped++
+			continue
+		}
+		projectPhase := model.ProjectPhase{
+			BaseModel:  model.BaseModel{ID: uuid.New().String()},
+			PhaseID:    phaseID,
+			ProjectID:  project.ID,
+			ReviewSpmo: phase.ReviewSpmo,
+			SpmoChain:  phase.SpmoChain,
+			SpmoQuorum: phase.SpmoQuorum,
+			StartDate:  phase.StartDate,
+			EndDate:    phase.EndDate,
+			Guideline:  phase.Guideline,
+			ShowChart:  phase.ShowChart,
+		}
+		projectPhaseByOrder[phase.Order] = projectPhase.ID
+		project.ProjectPhases = append(project.ProjectPhases, projectPhase)
+	}
+
+	for _, setting := range bundle.RemarkSettings {
+		project.RemarkSettings = append(project.RemarkSettings, model.RemarkSetting{
+			ProjectID:         project.ID,
+			JustificationType: setting.JustificationType,
+			ScoringType:       setting.ScoringType,
+			Level:             setting.Level,
+			From:              setting.From,
+			To:                setting.To,
+		})
+	}
+
+	businessUnits, err := u.bu.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	buIDs := map[string]bool{}
+	for _, businessUnit := range businessUnits {
+		buIDs[businessUnit.ID] = true
+	}
+	for _, quota := range bundle.RatingQuotas {
+		if !buIDs[quota.BusinessUnitID] {
+			report.AddConflict("RatingQuotas", quota.BusinessUnitID, "Business unit not found")
+			report.Skipped++
+			continue
+		}
+		project.RatingQuotas = append(project.RatingQuotas, model.RatingQuota{
+			ProjectID:      project.ID,
+			BusinessUnitID: quota.BusinessUnitID,
+			APlusQuota:     quota.APlusQuota,
+			AQuota:         quota.AQuota,
+			BPlusQuota:     quota.BPlusQuota,
+			BQuota:         quota.BQuota,
+			CQuota:         quota.CQuota,
+			DQuota:         quota.DQuota,
+			Remaining:      quota.Remaining,
+			Excess:         quota.Excess,
+		})
+	}
+
+	groups, err := u.groupBu.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	groupByName := map[string]string{}
+	for _, group := range groups {
+		groupByName[group.GroupName] = group.ID
+	}
+	for _, distribution := range bundle.ScoreDistributions {
+		groupID, ok := groupByName[distribution.GroupBusinessUnitName]
+		if !ok {
+			report.AddConflict("ScoreDistributions", distribution.GroupBusinessUnitName, "Group business unit not found")
+			report.Skipped++
+			continue
+		}
+		project.ScoreDistributions = append(project.ScoreDistributions, model.ScoreDistribution{
+			ProjectID:           project.ID,
+			GroupBusinessUnitID: groupID,
+			APlusUpperLimit:     distribution.APlusUpperLimit,
+			APlusLowerLimit:     distribution.APlusLowerLimit,
+			AUpperLimit:         distribution.AUpperLimit,
+			ALowerLimit:         distribution.ALowerLimit,
+			BPlusUpperLimit:     distribution.BPlusUpperLimit,
+			BPlusLowerLimit:     distribution.BPlusLowerLimit,
+			BUpperLimit:         distribution.BUpperLimit,
+			BLowerLimit:         distribution.BLowerLimit,
+			CUpperLimit:         distribution.CUpperLimit,
+			CLowerLimit:         distribution.CLowerLimit,
+			DUpperLimit:         distribution.DUpperLimit,
+			DLowerLimit:         distribution.DLowerLimit,
+		})
+	}
+
+	var bottomRemarks []model.BottomRemark
+	var topRemarks []model.TopRemark
+	if len(bundle.ActualScores) > 0 || len(bundle.Calibrations) > 0 {
+		users, err := u.user.SearchAll()
+		if err != nil {
+			return nil, err
+		}
+		userByNik := map[string]string{}
+		for _, user := range users {
+			userByNik[user.Nik] = user.ID
+		}
+
+		for _, score := range bundle.ActualScores {
+			employeeID, ok := userByNik[score.EmployeeNik]
+			if !ok {
+				report.AddConflict("ActualScores", score.EmployeeNik, "Employee not found")
+				report.Skipped++
+				continue
+			}
+			project.ActualScores = append(project.ActualScores, model.ActualScore{
+				ProjectID:    project.ID,
+				EmployeeID:   employeeID,
+				ActualScore:  score.ActualScore,
+				ActualRating: score.ActualRating,
+				Y1Rating:     score.Y1Rating,
+				Y2Rating:     score.Y2Rating,
+				PTTScore:     score.PTTScore,
+				PATScore:     score.PATScore,
+				Score360:     score.Score360,
+			})
+		}
+
+		for i, calibration := range bundle.Calibrations {
+			if err := trackProgress(ctx, i, len(bundle.Calibrations)); err != nil {
+				return nil, err
+			}
+
+			key := fmt.Sprintf("%s phase %d", calibration.EmployeeNik, calibration.PhaseOrder)
+			projectPhaseID, ok := projectPhaseByOrder[calibration.PhaseOrder]
+			if !ok {
+				report.AddConflict("Calibrations", key, "Phase not in the project")
+				report.Skipped++
+				continue
+			}
+
+			ids := map[string]string{}
+			missing := ""
+			for field, nik := range map[string]string{
+				"Employee":   calibration.EmployeeNik,
+				"Calibrator": calibration.CalibratorNik,
+				"SPMO":       calibration.SpmoNik,
+				"SPMO 2":     calibration.Spmo2Nik,
+				"SPMO 3":     calibration.Spmo3Nik,
+			} {
+				if nik == "" {
+					continue
+				}
+				if id, ok := userByNik[nik]; ok {
+					ids[field] = id
+				} else if missing == "" || field < missing {
+					missing = field
+				}
+			}
+			if missing != "" {
+				report.AddConflict("Calibrations", key, fmt.Sprintf("%s not found", missing))
+				report.Skipped++
+				continue
+			}
+
+			project.Calibrations = append(project.Calibrations, model.Calibration{
+				ProjectID:                 project.ID,
+				ProjectPhaseID:            projectPhaseID,
+				EmployeeID:                ids["Employee"],
+				CalibratorID:              ids["Calibrator"],
+				SpmoID:                    ids["SPMO"],
+				Spmo2ID:                   optionalID(ids["SPMO 2"]),
+				Spmo3ID:                   optionalID(ids["SPMO 3"]),
+				CalibrationScore:          calibration.CalibrationScore,
+				CalibrationRating:         calibration.CalibrationRating,
+				Status:                    calibration.Status,
+				SpmoStatus:                calibration.SpmoStatus,
+				Comment:                   calibration.Comment,
+				SpmoComment:               calibration.SpmoComment,
+				JustificationType:         calibration.JustificationType,
+				JustificationReviewStatus: calibration.JustificationReviewStatus,
+				SendBackDeadline:          calibration.SendBackDeadline,
+				FilledTopBottomMark:       calibration.FilledTopBottomMark,
+				EmployeeLeft:              calibration.EmployeeLeft,
+			})
+
+			if remark := calibration.BottomRemark; remark != nil {
+				bottomRemarks = append(bottomRemarks, model.BottomRemark{
+					ProjectID:      project.ID,
+					EmployeeID:     ids["Employee"],
+					ProjectPhaseID: projectPhaseID,
+					LowPerformance: remark.LowPerformance,
+					Indisipliner:   remark.Indisipliner,
+					Attitude:       remark.Attitude,
+					WarningLetter:  remark.WarningLetter,
+				})
+			}
+			for _, remark := range calibration.TopRemarks {
+				topRemark := model.TopRemark{
+					ProjectID:      project.ID,
+					EmployeeID:     ids["Employee"],
+					ProjectPhaseID: projectPhaseID,
+					Initiative:     remark.Initiative,
+					Description:    remark.Description,
+					Result:         remark.Result,
+					StartDate:      remark.StartDate,
+					EndDate:        remark.EndDate,
+					Comment:        remark.Comment,
+					EvidenceName:   remark.EvidenceName,
+					IsProject:      remark.IsProject,
+					IsInitiative:   remark.IsInitiative,
+					EvidenceLink:   remark.EvidenceLink,
+				}
+				if remark.EvidenceFile != "" {
+					evidence, err := readZipFile(files[remark.EvidenceFile])
+					if err != nil {
+						report.AddConflict("Attachments", remark.EvidenceFile, err.Error())
+					} else {
+						topRemark.Evidence = evidence
+						report.Attachments++
+					}
+				}
+				topRemarks = append(topRemarks, topRemark)
+			}
+		}
+	}
+
+	report.Phases = len(project.ProjectPhases)
+	report.RatingQuotas = len(project.RatingQuotas)
+	report.Distributions = len(project.ScoreDistributions)
+	report.ActualScores = len(project.ActualScores)
+	report.Calibrations = len(project.Calibrations)
+
+	if len(report.Conflicts) > 0 && !skipConflicts {
+		return report, fmt.Errorf("%d conflict(s) found in the project archive, nothing was imported", len(report.Conflicts))
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.Import(&project, bottomRemarks, topRemarks); err != nil {
+		return nil, err
+	}
+	report.Committed = true
+	report.ProjectID = project.ID
+	return report, nil
+}
+
+// projectBundle maps a loaded project to the manifest, attachments are keyed by their path in the archive
+func projectBundle(project *model.Project, scope string, niks map[string]string) (*model.ProjectBundle, map[string][]byte) {
+	bundle := &model.ProjectBundle{
+		Version:    model.ProjectBundleVersion,
+		Scope:      scope,
+		ExportedAt: time.Now(),
+		Project: model.BundleProject{
+			Name:             project.Name,
+			Year:             project.Year,
+			APlusExcess:      project.APlusExcess,
+			AExcess:          project.AExcess,
+			BPlusExcess:      project.BPlusExcess,
+			BExcess:          project.BExcess,
+			CExcess:          project.CExcess,
+			DExcess:          project.DExcess,
+			Timezone:         project.Timezone,
+			SendBackPolicy:   project.SendBackPolicy,
+			ResultVisibility: project.ResultVisibility,
+			AppealWindowDays: project.AppealWindowDays,
+		},
+	}
+
+	phaseOrders := map[string]int{}
+	for _, projectPhase := range project.ProjectPhases {
+		phaseOrders[projectPhase.ID] = projectPhase.Phase.Order
+		bundle.Phases = append(bundle.Phases, model.BundlePhase{
+			Order:      projectPhase.Phase.Order,
+			Name:       projectPhase.Phase.Name,
+			ReviewSpmo: projectPhase.ReviewSpmo,
+			SpmoChain:  projectPhase.SpmoChain,
+			SpmoQuorum: projectPhase.SpmoQuorum,
+			StartDate:  projectPhase.StartDate,
+			EndDate:    projectPhase.EndDate,
+			Guideline:  projectPhase.Guideline,
+			ShowChart:  projectPhase.ShowChart,
+		})
+	}
+	sort.Slice(bundle.Phases, func(i, j int) bool {
+		return bundle.Phases[i].Order < bundle.Phases[j].Order
+	})
+
+	for _, setting := range project.RemarkSettings {
+		bundle.RemarkSettings = append(bundle.RemarkSettings, model.BundleRemarkSetting{
+			JustificationType: setting.JustificationType,
+			ScoringType:       setting.ScoringType,
+			Level:             setting.Level,
+			From:              setting.From,
+			To:                setting.To,
+		})
+	}
+
+	for _, quota := range project.RatingQuotas {
+		bundle.RatingQuotas = append(bundle.RatingQuotas, model.BundleRatingQuota{
+			BusinessUnitID: quota.BusinessUnitID,
+			APlusQuota:     quota.APlusQuota,
+			AQuota:         quota.AQuota,
+			BPlusQuota:     quota.BPlusQuota,
+			BQuota:         quota.BQuota,
+			CQuota:         quota.CQuota,
+			DQuota:         quota.DQuota,
+			Remaining:      quota.Remaining,
+			Excess:         quota.Excess,
+		})
+	}
+
+	for _, distribution := range project.ScoreDistributions {
+		bundle.ScoreDistributions = append(bundle.ScoreDistributions, model.BundleScoreDistribution{
+			GroupBusinessUnitName: distribution.GroupBusinessUnit.GroupName,
+			APlusUpperLimit:       distribution.APlusUpperLimit,
+			APlusLowerLimit:       distribution.APlusLowerLimit,
+			AUpperLimit:           distribution.AUpperLimit,
+			ALowerLimit:           distribution.ALowerLimit,
+			BPlusUpperLimit:       distribution.BPlusUpperLimit,
+			BPlusLowerLimit:       distribution.BPlusLowerLimit,
+			BUpperLimit:           distribution.BUpperLimit,
+			BLowerLimit:           distribution.BLowerLimit,
+			CUpperLimit:           distribution.CUpperLimit,
+			CLowerLimit:           distribution.CLowerLimit,
+			DUpperLimit:           distribution.DUpperLimit,
+			DLowerLimit:           distribution.DLowerLimit,
+		})
+	}
+
+	attachments := map[string][]byte{}
+	if scope != model.BundleScopeFull {
+		return bundle, attachments
+	}
+
+	for _, score := range project.ActualScores {
+		bundle.ActualScores = append(bundle.ActualScores, model.BundleActualScore{
+			EmployeeNik:  niks[score.EmployeeID],
+			ActualScore:  score.ActualScore,
+			ActualRating: score.ActualRating,
+			Y1Rating:     score.Y1Rating,
+			Y2Rating:     score.Y2Rating,
+			PTTScore:     score.PTTScore,
+			PATScore:     score.PATScore,
+			Score360:     score.Score360,
+		})
+	}
+
+	for _, calibration := range project.Calibrations {
+		entry := model.BundleCalibration{
+			EmployeeNik:               niks[calibration.EmployeeID],
+			PhaseOrder:                phaseOrders[calibration.ProjectPhaseID],
+			CalibratorNik:             niks[calibration.CalibratorID],
+			SpmoNik:                   niks[calibration.SpmoID],
+			CalibrationScore:          calibration.CalibrationScore,
+			CalibrationRating:         calibration.CalibrationRating,
+			Status:                    calibration.Status,
+			SpmoStatus:                calibration.SpmoStatus,
+			Comment:                   calibration.Comment,
+			SpmoComment:               calibration.SpmoComment,
+			JustificationType:         calibration.JustificationType,
+			JustificationReviewStatus: calibration.JustificationReviewStatus,
+			SendBackDeadline:          calibration.SendBackDeadline,
+			FilledTopBottomMark:       calibration.FilledTopBottomMark,
+			EmployeeLeft:              calibration.EmployeeLeft,
+		}
+		if calibration.Spmo2ID != nil {
+			entry.Spmo2Nik = niks[*calibration.Spmo2ID]
+		}
+		if calibration.Spmo3ID != nil {
+			entry.Spmo3Nik = niks[*calibration.Spmo3ID]
+		}
+
+		if remark := calibration.BottomRemark; remark.EmployeeID != "" {
+			entry.BottomRemark = &model.BundleBottomRemark{
+				LowPerformance: remark.LowPerformance,
+				Indisipliner:   remark.Indisipliner,
+				Attitude:       remark.Attitude,
+				WarningLetter:  remark.WarningLetter,
+			}
+		}
+		for _, remark := range calibration.TopRemarks {
+			topRemark := model.BundleTopRemark{
+				Initiative:   remark.Initiative,
+				Description:  remark.Description,
+				Result:       remark.Result,
+				StartDate:    remark.StartDate,
+				EndDate:      remark.EndDate,
+				Comment:      remark.Comment,
+				EvidenceName: remark.EvidenceName,
+				IsProject:    remark.IsProject,
+				IsInitiative: remark.IsInitiative,
+				EvidenceLink: remark.EvidenceLink,
+			}
+			if len(remark.Evidence) > 0 {
+				topRemark.EvidenceFile = fmt.Sprintf("evidence/%s/%s", remark.ID, path.Base("/"+remark.EvidenceName))
+				attachments[topRemark.EvidenceFile] = remark.Evidence
+			}
+			entry.TopRemarks = append(entry.TopRemarks, topRemark)
+		}
+		bundle.Calibrations = append(bundle.Calibrations, entry)
+	}
+	sort.SliceStable(bundle.Calibrations, func(i, j int) bool {
+		if bundle.Calibrations[i].EmployeeNik != bundle.Calibrations[j].EmployeeNik {
+			return bundle.Calibrations[i].EmployeeNik < bundle.Calibrations[j].EmployeeNik
+		}
+		return bundle.Calibrations[i].PhaseOrder < bundle.Calibrations[j].PhaseOrder
+	})
+	return bundle, attachments
+}
+
+func readZipJSON(file *zip.File, payload interface{}) error {
+	content, err := readZipFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, payload); err != nil {
+		return fmt.Errorf("Invalid %s: %v", file.Name, err)
+	}
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	if file == nil {
+		return nil, fmt.Errorf("File is missing from the project archive")
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func optionalID(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
+
+func sortedKeys(values map[string][]byte) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func NewProjectBundleUsecase(repo repository.ProjectBundleRepo, user repository.UserRepo, phase PhaseUsecase, bu BusinessUnitUsecase, groupBu GroupBusinessUnitUsecase) ProjectBundleUsecase {
+	return &projectBundleUsecase{
+		repo:    repo,
+		user:    user,
+		phase:   phase,
+		bu:      bu,
+		groupBu: groupBu,
+	}
+}
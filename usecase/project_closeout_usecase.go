@@ -0,0 +1,317 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+)
+
+type ProjectCloseOutUsecase interface {
+	FindReport(projectID string) (*model.ProjectCloseOutReport, error)
+	Start(projectID string) (*model.ProjectCloseOutReport, error)
+	SignOff(projectID string, payload *model.ProjectSignOff, userID string) error
+	Cancel(projectID string) error
+	Finalize(projectID string) (*model.ProjectCloseOutReport, error)
+	FindFinalResults(projectID string) ([]model.FinalResult, error)
+	FindEmployeeResults(employeeID string) ([]response.EmployeeResult, error)
+}
+
+// ProjectFrozenError is returned for calibration writes to a project in close-out so handlers can tell it from a
+// failure
+type ProjectFrozenError struct {
+	Message string
+}
+
+func (e *ProjectFrozenError) Error() string {
+	return e.Message
+}
+
+func checkProjectOpen(project *model.Project) error {
+	switch project.Status {
+	case "", model.ProjectOpen:
+		return nil
+	case model.ProjectClosing:
+		return &ProjectFrozenError{fmt.Sprintf("Project %s is being closed out, cancel the close-out to change its calibrations", project.Name)}
+	default:
+		return &ProjectFrozenError{fmt.Sprintf("Project %s is closed, its calibrations are read-only", project.Name)}
+	}
+}
+
+type projectCloseOutUsecase struct {
+	repo repository.ProjectCloseOutRepo
+	user repository.UserRepo
+}
+
+// FindReport lists the employees whose last calibration is not complete, or not accepted where the phase is reviewed
+// by SPMO, and the sign-off of every business unit in the project
+func (u *projectCloseOutUsecase) FindReport(projectID string) (*model.ProjectCloseOutReport, error) {
+	project, err := u.repo.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+	report, _, err := u.report(project)
+	return report, err
+}
+
+// Start freezes the calibrations of a project that is ready so the business unit heads sign off on final data
+func (u *projectCloseOutUsecase) Start(projectID string) (*model.ProjectCloseOutReport, error) {
+	project, err := u.repo.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+	if err := checkProjectOpen(project); err != nil {
+		return nil, err
+	}
+
+	report, _, err := u.report(project)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Pending) > 0 {
+		return report, fmt.Errorf("%d employee(s) have no accepted final calibration", len(report.Pending))
+	}
+
+	if err := u.repo.UpdateStatus(projectID, model.ProjectOpen, model.ProjectClosing); err != nil {
+		return nil, err
+	}
+	report.Status = model.ProjectClosing
+	return report, nil
+}
+
+func (u *projectCloseOutUsecase) SignOff(projectID string, payload *model.ProjectSignOff, userID string) error {
+	project, err := u.repo.GetProject(projectID)
+	if err != nil {
+		return fmt.Errorf("Project Not Found")
+	}
+	if project.Status != model.ProjectClosing {
+		return fmt.Errorf("Project %s is not being closed out", project.Name)
+	}
+
+	report, _, err := u.report(project)
+	if err != nil {
+		return err
+	}
+	var signOff *model.CloseOutSignOff
+	for i := range report.SignOffs {
+		if report.SignOffs[i].BusinessUnitID == payload.BusinessUnitID {
+			signOff = &report.SignOffs[i]
+		}
+	}
+	if signOff == nil {
+		return fmt.Errorf("Business unit %s has no employees in project %s", payload.BusinessUnitID, project.Name)
+	}
+	if signOff.HeadNik == "" {
+		return fmt.Errorf("Business unit %s has no head, set its HeadNik first", signOff.BusinessUnitName)
+	}
+
+	user, err := u.user.Get(userID)
+	if err != nil {
+		return fmt.Errorf("User Not Found")
+	}
+	if user.Nik != signOff.HeadNik {
+		return fmt.Errorf("Only the head of %s can sign off its ratings", signOff.BusinessUnitName)
+	}
+
+	payload.ID = ""
+	payload.ProjectID = projectID
+	payload.SignedBy = userID
+	payload.SignedAt = time.Now()
+	return u.repo.SaveSignOff(payload)
+}
+
+func (u *projectCloseOutUsecase) Cancel(projectID string) error {
+	if _, err := u.repo.GetProject(projectID); err != nil {
+		return fmt.Errorf("Project Not Found")
+	}
+	return u.repo.Cancel(projectID)
+}
+
+// Finalize publishes the final ratings once every business unit signed off, the project is closed afterwards
+func (u *projectCloseOutUsecase) Finalize(projectID string) (*model.ProjectCloseOutReport, error) {
+	project, err := u.repo.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+	if project.Status != model.ProjectClosing {
+		return nil, fmt.Errorf("Project %s is not being closed out", project.Name)
+	}
+
+	report, rows, err := u.report(project)
+	if err != nil {
+		return nil, err
+	}
+	if !report.CanFinalize {
+		return report, fmt.Errorf("Project %s is not ready to be finalized", project.Name)
+	}
+
+	results := make([]model.FinalResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, model.FinalResult{
+			ProjectID:      projectID,
+			EmployeeID:     row.EmployeeID,
+			EmployeeNik:    row.EmployeeNik,
+			EmployeeName:   row.EmployeeName,
+			BusinessUnitID: row.BusinessUnitID,
+			ActualScore:    row.ActualScore,
+			ActualRating:   row.ActualRating,
+			PTTScore:       row.PTTScore,
+			PATScore:       row.PATScore,
+			Score360:       row.Score360,
+			Y1Rating:       row.Y1Rating,
+			Y2Rating:       row.Y2Rating,
+			FinalScore:     row.CalibrationScore,
+			FinalRating:    row.CalibrationRating,
+			FinalPhase:     row.PhaseOrder,
+			CalibratorID:   row.CalibratorID,
+		})
+	}
+
+	if err := u.repo.Finalize(projectID, results); err != nil {
+		return nil, err
+	}
+	report.Status = model.ProjectClosed
+	report.CanFinalize = false
+	return report, nil
+}
+
+func (u *projectCloseOutUsecase) FindFinalResults(projectID string) ([]model.FinalResult, error) {
+	if _, err := u.repo.GetProject(projectID); err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+	return u.repo.ListFinalResults(projectID)
+}
+
+// FindEmployeeResults shows an employee their released results, calibrations are never read so interim ratings stay
+// hidden
+func (u *projectCloseOutUsecase) FindEmployeeResults(employeeID string) ([]response.EmployeeResult, error) {
+	results, err := u.repo.ListEmployeeResults(employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	employeeResults := []response.EmployeeResult{}
+	for i := range results {
+		result := &results[i]
+		visibility := result.Project.ResultVisibility
+		if releasedAt := resultsReleasedAt(&result.Project); releasedAt == nil || now.Before(*releasedAt) {
+			continue
+		}
+
+		employeeResult := response.EmployeeResult{
+			ProjectID:   result.ProjectID,
+			ProjectName: result.Project.Name,
+			Year:        result.Project.Year,
+			PublishedAt: result.PublishedAt,
+			FinalRating: result.FinalRating,
+		}
+		if !visibility.HideScore {
+			employeeResult.FinalScore = &result.FinalScore
+		}
+		if !visibility.HideComponents {
+			employeeResult.PTTScore = &result.PTTScore
+			employeeResult.PATScore = &result.PATScore
+			employeeResult.Score360 = &result.Score360
+		}
+		if !visibility.HideHistory {
+			employeeResult.Y1Rating = result.Y1Rating
+			employeeResult.Y2Rating = result.Y2Rating
+		}
+		employeeResults = append(employeeResults, employeeResult)
+	}
+	return employeeResults, nil
+}
+
+func (u *projectCloseOutUsecase) report(project *model.Project) (*model.ProjectCloseOutReport, []model.CloseOutRow, error) {
+	rows, err := u.repo.ListRows(project.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status := project.Status
+	if status == "" {
+		status = model.ProjectOpen
+	}
+	report := &model.ProjectCloseOutReport{
+		ProjectID: project.ID,
+		Status:    status,
+		Employees: len(rows),
+		Pending:   []model.CloseOutPending{},
+		SignOffs:  []model.CloseOutSignOff{},
+	}
+
+	var businessUnitIDs []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if reason := pendingReason(row); reason != "" {
+			report.Pending = append(report.Pending, model.CloseOutPending{
+				EmployeeID:  row.EmployeeID,
+				EmployeeNik: row.EmployeeNik,
+				Name:        row.EmployeeName,
+				PhaseOrder:  row.PhaseOrder,
+				Reason:      reason,
+			})
+		}
+		if row.BusinessUnitID != "" && !seen[row.BusinessUnitID] {
+			seen[row.BusinessUnitID] = true
+			businessUnitIDs = append(businessUnitIDs, row.BusinessUnitID)
+		}
+	}
+
+	businessUnits, err := u.repo.ListBusinessUnits(businessUnitIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	signOffs, err := u.repo.ListSignOffs(project.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	signedOff := map[string]model.ProjectSignOff{}
+	for _, signOff := range signOffs {
+		signedOff[signOff.BusinessUnitID] = signOff
+	}
+
+	allSigned := true
+	for _, businessUnit := range businessUnits {
+		entry := model.CloseOutSignOff{
+			BusinessUnitID:   businessUnit.ID,
+			BusinessUnitName: businessUnit.Name,
+			HeadNik:          businessUnit.HeadNik,
+		}
+		if signOff, ok := signedOff[businessUnit.ID]; ok {
+			signedAt := signOff.SignedAt
+			entry.SignedBy = signOff.SignedBy
+			entry.SignedAt = &signedAt
+			entry.Comment = signOff.Comment
+		} else {
+			allSigned = false
+		}
+		report.SignOffs = append(report.SignOffs, entry)
+	}
+
+	report.CanFinalize = status == model.ProjectClosing && len(report.Pending) == 0 && allSigned
+	return report, rows, nil
+}
+
+func pendingReason(row model.CloseOutRow) string {
+	if row.ProjectPhaseID == "" {
+		return "No calibration"
+	}
+	if row.Status != "Complete" {
+		return fmt.Sprintf("Phase %d calibration is %s", row.PhaseOrder, row.Status)
+	}
+	if row.ReviewSpmo && row.SpmoStatus != "Accepted" {
+		return fmt.Sprintf("Phase %d is not accepted by SPMO yet", row.PhaseOrder)
+	}
+	return ""
+}
+
+func NewProjectCloseOutUsecase(repo repository.ProjectCloseOutRepo, user repository.UserRepo) ProjectCloseOutUsecase {
+	return &projectCloseOutUsecase{
+		repo: repo,
+		user: user,
+	}
+}
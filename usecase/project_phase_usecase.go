@@ -9,7 +9,7 @@ import (
 
 type ProjectPhaseUsecase interface {
 	BaseUsecase[model.ProjectPhase]
-	FindAllActive() ([]model.ProjectPhase, error)
+	FindAllActive(projectID string) ([]model.ProjectPhase, error)
 	FindAllActiveHigherThanID(id string) ([]model.ProjectPhase, error)
 }
 
@@ -23,8 +23,8 @@ func (r *projectPhaseUsecase) FindAll() ([]model.ProjectPhase, error) {
 	return r.repo.List()
 }
 
-func (r *projectPhaseUsecase) FindAllActive() ([]model.ProjectPhase, error) {
-	return r.repo.ListActive()
+func (r *projectPhaseUsecase) FindAllActive(projectID string) ([]model.ProjectPhase, error) {
+	return r.repo.ListActive(projectID)
 }
 
 func (r *projectPhaseUsecase) FindAllActiveHigherThanID(id string) ([]model.ProjectPhase, error) {
@@ -49,6 +49,21 @@ func (r *projectPhaseUsecase) SaveData(payload *model.ProjectPhase) error {
 			return fmt.Errorf("Project Not Found")
 		}
 	}
+
+	switch payload.SpmoChain {
+	case "":
+		payload.SpmoChain = model.SpmoChainParallel
+	case model.SpmoChainParallel, model.SpmoChainSequential:
+	default:
+		return fmt.Errorf("SpmoChain must be parallel or sequential")
+	}
+	switch payload.SpmoQuorum {
+	case "":
+		payload.SpmoQuorum = model.SpmoQuorumAny
+	case model.SpmoQuorumAny, model.SpmoQuorumAll:
+	default:
+		return fmt.Errorf("SpmoQuorum must be any or all")
+	}
 	return r.repo.Save(payload)
 }
 
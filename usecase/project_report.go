@@ -0,0 +1,570 @@
+package usecase
+
+import (
+	"fmt"
+	"io"
+
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/utils/exporter"
+)
+
+// employees are read from the database one page at a time while the workbook is written
+const reportPageSize = 500
+
+// calibration sheets start with the distribution table and chart, the employee header sits below them
+const (
+	reportHeaderRow = 13
+	reportFirstRow  = 15
+)
+
+var reportPhaseColors = []string{
+	"#F2C4DE", "#71B1D9", "#AED8F2", "#ABD3DB", "#C2E6DF", "#D1EBD8", "#E5F5DC", "#F2DEA2", "#FFFFE1", "#F2CDC4",
+}
+
+// reportColumn is one column of the calibration report, columns sharing a group get a merged title above their names
+type reportColumn struct {
+	group string
+	name  string
+	phase int
+	width float64
+}
+
+func (c reportColumn) title() string {
+	if c.group == "" {
+		return c.name
+	}
+	return c.group + " " + c.name
+}
+
+type calibrationReportQuery struct {
+	types          string
+	calibratorID   string
+	businessUnit   string
+	prevCalibrator string
+	projectID      string
+}
+
+type reportStyles struct {
+	header int
+	cell   int
+}
+
+func calibrationReportColumns(phases int) []reportColumn {
+	columns := []reportColumn{
+		{name: "No", width: 4},
+		{name: "Nik", width: 15},
+		{name: "Employee", width: 20},
+		{name: "Grade", width: 4},
+		{name: "BU"},
+		{name: "OU", width: 15},
+		{name: "Supervisor", width: 20},
+		{group: "PrevRating", name: "Y-2"},
+		{group: "PrevRating", name: "Y-1"},
+		{name: "PTT Score"},
+		{name: "PAT Score"},
+		{name: "360 Score"},
+		{group: "Actual", name: "Score"},
+		{group: "Actual", name: "Rating"},
+	}
+	for i := 1; i <= phases; i++ {
+		calibration := fmt.Sprintf("Calibration-%d", i)
+		columns = append(columns,
+			reportColumn{group: calibration, name: "Score", phase: i},
+			reportColumn{group: calibration, name: "Rating", phase: i},
+			reportColumn{name: fmt.Sprintf("JustificationType-%d", i), phase: i},
+			reportColumn{name: fmt.Sprintf("Justification-%d", i), phase: i, width: 25},
+		)
+	}
+	return columns
+}
+
+// calibrationReportRow lines up with calibrationReportColumns, phases without a calibration are left nil
+func calibrationReportRow(no int, user response.UserResponse, phases int) []interface{} {
+	row := []interface{}{no, user.Nik, user.Name, user.Grade, user.BusinessUnit.Name, user.OrganizationUnit, user.SupervisorNames}
+	if len(user.ActualScores) > 0 {
+		actualScore := user.ActualScores[0]
+		row = append(row, actualScore.Y2Rating, actualScore.Y1Rating, actualScore.PTTScore, actualScore.PATScore, actualScore.Score360, actualScore.ActualScore, actualScore.ActualRating)
+	} else {
+		row = append(row, nil, nil, nil, nil, nil, nil, nil)
+	}
+
+	for order := 1; order <= phases; order++ {
+		phaseColumns := []interface{}{nil, nil, nil, nil}
+		for _, calibrationScore := range user.CalibrationScores {
+			if calibrationScore.ProjectPhase.Phase.Order == order {
+				phaseColumns = []interface{}{
+					calibrationScore.CalibrationScore,
+					calibrationScore.CalibrationRating,
+					calibrationScore.JustificationType,
+					calibrationJustification(calibrationScore),
+				}
+			}
+		}
+		row = append(row, phaseColumns...)
+	}
+	return row
+}
+
+// calibrationJustification renders the justification cell from the comment or the top and bottom remarks
+func calibrationJustification(calibration model.Calibration) string {
+	switch calibration.JustificationType {
+	case "default":
+		return calibration.Comment
+	case "top":
+		var justifications string
+		for _, topJustification := range calibration.TopRemarks {
+			justifications = justifications + fmt.Sprintf("Initiative: %s\nDescription: %s\nResult: %s\nComment: %s\nDate: %s - %s\nEvidence: %s",
+				topJustification.Initiative,
+				topJustification.Description,
+				topJustification.Result,
+				topJustification.Comment,
+				topJustification.StartDate,
+				topJustification.EndDate,
+				topJustification.EvidenceLink,
+			)
+		}
+		return justifications
+	default:
+		return fmt.Sprintf("Attitude: %s\nIndisipliner: %s\nLow Performance: %s\nWarning Letter: %s",
+			calibration.BottomRemark.Attitude,
+			calibration.BottomRemark.Indisipliner,
+			calibration.BottomRemark.LowPerformance,
+			calibration.BottomRemark.WarningLetter,
+		)
+	}
+}
+
+// reportCalibrationsPage reads one page of a calibration report, the number one list is not paged and comes back whole on the first page
+func (r *projectUsecase) reportCalibrationsPage(query calibrationReportQuery, phase int, pagination model.PaginationQuery) ([]response.UserResponse, error) {
+	var result response.UserCalibration
+	var err error
+
+	switch query.types {
+	case "numberOne":
+		if pagination.Skip > 0 {
+			return nil, nil
+		}
+		result, err = r.FindNumberOneCalibrationsByPrevCalibratorBusinessUnit(query.calibratorID, query.prevCalibrator, query.businessUnit, query.projectID)
+	case "n-1":
+		result, err = r.repo.GetAllDataNMinusOneCalibrationsByBusinessUnit(query.businessUnit, phase, query.calibratorID, query.projectID, pagination)
+	case "default":
+		result, err = r.repo.GetAllDataCalibrationsByPrevCalibratorBusinessUnit(query.calibratorID, query.prevCalibrator, query.businessUnit, query.projectID, phase, pagination)
+	default:
+		result, err = r.repo.GetAllDataCalibrationsByBusinessUnit(query.calibratorID, query.businessUnit, query.projectID, phase, pagination)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.UserData, nil
+}
+
+// calibratedRating counts the final ratings of the report population without loading it, the number one list is counted from its rows
+func (r *projectUsecase) calibratedRating(query calibrationReportQuery, users []response.UserResponse) (*response.TotalCalibratedRating, error) {
+	switch query.types {
+	case "numberOne":
+		total := &response.TotalCalibratedRating{}
+		for _, user := range users {
+			if len(user.CalibrationScores) == 0 {
+				continue
+			}
+			switch user.CalibrationScores[len(user.CalibrationScores)-1].CalibrationRating {
+			case "A+":
+				total.APlus++
+			case "A":
+				total.A++
+			case "B+":
+				total.BPlus++
+			case "B":
+				total.B++
+			case "C":
+				total.C++
+			case "D":
+				total.D++
+			}
+			total.Total++
+		}
+		return total, nil
+	case "n-1", "default":
+		return r.repo.GetCalibratedRating(query.calibratorID, query.prevCalibrator, query.businessUnit, query.types, query.projectID)
+	default:
+		return r.repo.GetCalibratedRating(query.calibratorID, query.prevCalibrator, query.businessUnit, "all", query.projectID)
+	}
+}
+
+func (r *projectUsecase) ReportCalibrations(w io.Writer, types, calibratorID, businessUnit, prevCalibrator, projectID string) error {
+	projectPhase, err := r.FindCalibratorPhase(calibratorID, projectID)
+	if err != nil {
+		return err
+	}
+
+	workbook := exporter.NewWorkbook(w)
+	query := calibrationReportQuery{
+		types:          types,
+		calibratorID:   calibratorID,
+		businessUnit:   businessUnit,
+		prevCalibrator: prevCalibrator,
+		projectID:      projectID,
+	}
+	err = r.writeCalibrationSheet(workbook, newReportStyles(workbook), query, projectPhase.Phase.Order, "Report")
+	if err != nil {
+		return err
+	}
+	return workbook.Close()
+}
+
+func (r *projectUsecase) SummaryReportCalibrations(w io.Writer, calibratorID, projectID string) error {
+	summary, err := r.FindSummaryProjectByCalibratorID(calibratorID, projectID, []string{})
+	if err != nil {
+		return err
+	}
+
+	projectPhase, err := r.FindCalibratorPhase(calibratorID, projectID)
+	if err != nil {
+		return err
+	}
+
+	workbook := exporter.NewWorkbook(w)
+	styles := newReportStyles(workbook)
+	err = writeSummarySheet(workbook, styles, summary)
+	if err != nil {
+		return err
+	}
+
+	for _, summaryBusinessUnit := range summary.Summary {
+		query := calibrationReportQuery{
+			types:        "all",
+			calibratorID: calibratorID,
+			businessUnit: summaryBusinessUnit.CalibratorBusinessUnitID,
+			projectID:    projectID,
+		}
+		err = r.writeCalibrationSheet(workbook, styles, query, projectPhase.Phase.Order, summaryBusinessUnit.CalibratorBusinessUnitName)
+		if err != nil {
+			return err
+		}
+	}
+	return workbook.Close()
+}
+
+func newReportStyles(workbook *exporter.Workbook) reportStyles {
+	return reportStyles{
+		header: workbook.AddStyle(exporter.Style{Bold: true}),
+		cell:   workbook.AddStyle(exporter.Style{}),
+	}
+}
+
+// writeSummarySheet writes one block per business unit, the calibrated rows per previous calibrator followed by the guidance and the total
+func writeSummarySheet(workbook *exporter.Workbook, styles reportStyles, summary *response.SummaryProject) error {
+	sheet, err := workbook.AddSheet("Summary")
+	if err != nil {
+		return err
+	}
+
+	headers := []interface{}{"Business Unit", "Previous Calibrator", "Indicator", "A+", "A", "B+", "B", "C", "D", "Total", "Average", "Status"}
+	row := 1
+	err = sheet.WriteRow(row, reportCells(styles.cell, headers...))
+	if err != nil {
+		return err
+	}
+
+	write := func(values ...interface{}) error {
+		row++
+		return sheet.WriteRow(row, reportCells(styles.cell, values...))
+	}
+
+	for _, summaryData := range summary.Summary {
+		startRow := row + 1
+		for _, prevCalibratorData := range summaryData.CalibratorBusinessUnit {
+			err = write(summaryData.CalibratorBusinessUnitName, prevCalibratorData.CalibratorName, "Calibrated",
+				prevCalibratorData.APlus, prevCalibratorData.A, prevCalibratorData.BPlus, prevCalibratorData.B, prevCalibratorData.C, prevCalibratorData.D,
+				prevCalibratorData.TotalCalibratedScore, prevCalibratorData.AverageScore, prevCalibratorData.Status)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = write(summaryData.CalibratorBusinessUnitName, "Rating", "Guidance",
+			summaryData.APlusGuidance, summaryData.AGuidance, summaryData.BPlusGuidance, summaryData.BGuidance, summaryData.CGuidance, summaryData.DGuidance,
+			summaryData.APlusGuidance+summaryData.AGuidance+summaryData.BPlusGuidance+summaryData.BGuidance+summaryData.CGuidance+summaryData.DGuidance,
+			summaryData.AverageScore)
+		if err != nil {
+			return err
+		}
+
+		err = write(summaryData.CalibratorBusinessUnitName, "Total", "Calibrated",
+			summaryData.APlusCalibrated, summaryData.ACalibrated, summaryData.BPlusCalibrated, summaryData.BCalibrated, summaryData.CCalibrated, summaryData.DCalibrated,
+			summaryData.TotalCalibratedScore)
+		if err != nil {
+			return err
+		}
+		sheet.MergeCell(fmt.Sprintf("A%d", startRow), fmt.Sprintf("A%d", row))
+	}
+
+	if len(summary.Summary) > 1 {
+		startRow := row + 1
+		err = write("Grand Total", "Guidance", nil,
+			summary.APlusGuidance, summary.AGuidance, summary.BPlusGuidance, summary.BGuidance, summary.CGuidance, summary.DGuidance,
+			summary.APlusGuidance+summary.AGuidance+summary.BPlusGuidance+summary.BGuidance+summary.CGuidance+summary.DGuidance)
+		if err != nil {
+			return err
+		}
+		sheet.MergeCell(fmt.Sprintf("B%d", row), fmt.Sprintf("C%d", row))
+
+		err = write("Grand Total", "Calibrated", nil,
+			summary.APlusTotalScore, summary.ATotalScore, summary.BPlusTotalScore, summary.BTotalScore, summary.CTotalScore, summary.DTotalScore,
+			summary.APlusTotalScore+summary.ATotalScore+summary.BPlusTotalScore+summary.BTotalScore+summary.CTotalScore+summary.DTotalScore,
+			summary.AverageTotalScore)
+		if err != nil {
+			return err
+		}
+		sheet.MergeCell(fmt.Sprintf("B%d", row), fmt.Sprintf("C%d", row))
+		sheet.MergeCell(fmt.Sprintf("A%d", startRow), fmt.Sprintf("A%d", row))
+	}
+	return nil
+}
+
+// writeCalibrationSheet streams one calibration sheet: the rating distribution and its chart, the two header rows and then the employees page by page
+func (r *projectUsecase) writeCalibrationSheet(workbook *exporter.Workbook, styles reportStyles, query calibrationReportQuery, phases int, fallbackName string) error {
+	actualScore, err := r.FindTotalActualScoreByCalibratorID(query.calibratorID, query.prevCalibrator, query.businessUnit, query.types, query.projectID)
+	if err != nil {
+		return err
+	}
+
+	ratingQuota, err := r.FindRatingQuotaByCalibratorIDforSummaryHelper(query.calibratorID, query.prevCalibrator, query.businessUnit, query.types, query.projectID, 0)
+	if err != nil {
+		return err
+	}
+
+	pagination := model.PaginationQuery{Take: reportPageSize}
+	users, err := r.reportCalibrationsPage(query, phases, pagination)
+	if err != nil {
+		return err
+	}
+
+	calibrated, err := r.calibratedRating(query, users)
+	if err != nil {
+		return err
+	}
+
+	sheetName := fallbackName
+	if len(users) > 0 && users[0].BusinessUnit.Name != "" {
+		sheetName = users[0].BusinessUnit.Name
+	}
+	sheet, err := workbook.AddSheet(sheetName)
+	if err != nil {
+		return err
+	}
+
+	columns := calibrationReportColumns(phases)
+	for col, column := range columns {
+		if column.width > 0 {
+			sheet.SetColWidth(col, column.width)
+		}
+	}
+
+	err = writeRatingDistribution(sheet, styles, ratingQuota, actualScore, calibrated)
+	if err != nil {
+		return err
+	}
+
+	phaseStyles := map[int]reportStyles{}
+	for phase := 1; phase <= phases; phase++ {
+		color := reportPhaseColors[(phase-1)%len(reportPhaseColors)]
+		phaseStyles[phase] = reportStyles{
+			header: workbook.AddStyle(exporter.Style{Bold: true, Fill: color}),
+			cell:   workbook.AddStyle(exporter.Style{Fill: color}),
+		}
+	}
+
+	groupRow := make([]exporter.Cell, len(columns))
+	nameRow := make([]exporter.Cell, len(columns))
+	for col, column := range columns {
+		style := styles.header
+		if column.phase > 0 {
+			style = phaseStyles[column.phase].header
+		}
+
+		top := exporter.CellName(col, reportHeaderRow)
+		switch {
+		case column.group == "":
+			groupRow[col] = exporter.Cell{Value: column.name, Style: style}
+			sheet.MergeCell(top, exporter.CellName(col, reportHeaderRow+1))
+		case col == 0 || columns[col-1].group != column.group:
+			groupRow[col] = exporter.Cell{Value: column.group, Style: style}
+			nameRow[col] = exporter.Cell{Value: column.name, Style: style}
+			span := col
+			for span+1 < len(columns) && columns[span+1].group == column.group {
+				span++
+			}
+			sheet.MergeCell(top, exporter.CellName(span, reportHeaderRow))
+		default:
+			groupRow[col] = exporter.Cell{Style: style}
+			nameRow[col] = exporter.Cell{Value: column.name, Style: style}
+		}
+	}
+	err = sheet.WriteRow(reportHeaderRow, groupRow)
+	if err != nil {
+		return err
+	}
+	err = sheet.WriteRow(reportHeaderRow+1, nameRow)
+	if err != nil {
+		return err
+	}
+
+	if len(users) == 0 {
+		return sheet.WriteRow(reportFirstRow, []exporter.Cell{{Value: "No employees to report", Style: styles.cell}})
+	}
+
+	no := 0
+	for len(users) > 0 {
+		for _, user := range users {
+			no++
+			values := calibrationReportRow(no, user, phases)
+			cells := make([]exporter.Cell, len(values))
+			for col, value := range values {
+				style := styles.cell
+				if columns[col].phase > 0 {
+					style = phaseStyles[columns[col].phase].cell
+					if value == nil {
+						value = "-"
+					}
+				}
+				cells[col] = exporter.Cell{Value: value, Style: style}
+			}
+
+			err = sheet.WriteRow(reportFirstRow+no-1, cells)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(users) < reportPageSize {
+			break
+		}
+		pagination.Skip += reportPageSize
+		users, err = r.reportCalibrationsPage(query, phases, pagination)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRatingDistribution writes the guidance, actual and calibrated counts per rating in H3:M10 and charts them in the top left corner
+func writeRatingDistribution(sheet *exporter.Sheet, styles reportStyles, ratingQuota *response.RatingQuota, actualScore *response.TotalActualScore, calibrated *response.TotalCalibratedRating) error {
+	rows := [][]interface{}{
+		{"Category", "Rating Scale", "Guidance", "Actual", "Calibrated", "Deviation Rating"},
+		{"A+", "5-5", ratingQuota.APlus, actualScore.APlus, calibrated.APlus, calibrated.APlus - ratingQuota.APlus},
+		{"A", "4.5 - 4.99", ratingQuota.A, actualScore.A, calibrated.A, calibrated.A - ratingQuota.A},
+		{"B+", "3.5 - 4.49", ratingQuota.BPlus, actualScore.BPlus, calibrated.BPlus, calibrated.BPlus - ratingQuota.BPlus},
+		{"B", "3 - 3.49", ratingQuota.B, actualScore.B, calibrated.B, calibrated.B - ratingQuota.B},
+		{"C", "2 - 2.99", ratingQuota.C, actualScore.C, calibrated.C, calibrated.C - ratingQuota.C},
+		{"D", "0 - 1.99", ratingQuota.D, actualScore.D, calibrated.D, calibrated.D - ratingQuota.D},
+	}
+
+	guidanceTotal := ratingQuota.APlus + ratingQuota.A + ratingQuota.BPlus + ratingQuota.B + ratingQuota.C + ratingQuota.D
+	actualTotal := actualScore.APlus + actualScore.A + actualScore.BPlus + actualScore.B + actualScore.C + actualScore.D
+	calibratedTotal := calibrated.APlus + calibrated.A + calibrated.BPlus + calibrated.B + calibrated.C + calibrated.D
+	rows = append(rows, []interface{}{"Total", nil, guidanceTotal, actualTotal, calibratedTotal, calibratedTotal - guidanceTotal})
+
+	for i, values := range rows {
+		// the table starts at column H
+		cells := make([]exporter.Cell, 7)
+		cells = append(cells, reportCells(0, values...)...)
+		err := sheet.WriteRow(3+i, cells)
+		if err != nil {
+			return err
+		}
+	}
+
+	sheet.AddLineChart(exporter.LineChart{
+		Anchor: "A1",
+		Width:  600,
+		Height: 225,
+		Title:  "Chart",
+		Series: []exporter.ChartSeries{
+			{Name: "$J$3", Categories: "$H$4:$H$9", Values: "$J$4:$J$9", Color: "#BEBEBE"},
+			{Name: "$K$3", Categories: "$H$4:$H$9", Values: "$K$4:$K$9", Color: "#02B4CC"},
+			{Name: "$L$3", Categories: "$H$4:$H$9", Values: "$L$4:$L$9", Color: "#FF7300"},
+		},
+		Max: calibratedTotal,
+	})
+	return nil
+}
+
+func reportCells(style int, values ...interface{}) []exporter.Cell {
+	cells := make([]exporter.Cell, len(values))
+	for i, value := range values {
+		cells[i] = exporter.Cell{Value: value, Style: style}
+	}
+	return cells
+}
+
+// ReportCalibrationsTable has the columns of the calibration workbook without the styling, for csv and json exports
+func (r *projectUsecase) ReportCalibrationsTable(types, calibratorID, businessUnit, prevCalibrator, projectID string) (*exporter.Table, error) {
+	projectPhase, err := r.FindCalibratorPhase(calibratorID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := calibrationReportQuery{
+		types:          types,
+		calibratorID:   calibratorID,
+		businessUnit:   businessUnit,
+		prevCalibrator: prevCalibrator,
+		projectID:      projectID,
+	}
+	users, err := r.reportCalibrationsPage(query, projectPhase.Phase.Order, model.PaginationQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	table := &exporter.Table{}
+	for _, column := range calibrationReportColumns(projectPhase.Phase.Order) {
+		table.Headers = append(table.Headers, column.title())
+	}
+	for i, user := range users {
+		table.Append(calibrationReportRow(i+1, user, projectPhase.Phase.Order)...)
+	}
+	return table, nil
+}
+
+// SummaryReportCalibrationsTable flattens the Summary sheet, one row per indicator like the workbook
+func (r *projectUsecase) SummaryReportCalibrationsTable(calibratorID, projectID string) (*exporter.Table, error) {
+	summary, err := r.FindSummaryProjectByCalibratorID(calibratorID, projectID, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	table := &exporter.Table{
+		Headers: []string{"Business Unit", "Previous Calibrator", "Indicator", "A+", "A", "B+", "B", "C", "D", "Total", "Average", "Status"},
+	}
+	for _, summaryData := range summary.Summary {
+		for _, prevCalibratorData := range summaryData.CalibratorBusinessUnit {
+			table.Append(summaryData.CalibratorBusinessUnitName, prevCalibratorData.CalibratorName, "Calibrated",
+				prevCalibratorData.APlus, prevCalibratorData.A, prevCalibratorData.BPlus, prevCalibratorData.B, prevCalibratorData.C, prevCalibratorData.D,
+				prevCalibratorData.TotalCalibratedScore, prevCalibratorData.AverageScore, prevCalibratorData.Status)
+		}
+
+		guidanceTotal := summaryData.APlusGuidance + summaryData.AGuidance + summaryData.BPlusGuidance + summaryData.BGuidance + summaryData.CGuidance + summaryData.DGuidance
+		table.Append(summaryData.CalibratorBusinessUnitName, "Rating", "Guidance",
+			summaryData.APlusGuidance, summaryData.AGuidance, summaryData.BPlusGuidance, summaryData.BGuidance, summaryData.CGuidance, summaryData.DGuidance,
+			guidanceTotal, summaryData.AverageScore, nil)
+		table.Append(summaryData.CalibratorBusinessUnitName, "Total", "Calibrated",
+			summaryData.APlusCalibrated, summaryData.ACalibrated, summaryData.BPlusCalibrated, summaryData.BCalibrated, summaryData.CCalibrated, summaryData.DCalibrated,
+			summaryData.TotalCalibratedScore, nil, nil)
+	}
+
+	if len(summary.Summary) > 1 {
+		guidanceTotal := summary.APlusGuidance + summary.AGuidance + summary.BPlusGuidance + summary.BGuidance + summary.CGuidance + summary.DGuidance
+		table.Append("Grand Total", nil, "Guidance",
+			summary.APlusGuidance, summary.AGuidance, summary.BPlusGuidance, summary.BGuidance, summary.CGuidance, summary.DGuidance,
+			guidanceTotal, nil, nil)
+		calibratedTotal := summary.APlusTotalScore + summary.ATotalScore + summary.BPlusTotalScore + summary.BTotalScore + summary.CTotalScore + summary.DTotalScore
+		table.Append("Grand Total", nil, "Calibrated",
+			summary.APlusTotalScore, summary.ATotalScore, summary.BPlusTotalScore, summary.BTotalScore, summary.CTotalScore, summary.DTotalScore,
+			calibratedTotal, summary.AverageTotalScore, nil)
+	}
+
+	return table, nil
+}
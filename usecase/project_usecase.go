@@ -2,17 +2,19 @@ package usecase
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"sort"
 	"strings"
+	"time"
 
 	"calibration-system.com/delivery/api/request"
 	"calibration-system.com/delivery/api/response"
 	"calibration-system.com/model"
 	"calibration-system.com/repository"
 	"calibration-system.com/utils"
-	"github.com/360EntSecGroup-Skylar/excelize"
-	"github.com/gin-gonic/gin"
+	"calibration-system.com/utils/exporter"
+	"github.com/google/uuid"
 )
 
 type ProjectUsecase interface {
@@ -43,18 +45,22 @@ type ProjectUsecase interface {
 	FindCalibrationsByRating(calibratorID, rating, projectID string, param request.PaginationParam) (response.UserCalibrationNew, response.Paging, error)
 	FindCalibratorPhase(calibratorID, projectID string) (*model.ProjectPhase, error)
 	FindActiveProjectPhase(projectID string) ([]model.ProjectPhase, error)
-	FindActiveManagerPhase() (model.ProjectPhase, error)
+	FindActiveManagerPhase(projectID string) (model.ProjectPhase, error)
 	FindActiveProject() ([]model.Project, error)
 	FindProjectRatingQuotaByBusinessUnit(businessUnitID, projectID string) (*model.Project, error)
 	FindSummaryProjectTotalByCalibratorID(calibratorID, projectID string) (*response.SummaryTotal, error)
-	ReportCalibrations(types, calibratorID, businessUnit, prevCalibrator, projectID string, c *gin.Context) (string, error)
-	SummaryReportCalibrations(calibratorID, projectID string, c *gin.Context) (string, error)
+	ReportCalibrations(w io.Writer, types, calibratorID, businessUnit, prevCalibrator, projectID string) error
+	SummaryReportCalibrations(w io.Writer, calibratorID, projectID string) error
+	ReportCalibrationsTable(types, calibratorID, businessUnit, prevCalibrator, projectID string) (*exporter.Table, error)
+	SummaryReportCalibrationsTable(calibratorID, projectID string) (*exporter.Table, error)
 	FindRatingQuotaByCalibratorIDforSummaryHelper(calibratorID, prevCalibrator, businessUnitID, types, projectID string, countCurrentUser int) (*response.RatingQuota, error)
 	FindActiveProjectByCalibratorID(calibratorID string) ([]model.Project, error)
 	FindActiveProjectBySpmoID(spmoID string) ([]model.Project, error)
 	FindReportCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID string) (response.UserCalibration, error)
 	FindReportNMinusOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, businessUnit, projectID string) (response.UserCalibration, error)
 	FindReportCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID string) (response.UserCalibration, error)
+	Clone(id string, options model.ProjectCloneOptions) (*model.Project, error)
+	CheckEditable(projectID string) error
 }
 
 type projectUsecase struct {
@@ -75,14 +81,168 @@ func (r *projectUsecase) FindById(id string) (*model.Project, error) {
 }
 
 func (r *projectUsecase) SaveData(payload *model.Project) error {
+	if payload.Timezone != "" {
+		if _, err := time.LoadLocation(payload.Timezone); err != nil {
+			return fmt.Errorf("Unknown timezone %s", payload.Timezone)
+		}
+	}
+	if payload.AppealWindowDays < 0 {
+		return fmt.Errorf("AppealWindowDays can't be negative")
+	}
+	if payload.SendBackPolicy != "" && payload.SendBackPolicy != model.SendBackAutoAdvance && payload.SendBackPolicy != model.SendBackEscalate {
+		return fmt.Errorf("SendBackPolicy must be %s or %s", model.SendBackAutoAdvance, model.SendBackEscalate)
+	}
+
+	// close-out owns the status, an edit of the project settings keeps whatever it is
+	payload.Status = model.ProjectOpen
+	payload.ClosedAt = nil
+	if payload.ID != "" {
+		if existing, err := r.repo.Get(payload.ID); err == nil {
+			payload.Status = existing.Status
+			payload.ClosedAt = existing.ClosedAt
+		}
+	}
 	return r.repo.Save(payload)
 }
 
+// Clone creates an inactive project from the chosen parts of another one. Actual scores are never copied, they belong
+// to the year being calibrated.
+func (r *projectUsecase) Clone(id string, options model.ProjectCloneOptions) (*model.Project, error) {
+	if options.CalibratorChains && !options.Phases {
+		return nil, fmt.Errorf("Calibrator chains can only be cloned together with phases")
+	}
+
+	source, err := r.repo.GetCloneSource(id, options.CalibratorChains)
+	if err != nil {
+		return nil, fmt.Errorf("Project Not Found")
+	}
+
+	project := model.Project{
+		BaseModel:        model.BaseModel{ID: uuid.New().String()},
+		Name:             options.Name,
+		Year:             source.Year + options.YearIncrement,
+		APlusExcess:      source.APlusExcess,
+		AExcess:          source.AExcess,
+		BPlusExcess:      source.BPlusExcess,
+		BExcess:          source.BExcess,
+		CExcess:          source.CExcess,
+		DExcess:          source.DExcess,
+		Timezone:         source.Timezone,
+		SendBackPolicy:   source.SendBackPolicy,
+		ResultVisibility: source.ResultVisibility,
+		AppealWindowDays: source.AppealWindowDays,
+	}
+	if project.Name == "" {
+		project.Name = fmt.Sprintf("%s (copy)", source.Name)
+	}
+	if releaseAt := source.ResultVisibility.ReleaseAt; releaseAt != nil {
+		shifted := releaseAt.AddDate(options.YearIncrement, 0, options.ShiftDays)
+		project.ResultVisibility.ReleaseAt = &shifted
+	}
+
+	phaseIDs := map[string]string{}
+	if options.Phases {
+		for _, phase := range source.ProjectPhases {
+			projectPhase := model.ProjectPhase{
+				BaseModel:  model.BaseModel{ID: uuid.New().String()},
+				PhaseID:    phase.PhaseID,
+				ProjectID:  project.ID,
+				ReviewSpmo: phase.ReviewSpmo,
+				SpmoChain:  phase.SpmoChain,
+				SpmoQuorum: phase.SpmoQuorum,
+				StartDate:  phase.StartDate.AddDate(options.YearIncrement, 0, options.ShiftDays),
+				EndDate:    phase.EndDate.AddDate(options.YearIncrement, 0, options.ShiftDays),
+				Guideline:  phase.Guideline,
+				ShowChart:  phase.ShowChart,
+			}
+			phaseIDs[phase.ID] = projectPhase.ID
+			project.ProjectPhases = append(project.ProjectPhases, projectPhase)
+		}
+	}
+
+	if options.RatingQuotas {
+		for _, quota := range source.RatingQuotas {
+			project.RatingQuotas = append(project.RatingQuotas, model.RatingQuota{
+				ProjectID:      project.ID,
+				BusinessUnitID: quota.BusinessUnitID,
+				APlusQuota:     quota.APlusQuota,
+				AQuota:         quota.AQuota,
+				BPlusQuota:     quota.BPlusQuota,
+				BQuota:         quota.BQuota,
+				CQuota:         quota.CQuota,
+				DQuota:         quota.DQuota,
+				Remaining:      quota.Remaining,
+				Excess:         quota.Excess,
+			})
+		}
+	}
+
+	if options.ScoreDistributions {
+		for _, scoreD := range source.ScoreDistributions {
+			project.ScoreDistributions = append(project.ScoreDistributions, model.ScoreDistribution{
+				ProjectID:           project.ID,
+				GroupBusinessUnitID: scoreD.GroupBusinessUnitID,
+				APlusUpperLimit:     scoreD.APlusUpperLimit,
+				APlusLowerLimit:     scoreD.APlusLowerLimit,
+				AUpperLimit:         scoreD.AUpperLimit,
+				ALowerLimit:         scoreD.ALowerLimit,
+				BPlusUpperLimit:     scoreD.BPlusUpperLimit,
+				BPlusLowerLimit:     scoreD.BPlusLowerLimit,
+				BUpperLimit:         scoreD.BUpperLimit,
+				BLowerLimit:         scoreD.BLowerLimit,
+				CUpperLimit:         scoreD.CUpperLimit,
+				CLowerLimit:         scoreD.CLowerLimit,
+				DUpperLimit:         scoreD.DUpperLimit,
+				DLowerLimit:         scoreD.DLowerLimit,
+			})
+		}
+	}
+
+	if options.RemarkSettings {
+		for _, remarks := range source.RemarkSettings {
+			project.RemarkSettings = append(project.RemarkSettings, model.RemarkSetting{
+				ProjectID:         project.ID,
+				JustificationType: remarks.JustificationType,
+				ScoringType:       remarks.ScoringType,
+				Level:             remarks.Level,
+				From:              remarks.From,
+				To:                remarks.To,
+			})
+		}
+	}
+
+	if options.CalibratorChains {
+		for _, calibration := range source.Calibrations {
+			project.Calibrations = append(project.Calibrations, model.Calibration{
+				ProjectID:      project.ID,
+				ProjectPhaseID: phaseIDs[calibration.ProjectPhaseID],
+				EmployeeID:     calibration.EmployeeID,
+				CalibratorID:   calibration.CalibratorID,
+				SpmoID:         calibration.SpmoID,
+				Spmo2ID:        calibration.Spmo2ID,
+				Spmo3ID:        calibration.Spmo3ID,
+			})
+		}
+	}
+
+	if err := r.repo.Clone(&project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
 func (r *projectUsecase) DeleteData(id string) error {
 	return r.repo.Delete(id)
 }
 
 func (r *projectUsecase) PublishProject(id string) error {
+	project, err := r.repo.Get(id)
+	if err != nil {
+		return fmt.Errorf("Project Not Found")
+	}
+	if project.Status == model.ProjectClosed {
+		return fmt.Errorf("Project %s is closed and can't be published again", project.Name)
+	}
 	return r.repo.ActivateByID(id)
 }
 
@@ -90,6 +250,15 @@ func (r *projectUsecase) DeactivateProject(id string) error {
 	return r.repo.NonactivateByID(id)
 }
 
+// CheckEditable rejects changes to the calibration data of a project that is being closed out or is closed
+func (r *projectUsecase) CheckEditable(projectID string) error {
+	project, err := r.repo.Get(projectID)
+	if err != nil {
+		return fmt.Errorf("Project Not Found")
+	}
+	return checkProjectOpen(project)
+}
+
 func (r *projectUsecase) FindActiveProject() ([]model.Project, error) {
 	return r.repo.GetActiveProject()
 }
@@ -690,7 +859,7 @@ func (r *projectUsecase) FindNumberOneCalibrationsByPrevCalibratorBusinessUnit(c
 		return response.UserCalibration{}, err
 	}
 
-	results, err := r.repo.GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, phase, users)
+	results, err := r.repo.GetNumberOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID, phase, users)
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
@@ -861,8 +1030,8 @@ func (r *projectUsecase) FindActiveProjectPhase(projectID string) ([]model.Proje
 	return projectPhase, nil
 }
 
-func (r *projectUsecase) FindActiveManagerPhase() (model.ProjectPhase, error) {
-	projectPhase, err := r.repo.GetActiveManagerPhase()
+func (r *projectUsecase) FindActiveManagerPhase(projectID string) (model.ProjectPhase, error) {
+	projectPhase, err := r.repo.GetActiveManagerPhase(projectID)
 	if err != nil {
 		return model.ProjectPhase{}, err
 	}
@@ -879,19 +1048,13 @@ func (r *projectUsecase) FindProjectRatingQuotaByBusinessUnit(businessUnitID, pr
 	return projects, nil
 }
 
-func truncateFloat(f float64, decimals int) float64 {
-	factor := math.Pow(10, float64(decimals))
-	truncated := math.Trunc(f*factor) / factor
-	return truncated
-}
-
 func (r *projectUsecase) FindReportCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID string) (response.UserCalibration, error) {
 	phase, err := r.repo.GetProjectPhaseOrder(calibratorID, projectID)
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
 
-	calibration, err := r.repo.GetAllDataCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID, phase)
+	calibration, err := r.repo.GetAllDataCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID, phase, model.PaginationQuery{})
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
@@ -904,7 +1067,7 @@ func (r *projectUsecase) FindReportCalibrationsByPrevCalibratorBusinessUnit(cali
 		return response.UserCalibration{}, err
 	}
 
-	calibration, err := r.repo.GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID, phase)
+	calibration, err := r.repo.GetAllDataCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID, phase, model.PaginationQuery{})
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
@@ -917,989 +1080,13 @@ func (r *projectUsecase) FindReportNMinusOneCalibrationsByPrevCalibratorBusiness
 		return response.UserCalibration{}, err
 	}
 
-	calibration, err := r.repo.GetAllDataNMinusOneCalibrationsByBusinessUnit(businessUnit, phase, calibratorID, projectID)
+	calibration, err := r.repo.GetAllDataNMinusOneCalibrationsByBusinessUnit(businessUnit, phase, calibratorID, projectID, model.PaginationQuery{})
 	if err != nil {
 		return response.UserCalibration{}, err
 	}
 	return calibration, nil
 }
 
-func (r *projectUsecase) ReportCalibrations(types, calibratorID, businessUnit, prevCalibrator, projectID string, c *gin.Context) (string, error) {
-	var responseData response.UserCalibration
-	var err error
-
-	if types == "numberOne" {
-		responseData, err = r.FindNumberOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID)
-	} else if types == "n-1" {
-		responseData, err = r.FindReportNMinusOneCalibrationsByPrevCalibratorBusinessUnit(calibratorID, businessUnit, projectID)
-	} else if types == "default" {
-		responseData, err = r.FindReportCalibrationsByPrevCalibratorBusinessUnit(calibratorID, prevCalibrator, businessUnit, projectID)
-	} else {
-		responseData, err = r.FindReportCalibrationsByBusinessUnit(calibratorID, businessUnit, projectID)
-	}
-	if err != nil {
-		return "", err
-	}
-
-	projectPhase, err := r.FindCalibratorPhase(calibratorID, projectID)
-	if err != nil {
-		return "", err
-	}
-
-	actualScore, err := r.FindTotalActualScoreByCalibratorID(calibratorID, prevCalibrator, businessUnit, types, projectID)
-	if err != nil {
-		return "", err
-	}
-
-	ratingQuota, err := r.FindRatingQuotaByCalibratorIDforSummaryHelper(calibratorID, prevCalibrator, businessUnit, types, projectID, 0)
-	if err != nil {
-		return "", err
-	}
-
-	file := excelize.NewFile()
-	sheetName := responseData.UserData[0].BusinessUnit.Name
-	index := file.NewSheet(sheetName)
-
-	headers := []string{
-		"No",
-		"Nik",
-		"Employee",
-		"Grade",
-		"BU",
-		"OU",
-		"Supervisor",
-		"PrevRating Y-2",
-		"PrevRating Y-1",
-		"PTT Score",
-		"PAT Score",
-		"360 Score",
-		"Actual Score",
-		"Actual Rating",
-	}
-
-	colorPallete := []string{
-		"#F2C4DE", "#71B1D9", "#AED8F2", "#ABD3DB", "#C2E6DF", "#D1EBD8", "#E5F5DC", "#F2DEA2", "#FFFFE1", "#F2CDC4",
-	}
-
-	for i := 0; i < projectPhase.Phase.Order; i++ {
-		headers = append(headers, fmt.Sprintf("Calibration-%d Score %s", i+1, colorPallete[i]))
-		headers = append(headers, fmt.Sprintf("Calibration-%d Rating %s", i+1, colorPallete[i]))
-		headers = append(headers, fmt.Sprintf("JustificationType-%d %s", i+1, colorPallete[i]))
-		headers = append(headers, fmt.Sprintf("Justification-%d %s", i+1, colorPallete[i]))
-	}
-
-	style1, err := file.NewStyle(`{"alignment":{"vertical":"center"}, "font":{"bold":true}}`)
-	if err != nil {
-		return "", err
-	}
-
-	style2, err := file.NewStyle(`{"alignment":{"vertical":"center"}}`)
-	if err != nil {
-		return "", err
-	}
-
-	countCalibrated := map[string]int{
-		"APlus": 0,
-		"A":     0,
-		"BPlus": 0,
-		"B":     0,
-		"C":     0,
-		"D":     0,
-	}
-
-	firstAppear := true
-	for col, header := range headers {
-		colName := excelize.ToAlphaString(col)
-		cellRef := fmt.Sprintf("%s%d", colName, 13)
-		cellRefUnder := fmt.Sprintf("%s%d", colName, 14)
-		nextColName := excelize.ToAlphaString(col + 1)
-		cellNextCollRef := fmt.Sprintf("%s%d", nextColName, 13)
-		file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, style1)
-
-		if (strings.Contains(header, "Prev") || strings.Contains(header, "Actual") || strings.Contains(header, "Calibration")) && firstAppear {
-			file.MergeCell(responseData.UserData[0].BusinessUnit.Name, cellRef, cellNextCollRef)
-			words := strings.Fields(header)
-			file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRef, words[0])
-			file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, words[1])
-			if strings.Contains(header, "Calibration") {
-				backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern", "color":["%s"],"pattern":1}, "font":{"bold":true}}`, words[2]))
-				if err != nil {
-					return "", err
-				}
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, backgroundColorCalibration)
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, backgroundColorCalibration)
-			} else {
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, style1)
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, style1)
-			}
-			firstAppear = false
-		} else if (strings.Contains(header, "Prev") || strings.Contains(header, "Actual") || strings.Contains(header, "Calibration")) && !firstAppear {
-			words := strings.Fields(header)
-			file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, words[1])
-			if strings.Contains(header, "Calibration") {
-				backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern","color":["%s"],"pattern":1}, "font":{"bold":true}}`, words[2]))
-				if err != nil {
-					return "", err
-				}
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, backgroundColorCalibration)
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, backgroundColorCalibration)
-			} else {
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, style1)
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, style1)
-			}
-			firstAppear = true
-		} else if strings.Contains(header, "Justification") {
-			file.MergeCell(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRefUnder)
-			words := strings.Fields(header)
-			backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern","color":["%s"],"pattern":1}, "font":{"bold":true}}`, words[1]))
-			if err != nil {
-				return "", err
-			}
-			file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, backgroundColorCalibration)
-			file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRef, words[0])
-		} else {
-			file.MergeCell(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRefUnder)
-			file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRef, header)
-		}
-	}
-
-	fmt.Println("==========================DATA USER", responseData.UserData[0].Name, responseData.UserData[0].CalibrationScores)
-	for i, user := range responseData.UserData {
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("A%d", i+15), i+1)
-		file.SetColWidth(user.BusinessUnit.Name, "A", "A", 4)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("A%d", i+15), fmt.Sprintf("A%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("B%d", i+15), user.Nik)
-		file.SetColWidth(user.BusinessUnit.Name, "B", "B", 15)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("B%d", i+15), fmt.Sprintf("B%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("C%d", i+15), user.Name)
-		file.SetColWidth(user.BusinessUnit.Name, "C", "C", 20)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("C%d", i+15), fmt.Sprintf("C%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("D%d", i+15), user.Grade)
-		file.SetColWidth(user.BusinessUnit.Name, "D", "D", 4)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("D%d", i+15), fmt.Sprintf("D%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("E%d", i+15), user.BusinessUnit.Name)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("E%d", i+15), fmt.Sprintf("E%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("F%d", i+15), user.OrganizationUnit)
-		file.SetColWidth(user.BusinessUnit.Name, "F", "F", 15)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("F%d", i+15), fmt.Sprintf("F%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("G%d", i+15), user.SupervisorNames)
-		file.SetColWidth(user.BusinessUnit.Name, "G", "G", 20)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("G%d", i+15), fmt.Sprintf("G%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("H%d", i+15), user.ActualScores[0].Y2Rating)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("H%d", i+15), fmt.Sprintf("H%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("I%d", i+15), user.ActualScores[0].Y1Rating)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("I%d", i+15), fmt.Sprintf("I%d", i+15), style2)
-
-		// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("J%d", i+15), truncateFloat(user.ActualScores[0].PTTScore, 2))
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("J%d", i+15), user.ActualScores[0].PTTScore)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("J%d", i+15), fmt.Sprintf("J%d", i+15), style2)
-
-		// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("K%d", i+15), truncateFloat(user.ActualScores[0].PATScore, 2))
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("K%d", i+15), user.ActualScores[0].PATScore)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("K%d", i+15), fmt.Sprintf("K%d", i+15), style2)
-
-		// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("L%d", i+15), truncateFloat(user.ActualScores[0].Score360, 2))
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("L%d", i+15), user.ActualScores[0].Score360)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("L%d", i+15), fmt.Sprintf("L%d", i+15), style2)
-
-		// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("M%d", i+15), truncateFloat(user.ActualScores[0].ActualScore, 2))
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("M%d", i+15), user.ActualScores[0].ActualScore)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("M%d", i+15), fmt.Sprintf("M%d", i+15), style2)
-
-		file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("N%d", i+15), user.ActualScores[0].ActualRating)
-		file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("N%d", i+15), fmt.Sprintf("N%d", i+15), style2)
-		column := int('O')
-
-		rating := user.CalibrationScores[len(user.CalibrationScores)-1].CalibrationRating
-		switch rating {
-		case "A+":
-			countCalibrated["APlus"] += 1
-		case "A":
-			countCalibrated["A"] += 1
-		case "B+":
-			countCalibrated["BPlus"] += 1
-		case "B":
-			countCalibrated["B"] += 1
-		case "C":
-			countCalibrated["C"] += 1
-		case "D":
-			countCalibrated["D"] += 1
-		default:
-			//
-		}
-
-		startCalibrationPhaseCol := 14
-		for j := 1; j < projectPhase.Phase.Order+1; j++ {
-			columnBefore := column
-			words := strings.Fields(headers[startCalibrationPhaseCol])
-			startCalibrationPhaseCol += 4
-			backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern","color":["%s"],"pattern":1}}`, words[2]))
-			if err != nil {
-				return "", err
-			}
-
-			for _, calibrationScore := range user.CalibrationScores {
-				if j == calibrationScore.ProjectPhase.Phase.Order {
-					fmt.Println("sheet= ", user.BusinessUnit.Name, " axis=", fmt.Sprintf("%s%d", asciiToName(column), i+15), " value=", truncateFloat(calibrationScore.CalibrationScore, 2))
-					file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.CalibrationScore)
-					file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					column++
-
-					file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.CalibrationRating)
-					file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					column++
-
-					file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.JustificationType)
-					file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					column++
-
-					if calibrationScore.JustificationType == "default" {
-						file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.Comment)
-						file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					} else if calibrationScore.JustificationType == "top" {
-						var justifications string
-						for _, topJustification := range calibrationScore.TopRemarks {
-							justifications = justifications + fmt.Sprintf("Initiative: %s\nDescription: %s\nResult: %s\nComment: %s\nDate: %s - %s\nEvidence: %s",
-								topJustification.Initiative,
-								topJustification.Description,
-								topJustification.Result,
-								topJustification.Comment,
-								topJustification.StartDate,
-								topJustification.EndDate,
-								topJustification.EvidenceLink,
-							)
-						}
-						file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), justifications)
-						file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-						file.SetColWidth(user.BusinessUnit.Name, fmt.Sprintf("%s", asciiToName(column)), fmt.Sprintf("%s", asciiToName(column)), 25)
-					} else {
-						justification := fmt.Sprintf("Attitude: %s\nIndisipliner: %s\nLow Performance: %s\nWarning Letter: %s",
-							calibrationScore.BottomRemark.Attitude,
-							calibrationScore.BottomRemark.Indisipliner,
-							calibrationScore.BottomRemark.LowPerformance,
-							calibrationScore.BottomRemark.WarningLetter,
-						)
-						file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), justification)
-						file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-						file.SetColWidth(user.BusinessUnit.Name, fmt.Sprintf("%s", asciiToName(column)), fmt.Sprintf("%s", asciiToName(column)), 25)
-					}
-					column++
-				}
-			}
-			if columnBefore == column {
-				file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-				file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-				column++
-
-				file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-				file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-				column++
-
-				file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-				file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-				column++
-
-				file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-				file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-				column++
-			}
-		}
-	}
-
-	file.SetActiveSheet(index)
-	file.DeleteSheet("Sheet1")
-
-	deviationRatingAP := countCalibrated["APlus"] - ratingQuota.APlus
-	deviationRatingA := countCalibrated["A"] - ratingQuota.A
-	deviationRatingBP := countCalibrated["BPlus"] - ratingQuota.BPlus
-	deviationRatingB := countCalibrated["B"] - ratingQuota.B
-	deviationRatingC := countCalibrated["C"] - ratingQuota.C
-	deviationRatingD := countCalibrated["D"] - ratingQuota.D
-
-	// Add data for the chart
-	data := map[string]interface{}{
-		"H3": "Category", "I3": "Rating Scale", "J3": "Guidance", "K3": "Actual", "L3": "Calibrated", "M3": "Deviation Rating",
-		"H4": "A+", "I4": "5-5", "J4": ratingQuota.APlus, "K4": actualScore.APlus, "L4": countCalibrated["APlus"], "M4": deviationRatingAP,
-		"H5": "A", "I5": "4.5 - 4.99", "J5": ratingQuota.A, "K5": actualScore.A, "L5": countCalibrated["A"], "M5": deviationRatingA,
-		"H6": "B+", "I6": "3.5 - 4.49", "J6": ratingQuota.BPlus, "K6": actualScore.BPlus, "L6": countCalibrated["BPlus"], "M6": deviationRatingBP,
-		"H7": "B", "I7": "3 - 3.49", "J7": ratingQuota.B, "K7": actualScore.B, "L7": countCalibrated["B"], "M7": deviationRatingB,
-		"H8": "C", "I8": "2 - 2.99", "J8": ratingQuota.C, "K8": actualScore.C, "L8": countCalibrated["C"], "M8": deviationRatingC,
-		"H9": "D", "I9": "0 - 1.99", "J9": ratingQuota.D, "K9": actualScore.D, "L9": countCalibrated["D"], "M9": deviationRatingD,
-		"H10": "Total", "J10": ratingQuota.APlus + ratingQuota.A + ratingQuota.BPlus + ratingQuota.B + ratingQuota.C + ratingQuota.D,
-		"K10": actualScore.APlus + actualScore.A + actualScore.BPlus + actualScore.B + actualScore.C + actualScore.D,
-		"L10": countCalibrated["APlus"] + countCalibrated["A"] + countCalibrated["BPlus"] + countCalibrated["B"] + countCalibrated["C"] + countCalibrated["D"],
-		"M10": deviationRatingAP + deviationRatingA + deviationRatingBP + deviationRatingB + deviationRatingC + deviationRatingD,
-	}
-	for k, v := range data {
-		file.SetCellValue(sheetName, k, v)
-	}
-
-	chartJSON := fmt.Sprintf(`{
-		"type": "line",
-		"dimension": {
-			"width": 600,
-			"height": 225
-		},
-		"series": [
-			{
-				"name": "'%s'!$J$3",
-				"categories": "'%s'!$H$4:$H$9",
-				"values": "'%s'!$J$4:$J$9",
-				"line": {
-					"color": "#BEBEBE"
-				}
-			},
-			{
-				"name": "'%s'!$K$3",
-				"categories": "'%s'!$H$4:$H$9",
-				"values": "'%s'!$K$4:$K$9",
-				"line": {
-					"color": "#02B4CC"
-				}
-			},
-			{
-				"name": "'%s'!$L$3",
-				"categories": "'%s'!$H$4:$H$9",
-				"values": "'%s'!$L$4:$L$9",
-				"line": {
-					"color": "#FF7300"
-				}
-			}
-		],
-		"legend": {
-			"position": "bottom",
-			"show_legend_key": true
-		},
-		"title": {
-			"name": "Chart"
-		},
-		"plotarea": {
-			"show_val": false
-		},
-		"x_axis": {
-			"reverse_order": false
-		},
-		"y_axis": {
-			"maximum": %d,
-			"minimum": 0
-		}
-	}`, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, len(responseData.UserData))
-	// fmt.Println("=====================,", len(responseData.UserData), chartJSON)
-
-	// Add the chart at a specific location
-	err = file.AddChart(sheetName, "A1", chartJSON)
-	if err != nil {
-		return "", err
-	}
-
-	err = file.SaveAs("report.xlsx")
-	if err != nil {
-		return "", err
-	}
-
-	return "report.xlsx", nil
-}
-
-func (r *projectUsecase) SummaryReportCalibrations(calibratorID, projectID string, c *gin.Context) (string, error) {
-	file := excelize.NewFile()
-	summary, err := r.FindSummaryProjectByCalibratorID(calibratorID, projectID, []string{})
-	if err != nil {
-		return "", err
-	}
-
-	style1, err := file.NewStyle(`{"alignment":{"vertical":"center"}, "font":{"bold":true}}`)
-	if err != nil {
-		return "", err
-	}
-
-	style2, err := file.NewStyle(`{"alignment":{"vertical":"center"}}`)
-	if err != nil {
-		return "", err
-	}
-
-	summaryHeaders := []string{
-		"Business Unit",
-		"Previous Calibrator",
-		"Indicator",
-		"A+",
-		"A",
-		"B+",
-		"B",
-		"C",
-		"D",
-		"Total",
-		"Average",
-		"Status",
-	}
-
-	for col, header := range summaryHeaders {
-		colName := excelize.ToAlphaString(col)
-		cellRef := fmt.Sprintf("%s%d", colName, 1)
-		file.SetCellStyle("Sheet1", cellRef, cellRef, style2)
-		file.SetCellValue("Sheet1", cellRef, header)
-	}
-
-	firstRow := 2
-	for _, summaryData := range summary.Summary {
-		startRow := firstRow
-		for _, prevCalibratorData := range summaryData.CalibratorBusinessUnit {
-			file.SetCellValue("Sheet1", fmt.Sprintf("A%d", firstRow), summaryData.CalibratorBusinessUnitName)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("A%d", firstRow), fmt.Sprintf("A%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("B%d", firstRow), prevCalibratorData.CalibratorName)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("B%d", firstRow), fmt.Sprintf("B%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("C%d", firstRow), "Calibrated")
-			file.SetCellStyle("Sheet1", fmt.Sprintf("C%d", firstRow), fmt.Sprintf("C%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("D%d", firstRow), prevCalibratorData.APlus)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("D%d", firstRow), fmt.Sprintf("D%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("E%d", firstRow), prevCalibratorData.A)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("E%d", firstRow), fmt.Sprintf("E%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("F%d", firstRow), prevCalibratorData.BPlus)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("F%d", firstRow), fmt.Sprintf("F%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("G%d", firstRow), prevCalibratorData.B)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("G%d", firstRow), fmt.Sprintf("G%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("H%d", firstRow), prevCalibratorData.C)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("H%d", firstRow), fmt.Sprintf("H%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("I%d", firstRow), prevCalibratorData.D)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("I%d", firstRow), fmt.Sprintf("I%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("J%d", firstRow), prevCalibratorData.TotalCalibratedScore)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("J%d", firstRow), fmt.Sprintf("J%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("K%d", firstRow), prevCalibratorData.AverageScore)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("K%d", firstRow), fmt.Sprintf("K%d", firstRow), style2)
-
-			file.SetCellValue("Sheet1", fmt.Sprintf("L%d", firstRow), prevCalibratorData.Status)
-			file.SetCellStyle("Sheet1", fmt.Sprintf("L%d", firstRow), fmt.Sprintf("L%d", firstRow), style2)
-			firstRow += 1
-		}
-		file.SetCellValue("Sheet1", fmt.Sprintf("A%d", firstRow), summaryData.CalibratorBusinessUnitName)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("A%d", firstRow), fmt.Sprintf("A%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("B%d", firstRow), "Rating")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("B%d", firstRow), fmt.Sprintf("B%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("C%d", firstRow), "Guidance")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("C%d", firstRow), fmt.Sprintf("C%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("D%d", firstRow), summaryData.APlusGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("D%d", firstRow), fmt.Sprintf("D%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("E%d", firstRow), summaryData.AGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("E%d", firstRow), fmt.Sprintf("E%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("F%d", firstRow), summaryData.BPlusGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("F%d", firstRow), fmt.Sprintf("F%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("G%d", firstRow), summaryData.BGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("G%d", firstRow), fmt.Sprintf("G%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("H%d", firstRow), summaryData.CGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("H%d", firstRow), fmt.Sprintf("H%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("I%d", firstRow), summaryData.DGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("I%d", firstRow), fmt.Sprintf("I%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("J%d", firstRow), summaryData.APlusGuidance+summaryData.AGuidance+summaryData.BPlusGuidance+summaryData.BGuidance+summaryData.CGuidance+summaryData.DGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("J%d", firstRow), fmt.Sprintf("J%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("K%d", firstRow), summaryData.AverageScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("K%d", firstRow), fmt.Sprintf("K%d", firstRow), style2)
-		firstRow += 1
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("A%d", firstRow), summaryData.CalibratorBusinessUnitName)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("A%d", firstRow), fmt.Sprintf("A%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("B%d", firstRow), "Total")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("B%d", firstRow), fmt.Sprintf("B%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("C%d", firstRow), "Calibrated")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("C%d", firstRow), fmt.Sprintf("C%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("D%d", firstRow), summaryData.APlusCalibrated)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("D%d", firstRow), fmt.Sprintf("D%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("E%d", firstRow), summaryData.ACalibrated)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("E%d", firstRow), fmt.Sprintf("E%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("F%d", firstRow), summaryData.BPlusCalibrated)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("F%d", firstRow), fmt.Sprintf("F%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("G%d", firstRow), summaryData.BCalibrated)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("G%d", firstRow), fmt.Sprintf("G%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("H%d", firstRow), summaryData.CCalibrated)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("H%d", firstRow), fmt.Sprintf("H%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("I%d", firstRow), summaryData.DCalibrated)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("I%d", firstRow), fmt.Sprintf("I%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("J%d", firstRow), summaryData.TotalCalibratedScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("J%d", firstRow), fmt.Sprintf("J%d", firstRow), style2)
-		firstRow += 1
-
-		endRow := firstRow - 1  // Adjust to get the last row of the group
-		if startRow != endRow { // Only merge if there's more than one row
-			file.MergeCell("Sheet1", fmt.Sprintf("A%d", startRow), fmt.Sprintf("A%d", endRow))
-		}
-	}
-
-	if len(summary.Summary) > 1 {
-		total := firstRow
-		file.SetCellValue("Sheet1", fmt.Sprintf("A%d", firstRow), "Grand Total")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("A%d", firstRow), fmt.Sprintf("A%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("B%d", firstRow), "Guidance")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("B%d", firstRow), fmt.Sprintf("B%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("D%d", firstRow), summary.APlusGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("D%d", firstRow), fmt.Sprintf("D%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("E%d", firstRow), summary.AGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("E%d", firstRow), fmt.Sprintf("E%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("F%d", firstRow), summary.BPlusGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("F%d", firstRow), fmt.Sprintf("F%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("G%d", firstRow), summary.BGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("G%d", firstRow), fmt.Sprintf("G%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("H%d", firstRow), summary.CGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("H%d", firstRow), fmt.Sprintf("H%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("I%d", firstRow), summary.DGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("I%d", firstRow), fmt.Sprintf("I%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("J%d", firstRow), summary.APlusGuidance+summary.AGuidance+summary.BPlusGuidance+summary.BGuidance+summary.CGuidance+summary.DGuidance)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("J%d", firstRow), fmt.Sprintf("J%d", firstRow), style2)
-		file.MergeCell("Sheet1", fmt.Sprintf("B%d", firstRow), fmt.Sprintf("C%d", firstRow))
-
-		firstRow += 1
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("A%d", firstRow), "Grand Total")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("A%d", firstRow), fmt.Sprintf("A%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("B%d", firstRow), "Calibrated")
-		file.SetCellStyle("Sheet1", fmt.Sprintf("B%d", firstRow), fmt.Sprintf("B%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("D%d", firstRow), summary.APlusTotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("D%d", firstRow), fmt.Sprintf("D%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("E%d", firstRow), summary.ATotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("E%d", firstRow), fmt.Sprintf("E%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("F%d", firstRow), summary.BPlusTotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("F%d", firstRow), fmt.Sprintf("F%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("G%d", firstRow), summary.BTotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("G%d", firstRow), fmt.Sprintf("G%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("H%d", firstRow), summary.CTotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("H%d", firstRow), fmt.Sprintf("H%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("I%d", firstRow), summary.DTotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("I%d", firstRow), fmt.Sprintf("I%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("J%d", firstRow), summary.APlusTotalScore+summary.ATotalScore+summary.BPlusTotalScore+summary.BTotalScore+summary.CTotalScore+summary.DTotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("J%d", firstRow), fmt.Sprintf("J%d", firstRow), style2)
-
-		file.SetCellValue("Sheet1", fmt.Sprintf("K%d", firstRow), summary.AverageTotalScore)
-		file.SetCellStyle("Sheet1", fmt.Sprintf("K%d", firstRow), fmt.Sprintf("K%d", firstRow), style2)
-		file.MergeCell("Sheet1", fmt.Sprintf("B%d", firstRow), fmt.Sprintf("C%d", firstRow))
-
-		firstRow += 1
-		file.MergeCell("Sheet1", fmt.Sprintf("A%d", total), fmt.Sprintf("A%d", firstRow-1))
-	}
-	file.SetSheetName("Sheet1", "Summary")
-
-	for _, summaryBusinessUnit := range summary.Summary {
-		responseData, err := r.FindReportCalibrationsByBusinessUnit(calibratorID, summaryBusinessUnit.CalibratorBusinessUnitID, projectID)
-		if err != nil {
-			return "", err
-		}
-
-		actualScore, err := r.FindTotalActualScoreByCalibratorID(calibratorID, "", summaryBusinessUnit.CalibratorBusinessUnitID, "all", projectID)
-		if err != nil {
-			return "", err
-		}
-
-		ratingQuota, err := r.FindRatingQuotaByCalibratorIDforSummaryHelper(calibratorID, "", summaryBusinessUnit.CalibratorBusinessUnitID, "all", projectID, 0)
-		if err != nil {
-			return "", err
-		}
-
-		projectPhase, err := r.FindCalibratorPhase(calibratorID, projectID)
-		if err != nil {
-			return "", err
-		}
-
-		sheetName := responseData.UserData[0].BusinessUnit.Name
-		index := file.NewSheet(sheetName)
-
-		headers := []string{
-			"No",
-			"Nik",
-			"Employee",
-			"Grade",
-			"BU",
-			"OU",
-			"Supervisor",
-			"PrevRating Y-2",
-			"PrevRating Y-1",
-			"PTT Score",
-			"PAT Score",
-			"360 Score",
-			"Actual Score",
-			"Actual Rating",
-		}
-
-		colorPallete := []string{
-			"#F2C4DE", "#71B1D9", "#AED8F2", "#ABD3DB", "#C2E6DF", "#D1EBD8", "#E5F5DC", "#F2DEA2", "#FFFFE1", "#F2CDC4",
-		}
-
-		for i := 0; i < projectPhase.Phase.Order; i++ {
-			headers = append(headers, fmt.Sprintf("Calibration-%d Score %s", i+1, colorPallete[i]))
-			headers = append(headers, fmt.Sprintf("Calibration-%d Rating %s", i+1, colorPallete[i]))
-			headers = append(headers, fmt.Sprintf("JustificationType-%d %s", i+1, colorPallete[i]))
-			headers = append(headers, fmt.Sprintf("Justification-%d %s", i+1, colorPallete[i]))
-		}
-
-		countCalibrated := map[string]int{
-			"APlus": 0,
-			"A":     0,
-			"BPlus": 0,
-			"B":     0,
-			"C":     0,
-			"D":     0,
-		}
-
-		firstAppear := true
-		for col, header := range headers {
-			colName := excelize.ToAlphaString(col)
-			cellRef := fmt.Sprintf("%s%d", colName, 13)
-			cellRefUnder := fmt.Sprintf("%s%d", colName, 14)
-			nextColName := excelize.ToAlphaString(col + 1)
-			cellNextCollRef := fmt.Sprintf("%s%d", nextColName, 13)
-			file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, style1)
-
-			if (strings.Contains(header, "Prev") || strings.Contains(header, "Actual") || strings.Contains(header, "Calibration")) && firstAppear {
-				file.MergeCell(responseData.UserData[0].BusinessUnit.Name, cellRef, cellNextCollRef)
-				words := strings.Fields(header)
-				file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRef, words[0])
-				file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, words[1])
-				if strings.Contains(header, "Calibration") {
-					backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern", "color":["%s"],"pattern":1}, "font":{"bold":true}}`, words[2]))
-					if err != nil {
-						return "", err
-					}
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, backgroundColorCalibration)
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, backgroundColorCalibration)
-				} else {
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, style1)
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, style1)
-				}
-				firstAppear = false
-			} else if (strings.Contains(header, "Prev") || strings.Contains(header, "Actual") || strings.Contains(header, "Calibration")) && !firstAppear {
-				words := strings.Fields(header)
-				file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, words[1])
-				if strings.Contains(header, "Calibration") {
-					backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern","color":["%s"],"pattern":1}, "font":{"bold":true}}`, words[2]))
-					if err != nil {
-						return "", err
-					}
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, backgroundColorCalibration)
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, backgroundColorCalibration)
-				} else {
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, style1)
-					file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRefUnder, cellRefUnder, style1)
-				}
-				firstAppear = true
-			} else if strings.Contains(header, "Justification") {
-				file.MergeCell(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRefUnder)
-				words := strings.Fields(header)
-				backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern","color":["%s"],"pattern":1}, "font":{"bold":true}}`, words[1]))
-				if err != nil {
-					return "", err
-				}
-				file.SetCellStyle(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRef, backgroundColorCalibration)
-				file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRef, words[0])
-			} else {
-				file.MergeCell(responseData.UserData[0].BusinessUnit.Name, cellRef, cellRefUnder)
-				file.SetCellValue(responseData.UserData[0].BusinessUnit.Name, cellRef, header)
-			}
-		}
-
-		for i, user := range responseData.UserData {
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("A%d", i+15), i+1)
-			file.SetColWidth(user.BusinessUnit.Name, "A", "A", 4)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("A%d", i+15), fmt.Sprintf("A%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("B%d", i+15), user.Nik)
-			file.SetColWidth(user.BusinessUnit.Name, "B", "B", 15)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("B%d", i+15), fmt.Sprintf("B%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("C%d", i+15), user.Name)
-			file.SetColWidth(user.BusinessUnit.Name, "C", "C", 20)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("C%d", i+15), fmt.Sprintf("C%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("D%d", i+15), user.Grade)
-			file.SetColWidth(user.BusinessUnit.Name, "D", "D", 4)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("D%d", i+15), fmt.Sprintf("D%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("E%d", i+15), user.BusinessUnit.Name)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("E%d", i+15), fmt.Sprintf("E%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("F%d", i+15), user.OrganizationUnit)
-			file.SetColWidth(user.BusinessUnit.Name, "F", "F", 15)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("F%d", i+15), fmt.Sprintf("F%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("G%d", i+15), user.SupervisorNames)
-			file.SetColWidth(user.BusinessUnit.Name, "G", "G", 20)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("G%d", i+15), fmt.Sprintf("G%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("H%d", i+15), user.ActualScores[0].Y2Rating)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("H%d", i+15), fmt.Sprintf("H%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("I%d", i+15), user.ActualScores[0].Y1Rating)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("I%d", i+15), fmt.Sprintf("I%d", i+15), style2)
-
-			// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("J%d", i+3), truncateFloat(user.ActualScores[0].PTTScore, 2))
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("J%d", i+15), user.ActualScores[0].PTTScore)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("J%d", i+15), fmt.Sprintf("J%d", i+15), style2)
-
-			// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("K%d", i+15), truncateFloat(user.ActualScores[0].PATScore, 2))
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("K%d", i+15), user.ActualScores[0].PATScore)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("K%d", i+15), fmt.Sprintf("K%d", i+15), style2)
-
-			// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("L%d", i+15), truncateFloat(user.ActualScores[0].Score360, 2))
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("L%d", i+15), user.ActualScores[0].Score360)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("L%d", i+15), fmt.Sprintf("L%d", i+15), style2)
-
-			// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("M%d", i+15), truncateFloat(user.ActualScores[0].ActualScore, 2))
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("M%d", i+15), user.ActualScores[0].ActualScore)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("M%d", i+15), fmt.Sprintf("M%d", i+15), style2)
-
-			file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("N%d", i+15), user.ActualScores[0].ActualRating)
-			file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("N%d", i+15), fmt.Sprintf("N%d", i+15), style2)
-			column := int('O')
-
-			rating := user.CalibrationScores[len(user.CalibrationScores)-1].CalibrationRating
-			switch rating {
-			case "A+":
-				countCalibrated["APlus"] += 1
-			case "A":
-				countCalibrated["A"] += 1
-			case "B+":
-				countCalibrated["BPlus"] += 1
-			case "B":
-				countCalibrated["B"] += 1
-			case "C":
-				countCalibrated["C"] += 1
-			case "D":
-				countCalibrated["D"] += 1
-			default:
-				//
-			}
-
-			startCalibrationPhaseCol := 14
-			for j := 1; j < projectPhase.Phase.Order+1; j++ {
-				columnBefore := column
-				words := strings.Fields(headers[startCalibrationPhaseCol])
-				startCalibrationPhaseCol += 4
-				backgroundColorCalibration, err := file.NewStyle(fmt.Sprintf(`{"alignment":{"vertical":"center"}, "fill":{"type":"pattern","color":["%s"],"pattern":1}}`, words[2]))
-				if err != nil {
-					return "", err
-				}
-
-				for _, calibrationScore := range user.CalibrationScores {
-					if j == calibrationScore.ProjectPhase.Phase.Order {
-
-						// file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), truncateFloat(calibrationScore.CalibrationScore, 2))
-						file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.CalibrationScore)
-						file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-						column++
-
-						file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.CalibrationRating)
-						file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-						column++
-
-						file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.JustificationType)
-						file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-						column++
-
-						if calibrationScore.JustificationType == "default" {
-							file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), calibrationScore.Comment)
-							file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-						} else if calibrationScore.JustificationType == "top" {
-							var justifications string
-							for _, topJustification := range calibrationScore.TopRemarks {
-								justifications = justifications + fmt.Sprintf("Initiative: %s\nDescription: %s\nResult: %s\nComment: %s\nDate: %s - %s\nEvidence: %s",
-									topJustification.Initiative,
-									topJustification.Description,
-									topJustification.Result,
-									topJustification.Comment,
-									topJustification.StartDate,
-									topJustification.EndDate,
-									topJustification.EvidenceLink,
-								)
-							}
-							file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), justifications)
-							file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-							file.SetColWidth(user.BusinessUnit.Name, fmt.Sprintf("%s", asciiToName(column)), fmt.Sprintf("%s", asciiToName(column)), 25)
-						} else {
-							justification := fmt.Sprintf("Attitude: %s\nIndisipliner: %s\nLow Performance: %s\nWarning Letter: %s",
-								calibrationScore.BottomRemark.Attitude,
-								calibrationScore.BottomRemark.Indisipliner,
-								calibrationScore.BottomRemark.LowPerformance,
-								calibrationScore.BottomRemark.WarningLetter,
-							)
-							file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), justification)
-							file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-							file.SetColWidth(user.BusinessUnit.Name, fmt.Sprintf("%s", asciiToName(column)), fmt.Sprintf("%s", asciiToName(column)), 25)
-						}
-						column++
-					}
-				}
-				if columnBefore == column {
-					file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-					file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					column++
-
-					file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-					file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					column++
-
-					file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-					file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					column++
-
-					file.SetCellValue(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), "-")
-					file.SetCellStyle(user.BusinessUnit.Name, fmt.Sprintf("%s%d", asciiToName(column), i+15), fmt.Sprintf("%s%d", asciiToName(column), i+15), backgroundColorCalibration)
-					column++
-				}
-			}
-		}
-		file.SetActiveSheet(index)
-
-		deviationRatingAP := countCalibrated["APlus"] - ratingQuota.APlus
-		deviationRatingA := countCalibrated["A"] - ratingQuota.A
-		deviationRatingBP := countCalibrated["BPlus"] - ratingQuota.BPlus
-		deviationRatingB := countCalibrated["B"] - ratingQuota.B
-		deviationRatingC := countCalibrated["C"] - ratingQuota.C
-		deviationRatingD := countCalibrated["D"] - ratingQuota.D
-
-		// Add data for the chart
-		data := map[string]interface{}{
-			"H3": "Category", "I3": "Rating Scale", "J3": "Guidance", "K3": "Actual", "L3": "Calibrated", "M3": "Deviation Rating",
-			"H4": "A+", "I4": "5-5", "J4": ratingQuota.APlus, "K4": actualScore.APlus, "L4": countCalibrated["APlus"], "M4": deviationRatingAP,
-			"H5": "A", "I5": "4.5 - 4.99", "J5": ratingQuota.A, "K5": actualScore.A, "L5": countCalibrated["A"], "M5": deviationRatingA,
-			"H6": "B+", "I6": "3.5 - 4.49", "J6": ratingQuota.BPlus, "K6": actualScore.BPlus, "L6": countCalibrated["BPlus"], "M6": deviationRatingBP,
-			"H7": "B", "I7": "3 - 3.49", "J7": ratingQuota.B, "K7": actualScore.B, "L7": countCalibrated["B"], "M7": deviationRatingB,
-			"H8": "C", "I8": "2 - 2.99", "J8": ratingQuota.C, "K8": actualScore.C, "L8": countCalibrated["C"], "M8": deviationRatingC,
-			"H9": "D", "I9": "0 - 1.99", "J9": ratingQuota.D, "K9": actualScore.D, "L9": countCalibrated["D"], "M9": deviationRatingD,
-			"H10": "Total", "J10": ratingQuota.APlus + ratingQuota.A + ratingQuota.BPlus + ratingQuota.B + ratingQuota.C + ratingQuota.D,
-			"K10": actualScore.APlus + actualScore.A + actualScore.BPlus + actualScore.B + actualScore.C + actualScore.D,
-			"L10": countCalibrated["APlus"] + countCalibrated["A"] + countCalibrated["BPlus"] + countCalibrated["B"] + countCalibrated["C"] + countCalibrated["D"],
-			"M10": deviationRatingAP + deviationRatingA + deviationRatingBP + deviationRatingB + deviationRatingC + deviationRatingD,
-		}
-		for k, v := range data {
-			file.SetCellValue(sheetName, k, v)
-		}
-
-		chartJSON := fmt.Sprintf(`{
-			"type": "line",
-			"dimension": {
-				"width": 600,
-				"height": 225
-			},
-			"series": [
-				{
-					"name": "'%s'!$J$3",
-					"categories": "'%s'!$H$4:$H$9",
-					"values": "'%s'!$J$4:$J$9",
-					"line": {
-						"color": "#BEBEBE"
-					}
-				},
-				{
-					"name": "'%s'!$K$3",
-					"categories": "'%s'!$H$4:$H$9",
-					"values": "'%s'!$K$4:$K$9",
-					"line": {
-						"color": "#02B4CC"
-					}
-				},
-				{
-					"name": "'%s'!$L$3",
-					"categories": "'%s'!$H$4:$H$9",
-					"values": "'%s'!$L$4:$L$9",
-					"line": {
-						"color": "#FF7300"
-					}
-				}
-			],
-			"legend": {
-				"position": "bottom",
-				"show_legend_key": true
-			},
-			"title": {
-				"name": "Chart"
-			},
-			"plotarea": {
-				"show_val": false
-			},
-			"x_axis": {
-				"reverse_order": false
-			},
-			"y_axis": {
-				"maximum": %d,
-				"minimum": 0
-			}
-		}`, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, sheetName, len(responseData.UserData))
-
-		// Add the chart at a specific location
-		err = file.AddChart(sheetName, "A1", chartJSON)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	sheetIndex := file.GetSheetIndex("Summary")
-	file.SetActiveSheet(sheetIndex)
-	err = file.SaveAs("report.xlsx")
-	if err != nil {
-		return "", err
-	}
-
-	return "report.xlsx", nil
-}
-
-func asciiToName(column int) string {
-	columnName := fmt.Sprintf("%c", column)
-	if column > int('Z') {
-		offset := (column - int('A')) % 26
-		firstLetterOffset := int((column - int('A')) / 26)
-		firstLetter := int('A') - 1 + firstLetterOffset
-		columnName = fmt.Sprintf("%c%c", firstLetter, offset+int('A'))
-	}
-	return columnName
-}
-
 func contains(slice []string, item string) bool {
 	for _, v := range slice {
 		if strings.HasPrefix(v, item) {
@@ -1,16 +1,16 @@
 package usecase
 
 import (
+	"context"
 	"fmt"
-	"mime/multipart"
-	"strconv"
+	"io"
 
 	"calibration-system.com/delivery/api/request"
 	"calibration-system.com/delivery/api/response"
 	"calibration-system.com/model"
 	"calibration-system.com/repository"
 	"calibration-system.com/utils"
-	"github.com/360EntSecGroup-Skylar/excelize"
+	"calibration-system.com/utils/importer"
 )
 
 type RatingQuotaUsecase interface {
@@ -19,7 +19,7 @@ type RatingQuotaUsecase interface {
 	FindById(projectID, businessUnitID string) (*model.RatingQuota, error)
 	SaveData(payload *model.RatingQuota) error
 	DeleteData(projectId, businessUnitId string) error
-	BulkInsert(file *multipart.FileHeader, projectId string) ([]string, error)
+	BulkInsert(ctx context.Context, file io.Reader, projectId string, dryRun bool) (*importer.Report, error)
 	FindPagination(param request.PaginationParam, id string) ([]model.RatingQuota, response.Paging, error)
 }
 
@@ -67,125 +67,82 @@ func (r *ratingQuotaUsecase) DeleteData(projectId, businessUnitId string) error
 	return r.repo.Delete(projectId, businessUnitId)
 }
 
-func (r *ratingQuotaUsecase) BulkInsert(file *multipart.FileHeader, projectId string) ([]string, error) {
-	logs := map[string]string{}
-	var ratingQuotas []model.RatingQuota
-
+func (r *ratingQuotaUsecase) BulkInsert(ctx context.Context, file io.Reader, projectId string, dryRun bool) (*importer.Report, error) {
 	_, err := r.project.FindById(projectId)
 	if err != nil {
 		return nil, err
 	}
 
-	// Membuka file Excel yang diunggah
-	excelFile, err := file.Open()
+	report, rows, err := importer.Read(file, ratingQuotaImportSchema, dryRun)
 	if err != nil {
 		return nil, err
 	}
-	defer excelFile.Close()
 
-	xlsFile, err := excelize.OpenReader(excelFile)
+	existing := map[string]bool{}
+	ratingQuotas, err := r.repo.GetByProject(projectId)
 	if err != nil {
 		return nil, err
 	}
+	for _, ratingQuota := range ratingQuotas {
+		existing[ratingQuota.BusinessUnitID] = true
+	}
 
-	sheetName := xlsFile.GetSheetName(5)
-	rows := xlsFile.GetRows(sheetName)
-
+	var payload []model.RatingQuota
+	buIds := map[string]int{}
 	for i, row := range rows {
-		passed := true
-		if i == 0 {
-			continue
-		}
-
-		buId := row[0]
-		_, err := r.businessUnit.FindById(buId)
-		if err != nil {
-			if _, ok := logs[buId]; !ok {
-				logs[buId] = buId
-			}
-			passed = false
-		}
-
-		aPlusQuota, err := strconv.ParseFloat(row[1], 64)
-		if err != nil {
-			if _, ok := logs[buId]; !ok {
-				logs[buId] = buId
-			}
-			passed = false
-		}
-
-		aQuota, err := strconv.ParseFloat(row[2], 64)
-		if err != nil {
-			if _, ok := logs[buId]; !ok {
-				logs[buId] = buId
-			}
-			passed = false
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
+			return nil, err
 		}
 
-		bPlusQuota, err := strconv.ParseFloat(row[3], 64)
-		if err != nil {
-			if _, ok := logs[buId]; !ok {
-				logs[buId] = buId
-			}
-			passed = false
+		buId := row.Get("Business Unit ID")
+		if previous, ok := buIds[buId]; ok {
+			report.AddError(row.Number, "Business Unit ID", buId, fmt.Sprintf("Duplicate of row %d", previous))
+			continue
 		}
+		buIds[buId] = row.Number
 
-		bQuota, err := strconv.ParseFloat(row[4], 64)
+		_, err := r.businessUnit.FindById(buId)
 		if err != nil {
-			if _, ok := logs[buId]; !ok {
-				logs[buId] = buId
-			}
-			passed = false
+			report.AddError(row.Number, "Business Unit ID", buId, "Business unit not found")
+			continue
 		}
 
-		cQuota, err := strconv.ParseFloat(row[5], 64)
-		if err != nil {
-			if _, ok := logs[buId]; !ok {
-				logs[buId] = buId
-			}
-			passed = false
+		action := importer.ActionCreate
+		if existing[buId] {
+			action = importer.ActionUpdate
 		}
 
-		dQuota, err := strconv.ParseFloat(row[6], 64)
-		if err != nil {
-			if _, ok := logs[buId]; !ok {
-				logs[buId] = buId
-			}
-			passed = false
-		}
-
-		if passed {
-			ratingQuota := model.RatingQuota{
-				ProjectID:      projectId,
-				BusinessUnitID: buId,
-				APlusQuota:     aPlusQuota,
-				AQuota:         aQuota,
-				BPlusQuota:     bPlusQuota,
-				BQuota:         bQuota,
-				CQuota:         cQuota,
-				DQuota:         dQuota,
-				Remaining:      row[7],
-				Excess:         row[8],
-			}
-			ratingQuotas = append(ratingQuotas, ratingQuota)
-		}
+		payload = append(payload, model.RatingQuota{
+			ProjectID:      projectId,
+			BusinessUnitID: buId,
+			APlusQuota:     row.Float("A+ Quota"),
+			AQuota:         row.Float("A Quota"),
+			BPlusQuota:     row.Float("B+ Quota"),
+			BQuota:         row.Float("B Quota"),
+			CQuota:         row.Float("C Quota"),
+			DQuota:         row.Float("D Quota"),
+			Remaining:      row.Get("Remaining"),
+			Excess:         row.Get("Excess"),
+		})
+		report.AddRow(row.Number, buId, action)
 	}
 
-	var dataError []string
-	for _, key := range logs {
-		dataError = append(dataError, key)
+	if report.HasErrors() {
+		return report, report.Err()
 	}
-
-	if len(dataError) > 0 {
-		return dataError, fmt.Errorf("Error when insert data")
+	if dryRun {
+		return report, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	err = r.repo.Bulksave(&ratingQuotas)
+	err = r.repo.Bulksave(&payload)
 	if err != nil {
 		return nil, err
 	}
-
-	return dataError, nil
+	report.Committed = true
+	return report, nil
 }
 
 func NewRatingQuotaUsecase(repo repository.RatingQuotaRepo, businessUnit BusinessUnitUsecase, project ProjectUsecase) RatingQuotaUsecase {
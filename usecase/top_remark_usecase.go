@@ -72,6 +72,9 @@ func (r *topRemarkUsecase) SaveData(payload *model.TopRemark) error {
 		if err != nil {
 			return fmt.Errorf("Project Not Found")
 		}
+		if err := r.project.CheckEditable(payload.ProjectID); err != nil {
+			return err
+		}
 	}
 
 	if payload.EmployeeID != "" {
@@ -91,6 +94,10 @@ func (r *topRemarkUsecase) SaveData(payload *model.TopRemark) error {
 }
 
 func (r *topRemarkUsecase) SaveDataByProject(payload []*model.TopRemark) error {
+	if err := r.project.CheckEditable(payload[0].ProjectID); err != nil {
+		return err
+	}
+
 	projectPhases, err := r.projectPhase.FindAllActiveHigherThanID(payload[0].ProjectPhaseID)
 	if err != nil {
 		return err
@@ -99,10 +106,24 @@ func (r *topRemarkUsecase) SaveDataByProject(payload []*model.TopRemark) error {
 }
 
 func (r *topRemarkUsecase) DeleteData(projectID, employeeID, projectPhaseID string) error {
+	if err := r.project.CheckEditable(projectID); err != nil {
+		return err
+	}
 	return r.repo.Delete(projectID, employeeID, projectPhaseID)
 }
 
 func (r *topRemarkUsecase) BulkDeleteData(payload request.DeleteTopRemarks) error {
+	checked := map[string]bool{}
+	for _, id := range payload.IDs {
+		topRemark, err := r.repo.GetByID(id)
+		if err != nil || checked[topRemark.ProjectID] {
+			continue
+		}
+		if err := r.project.CheckEditable(topRemark.ProjectID); err != nil {
+			return err
+		}
+		checked[topRemark.ProjectID] = true
+	}
 	return r.repo.BulkDelete(payload)
 }
 
@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"calibration-system.com/config"
+	"calibration-system.com/delivery/api/response"
+	"calibration-system.com/model"
+	"calibration-system.com/repository"
+	"calibration-system.com/utils"
+	"gorm.io/gorm"
+)
+
+const totalRecoveryCodes = 10
+
+type TwoFactorUsecase interface {
+	IsRequired(user *model.User) bool
+	IsEnabled(userID string) bool
+	FindStatus(userID string) (*response.TwoFactorStatus, error)
+	Enroll(userID string) (*response.TwoFactorEnrollment, error)
+	Confirm(userID, code string) (*response.TwoFactorRecoveryCodes, error)
+	Verify(userID, code string) error
+	RegenerateRecoveryCodes(userID, code string) (*response.TwoFactorRecoveryCodes, error)
+	Disable(userID, code string) error
+}
+
+type twoFactorUsecase struct {
+	repo repository.TwoFactorRepo
+	user UserUsecase
+	cfg  *config.Config
+}
+
+func (r *twoFactorUsecase) IsRequired(user *model.User) bool {
+	for _, role := range user.Roles {
+		for _, requiredRole := range r.cfg.RequiredRoles {
+			if strings.EqualFold(role.Name, strings.TrimSpace(requiredRole)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *twoFactorUsecase) IsEnabled(userID string) bool {
+	twoFactor, err := r.repo.GetByUserID(userID)
+	if err != nil {
+		return false
+	}
+	return twoFactor.Enabled
+}
+
+func (r *twoFactorUsecase) FindStatus(userID string) (*response.TwoFactorStatus, error) {
+	user, err := r.user.FindById(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := response.TwoFactorStatus{
+		Required: r.IsRequired(user),
+	}
+
+	twoFactor, err := r.repo.GetByUserID(userID)
+	if err == gorm.ErrRecordNotFound {
+		return &status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = twoFactor.Enabled
+	status.EnabledAt = twoFactor.EnabledAt
+	status.RemainingRecoveryCodes = len(twoFactor.RecoveryCodes)
+	return &status, nil
+}
+
+func (r *twoFactorUsecase) Enroll(userID string) (*response.TwoFactorEnrollment, error) {
+	user, err := r.user.FindById(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor, err := r.repo.GetByUserID(userID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		return nil, fmt.Errorf("Two factor authentication already enabled")
+	}
+	if twoFactor == nil {
+		twoFactor = &model.TwoFactor{UserID: userID}
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	twoFactor.Secret = secret
+	twoFactor.LastUsedStep = 0
+
+	if err := r.repo.Save(twoFactor); err != nil {
+		return nil, err
+	}
+
+	return &response.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(r.cfg.Issuer, user.Email, secret),
+	}, nil
+}
+
+func (r *twoFactorUsecase) Confirm(userID, code string) (*response.TwoFactorRecoveryCodes, error) {
+	twoFactor, err := r.repo.GetByUserID(userID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("Two factor enrollment not started")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.Enabled {
+		return nil, fmt.Errorf("Two factor authentication already enabled")
+	}
+
+	step, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("Two factor code invalid")
+	}
+
+	twoFactor.Enabled = true
+	twoFactor.EnabledAt = time.Now()
+	twoFactor.LastUsedStep = step
+	if err := r.repo.Save(twoFactor); err != nil {
+		return nil, err
+	}
+
+	return r.generateRecoveryCodes(twoFactor.ID)
+}
+
+func (r *twoFactorUsecase) Verify(userID, code string) error {
+	twoFactor, err := r.repo.GetByUserID(userID)
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("Two factor authentication is not enabled")
+	}
+	if err != nil {
+		return err
+	}
+	if !twoFactor.Enabled {
+		return fmt.Errorf("Two factor authentication is not enabled")
+	}
+
+	step, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now())
+	if ok {
+		if step <= twoFactor.LastUsedStep {
+			return fmt.Errorf("Two factor code already used")
+		}
+		twoFactor.LastUsedStep = step
+		return r.repo.Save(twoFactor)
+	}
+
+	for _, recoveryCode := range twoFactor.RecoveryCodes {
+		if utils.ComparePassword(recoveryCode.CodeHash, []byte(strings.ToLower(strings.TrimSpace(code)))) {
+			return r.repo.MarkRecoveryCodeUsed(recoveryCode.ID)
+		}
+	}
+
+	return fmt.Errorf("Two factor code invalid")
+}
+
+func (r *twoFactorUsecase) RegenerateRecoveryCodes(userID, code string) (*response.TwoFactorRecoveryCodes, error) {
+	if err := r.Verify(userID, code); err != nil {
+		return nil, err
+	}
+
+	twoFactor, err := r.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.generateRecoveryCodes(twoFactor.ID)
+}
+
+func (r *twoFactorUsecase) Disable(userID, code string) error {
+	user, err := r.user.FindById(userID)
+	if err != nil {
+		return err
+	}
+	if r.IsRequired(user) {
+		return fmt.Errorf("Two factor authentication is mandatory for your role")
+	}
+
+	if err := r.Verify(userID, code); err != nil {
+		return err
+	}
+	return r.repo.DeleteByUserID(userID)
+}
+
+func (r *twoFactorUsecase) generateRecoveryCodes(twoFactorID string) (*response.TwoFactorRecoveryCodes, error) {
+	codes, err := utils.GenerateRecoveryCodes(totalRecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []model.TwoFactorRecoveryCode
+	for _, code := range codes {
+		hash, err := utils.SaltPassword([]byte(code))
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes = append(recoveryCodes, model.TwoFactorRecoveryCode{
+			CodeHash: hash,
+		})
+	}
+
+	if err := r.repo.ReplaceRecoveryCodes(twoFactorID, recoveryCodes); err != nil {
+		return nil, err
+	}
+
+	return &response.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+func NewTwoFactorUsecase(repo repository.TwoFactorRepo, user UserUsecase, cfg *config.Config) TwoFactorUsecase {
+	return &twoFactorUsecase{
+		repo: repo,
+		user: user,
+		cfg:  cfg,
+	}
+}
@@ -1,10 +1,10 @@
 package usecase
 
 import (
+	"context"
 	"fmt"
-	"mime/multipart"
+	"io"
 	"sort"
-	"time"
 
 	"calibration-system.com/config"
 	"calibration-system.com/delivery/api/request"
@@ -12,7 +12,7 @@ import (
 	"calibration-system.com/model"
 	"calibration-system.com/repository"
 	"calibration-system.com/utils"
-	"github.com/360EntSecGroup-Skylar/excelize"
+	"calibration-system.com/utils/importer"
 )
 
 type UserUsecase interface {
@@ -25,9 +25,10 @@ type UserUsecase interface {
 	CreateUser(payload model.User, role []string) error
 	SaveUser(payload model.User, role []string) error
 	UpdateData(payload *model.User) error
-	BulkInsert(file *multipart.FileHeader) ([]string, error)
-	BulkChangePassword(file *multipart.FileHeader) ([]string, error)
+	BulkInsert(ctx context.Context, file io.Reader, dryRun, assignRoles bool) (*importer.Report, error)
+	BulkChangePassword(ctx context.Context, file io.Reader, dryRun bool) (*importer.Report, error)
 	FindByNik(nik string) (*model.User, error)
+	FindByNiks(niks []string) (map[string]*model.User, error)
 	FindByGenerateToken(generateToken string) (*model.User, error)
 	FindPagination(param request.PaginationParam) ([]model.User, response.Paging, error)
 	FindByProjectIdPagination(param request.PaginationParam, projectId string) ([]model.UserShow, response.Paging, error)
@@ -39,6 +40,7 @@ type userUsecase struct {
 	repo repository.UserRepo
 	role RoleUsecase
 	bu   BusinessUnitUsecase
+	org  OrgHierarchyUsecase
 	cfg  *config.Config
 }
 
@@ -50,6 +52,29 @@ func (u *userUsecase) FindByNik(nik string) (*model.User, error) {
 	return u.repo.SearchByNik(nik)
 }
 
+// FindByNiks looks up many employees in one go, keyed by nik. Niks that are not found are left out of the map.
+func (u *userUsecase) FindByNiks(niks []string) (map[string]*model.User, error) {
+	unique := map[string]bool{}
+	var search []string
+	for _, nik := range niks {
+		if nik != "" && !unique[nik] {
+			unique[nik] = true
+			search = append(search, nik)
+		}
+	}
+
+	users, err := u.repo.SearchByNiks(search)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]*model.User{}
+	for i := range users {
+		result[users[i].Nik] = &users[i]
+	}
+	return result, nil
+}
+
 func (u *userUsecase) FindByGenerateToken(generateToken string) (*model.User, error) {
 	return u.repo.SearchByGenerateToken(generateToken)
 }
@@ -180,170 +205,167 @@ func (u *userUsecase) UpdateData(payload *model.User) error {
 	return u.repo.Update(payload)
 }
 
-func (u *userUsecase) BulkInsert(file *multipart.FileHeader) ([]string, error) {
-	var logs []string
-	var dateLogs []string
-	var buLogs []string
+// BulkInsert creates and updates employees from the upload sheet. Without assignRoles, as for service accounts, rows
+// that carry a role or update a user who has one are rejected.
+func (u *userUsecase) BulkInsert(ctx context.Context, file io.Reader, dryRun, assignRoles bool) (*importer.Report, error) {
+	report, rows, err := importer.Read(file, userImportSchema, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
 	var users []model.User
-	var businessunits []model.BusinessUnit
+	businessUnits := map[string]bool{}
+	roles := map[string]*model.Role{}
+	niks := map[string]int{}
 
-	// Membuka file Excel yang diunggah
-	excelFile, err := file.Open()
+	password, err := utils.SaltPassword([]byte("password"))
 	if err != nil {
 		return nil, err
 	}
-	defer excelFile.Close()
 
-	xlsFile, err := excelize.OpenReader(excelFile)
+	existingUsers, err := u.FindByNiks(columnValues(rows, "Employee NIK"))
 	if err != nil {
 		return nil, err
 	}
 
-	sheetName := xlsFile.GetSheetName(2)
-	rows := xlsFile.GetRows(sheetName)
-
 	for i, row := range rows {
-		passed := true
-		if i == 0 {
-			continue
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
+			return nil, err
 		}
 
-		layout := "01-02-06"
-		parsedTime, _ := time.Parse(layout, row[2])
-		// if err != nil {
-		// dateLogs = append(dateLogs, fmt.Sprintf("Cannot parse date on Employee NIK %s", row[1]))
-		// passed = false
-		// }
-
-		var found bool
-		buId := row[4]
-		for _, bu := range businessunits {
-			if bu.ID == buId {
-				found = true
-				break
-			}
+		nik := row.Get("Employee NIK")
+		if previous, ok := niks[nik]; ok {
+			report.AddError(row.Number, "Employee NIK", nik, fmt.Sprintf("Duplicate of row %d", previous))
+			continue
 		}
+		niks[nik] = row.Number
 
-		if !found {
-			bu, err := u.bu.FindById(buId)
-			if err != nil {
-				buLogs = append(buLogs, fmt.Sprintf("Error Business Unit Id on Row %d", i+1))
-				passed = false
-			} else {
-				businessunits = append(businessunits, *bu)
-			}
+		buId := row.Get("Business Unit ID")
+		if _, ok := businessUnits[buId]; !ok && buId != "" {
+			_, err := u.bu.FindById(buId)
+			businessUnits[buId] = err == nil
+		}
+		if buId != "" && !businessUnits[buId] {
+			report.AddError(row.Number, "Business Unit ID", buId, "Business unit not found")
 		}
 
-		password, err := utils.SaltPassword([]byte("password"))
-		if err != nil {
-			return nil, err
+		roleName := row.Get("Role")
+		if roleName != "" && !assignRoles {
+			report.AddError(row.Number, "Role", roleName, "Roles can't be assigned by a service account")
+			roleName = ""
+		}
+		if _, ok := roles[roleName]; !ok && roleName != "" {
+			roles[roleName], _ = u.role.FindByName(roleName)
+		}
+		if roleName != "" && roles[roleName] == nil {
+			report.AddError(row.Number, "Role", roleName, "Role not found")
 		}
 
+		// existing employees keep their password and the fields the sheet does not carry
 		user := model.User{
-			Email:            row[10],
-			Name:             row[1],
-			Nik:              row[0],
-			DateOfBirth:      time.Time{},
-			SupervisorNik:    row[3],
-			BusinessUnitId:   nil,
-			OrganizationUnit: row[5],
-			Division:         row[6],
-			Department:       row[7],
-			JoinDate:         parsedTime,
-			Grade:            row[9],
-			Position:         row[8],
-			GeneratePassword: false,
-			PhoneNumber:      row[11],
-			ScoringMethod:    row[12],
-			Password:         password,
+			Password: password,
 		}
-
-		role, _ := u.role.FindByName(row[13])
-		// if err != nil {
-		// 	buLogs = append(buLogs, fmt.Sprintf("Error Role Name on Row %d", i+1))
-		// }
-
-		if role != nil {
-			user.Roles = []model.Role{*role}
+		action := importer.ActionCreate
+		if inputedData := existingUsers[nik]; inputedData != nil {
+			if !assignRoles && len(inputedData.Roles) > 0 {
+				report.AddError(row.Number, "Employee NIK", nik, "Users with a role can't be changed by a service account")
+			}
+			user = *inputedData
+			action = importer.ActionUpdate
 		}
 
-		inputedData, _ := u.repo.SearchByNik(row[0])
-
-		if inputedData != nil {
-			user.ID = inputedData.ID
+		user.Email = row.Get("Email")
+		user.Name = row.Get("Name")
+		user.Nik = nik
+		user.SupervisorNik = row.Get("Supervisor NIK")
+		user.BusinessUnitId = &buId
+		user.OrganizationUnit = row.Get("Organization Unit")
+		user.Division = row.Get("Division")
+		user.Department = row.Get("Department")
+		user.JoinDate = row.Date("Join Date")
+		user.Grade = row.Get("Grade")
+		user.Position = row.Get("Position")
+		user.PhoneNumber = row.Get("Phone Number")
+		user.ScoringMethod = row.Get("Scoring Method")
+		if user.ScoringMethod == "" {
+			user.ScoringMethod = "Score"
 		}
-
-		if passed {
-			user.BusinessUnitId = &buId
+		if roles[roleName] != nil {
+			user.Roles = []model.Role{*roles[roleName]}
 		}
+
 		users = append(users, user)
+		report.AddRow(row.Number, nik, action)
 	}
 
-	logs = append(logs, dateLogs...)
-	logs = append(logs, buLogs...)
+	if report.HasErrors() {
+		return report, report.Err()
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	err = u.repo.Bulksave(&users)
 	if err != nil {
 		return nil, err
 	}
-
-	if len(logs) > 0 {
-		return logs, fmt.Errorf("Error when insert data")
-	}
-	return logs, nil
+	report.Committed = true
+	rebuildOrgHierarchy(u.org)
+	return report, nil
 }
 
-func (u *userUsecase) BulkChangePassword(file *multipart.FileHeader) ([]string, error) {
-	var logs []string
-	var users []model.User
-
-	// Membuka file Excel yang diunggah
-	excelFile, err := file.Open()
+func (u *userUsecase) BulkChangePassword(ctx context.Context, file io.Reader, dryRun bool) (*importer.Report, error) {
+	report, rows, err := importer.Read(file, userPasswordImportSchema, dryRun)
 	if err != nil {
 		return nil, err
 	}
-	defer excelFile.Close()
 
-	xlsFile, err := excelize.OpenReader(excelFile)
+	existingUsers, err := u.FindByNiks(columnValues(rows, "Employee NIK"))
 	if err != nil {
 		return nil, err
 	}
 
-	sheetName := xlsFile.GetSheetName(1)
-	rows := xlsFile.GetRows(sheetName)
-
+	var users []model.User
 	for i, row := range rows {
-		if i == 0 {
-			continue
-		}
-
-		password, err := utils.SaltPassword([]byte(row[3]))
-		if err != nil {
+		if err := trackProgress(ctx, i, len(rows)); err != nil {
 			return nil, err
 		}
 
-		inputedData, _ := u.repo.SearchByNik(row[1])
-		if inputedData != nil {
-			inputedData.Password = password
-			users = append(users, *inputedData)
-		} else {
-			logs = append(logs, fmt.Sprintf("Error NIK %s Not found on Row %d", row[1], i))
+		nik := row.Get("Employee NIK")
+		inputedData := existingUsers[nik]
+		if inputedData == nil {
+			report.AddError(row.Number, "Employee NIK", nik, "Employee not found")
+			continue
 		}
 
-	}
-
-	if len(logs) > 0 {
-		fmt.Println("==========len logs=========", len(logs))
-		return logs, fmt.Errorf("Error when insert data")
-	} else {
-		fmt.Println("==========len logs=========", len(logs))
-		err = u.repo.Bulksave(&users)
+		password, err := utils.SaltPassword([]byte(row.Get("Password")))
 		if err != nil {
 			return nil, err
 		}
+		inputedData.Password = password
+		users = append(users, *inputedData)
+		report.AddRow(row.Number, nik, importer.ActionUpdate)
+	}
+
+	if report.HasErrors() {
+		return report, report.Err()
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	return logs, nil
+	err = u.repo.Bulksave(&users)
+	if err != nil {
+		return nil, err
+	}
+	report.Committed = true
+	return report, nil
 }
 
 func (u *userUsecase) GeneratePasswordById(id string) error {
@@ -371,12 +393,14 @@ func NewUserUseCase(
 	repo repository.UserRepo,
 	role RoleUsecase,
 	bu BusinessUnitUsecase,
+	org OrgHierarchyUsecase,
 	cfg *config.Config,
 ) UserUsecase {
 	return &userUsecase{
 		repo: repo,
 		role: role,
 		bu:   bu,
+		org:  org,
 		cfg:  cfg,
 	}
 }
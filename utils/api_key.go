@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	ApiKeyPrefix       = "csk_"
+	apiKeyLookupLength = 8
+)
+
+// GenerateApiKey returns the lookup prefix and the full key, the full key is only ever shown once
+func GenerateApiKey() (string, string, error) {
+	lookup := make([]byte, apiKeyLookupLength/2)
+	if _, err := rand.Read(lookup); err != nil {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix := hex.EncodeToString(lookup)
+	return prefix, fmt.Sprintf("%s%s_%s", ApiKeyPrefix, prefix, hex.EncodeToString(secret)), nil
+}
+
+func SplitApiKey(key string) (string, string, error) {
+	if !strings.HasPrefix(key, ApiKeyPrefix) {
+		return "", "", fmt.Errorf("Api key invalid")
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(key, ApiKeyPrefix), "_", 2)
+	if len(parts) != 2 || len(parts[0]) != apiKeyLookupLength || parts[1] == "" {
+		return "", "", fmt.Errorf("Api key invalid")
+	}
+	return parts[0], parts[1], nil
+}
+
+// HashApiKey uses sha256 instead of bcrypt, the key is 256 bit random and is checked on every request
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
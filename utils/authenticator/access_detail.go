@@ -5,4 +5,10 @@ type AccessDetail struct {
 	Email      string
 	Roles      []string
 	ID         string
+	ActorID    string
+	SubjectID  string
+	Scope      string
+	// Only filled for service account principals authenticated by api key
+	ServiceAccountID string
+	Scopes           []string
 }
@@ -13,23 +13,54 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiKeyAuthenticator interface {
+	Authenticate(key, clientIP string) (*model.ApiKey, error)
+}
+
+// EnrollmentScope is the scope of the token given to a user who has to enroll in two factor before anything else
+const EnrollmentScope = "2fa-enroll"
+
 type AccessToken interface {
 	CreateAccessToken(cred *model.TokenModel) (TokenDetail, error)
+	CreateEnrollmentToken(cred *model.TokenModel) (TokenDetail, error)
+	CreateImpersonationToken(actor *model.TokenModel, subject *model.TokenModel) (TokenDetail, error)
 	VerifyAccessToken(tokenStr string) (AccessDetail, error)
 	StoreAccessToken(username string, tokenDetail TokenDetail) error
 	FetchAccessToken(token string) (string, error)
 	DeleteAccessToken(accessUUID string) error
+	StoreTwoFactorChallenge(userID string) (string, error)
+	FetchTwoFactorChallenge(challenge string) (string, error)
+	DeleteTwoFactorChallenge(challenge string) error
+	CountTwoFactorAttempt(challenge string) (int64, error)
+	CountTwoFactorFailure(userID string) error
+	IsTwoFactorLocked(userID string) bool
+	StoreTwoFactorVerified(accessUUID string) error
+	IsTwoFactorVerified(accessUUID string) bool
+	VerifyApiKey(key, clientIP string) (AccessDetail, error)
 }
 
 type accessToken struct {
-	Config config.Config
-	client *redis.Client
+	Config  config.Config
+	client  *redis.Client
+	apiKeys ApiKeyAuthenticator
 }
 
 func (t *accessToken) CreateAccessToken(cred *model.TokenModel) (TokenDetail, error) {
+	return t.createToken(cred, "", "", t.Config.AccessTokenLifeTime)
+}
+
+func (t *accessToken) CreateEnrollmentToken(cred *model.TokenModel) (TokenDetail, error) {
+	return t.createToken(cred, "", EnrollmentScope, t.Config.EnrollmentLifeTime)
+}
+
+func (t *accessToken) CreateImpersonationToken(actor *model.TokenModel, subject *model.TokenModel) (TokenDetail, error) {
+	return t.createToken(subject, actor.ID, "", t.Config.ImpersonationTokenLifeTime)
+}
+
+func (t *accessToken) createToken(cred *model.TokenModel, actorID, scope string, lifeTime time.Duration) (TokenDetail, error) {
 	tokenDetail := TokenDetail{}
 	tokenDetail.AccessUUID = uuid.New().String()
-	tokenDetail.AtExpired = time.Now().Add(t.Config.AccessTokenLifeTime).Unix()
+	tokenDetail.AtExpired = time.Now().Add(lifeTime).Unix()
 	claims := MyClaims{
 		StandardClaims: jwt.StandardClaims{Issuer: t.Config.ApplicationName},
 		TokenModel: model.TokenModel{
@@ -40,13 +71,19 @@ func (t *accessToken) CreateAccessToken(cred *model.TokenModel) (TokenDetail, er
 			Name:     cred.Name,
 		},
 		AccessUUID: tokenDetail.AccessUUID,
+		Scope:      scope,
+	}
+
+	if actorID != "" {
+		claims.Actor = actorID
+		claims.Subject = cred.ID
 	}
 
 	if cred.Role == nil {
 		claims.TokenModel.Role = []string{}
 	}
 	now := time.Now().UTC()
-	end := now.Add(t.Config.AccessTokenLifeTime)
+	end := now.Add(lifeTime)
 	claims.IssuedAt = now.Unix()
 	claims.ExpiresAt = end.Unix()
 	token := jwt.NewWithClaims(
@@ -87,16 +124,22 @@ func (t *accessToken) VerifyAccessToken(tokenString string) (AccessDetail, error
 		roles = append(roles, r)
 	}
 	id := claims["ID"].(string)
+	actor, _ := claims["Actor"].(string)
+	subject, _ := claims["Subject"].(string)
+	scope, _ := claims["Scope"].(string)
 	return AccessDetail{
 		AccessUUID: uuid,
 		Email:      email,
 		Roles:      roles,
 		ID:         id,
+		ActorID:    actor,
+		SubjectID:  subject,
+		Scope:      scope,
 	}, nil
 }
 
 func (a *accessToken) DeleteAccessToken(accessUUID string) error {
-	rowsAffected, err := a.client.Del(context.Background(), accessUUID).Result()
+	rowsAffected, err := a.client.Del(context.Background(), accessUUID, twoFactorVerifiedKey(accessUUID)).Result()
 	if err != nil {
 		return err
 	} else if rowsAffected == 0 {
@@ -125,9 +168,115 @@ func (a *accessToken) FetchAccessToken(token string) (string, error) {
 	return email, nil
 }
 
-func NewTokenService(config config.Config, client *redis.Client) AccessToken {
+func (a *accessToken) StoreTwoFactorChallenge(userID string) (string, error) {
+	challenge := uuid.New().String()
+	err := a.client.Set(context.Background(), twoFactorChallengeKey(challenge), userID, a.Config.ChallengeLifeTime).Err()
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+func (a *accessToken) FetchTwoFactorChallenge(challenge string) (string, error) {
+	userID, err := a.client.Get(context.Background(), twoFactorChallengeKey(challenge)).Result()
+	if err == redis.Nil || userID == "" {
+		return "", errors.New("Two factor challenge invalid or expired")
+	}
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+func (a *accessToken) DeleteTwoFactorChallenge(challenge string) error {
+	return a.client.Del(context.Background(), twoFactorChallengeKey(challenge), twoFactorAttemptsKey(challenge)).Err()
+}
+
+// CountTwoFactorAttempt counts a code tried against a challenge, the count lives as long as the challenge
+func (a *accessToken) CountTwoFactorAttempt(challenge string) (int64, error) {
+	return a.count(twoFactorAttemptsKey(challenge), a.Config.ChallengeLifeTime)
+}
+
+// CountTwoFactorFailure counts a wrong code given by a signed-in user, the count lives as long as a login challenge
+func (a *accessToken) CountTwoFactorFailure(userID string) error {
+	_, err := a.count(twoFactorFailuresKey(userID), a.Config.ChallengeLifeTime)
+	return err
+}
+
+// IsTwoFactorLocked tells whether a signed-in user gave as many wrong codes as a login challenge takes, an unreadable
+// count locks too
+func (a *accessToken) IsTwoFactorLocked(userID string) bool {
+	count, err := a.client.Get(context.Background(), twoFactorFailuresKey(userID)).Int64()
+	if err == redis.Nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return count >= a.Config.ChallengeAttempts
+}
+
+// count increments a counter that expires lifeTime after its first increment
+func (a *accessToken) count(key string, lifeTime time.Duration) (int64, error) {
+	ctx := context.Background()
+	count, err := a.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := a.client.Expire(ctx, key, lifeTime).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+func (a *accessToken) StoreTwoFactorVerified(accessUUID string) error {
+	return a.client.Set(context.Background(), twoFactorVerifiedKey(accessUUID), time.Now().Unix(), a.Config.StepUpLifeTime).Err()
+}
+
+func (a *accessToken) IsTwoFactorVerified(accessUUID string) bool {
+	count, err := a.client.Exists(context.Background(), twoFactorVerifiedKey(accessUUID)).Result()
+	if err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func (a *accessToken) VerifyApiKey(key, clientIP string) (AccessDetail, error) {
+	apiKey, err := a.apiKeys.Authenticate(key, clientIP)
+	if err != nil {
+		return AccessDetail{}, err
+	}
+	return AccessDetail{
+		AccessUUID:       apiKey.ID,
+		Roles:            []string{model.RoleServiceAccount},
+		ID:               apiKey.ServiceAccountID,
+		ServiceAccountID: apiKey.ServiceAccountID,
+		Scopes:           apiKey.Scopes,
+	}, nil
+}
+
+func twoFactorChallengeKey(challenge string) string {
+	return fmt.Sprintf("2fa-challenge:%s", challenge)
+}
+
+func twoFactorAttemptsKey(challenge string) string {
+	return fmt.Sprintf("2fa-attempts:%s", challenge)
+}
+
+func twoFactorFailuresKey(userID string) string {
+	return fmt.Sprintf("2fa-failures:%s", userID)
+}
+
+func twoFactorVerifiedKey(accessUUID string) string {
+	return fmt.Sprintf("2fa-verified:%s", accessUUID)
+}
+
+func NewTokenService(config config.Config, client *redis.Client, apiKeys ApiKeyAuthenticator) AccessToken {
 	return &accessToken{
-		Config: config,
-		client: client,
+		Config:  config,
+		client:  client,
+		apiKeys: apiKeys,
 	}
 }
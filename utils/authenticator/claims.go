@@ -9,4 +9,8 @@ type MyClaims struct {
 	jwt.StandardClaims
 	model.TokenModel
 	AccessUUID string
+	Actor      string `json:",omitempty"`
+	Subject    string `json:",omitempty"`
+	// Scope limits the token to a few routes, empty is a full session
+	Scope string `json:",omitempty"`
 }
@@ -0,0 +1,234 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatXLSX   = "xlsx"
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// rows are flushed to the client in batches so large reports start downloading right away
+const flushEvery = 200
+
+// Table is a flat report, every row lines up with Headers
+type Table struct {
+	// Sheet names the worksheet when the table is written as xlsx
+	Sheet   string
+	Headers []string
+	Rows    [][]interface{}
+}
+
+func (t *Table) Append(row ...interface{}) {
+	t.Rows = append(t.Rows, row)
+}
+
+// ParseFormat validates ?format=, an empty value means the excel workbook
+func ParseFormat(value string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(value)); format {
+	case "", FormatXLSX:
+		return FormatXLSX, nil
+	case FormatCSV, FormatJSON, FormatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("Unsupported format %s, use xlsx, csv, json or ndjson", value)
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case "zip":
+		return "application/zip"
+	default:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+}
+
+// Write streams the table as a single sheet workbook, csv, a json array or newline delimited json
+func Write(w io.Writer, format string, table *Table) error {
+	switch format {
+	case FormatXLSX:
+		return writeXLSX(w, table)
+	case FormatCSV:
+		return writeCSV(w, table)
+	case FormatJSON, FormatNDJSON:
+		return writeJSON(w, format, table)
+	default:
+		return fmt.Errorf("Unsupported format %s", format)
+	}
+}
+
+// Bytes renders the table in memory, used where the output is stored instead of streamed
+func Bytes(format string, table *Table) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := Write(&buffer, format, table)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeCSV(w io.Writer, table *Table) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(table.Headers)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(table.Headers))
+	for i, row := range table.Rows {
+		for j := range record {
+			record[j] = ""
+			if j < len(row) {
+				record[j] = formatValue(row[j])
+			}
+		}
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+
+		if (i+1)%flushEvery == 0 {
+			writer.Flush()
+			flush(w)
+		}
+	}
+
+	writer.Flush()
+	flush(w)
+	return writer.Error()
+}
+
+func writeJSON(w io.Writer, format string, table *Table) error {
+	// headers are encoded once and objects are built by hand so the keys keep the report column order
+	keys := make([][]byte, len(table.Headers))
+	for i, header := range table.Headers {
+		key, err := json.Marshal(header)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	separator := []byte("\n")
+	if format == FormatJSON {
+		separator = []byte(",")
+		if _, err := w.Write([]byte("[")); err != nil {
+			return err
+		}
+	}
+
+	var object bytes.Buffer
+	for i, row := range table.Rows {
+		object.Reset()
+		if format == FormatJSON && i > 0 {
+			object.Write(separator)
+		}
+		object.WriteByte('{')
+		for j, key := range keys {
+			if j > 0 {
+				object.WriteByte(',')
+			}
+			var value interface{}
+			if j < len(row) {
+				value = row[j]
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			object.Write(key)
+			object.WriteByte(':')
+			object.Write(encoded)
+		}
+		object.WriteByte('}')
+		if format == FormatNDJSON {
+			object.Write(separator)
+		}
+
+		if _, err := w.Write(object.Bytes()); err != nil {
+			return err
+		}
+		if (i+1)%flushEvery == 0 {
+			flush(w)
+		}
+	}
+
+	if format == FormatJSON {
+		if _, err := w.Write([]byte("]")); err != nil {
+			return err
+		}
+	}
+	flush(w)
+	return nil
+}
+
+func writeXLSX(w io.Writer, table *Table) error {
+	workbook := NewWorkbook(w)
+	bold := workbook.AddStyle(Style{Bold: true})
+
+	name := table.Sheet
+	if name == "" {
+		name = "Sheet1"
+	}
+	sheet, err := workbook.AddSheet(name)
+	if err != nil {
+		return err
+	}
+
+	cells := make([]Cell, len(table.Headers))
+	for i, header := range table.Headers {
+		cells[i] = Cell{Value: header, Style: bold}
+	}
+	if err := sheet.WriteRow(1, cells); err != nil {
+		return err
+	}
+
+	for i, row := range table.Rows {
+		cells = cells[:0]
+		for _, value := range row {
+			cells = append(cells, Cell{Value: value})
+		}
+		if err := sheet.WriteRow(i+2, cells); err != nil {
+			return err
+		}
+	}
+	return workbook.Close()
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func flush(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
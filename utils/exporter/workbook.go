@@ -0,0 +1,559 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	sheetNameLimit = 31
+	// drawing sizes are in EMU, 9525 per pixel
+	emuPerPixel = 9525
+)
+
+// Style is a cell format, every registered style is vertically centred like the rest of our reports
+type Style struct {
+	Bold bool
+	// Fill is a hex colour like #F2C4DE
+	Fill string
+}
+
+type Cell struct {
+	Value interface{}
+	Style int
+}
+
+type ChartSeries struct {
+	// Name, Categories and Values are cell references on the same sheet like $J$3 or $H$4:$H$9
+	Name       string
+	Categories string
+	Values     string
+	Color      string
+}
+
+type LineChart struct {
+	Anchor string
+	Width  int
+	Height int
+	Title  string
+	Series []ChartSeries
+	// Max fixes the top of the value axis, zero lets the spreadsheet pick
+	Max int
+}
+
+// Workbook writes an xlsx file to w one row at a time, only the styles, merges and sheet names are kept in memory.
+// Sheets are written in the order they are added and a sheet cannot be changed once the next one is started.
+type Workbook struct {
+	out        io.Writer
+	zip        *zip.Writer
+	sheets     []string
+	styles     []Style
+	styleIndex map[Style]int
+	charts     int
+	current    *Sheet
+}
+
+type Sheet struct {
+	workbook *Workbook
+	name     string
+	index    int
+	entry    io.Writer
+	started  bool
+	lastRow  int
+	widths   map[int]float64
+	merges   []string
+	chart    *LineChart
+	buffer   bytes.Buffer
+}
+
+func NewWorkbook(w io.Writer) *Workbook {
+	return &Workbook{
+		out:        w,
+		zip:        zip.NewWriter(w),
+		styleIndex: map[Style]int{},
+	}
+}
+
+// AddStyle registers a style and returns the id to put on cells
+func (wb *Workbook) AddStyle(style Style) int {
+	if id, ok := wb.styleIndex[style]; ok {
+		return id
+	}
+	wb.styles = append(wb.styles, style)
+	// id 0 is the default format
+	id := len(wb.styles)
+	wb.styleIndex[style] = id
+	return id
+}
+
+// AddSheet finishes the previous sheet and starts a new one, names are cleaned up to what excel accepts
+func (wb *Workbook) AddSheet(name string) (*Sheet, error) {
+	if err := wb.finishSheet(); err != nil {
+		return nil, err
+	}
+
+	name = wb.uniqueSheetName(name)
+	wb.sheets = append(wb.sheets, name)
+	index := len(wb.sheets)
+
+	entry, err := wb.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", index))
+	if err != nil {
+		return nil, err
+	}
+
+	wb.current = &Sheet{
+		workbook: wb,
+		name:     name,
+		index:    index,
+		entry:    entry,
+		widths:   map[int]float64{},
+	}
+	return wb.current, nil
+}
+
+// Close finishes the last sheet and writes the workbook parts, nothing is valid until it returns
+func (wb *Workbook) Close() error {
+	if len(wb.sheets) == 0 {
+		if _, err := wb.AddSheet("Sheet1"); err != nil {
+			return err
+		}
+	}
+	if err := wb.finishSheet(); err != nil {
+		return err
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", wb.workbookXML()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", wb.stylesXML()},
+	}
+	for _, part := range parts {
+		entry, err := wb.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return err
+		}
+	}
+
+	if err := wb.zip.Close(); err != nil {
+		return err
+	}
+	flush(wb.out)
+	return nil
+}
+
+func (s *Sheet) Name() string {
+	return s.name
+}
+
+// SetColWidth only takes effect before the first row is written, columns are counted from 0
+func (s *Sheet) SetColWidth(col int, width float64) {
+	s.widths[col] = width
+}
+
+func (s *Sheet) MergeCell(from, to string) {
+	s.merges = append(s.merges, from+":"+to)
+}
+
+// AddLineChart draws a chart over the sheet, one chart per sheet
+func (s *Sheet) AddLineChart(chart LineChart) {
+	s.chart = &chart
+}
+
+// WriteRow writes the cells of a row from column A, cells without a value or style are left out.
+// Rows are numbered from 1 and have to be written in ascending order.
+func (s *Sheet) WriteRow(row int, cells []Cell) error {
+	if s.workbook.current != s {
+		return fmt.Errorf("Sheet %s is already finished", s.name)
+	}
+	if row <= s.lastRow {
+		return fmt.Errorf("Row %d of sheet %s is written out of order", row, s.name)
+	}
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	s.buffer.Reset()
+	fmt.Fprintf(&s.buffer, `<row r="%d">`, row)
+	for col, cell := range cells {
+		writeCell(&s.buffer, CellName(col, row), cell)
+	}
+	s.buffer.WriteString(`</row>`)
+	if _, err := s.entry.Write(s.buffer.Bytes()); err != nil {
+		return err
+	}
+
+	s.lastRow = row
+	if row%flushEvery == 0 {
+		if err := s.workbook.zip.Flush(); err != nil {
+			return err
+		}
+		flush(s.workbook.out)
+	}
+	return nil
+}
+
+func (s *Sheet) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	var header bytes.Buffer
+	header.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	header.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	if s.index == 1 {
+		header.WriteString(`<sheetViews><sheetView tabSelected="1" workbookViewId="0"/></sheetViews>`)
+	}
+	if len(s.widths) > 0 {
+		header.WriteString(`<cols>`)
+		for col := 0; col <= maxKey(s.widths); col++ {
+			if width, ok := s.widths[col]; ok {
+				fmt.Fprintf(&header, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, col+1, col+1, strconv.FormatFloat(width, 'f', -1, 64))
+			}
+		}
+		header.WriteString(`</cols>`)
+	}
+	header.WriteString(`<sheetData>`)
+	_, err := s.entry.Write(header.Bytes())
+	return err
+}
+
+func (s *Sheet) finish() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	var footer bytes.Buffer
+	footer.WriteString(`</sheetData>`)
+	if len(s.merges) > 0 {
+		fmt.Fprintf(&footer, `<mergeCells count="%d">`, len(s.merges))
+		for _, merge := range s.merges {
+			fmt.Fprintf(&footer, `<mergeCell ref="%s"/>`, merge)
+		}
+		footer.WriteString(`</mergeCells>`)
+	}
+	if s.chart != nil {
+		footer.WriteString(`<drawing r:id="rId1"/>`)
+	}
+	footer.WriteString(`</worksheet>`)
+	if _, err := s.entry.Write(footer.Bytes()); err != nil {
+		return err
+	}
+
+	if s.chart == nil {
+		return nil
+	}
+	return s.workbook.writeChart(s)
+}
+
+func (wb *Workbook) finishSheet() error {
+	if wb.current == nil {
+		return nil
+	}
+	sheet := wb.current
+	err := sheet.finish()
+	wb.current = nil
+	return err
+}
+
+// writeChart adds the drawing, chart and relationship parts behind the chart of a finished sheet
+func (wb *Workbook) writeChart(sheet *Sheet) error {
+	wb.charts++
+	chart := sheet.chart
+	col, row, err := CellIndex(chart.Anchor)
+	if err != nil {
+		return err
+	}
+
+	sheetRef := "'" + strings.ReplaceAll(sheet.name, "'", "''") + "'!"
+	var series bytes.Buffer
+	for i, s := range chart.Series {
+		fmt.Fprintf(&series, `<c:ser><c:idx val="%d"/><c:order val="%d"/>`, i, i)
+		fmt.Fprintf(&series, `<c:tx><c:strRef><c:f>%s</c:f></c:strRef></c:tx>`, escape(sheetRef+s.Name))
+		fmt.Fprintf(&series, `<c:spPr><a:ln w="28575" cap="rnd"><a:solidFill><a:srgbClr val="%s"/></a:solidFill><a:round/></a:ln></c:spPr>`, strings.TrimPrefix(s.Color, "#"))
+		series.WriteString(`<c:marker><c:symbol val="none"/></c:marker>`)
+		fmt.Fprintf(&series, `<c:cat><c:strRef><c:f>%s</c:f></c:strRef></c:cat>`, escape(sheetRef+s.Categories))
+		fmt.Fprintf(&series, `<c:val><c:numRef><c:f>%s</c:f></c:numRef></c:val>`, escape(sheetRef+s.Values))
+		series.WriteString(`<c:smooth val="0"/></c:ser>`)
+	}
+
+	scaling := `<c:orientation val="minMax"/>`
+	if chart.Max > 0 {
+		scaling += fmt.Sprintf(`<c:max val="%d"/><c:min val="0"/>`, chart.Max)
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", sheet.index), `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			fmt.Sprintf(`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing" Target="../drawings/drawing%d.xml"/>`, wb.charts) +
+			`</Relationships>`},
+		{fmt.Sprintf("xl/drawings/drawing%d.xml", wb.charts), `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">` +
+			`<xdr:oneCellAnchor>` +
+			fmt.Sprintf(`<xdr:from><xdr:col>%d</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>%d</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>`, col, row-1) +
+			fmt.Sprintf(`<xdr:ext cx="%d" cy="%d"/>`, chart.Width*emuPerPixel, chart.Height*emuPerPixel) +
+			fmt.Sprintf(`<xdr:graphicFrame macro=""><xdr:nvGraphicFramePr><xdr:cNvPr id="%d" name="Chart %d"/><xdr:cNvGraphicFramePr/></xdr:nvGraphicFramePr>`, wb.charts+1, wb.charts) +
+			`<xdr:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/></xdr:xfrm>` +
+			`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/chart">` +
+			`<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId1"/>` +
+			`</a:graphicData></a:graphic></xdr:graphicFrame><xdr:clientData/></xdr:oneCellAnchor></xdr:wsDr>`},
+		{fmt.Sprintf("xl/drawings/_rels/drawing%d.xml.rels", wb.charts), `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			fmt.Sprintf(`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart" Target="../charts/chart%d.xml"/>`, wb.charts) +
+			`</Relationships>`},
+		{fmt.Sprintf("xl/charts/chart%d.xml", wb.charts), `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<c:chart>` +
+			fmt.Sprintf(`<c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>%s</a:t></a:r></a:p></c:rich></c:tx><c:overlay val="0"/></c:title>`, escape(chart.Title)) +
+			`<c:autoTitleDeleted val="0"/><c:plotArea><c:layout/>` +
+			`<c:lineChart><c:grouping val="standard"/><c:varyColors val="0"/>` + series.String() +
+			`<c:marker val="1"/><c:axId val="100"/><c:axId val="200"/></c:lineChart>` +
+			`<c:catAx><c:axId val="100"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="b"/>` +
+			`<c:numFmt formatCode="General" sourceLinked="1"/><c:tickLblPos val="nextTo"/><c:crossAx val="200"/><c:crosses val="autoZero"/>` +
+			`<c:auto val="1"/><c:lblAlgn val="ctr"/><c:lblOffset val="100"/></c:catAx>` +
+			`<c:valAx><c:axId val="200"/><c:scaling>` + scaling + `</c:scaling><c:delete val="0"/><c:axPos val="l"/><c:majorGridlines/>` +
+			`<c:numFmt formatCode="General" sourceLinked="1"/><c:tickLblPos val="nextTo"/><c:crossAx val="100"/><c:crosses val="autoZero"/>` +
+			`<c:crossBetween val="between"/></c:valAx>` +
+			`</c:plotArea><c:legend><c:legendPos val="b"/><c:overlay val="0"/></c:legend><c:plotVisOnly val="1"/><c:dispBlanksAs val="gap"/>` +
+			`</c:chart></c:chartSpace>`},
+	}
+	for _, part := range parts {
+		entry, err := wb.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wb *Workbook) contentTypes() string {
+	var types bytes.Buffer
+	types.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	types.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	types.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	types.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	types.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	types.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	for i := 1; i <= wb.charts; i++ {
+		fmt.Fprintf(&types, `<Override PartName="/xl/drawings/drawing%d.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"/>`, i)
+		fmt.Fprintf(&types, `<Override PartName="/xl/charts/chart%d.xml" ContentType="application/vnd.openxmlformats-officedocument.drawingml.chart+xml"/>`, i)
+	}
+	types.WriteString(`</Types>`)
+	return types.String()
+}
+
+func (wb *Workbook) workbookXML() string {
+	var workbook bytes.Buffer
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	workbook.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	workbook.WriteString(`<bookViews><workbookView activeTab="0"/></bookViews><sheets>`)
+	for i, name := range wb.sheets {
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(name), i+1, i+1)
+	}
+	workbook.WriteString(`</sheets></workbook>`)
+	return workbook.String()
+}
+
+func (wb *Workbook) workbookRels() string {
+	var rels bytes.Buffer
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.sheets)+1)
+	rels.WriteString(`</Relationships>`)
+	return rels.String()
+}
+
+func (wb *Workbook) stylesXML() string {
+	var fills bytes.Buffer
+	fillIndex := map[string]int{}
+	fills.WriteString(`<fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>`)
+	for _, style := range wb.styles {
+		if _, ok := fillIndex[style.Fill]; style.Fill == "" || ok {
+			continue
+		}
+		fillIndex[style.Fill] = len(fillIndex) + 2
+		fmt.Fprintf(&fills, `<fill><patternFill patternType="solid"><fgColor rgb="FF%s"/><bgColor indexed="64"/></patternFill></fill>`, strings.ToUpper(strings.TrimPrefix(style.Fill, "#")))
+	}
+
+	var xfs bytes.Buffer
+	xfs.WriteString(`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`)
+	for _, style := range wb.styles {
+		font := 0
+		if style.Bold {
+			font = 1
+		}
+		fmt.Fprintf(&xfs, `<xf numFmtId="0" fontId="%d" fillId="%d" borderId="0" xfId="0" applyFont="1" applyFill="1" applyAlignment="1"><alignment vertical="center"/></xf>`, font, fillIndex[style.Fill])
+	}
+
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/><family val="2"/></font><font><b/><sz val="11"/><name val="Calibri"/><family val="2"/></font></fonts>` +
+		fmt.Sprintf(`<fills count="%d">%s</fills>`, len(fillIndex)+2, fills.String()) +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		fmt.Sprintf(`<cellXfs count="%d">%s</cellXfs>`, len(wb.styles)+1, xfs.String()) +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`
+}
+
+// uniqueSheetName drops the characters excel rejects in sheet names, cuts them to 31 characters and numbers duplicates
+func (wb *Workbook) uniqueSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), "'")
+	if name == "" {
+		name = "Sheet"
+	}
+
+	candidate := truncate(name, sheetNameLimit)
+	for i := 2; wb.hasSheet(candidate); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		candidate = truncate(name, sheetNameLimit-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func (wb *Workbook) hasSheet(name string) bool {
+	for _, sheet := range wb.sheets {
+		if strings.EqualFold(sheet, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CellName turns a 0 based column and a 1 based row into a reference like AB12
+func CellName(col, row int) string {
+	return ColumnName(col) + strconv.Itoa(row)
+}
+
+// ColumnName turns a 0 based column into its letters, 0 is A and 26 is AA
+func ColumnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+// CellIndex parses a reference like AB12 into a 0 based column and a 1 based row
+func CellIndex(cell string) (int, int, error) {
+	split := strings.IndexAny(cell, "0123456789")
+	if split <= 0 {
+		return 0, 0, fmt.Errorf("Invalid cell %s", cell)
+	}
+
+	col := 0
+	for _, r := range strings.ToUpper(cell[:split]) {
+		if r < 'A' || r > 'Z' {
+			return 0, 0, fmt.Errorf("Invalid cell %s", cell)
+		}
+		col = col*26 + int(r-'A') + 1
+	}
+	row, err := strconv.Atoi(cell[split:])
+	if err != nil || row < 1 {
+		return 0, 0, fmt.Errorf("Invalid cell %s", cell)
+	}
+	return col - 1, row, nil
+}
+
+func writeCell(buffer *bytes.Buffer, ref string, cell Cell) {
+	style := ""
+	if cell.Style > 0 {
+		style = fmt.Sprintf(` s="%d"`, cell.Style)
+	}
+
+	switch v := cell.Value.(type) {
+	case nil:
+		if style != "" {
+			fmt.Fprintf(buffer, `<c r="%s"%s/>`, ref, style)
+		}
+	case bool:
+		value := "0"
+		if v {
+			value = "1"
+		}
+		fmt.Fprintf(buffer, `<c r="%s"%s t="b"><v>%s</v></c>`, ref, style, value)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprintf(buffer, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+	case float32:
+		writeNumber(buffer, ref, style, float64(v), 32)
+	case float64:
+		writeNumber(buffer, ref, style, v, 64)
+	case string:
+		writeString(buffer, ref, style, v)
+	default:
+		writeString(buffer, ref, style, fmt.Sprint(v))
+	}
+}
+
+func writeNumber(buffer *bytes.Buffer, ref, style string, value float64, bits int) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		writeString(buffer, ref, style, strconv.FormatFloat(value, 'f', -1, bits))
+		return
+	}
+	fmt.Fprintf(buffer, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(value, 'f', -1, bits))
+}
+
+func writeString(buffer *bytes.Buffer, ref, style, value string) {
+	fmt.Fprintf(buffer, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(value))
+}
+
+// escape also replaces characters that are not allowed in xml, free text from the justifications can contain them
+func escape(value string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
+
+func truncate(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return value
+}
+
+func maxKey(values map[int]float64) int {
+	max := -1
+	for key := range values {
+		if key > max {
+			max = key
+		}
+	}
+	return max
+}
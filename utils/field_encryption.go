@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"calibration-system.com/config"
+	"gorm.io/gorm/schema"
+)
+
+// Encrypted values are stored as "enc:<key version>:<base64 nonce+ciphertext>".
+// Anything without the prefix is treated as legacy plaintext so existing rows keep working until re-encrypted.
+const encryptedFieldPrefix = "enc:"
+
+var encryptedTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07",
+}
+
+type fieldCipher struct {
+	mu            sync.RWMutex
+	aeads         map[string]cipher.AEAD
+	activeVersion string
+	blindIndexKey []byte
+}
+
+var fieldEncryption = &fieldCipher{}
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// InitFieldEncryption loads the field keys, it has to run before the first query touching an encrypted column
+func InitFieldEncryption(cfg config.EncryptionConfig) error {
+	aeads := map[string]cipher.AEAD{}
+	for version, secret := range cfg.FieldKeys {
+		if strings.Contains(version, ":") {
+			return fmt.Errorf("Invalid field encryption key version %s", version)
+		}
+
+		key := sha256.Sum256([]byte(secret))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return fmt.Errorf("cipher err: %v", err.Error())
+		}
+
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("cipher GCM err: %v", err.Error())
+		}
+		aeads[version] = gcm
+	}
+
+	if _, ok := aeads[cfg.FieldKeyVersion]; !ok {
+		return fmt.Errorf("Field encryption key %s is not configured", cfg.FieldKeyVersion)
+	}
+
+	fieldEncryption.mu.Lock()
+	defer fieldEncryption.mu.Unlock()
+	fieldEncryption.aeads = aeads
+	fieldEncryption.activeVersion = cfg.FieldKeyVersion
+	fieldEncryption.blindIndexKey = []byte(cfg.BlindIndexKey)
+	return nil
+}
+
+func EncryptField(plainText []byte) (string, error) {
+	fieldEncryption.mu.RLock()
+	defer fieldEncryption.mu.RUnlock()
+
+	gcm, ok := fieldEncryption.aeads[fieldEncryption.activeVersion]
+	if !ok {
+		return "", errors.New("Field encryption is not initialized")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("nonce  err: %v", err.Error())
+	}
+
+	cipherText := gcm.Seal(nonce, nonce, plainText, nil)
+	return encryptedFieldPrefix + fieldEncryption.activeVersion + ":" + base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+func DecryptField(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, encryptedFieldPrefix) {
+		return []byte(value), nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedFieldPrefix), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	fieldEncryption.mu.RLock()
+	gcm, ok := fieldEncryption.aeads[parts[0]]
+	fieldEncryption.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Field encryption key %s is not configured", parts[0])
+	}
+
+	cipherText, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode ciphertext err: %v", err.Error())
+	}
+	if len(cipherText) <= gcm.NonceSize() {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	plainText, err := gcm.Open(nil, cipherText[:gcm.NonceSize()], cipherText[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt field err: %v", err.Error())
+	}
+	return plainText, nil
+}
+
+// NeedsReencryption reports whether a stored value is plaintext or sealed with a retired key
+func NeedsReencryption(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	fieldEncryption.mu.RLock()
+	defer fieldEncryption.mu.RUnlock()
+	return !strings.HasPrefix(value, encryptedFieldPrefix+fieldEncryption.activeVersion+":")
+}
+
+// BlindIndex is a keyed hash of the normalized value, used to look up encrypted columns by equality
+func BlindIndex(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+
+	fieldEncryption.mu.RLock()
+	mac := hmac.New(sha256.New, fieldEncryption.blindIndexKey)
+	fieldEncryption.mu.RUnlock()
+
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptedSerializer seals string, []byte and time.Time fields tagged with `serializer:encrypted`
+type EncryptedSerializer struct{}
+
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType).Elem()
+
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	case time.Time:
+		// column not migrated to text yet
+		fieldValue.Set(reflect.ValueOf(v))
+		field.ReflectValueOf(ctx, dst).Set(fieldValue)
+		return nil
+	default:
+		return fmt.Errorf("failed to decrypt value: %#v", dbValue)
+	}
+
+	if value != "" {
+		plainText, err := DecryptField(value)
+		if err != nil {
+			return err
+		}
+
+		switch fieldValue.Interface().(type) {
+		case string:
+			fieldValue.SetString(string(plainText))
+		case []byte:
+			fieldValue.SetBytes(plainText)
+		case time.Time:
+			parsed, err := parseEncryptedTime(string(plainText))
+			if err != nil {
+				return err
+			}
+			fieldValue.Set(reflect.ValueOf(parsed))
+		default:
+			return fmt.Errorf("invalid field type %s for EncryptedSerializer", field.FieldType)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue)
+	return nil
+}
+
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch v := fieldValue.(type) {
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		return EncryptField([]byte(v))
+	case []byte:
+		if len(v) == 0 {
+			return v, nil
+		}
+		cipherText, err := EncryptField(v)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(cipherText), nil
+	case time.Time:
+		if v.IsZero() {
+			return "", nil
+		}
+		return EncryptField([]byte(v.Format(time.RFC3339Nano)))
+	default:
+		return nil, fmt.Errorf("invalid field type %#v for EncryptedSerializer", v)
+	}
+}
+
+func parseEncryptedTime(value string) (time.Time, error) {
+	for _, layout := range encryptedTimeLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid encrypted time %s", value)
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"calibration-system.com/config"
+)
+
+func initTestFieldEncryption(t *testing.T, keys map[string]string, version string) {
+	t.Helper()
+	err := InitFieldEncryption(config.EncryptionConfig{
+		FieldKeys:       keys,
+		FieldKeyVersion: version,
+		BlindIndexKey:   "blind-index-secret",
+	})
+	if err != nil {
+		t.Fatalf("InitFieldEncryption error: %v", err)
+	}
+}
+
+func TestFieldEncryptionRoundTrip(t *testing.T) {
+	initTestFieldEncryption(t, map[string]string{"v1": "first-secret"}, "v1")
+
+	tests := []string{"", "jane@example.com", "1990-01-02", strings.Repeat("long value ", 100)}
+	for _, plainText := range tests {
+		cipherText, err := EncryptField([]byte(plainText))
+		if err != nil {
+			t.Fatalf("EncryptField(%q) error: %v", plainText, err)
+		}
+		if !strings.HasPrefix(cipherText, "enc:v1:") {
+			t.Errorf("EncryptField(%q) = %s, want the enc:v1: prefix", plainText, cipherText)
+		}
+		decrypted, err := DecryptField(cipherText)
+		if err != nil {
+			t.Fatalf("DecryptField(%s) error: %v", cipherText, err)
+		}
+		if string(decrypted) != plainText {
+			t.Errorf("DecryptField(EncryptField(%q)) = %q", plainText, decrypted)
+		}
+	}
+}
+
+func TestFieldEncryptionRotation(t *testing.T) {
+	initTestFieldEncryption(t, map[string]string{"v1": "first-secret"}, "v1")
+	old, err := EncryptField([]byte("jane@example.com"))
+	if err != nil {
+		t.Fatalf("EncryptField error: %v", err)
+	}
+
+	initTestFieldEncryption(t, map[string]string{"v1": "first-secret", "v2": "second-secret"}, "v2")
+	current, err := EncryptField([]byte("jane@example.com"))
+	if err != nil {
+		t.Fatalf("EncryptField error: %v", err)
+	}
+	if !strings.HasPrefix(current, "enc:v2:") {
+		t.Errorf("EncryptField after rotation = %s, want the enc:v2: prefix", current)
+	}
+
+	for _, cipherText := range []string{old, current} {
+		decrypted, err := DecryptField(cipherText)
+		if err != nil {
+			t.Fatalf("DecryptField(%s) error: %v", cipherText, err)
+		}
+		if string(decrypted) != "jane@example.com" {
+			t.Errorf("DecryptField(%s) = %q", cipherText, decrypted)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"retired key", old, true},
+		{"active key", current, false},
+		{"plaintext", "jane@example.com", true},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := NeedsReencryption(tt.value); got != tt.want {
+			t.Errorf("NeedsReencryption(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// once the old key is dropped its values can't be read anymore
+	initTestFieldEncryption(t, map[string]string{"v2": "second-secret"}, "v2")
+	if _, err := DecryptField(old); err == nil {
+		t.Error("DecryptField with a removed key version returned no error")
+	}
+}
+
+func TestDecryptField(t *testing.T) {
+	initTestFieldEncryption(t, map[string]string{"v1": "first-secret"}, "v1")
+	cipherText, err := EncryptField([]byte("secret"))
+	if err != nil {
+		t.Fatalf("EncryptField error: %v", err)
+	}
+	tampered := cipherText[:len(cipherText)-2] + "AA"
+	if tampered == cipherText {
+		tampered = cipherText[:len(cipherText)-2] + "BB"
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"legacy plaintext", "plain value", "plain value", false},
+		{"sealed", cipherText, "secret", false},
+		{"tampered", tampered, "", true},
+		{"missing version", "enc:abc", "", true},
+		{"unknown version", "enc:v9:" + strings.SplitN(cipherText, ":", 3)[2], "", true},
+		{"not base64", "enc:v1:%%%", "", true},
+		{"too short", "enc:v1:AAAA", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptField(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecryptField error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("DecryptField = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitFieldEncryption(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    map[string]string
+		version string
+	}{
+		{"active version missing", map[string]string{"v1": "first-secret"}, "v2"},
+		{"version with a colon", map[string]string{"v:1": "first-secret"}, "v:1"},
+	}
+	for _, tt := range tests {
+		err := InitFieldEncryption(config.EncryptionConfig{FieldKeys: tt.keys, FieldKeyVersion: tt.version})
+		if err == nil {
+			t.Errorf("InitFieldEncryption(%s) returned no error", tt.name)
+		}
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	initTestFieldEncryption(t, map[string]string{"v1": "first-secret"}, "v1")
+
+	if BlindIndex("") != "" {
+		t.Error("BlindIndex of an empty value is not empty")
+	}
+	if BlindIndex(" Jane@Example.com ") != BlindIndex("jane@example.com") {
+		t.Error("BlindIndex does not normalize case and spaces")
+	}
+	if BlindIndex("jane@example.com") == BlindIndex("john@example.com") {
+		t.Error("BlindIndex of different values collides")
+	}
+}
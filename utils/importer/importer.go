@@ -0,0 +1,323 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ColumnType int
+
+const (
+	String ColumnType = iota
+	Float
+	Date
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+var defaultDateLayouts = []string{"2006-01-02", time.RFC3339, "01-02-06", "01/02/2006"}
+
+// Column describes one header of an upload sheet, columns are matched by header so their order in the file does not matter
+type Column struct {
+	Header      string
+	Aliases     []string
+	Type        ColumnType
+	Required    bool
+	Options     []string
+	Layouts     []string
+	Description string
+}
+
+type Schema struct {
+	Kind string
+	// Sheet is optional, when empty the first sheet whose header row has every required column is used
+	Sheet   string
+	Columns []Column
+}
+
+type Row struct {
+	Number int
+	values map[string]string
+	floats map[string]float64
+	dates  map[string]time.Time
+}
+
+func (r Row) Get(header string) string {
+	return r.values[header]
+}
+
+func (r Row) Float(header string) float64 {
+	return r.floats[header]
+}
+
+func (r Row) Date(header string) time.Time {
+	return r.dates[header]
+}
+
+type RowError struct {
+	Row     int
+	Column  string
+	Value   string
+	Message string
+}
+
+type RowResult struct {
+	Row    int
+	Key    string
+	Action string
+}
+
+// Report is returned for every upload, on dry-run it previews what a commit would create, update or delete
+type Report struct {
+	Kind   string
+	Format string
+	Sheet  string
+	// Columns are the schema headers found in the file, columns missing from the file read as empty
+	Columns   []string
+	DryRun    bool
+	Committed bool
+	TotalRows int
+	Creates   int
+	Updates   int
+	Deletes   int
+	Rows      []RowResult
+	Errors    []RowError
+	// Warnings are worth a look but don't stop the upload
+	Warnings []RowError
+}
+
+func (r *Report) AddError(row int, column, value, message string) {
+	r.Errors = append(r.Errors, RowError{
+		Row:     row,
+		Column:  column,
+		Value:   value,
+		Message: message,
+	})
+}
+
+func (r *Report) AddWarning(row int, column, value, message string) {
+	r.Warnings = append(r.Warnings, RowError{
+		Row:     row,
+		Column:  column,
+		Value:   value,
+		Message: message,
+	})
+}
+
+func (r *Report) AddRow(row int, key, action string) {
+	switch action {
+	case ActionCreate:
+		r.Creates++
+	case ActionUpdate:
+		r.Updates++
+	case ActionDelete:
+		r.Deletes++
+	}
+	r.Rows = append(r.Rows, RowResult{
+		Row:    row,
+		Key:    key,
+		Action: action,
+	})
+}
+
+func (r *Report) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+func (r *Report) Err() error {
+	if !r.HasErrors() {
+		return nil
+	}
+	return fmt.Errorf("%d error(s) found in %s upload, nothing was saved", len(r.Errors), r.Kind)
+}
+
+// Read parses the upload and validates every row against the schema, xlsx, csv and json are told apart by their content.
+// Rows are always returned so the caller can keep checking foreign keys and report every problem at once.
+func Read(file io.Reader, schema Schema, dryRun bool) (*Report, []Row, error) {
+	report := &Report{
+		Kind:   schema.Kind,
+		DryRun: dryRun,
+	}
+
+	format, sheets, err := readSheets(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.Format = format
+
+	sheet, positions := findSheet(sheets, schema)
+	if sheet == nil {
+		var headers []string
+		for _, column := range schema.Columns {
+			if column.Required {
+				headers = append(headers, column.Header)
+			}
+		}
+		report.AddError(1, "", "", fmt.Sprintf("No sheet has the expected columns: %s", strings.Join(headers, ", ")))
+		return report, nil, nil
+	}
+	report.Sheet = sheet.name
+	for _, column := range schema.Columns {
+		if _, ok := positions[column.Header]; ok {
+			report.Columns = append(report.Columns, column.Header)
+		}
+	}
+
+	var rows []Row
+	for i, cells := range sheet.rows {
+		if i == 0 || isEmptyRow(cells) {
+			continue
+		}
+
+		row := Row{
+			Number: i + sheet.firstRow,
+			values: map[string]string{},
+			floats: map[string]float64{},
+			dates:  map[string]time.Time{},
+		}
+		for _, column := range schema.Columns {
+			position, ok := positions[column.Header]
+			var value string
+			if ok && position < len(cells) {
+				value = strings.TrimSpace(cells[position])
+			}
+			row.values[column.Header] = value
+			validateCell(report, &row, column, value)
+		}
+		rows = append(rows, row)
+	}
+	report.TotalRows = len(rows)
+
+	return report, rows, nil
+}
+
+func findSheet(sheets []sheet, schema Schema) (*sheet, map[string]int) {
+	for i := range sheets {
+		if schema.Sheet != "" && sheets[i].name != "" && !strings.EqualFold(sheets[i].name, schema.Sheet) {
+			continue
+		}
+		if len(sheets[i].rows) == 0 {
+			continue
+		}
+
+		positions := mapHeaders(sheets[i].rows[0], schema.Columns)
+		matched := true
+		for _, column := range schema.Columns {
+			if _, ok := positions[column.Header]; column.Required && !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &sheets[i], positions
+		}
+	}
+	return nil, nil
+}
+
+func mapHeaders(headerRow []string, columns []Column) map[string]int {
+	positions := map[string]int{}
+	for i, cell := range headerRow {
+		cell = normalizeHeader(cell)
+		if cell == "" {
+			continue
+		}
+		for _, column := range columns {
+			if _, ok := positions[column.Header]; !ok && matchHeader(cell, column) {
+				positions[column.Header] = i
+				break
+			}
+		}
+	}
+	return positions
+}
+
+func matchHeader(cell string, column Column) bool {
+	if cell == normalizeHeader(column.Header) {
+		return true
+	}
+	for _, alias := range column.Aliases {
+		if cell == normalizeHeader(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHeader(header string) string {
+	replacer := strings.NewReplacer(" ", "", "_", "", "-", "", ".", "")
+	return strings.ToLower(replacer.Replace(strings.TrimSpace(header)))
+}
+
+func isEmptyRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func validateCell(report *Report, row *Row, column Column, value string) {
+	if value == "" {
+		if column.Required {
+			report.AddError(row.Number, column.Header, value, "Value is required")
+		}
+		return
+	}
+
+	switch column.Type {
+	case Float:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			report.AddError(row.Number, column.Header, value, "Value must be a number")
+			return
+		}
+		row.floats[column.Header] = parsed
+	case Date:
+		parsed, err := parseDate(value, column.Layouts)
+		if err != nil {
+			report.AddError(row.Number, column.Header, value, err.Error())
+			return
+		}
+		row.dates[column.Header] = parsed
+	}
+
+	if len(column.Options) > 0 {
+		for _, option := range column.Options {
+			if strings.EqualFold(option, value) {
+				row.values[column.Header] = option
+				return
+			}
+		}
+		report.AddError(row.Number, column.Header, value, fmt.Sprintf("Value must be one of %s", strings.Join(column.Options, ", ")))
+	}
+}
+
+func parseDate(value string, layouts []string) (time.Time, error) {
+	if len(layouts) == 0 {
+		layouts = defaultDateLayouts
+	}
+	for _, layout := range layouts {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+
+	// unformatted date cells come through as the excel serial number
+	serial, err := strconv.ParseFloat(value, 64)
+	if err == nil && serial > 0 {
+		days := math.Floor(serial)
+		return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(days)), nil
+	}
+	return time.Time{}, fmt.Errorf("Date must use format %s", strings.Join(layouts, " or "))
+}
@@ -0,0 +1,206 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+)
+
+const (
+	FormatXLSX = "xlsx"
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// sheet is one table of the upload, the first row holds the headers
+type sheet struct {
+	name string
+	rows [][]string
+	// firstRow turns a slice index into the row number shown in the report
+	firstRow int
+}
+
+func readSheets(file io.Reader) (string, []sheet, error) {
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(512)
+
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		sheets, err := readXLSX(reader)
+		return FormatXLSX, sheets, err
+	}
+
+	// excel and most HRIS exports prefix csv with a byte order mark
+	if bytes.HasPrefix(head, utf8BOM) {
+		reader.Discard(len(utf8BOM))
+		head = head[len(utf8BOM):]
+	}
+
+	if trimmed := bytes.TrimSpace(head); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		sheets, err := readJSON(reader)
+		return FormatJSON, sheets, err
+	}
+
+	sheets, err := readCSV(reader, sniffDelimiter(head))
+	return FormatCSV, sheets, err
+}
+
+func readXLSX(file io.Reader) ([]sheet, error) {
+	xlsFile, err := excelize.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetMap := xlsFile.GetSheetMap()
+	var indexes []int
+	for index := range sheetMap {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var sheets []sheet
+	for _, index := range indexes {
+		sheets = append(sheets, sheet{
+			name:     sheetMap[index],
+			rows:     xlsFile.GetRows(sheetMap[index]),
+			firstRow: 1,
+		})
+	}
+	return sheets, nil
+}
+
+// readCSV keeps every cell as text, so niks keep their leading zeros
+func readCSV(file io.Reader, delimiter rune) ([]sheet, error) {
+	reader := csv.NewReader(file)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid csv: %v", err.Error())
+	}
+	return []sheet{{rows: rows, firstRow: 1}}, nil
+}
+
+// sniffDelimiter picks the most frequent separator on the header line, locales with decimal commas export with ;
+func sniffDelimiter(head []byte) rune {
+	if end := bytes.IndexByte(head, '\n'); end >= 0 {
+		head = head[:end]
+	}
+
+	delimiter := ','
+	count := bytes.Count(head, []byte{','})
+	for _, candidate := range []rune{';', '\t'} {
+		if n := bytes.Count(head, []byte(string(candidate))); n > count {
+			delimiter = candidate
+			count = n
+		}
+	}
+	return delimiter
+}
+
+// readJSON accepts an array of objects or newline delimited objects, keys are matched like sheet headers
+func readJSON(reader *bufio.Reader) ([]sheet, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	var records []map[string]interface{}
+	first, err := firstNonSpace(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if first == '[' {
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("Invalid json: %v", err.Error())
+		}
+		for decoder.More() {
+			var record map[string]interface{}
+			if err := decoder.Decode(&record); err != nil {
+				return nil, fmt.Errorf("Invalid json record %d: %v", len(records)+1, err.Error())
+			}
+			records = append(records, record)
+		}
+	} else {
+		for {
+			var record map[string]interface{}
+			err := decoder.Decode(&record)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("Invalid json record %d: %v", len(records)+1, err.Error())
+			}
+			records = append(records, record)
+		}
+	}
+
+	// keys spelled differently across records, like employee_nik and EmployeeNIK, share one column
+	positions := map[string]int{}
+	var headers []string
+	for _, record := range records {
+		for key := range record {
+			if _, ok := positions[normalizeHeader(key)]; !ok {
+				positions[normalizeHeader(key)] = -1
+				headers = append(headers, key)
+			}
+		}
+	}
+	sort.Strings(headers)
+	for i, header := range headers {
+		positions[normalizeHeader(header)] = i
+	}
+
+	rows := [][]string{headers}
+	for _, record := range records {
+		cells := make([]string, len(headers))
+		for key, value := range record {
+			if cell := jsonCell(value); cell != "" {
+				cells[positions[normalizeHeader(key)]] = cell
+			}
+		}
+		rows = append(rows, cells)
+	}
+
+	// records are numbered from 1, the header row is synthetic
+	return []sheet{{rows: rows, firstRow: 0}}, nil
+}
+
+func firstNonSpace(reader *bufio.Reader) (byte, error) {
+	for i := 1; ; i++ {
+		peek, err := reader.Peek(i)
+		if len(peek) < i {
+			return 0, fmt.Errorf("Invalid json: %v", err)
+		}
+		switch c := peek[i-1]; c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		default:
+			return c, nil
+		}
+	}
+}
+
+func jsonCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
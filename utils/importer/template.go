@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+)
+
+const (
+	instructionSheet = "Instructions"
+	listSheet        = "Lists"
+	// dropdowns and formats are applied to this many data rows
+	templateRows = 1000
+)
+
+// Template builds an empty workbook for the schema with the exact headers, dropdowns for every column with options
+// and an instructions sheet. lists adds options looked up at download time, keyed by column header.
+func Template(schema Schema, lists map[string][]string) (*bytes.Buffer, error) {
+	file := excelize.NewFile()
+	dataSheet := schema.Kind
+	file.SetSheetName("Sheet1", dataSheet)
+	file.NewSheet(instructionSheet)
+	file.NewSheet(listSheet)
+
+	headerStyle, err := file.NewStyle(`{"font":{"bold":true},"fill":{"type":"pattern","color":["#D9E1F2"],"pattern":1}}`)
+	if err != nil {
+		return nil, err
+	}
+
+	listCol := 0
+	for i, column := range schema.Columns {
+		colName := excelize.ToAlphaString(i)
+		cell := fmt.Sprintf("%s1", colName)
+		file.SetCellValue(dataSheet, cell, column.Header)
+		file.SetCellStyle(dataSheet, cell, cell, headerStyle)
+		file.SetColWidth(dataSheet, colName, colName, float64(len(column.Header)+6))
+
+		options := append(append([]string{}, column.Options...), lists[column.Header]...)
+		if len(options) == 0 {
+			continue
+		}
+
+		listColName := excelize.ToAlphaString(listCol)
+		file.SetCellValue(listSheet, fmt.Sprintf("%s1", listColName), column.Header)
+		for j, option := range options {
+			file.SetCellValue(listSheet, fmt.Sprintf("%s%d", listColName, j+2), option)
+		}
+		listCol++
+
+		dataValidation := excelize.NewDataValidation(!column.Required)
+		dataValidation.Sqref = fmt.Sprintf("%s2:%s%d", colName, colName, templateRows+1)
+		// excelize refuses cross-sheet sources, the formula itself is valid for excel
+		dataValidation.SetSqrefDropList(fmt.Sprintf("%s!$%s$2:$%s$%d", listSheet, listColName, listColName, len(options)+1), true)
+		dataValidation.SetError(excelize.DataValidationErrorStyleStop, column.Header, "Pick a value from the list")
+		file.AddDataValidation(dataSheet, dataValidation)
+	}
+
+	instructions := [][]string{
+		{fmt.Sprintf("Fill the %s sheet, one row per record starting on row 2.", dataSheet)},
+		{"Columns are matched by header name, do not rename the headers. Extra columns are ignored."},
+		{"Upload with ?dryRun=true first to preview creates, updates and row errors without saving."},
+		{"Nothing is saved when any row has an error."},
+		{},
+		{"Column", "Required", "Format", "Description"},
+	}
+	for _, column := range schema.Columns {
+		required := "No"
+		if column.Required {
+			required = "Yes"
+		}
+		instructions = append(instructions, []string{column.Header, required, columnFormat(column, lists[column.Header]), column.Description})
+	}
+
+	for i, line := range instructions {
+		row := line
+		file.SetSheetRow(instructionSheet, fmt.Sprintf("A%d", i+1), &row)
+	}
+	file.SetCellStyle(instructionSheet, "A6", "D6", headerStyle)
+	file.SetColWidth(instructionSheet, "A", "A", 30)
+	file.SetColWidth(instructionSheet, "C", "D", 50)
+
+	file.SetSheetVisible(listSheet, false)
+	file.SetActiveSheet(1)
+
+	return file.WriteToBuffer()
+}
+
+func columnFormat(column Column, list []string) string {
+	switch {
+	case column.Type == Float:
+		return "Number"
+	case column.Type == Date:
+		layouts := column.Layouts
+		if len(layouts) == 0 {
+			layouts = defaultDateLayouts
+		}
+		return fmt.Sprintf("Date, %s", strings.Join(layouts, " or "))
+	case len(column.Options) > 0:
+		return fmt.Sprintf("One of %s", strings.Join(column.Options, ", "))
+	case len(list) > 0:
+		return fmt.Sprintf("One of the %s dropdown values", column.Header)
+	}
+	return "Text"
+}